@@ -6,9 +6,13 @@ import (
 	"log"
 	"os"
 
+	"backend/internal/adapter/auth"
+	"backend/internal/adapter/blob"
 	"backend/internal/adapter/http"
 	"backend/internal/adapter/http/handler"
 	"backend/internal/adapter/http/middleware"
+	"backend/internal/adapter/mail"
+	"backend/internal/adapter/pdf"
 	"backend/internal/adapter/repositories"
 	"backend/internal/adapter/store/postgres/repository"
 	"backend/internal/config"
@@ -51,7 +55,7 @@ func main() {
 	pong, err := redisClient.Ping(ctx).Result()
 	if err != nil {
 		log.Printf("Warning: Failed to connect to Redis: %v", err)
-		log.Println("App will continue without Redis (email scheduling disabled)")
+		log.Println("App will continue without Redis (scheduled emails are sent from the outbox only)")
 		redisClient = nil
 	} else {
 		log.Printf("Redis connection successful: %s", pong)
@@ -65,31 +69,83 @@ func main() {
 	}
 
 	// Set up repositories
+	userRepo := repository.NewUserRepository(db)
 	requestRepo := repository.NewRequestRepository(db)
 	staffRequirementRepo := repository.NewStaffRequirementRepository(db)
 	customLineItemsRepo := repository.NewCustomLineItemsRepository(db)
-	rateCalculatorRepo := repository.NewRateCalculatorRepository(staffRequirementRepo, customLineItemsRepo)
+	pricingPolicyRepo := repository.NewPricingPolicyRepository(db)
+	promoCodeRepo := repository.NewPromoCodeRepository(db)
+	surchargeRepo := repository.NewSurchargeRepository(db)
+	rateRepo := repository.NewRateRepository(db)
+	dunningRepo := repository.NewDunningRepository(db)
+	rateCalculatorRepo := repository.NewRateCalculatorRepository(staffRequirementRepo, customLineItemsRepo, pricingPolicyRepo)
 	invoiceRepo := repository.NewInvoiceRepository(db, rateCalculatorRepo)
-	emailRepo := repository.NewEmailRepository(os.Getenv("MAILGUN_DOMAIN"), os.Getenv("MAILGUN_FROM"), os.Getenv("MAILGUN_API_KEY"), redisClient)
-	stripeRepo := repository.NewStripeRepository(db)
+	emailOutboxRepo := repository.NewEmailOutboxRepository(db, redisClient)
+	emailLogRepo := repository.NewEmailLogRepository(db)
+	mailTransport, err := mail.NewTransport(cfg.Email)
+	if err != nil {
+		log.Printf("Warning: email sending disabled: %v", err)
+	}
+	emailTemplateRepo := repository.NewEmailTemplateRepository(db)
+	emailRenderer := mail.NewTemplateRenderer(emailTemplateRepo)
+	emailAttachmentRepo := repository.NewEmailAttachmentRepository(db)
+	blobStore, err := blob.NewBlobStore(ctx, cfg)
+	if err != nil {
+		log.Printf("Warning: email attachments disabled: %v", err)
+	}
+	invoicePDFRepo := repository.NewInvoicePDFRepository(staffRequirementRepo, customLineItemsRepo, pdf.NewInvoiceRenderer(), cfg.TermsAndConditions)
+	emailRepo := repository.NewEmailRepository(mailTransport, cfg.Email.From, emailRenderer, emailOutboxRepo, emailLogRepo, emailAttachmentRepo, blobStore, invoicePDFRepo)
+	paymentLedgerRepo := repository.NewPaymentLedgerRepository(db)
+	clientRepo := repository.NewClientRepository(db)
+	eventRepo := repository.NewEventRepository(db)
+	shiftRepo := repository.NewShiftRepository(db)
+	stripeRepo := repository.NewStripeRepository(db, paymentLedgerRepo, clientRepo)
 	// stripeStore := repository.NewStripeStoreAdapter(stripeRepo, db)
 	// Set up services
 	geolocationRepo := repository.NewGeolocationRepository(os.Getenv("MAPBOX_TOKEN"), db)
 	geolocationService := services.NewGeolocationService(geolocationRepo)
-	staffRequirementService := services.NewStaffRequirementService(staffRequirementRepo)
-	invoiceService := services.NewInvoiceService(invoiceRepo, requestRepo, rateCalculatorRepo, cfg)
-	requestService := services.NewRequestService(requestRepo, geolocationService, staffRequirementService, invoiceService)
-	emailService := services.NewEmailService(emailRepo)
-	stripeService := services.NewStripeService(stripeRepo)
+	surchargeService := services.NewSurchargeService(surchargeRepo)
+	rateService := services.NewRateService(rateRepo)
+	staffRequirementService := services.NewStaffRequirementService(staffRequirementRepo, requestRepo, surchargeService, rateService)
+	invoiceService := services.NewInvoiceService(invoiceRepo, requestRepo, rateCalculatorRepo, promoCodeRepo, invoicePDFRepo, cfg)
+	requestService := services.NewRequestService(requestRepo, geolocationService, staffRequirementService, invoiceService, surchargeService, rateService)
+	emailService := services.NewEmailService(emailRepo, emailOutboxRepo, emailLogRepo)
+	stripeService := services.NewStripeService(stripeRepo, paymentLedgerRepo)
 	customLineItemsService := services.NewCustomLineItemsService(customLineItemsRepo)
 	calculateRatesService := services.NewCalculateRatesService(rateCalculatorRepo)
+	pricingPolicyService := services.NewPricingPolicyService(pricingPolicyRepo)
+	promoCodeService := services.NewPromoCodeService(promoCodeRepo)
+	emailTemplateService := services.NewEmailTemplateService(emailTemplateRepo, emailRenderer)
+	dunningService := services.NewDunningService(dunningRepo, emailService, stripeService, staffRequirementService)
+	cancellationService := services.NewCancellationService(requestRepo, invoiceService, stripeService, cfg)
+	clientService := services.NewClientService(clientRepo, invoiceService, stripeService)
+	userService := services.NewUserService(userRepo)
+	eventService := services.NewEventService(eventRepo, requestRepo, staffRequirementRepo)
+	shiftService := services.NewShiftService(shiftRepo, eventRepo)
 
 	// Set up cron system for scheduled emails
-	cronRepo := repository.NewCronRepository(db, redisClient, emailRepo)
+	cronRepo := repository.NewCronRepository(db, redisClient, emailRepo, emailOutboxRepo)
 	cronService := services.NewCronService(cronRepo)
+	if err := cronRepo.Schedule("@every 1h", "payment reminders", dunningService.SendDueReminders); err != nil {
+		log.Fatalf("Failed to schedule payment reminders: %v", err)
+	}
+	if err := cronRepo.Schedule("@every 1h", "overdue invoices", invoiceService.MarkOverdueInvoices); err != nil {
+		log.Fatalf("Failed to schedule overdue invoice sweep: %v", err)
+	}
+	if err := cronRepo.Schedule("@every 1h", "post-event charges", clientService.ChargeBalancesAfterEvents); err != nil {
+		log.Fatalf("Failed to schedule post-event charges: %v", err)
+	}
+	if err := cronRepo.Schedule("@every 15m", "event scheduling", eventService.ScheduleConfirmedRequests); err != nil {
+		log.Fatalf("Failed to schedule event scheduling: %v", err)
+	}
 
 	// Set up middleware
-	middlewareService := middleware.NewMiddlewareService(cfg, sessionAdapter, nil, nil, nil)
+	tokenService, err := auth.NewCognitoTokenService(cfg.Cognito)
+	if err != nil {
+		log.Printf("Warning: sign in disabled, every protected route will answer 401: %v", err)
+		tokenService = nil
+	}
+	middlewareService := middleware.NewMiddlewareService(cfg, sessionAdapter, userRepo, tokenService, nil)
 	middlewareImpl := middlewareService.(*middleware.MiddlewareService)
 
 	// Set up handlers
@@ -100,8 +156,19 @@ func main() {
 	emailHandler := handler.NewEmailHandler(emailService, invoiceService, staffRequirementService, stripeService)
 	stripeHandler := handler.NewStripeHandler(stripeService, invoiceService, staffRequirementService, os.Getenv("STRIPE_API_KEY"))
 	customLineItemsHandler := handler.NewCustomLineItemsHandler(cfg, customLineItemsService)
-	calculateRatesHandler := handler.NewCalculateRatesHandler(calculateRatesService, requestService)
+	calculateRatesHandler := handler.NewCalculateRatesHandler(calculateRatesService, requestService, invoiceService)
 	cronHandler := handler.NewCronHandler(cronService)
+	pricingPolicyHandler := handler.NewPricingPolicyHandler(pricingPolicyService)
+	promoCodeHandler := handler.NewPromoCodeHandler(promoCodeService)
+	surchargeHandler := handler.NewSurchargeHandler(surchargeService)
+	rateHandler := handler.NewRateHandler(rateService)
+	dunningHandler := handler.NewDunningHandler(dunningService)
+	emailTemplateHandler := handler.NewEmailTemplateHandler(emailTemplateService)
+	cancellationHandler := handler.NewCancellationHandler(cancellationService)
+	clientHandler := handler.NewClientHandler(clientService)
+	userHandler := handler.NewUserHandler(userService)
+	eventHandler := handler.NewEventHandler(eventService)
+	shiftHandler := handler.NewShiftHandler(shiftService)
 
 	// Set up router
 	router := http.NewRouter(
@@ -110,6 +177,8 @@ func main() {
 		requestHandler,
 		geolocationHandler,
 		invoiceHandler,
+		eventHandler,
+		shiftHandler,
 		staffRequirementHandler,
 		middlewareImpl,
 		emailHandler,
@@ -117,6 +186,15 @@ func main() {
 		customLineItemsHandler,
 		calculateRatesHandler,
 		cronHandler,
+		pricingPolicyHandler,
+		promoCodeHandler,
+		surchargeHandler,
+		rateHandler,
+		dunningHandler,
+		emailTemplateHandler,
+		cancellationHandler,
+		clientHandler,
+		userHandler,
 	)
 
 	// Start cron jobs for scheduled email processing
@@ -0,0 +1,301 @@
+// verifies the ID and access tokens Cognito issues to people signing in to the dashboard
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+
+	"backend/internal/config"
+	"backend/internal/core/models"
+	ports "backend/internal/core/ports"
+)
+
+const (
+	// clock drift allowed between us and Cognito
+	clockLeeway = time.Minute
+	// keys are refetched this often, Cognito rotates them rarely
+	jwksMaxAge = 24 * time.Hour
+	// an unknown kid refetches the keys, at most this often so bad tokens can't hammer Cognito.
+	// Stale keys wait this long after a failed refetch before trying again.
+	jwksMinRefresh = time.Minute
+	// a refetch is shared by every caller waiting on it, so it has a deadline of its own
+	jwksFetchTimeout = 10 * time.Second
+)
+
+type CognitoTokenService struct {
+	issuer    string
+	jwksURL   string
+	clientIDs []string
+	client    *http.Client
+
+	// refetches happen outside mu, one at a time however many requests need them
+	refetch singleflight.Group
+
+	mu        sync.Mutex
+	keys      map[string]*rsa.PublicKey
+	fetchedAt time.Time
+	failedAt  time.Time
+}
+
+// NewCognitoTokenService checks tokens from the configured user pool
+func NewCognitoTokenService(cfg *config.Cognito) (ports.TokenService, error) {
+	if cfg == nil {
+		return nil, errors.New("cognito is not configured")
+	}
+
+	issuer := cfg.Issuer
+	if issuer == "" {
+		if cfg.Region == "" || cfg.UserPoolID == "" {
+			return nil, errors.New("COGNITO_REGION and COGNITO_USER_POOL_ID are required")
+		}
+		issuer = fmt.Sprintf("https://cognito-idp.%s.amazonaws.com/%s", cfg.Region, cfg.UserPoolID)
+	}
+	if len(cfg.ClientIDs) == 0 {
+		return nil, errors.New("COGNITO_CLIENT_IDS is required")
+	}
+
+	jwksURL := cfg.JWKSURL
+	if jwksURL == "" {
+		jwksURL = issuer + "/.well-known/jwks.json"
+	}
+
+	return &CognitoTokenService{
+		issuer:    issuer,
+		jwksURL:   jwksURL,
+		clientIDs: cfg.ClientIDs,
+		client:    &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+type tokenHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+type tokenClaims struct {
+	Issuer    string   `json:"iss"`
+	Subject   string   `json:"sub"`
+	Audience  string   `json:"aud"`
+	ClientID  string   `json:"client_id"`
+	TokenUse  string   `json:"token_use"`
+	ExpiresAt int64    `json:"exp"`
+	IssuedAt  int64    `json:"iat"`
+	NotBefore int64    `json:"nbf"`
+	Email     string   `json:"email"`
+	Groups    []string `json:"cognito:groups"`
+	// Cognito sends a bool, older pools a "true" string
+	EmailVerified json.RawMessage `json:"email_verified"`
+}
+
+func (s *CognitoTokenService) VerifyToken(ctx context.Context, tokenString string) (*models.TokenPayload, error) {
+	parts := strings.Split(tokenString, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: malformed", models.ErrInvalidToken)
+	}
+
+	var header tokenHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("%w: bad header: %v", models.ErrInvalidToken, err)
+	}
+	if header.Alg != "RS256" {
+		return nil, fmt.Errorf("%w: unexpected algorithm %q", models.ErrInvalidToken, header.Alg)
+	}
+
+	key, err := s.key(ctx, header.Kid)
+	if err != nil {
+		return nil, err
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: bad signature encoding", models.ErrInvalidToken)
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature); err != nil {
+		return nil, fmt.Errorf("%w: bad signature", models.ErrInvalidToken)
+	}
+
+	var claims tokenClaims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("%w: bad claims: %v", models.ErrInvalidToken, err)
+	}
+	if err := s.checkClaims(&claims, time.Now()); err != nil {
+		return nil, err
+	}
+
+	return &models.TokenPayload{
+		Subject:       claims.Subject,
+		Email:         claims.Email,
+		EmailVerified: strings.Trim(string(claims.EmailVerified), `"`) == "true",
+		TokenUse:      claims.TokenUse,
+		Groups:        claims.Groups,
+		ExpiresAt:     time.Unix(claims.ExpiresAt, 0).UTC(),
+	}, nil
+}
+
+// checkClaims makes sure the token is from our pool, for one of our app clients and current
+func (s *CognitoTokenService) checkClaims(claims *tokenClaims, now time.Time) error {
+	if claims.Issuer != s.issuer {
+		return fmt.Errorf("%w: unexpected issuer %q", models.ErrInvalidToken, claims.Issuer)
+	}
+	if claims.Subject == "" {
+		return fmt.Errorf("%w: no subject", models.ErrInvalidToken)
+	}
+
+	// ID tokens name the app client in aud, access tokens in client_id
+	switch claims.TokenUse {
+	case "id":
+		if !slices.Contains(s.clientIDs, claims.Audience) {
+			return fmt.Errorf("%w: unexpected audience %q", models.ErrInvalidToken, claims.Audience)
+		}
+	case "access":
+		if !slices.Contains(s.clientIDs, claims.ClientID) {
+			return fmt.Errorf("%w: unexpected client %q", models.ErrInvalidToken, claims.ClientID)
+		}
+	default:
+		return fmt.Errorf("%w: unexpected token_use %q", models.ErrInvalidToken, claims.TokenUse)
+	}
+
+	if claims.ExpiresAt == 0 || now.After(time.Unix(claims.ExpiresAt, 0).Add(clockLeeway)) {
+		return fmt.Errorf("%w: expired", models.ErrInvalidToken)
+	}
+	if claims.NotBefore != 0 && now.Add(clockLeeway).Before(time.Unix(claims.NotBefore, 0)) {
+		return fmt.Errorf("%w: not valid yet", models.ErrInvalidToken)
+	}
+	if claims.IssuedAt != 0 && now.Add(clockLeeway).Before(time.Unix(claims.IssuedAt, 0)) {
+		return fmt.Errorf("%w: issued in the future", models.ErrInvalidToken)
+	}
+	return nil
+}
+
+// key returns the signing key with the kid, refetching the JWKS when it is stale or the kid
+// is new to us
+func (s *CognitoTokenService) key(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	s.mu.Lock()
+	key, ok := s.keys[kid]
+	fetchedAt, failedAt := s.fetchedAt, s.failedAt
+	s.mu.Unlock()
+
+	if ok {
+		if time.Since(fetchedAt) <= jwksMaxAge || time.Since(failedAt) < jwksMinRefresh {
+			return key, nil
+		}
+	} else if time.Since(fetchedAt) < jwksMinRefresh {
+		return nil, fmt.Errorf("%w: unknown key %q", models.ErrInvalidToken, kid)
+	}
+
+	var err error
+	select {
+	case result := <-s.refetch.DoChan("jwks", s.refetchKeys):
+		err = result.Err
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	if err != nil {
+		if ok {
+			// keep using the key we have until Cognito answers again
+			return key, nil
+		}
+		return nil, err
+	}
+
+	s.mu.Lock()
+	key, ok = s.keys[kid]
+	s.mu.Unlock()
+	if ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("%w: unknown key %q", models.ErrInvalidToken, kid)
+}
+
+// refetchKeys replaces the keys with Cognito's current set. It doesn't use any one request's
+// context, a caller giving up mustn't fail the fetch for the others waiting on it.
+func (s *CognitoTokenService) refetchKeys() (interface{}, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), jwksFetchTimeout)
+	defer cancel()
+
+	keys, err := s.fetchKeys(ctx)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err != nil {
+		s.failedAt = time.Now()
+		return nil, err
+	}
+	s.keys = keys
+	s.fetchedAt = time.Now()
+	return nil, nil
+}
+
+type jwks struct {
+	Keys []struct {
+		Kid string `json:"kid"`
+		Kty string `json:"kty"`
+		Alg string `json:"alg"`
+		Use string `json:"use"`
+		N   string `json:"n"`
+		E   string `json:"e"`
+	} `json:"keys"`
+}
+
+func (s *CognitoTokenService) fetchKeys(ctx context.Context) (map[string]*rsa.PublicKey, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.jwksURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build JWKS request: %w", err)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch JWKS: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch JWKS: %s", resp.Status)
+	}
+
+	var set jwks
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return nil, fmt.Errorf("failed to parse JWKS: %w", err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" || (k.Use != "" && k.Use != "sig") {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("bad modulus for key %q: %w", k.Kid, err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, fmt.Errorf("bad exponent for key %q: %w", k.Kid, err)
+		}
+		keys[k.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+	return keys, nil
+}
+
+func decodeSegment(segment string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
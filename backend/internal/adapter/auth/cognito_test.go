@@ -0,0 +1,398 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"backend/internal/config"
+	"backend/internal/core/models"
+)
+
+const (
+	testIssuer   = "https://cognito-idp.eu-west-2.amazonaws.com/eu-west-2_test"
+	testClientID = "dashboard-client"
+)
+
+// testJWKS serves the public halves of its keys and counts how often it is asked. With a gate,
+// each request signals arrived and then waits for the gate to close.
+type testJWKS struct {
+	mu      sync.Mutex
+	keys    map[string]*rsa.PrivateKey
+	fetches int
+	failing bool
+	arrived chan struct{}
+	gate    chan struct{}
+}
+
+func (j *testJWKS) setKey(kid string, key *rsa.PrivateKey) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.keys[kid] = key
+}
+
+func (j *testJWKS) setFailing(failing bool) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.failing = failing
+}
+
+func (j *testJWKS) fetchCount() int {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.fetches
+}
+
+func (j *testJWKS) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	j.mu.Lock()
+	arrived, gate := j.arrived, j.gate
+	j.mu.Unlock()
+	if gate != nil {
+		arrived <- struct{}{}
+		<-gate
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.fetches++
+	if j.failing {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		return
+	}
+
+	var set jwks
+	for kid, key := range j.keys {
+		set.Keys = append(set.Keys, struct {
+			Kid string `json:"kid"`
+			Kty string `json:"kty"`
+			Alg string `json:"alg"`
+			Use string `json:"use"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		}{
+			Kid: kid,
+			Kty: "RSA",
+			Alg: "RS256",
+			Use: "sig",
+			N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		})
+	}
+	json.NewEncoder(w).Encode(set)
+}
+
+func newKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	return key
+}
+
+// newTestService points a token service at a JWKS server holding key under kid "current"
+func newTestService(t *testing.T, key *rsa.PrivateKey) (*CognitoTokenService, *testJWKS) {
+	t.Helper()
+	set := &testJWKS{keys: map[string]*rsa.PrivateKey{"current": key}}
+	server := httptest.NewServer(set)
+	t.Cleanup(server.Close)
+
+	svc, err := NewCognitoTokenService(&config.Cognito{
+		Issuer:    testIssuer,
+		ClientIDs: []string{testClientID},
+		JWKSURL:   server.URL,
+	})
+	if err != nil {
+		t.Fatalf("failed to create token service: %v", err)
+	}
+	return svc.(*CognitoTokenService), set
+}
+
+func idClaims(now time.Time) map[string]any {
+	return map[string]any{
+		"iss":            testIssuer,
+		"sub":            "user-sub",
+		"aud":            testClientID,
+		"token_use":      "id",
+		"exp":            now.Add(time.Hour).Unix(),
+		"iat":            now.Unix(),
+		"email":          "person@example.com",
+		"email_verified": true,
+	}
+}
+
+func sign(t *testing.T, key *rsa.PrivateKey, alg, kid string, claims map[string]any) string {
+	t.Helper()
+	encode := func(v any) string {
+		data, err := json.Marshal(v)
+		if err != nil {
+			t.Fatalf("failed to encode token: %v", err)
+		}
+		return base64.RawURLEncoding.EncodeToString(data)
+	}
+
+	signed := encode(map[string]string{"alg": alg, "kid": kid}) + "." + encode(claims)
+	digest := sha256.Sum256([]byte(signed))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func TestVerifyTokenAcceptsIDAndAccessTokens(t *testing.T) {
+	key := newKey(t)
+	svc, _ := newTestService(t, key)
+	now := time.Now()
+
+	payload, err := svc.VerifyToken(context.Background(), sign(t, key, "RS256", "current", idClaims(now)))
+	if err != nil {
+		t.Fatalf("id token rejected: %v", err)
+	}
+	if payload.Subject != "user-sub" || payload.Email != "person@example.com" || !payload.EmailVerified || payload.TokenUse != "id" {
+		t.Fatalf("unexpected payload %+v", payload)
+	}
+
+	// older pools send email_verified as a string
+	claims := idClaims(now)
+	claims["email_verified"] = "true"
+	payload, err = svc.VerifyToken(context.Background(), sign(t, key, "RS256", "current", claims))
+	if err != nil || !payload.EmailVerified {
+		t.Fatalf("string email_verified: payload %+v, err %v", payload, err)
+	}
+
+	access := map[string]any{
+		"iss":       testIssuer,
+		"sub":       "user-sub",
+		"client_id": testClientID,
+		"token_use": "access",
+		"exp":       now.Add(time.Hour).Unix(),
+	}
+	payload, err = svc.VerifyToken(context.Background(), sign(t, key, "RS256", "current", access))
+	if err != nil {
+		t.Fatalf("access token rejected: %v", err)
+	}
+	if payload.TokenUse != "access" || payload.Email != "" {
+		t.Fatalf("unexpected payload %+v", payload)
+	}
+}
+
+func TestVerifyTokenRejectsBadSignatures(t *testing.T) {
+	key := newKey(t)
+	svc, _ := newTestService(t, key)
+	claims := idClaims(time.Now())
+	valid := sign(t, key, "RS256", "current", claims)
+	parts := strings.Split(valid, ".")
+
+	tampered := idClaims(time.Now())
+	tampered["sub"] = "someone-else"
+	tamperedPayload := strings.Split(sign(t, key, "RS256", "current", tampered), ".")[1]
+
+	tests := map[string]string{
+		"signed by another key": sign(t, newKey(t), "RS256", "current", claims),
+		"claims swapped":        parts[0] + "." + tamperedPayload + "." + parts[2],
+		"signature dropped":     parts[0] + "." + parts[1] + ".",
+		"not base64 signature":  parts[0] + "." + parts[1] + ".!!!",
+		"not RS256":             sign(t, key, "HS256", "current", claims),
+		"malformed":             parts[0] + "." + parts[1],
+	}
+	for name, token := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := svc.VerifyToken(context.Background(), token); !errors.Is(err, models.ErrInvalidToken) {
+				t.Fatalf("got %v, want ErrInvalidToken", err)
+			}
+		})
+	}
+}
+
+func TestCheckClaims(t *testing.T) {
+	svc := &CognitoTokenService{issuer: testIssuer, clientIDs: []string{testClientID}}
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		change func(c *tokenClaims)
+		valid  bool
+	}{
+		{"valid id token", func(c *tokenClaims) {}, true},
+		{"valid access token", func(c *tokenClaims) { c.TokenUse, c.Audience, c.ClientID = "access", "", testClientID }, true},
+		{"wrong issuer", func(c *tokenClaims) { c.Issuer = "https://cognito-idp.eu-west-2.amazonaws.com/other" }, false},
+		{"no subject", func(c *tokenClaims) { c.Subject = "" }, false},
+		{"id token for another client", func(c *tokenClaims) { c.Audience = "other-client" }, false},
+		// an id token names its client in aud, client_id doesn't count
+		{"id token with client_id only", func(c *tokenClaims) { c.Audience, c.ClientID = "", testClientID }, false},
+		{"access token for another client", func(c *tokenClaims) { c.TokenUse, c.ClientID = "access", "other-client" }, false},
+		{"access token with aud only", func(c *tokenClaims) { c.TokenUse, c.ClientID = "access", "" }, false},
+		{"refresh token", func(c *tokenClaims) { c.TokenUse = "refresh" }, false},
+		{"no token_use", func(c *tokenClaims) { c.TokenUse = "" }, false},
+		{"no expiry", func(c *tokenClaims) { c.ExpiresAt = 0 }, false},
+		{"expired within leeway", func(c *tokenClaims) { c.ExpiresAt = now.Add(-clockLeeway + time.Second).Unix() }, true},
+		{"expired past leeway", func(c *tokenClaims) { c.ExpiresAt = now.Add(-clockLeeway - time.Second).Unix() }, false},
+		{"nbf within leeway", func(c *tokenClaims) { c.NotBefore = now.Add(clockLeeway - time.Second).Unix() }, true},
+		{"nbf past leeway", func(c *tokenClaims) { c.NotBefore = now.Add(clockLeeway + time.Second).Unix() }, false},
+		{"issued in the future", func(c *tokenClaims) { c.IssuedAt = now.Add(clockLeeway + time.Second).Unix() }, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := tokenClaims{
+				Issuer:    testIssuer,
+				Subject:   "user-sub",
+				Audience:  testClientID,
+				TokenUse:  "id",
+				ExpiresAt: now.Add(time.Hour).Unix(),
+				IssuedAt:  now.Unix(),
+			}
+			tt.change(&claims)
+
+			err := svc.checkClaims(&claims, now)
+			if tt.valid && err != nil {
+				t.Fatalf("rejected: %v", err)
+			}
+			if !tt.valid && !errors.Is(err, models.ErrInvalidToken) {
+				t.Fatalf("got %v, want ErrInvalidToken", err)
+			}
+		})
+	}
+}
+
+func TestKeyRefetchesUnknownKidAtMostOncePerInterval(t *testing.T) {
+	key := newKey(t)
+	svc, set := newTestService(t, key)
+	ctx := context.Background()
+
+	if _, err := svc.key(ctx, "current"); err != nil {
+		t.Fatalf("known key: %v", err)
+	}
+	if got := set.fetchCount(); got != 1 {
+		t.Fatalf("fetched %d times, want 1", got)
+	}
+
+	// Cognito rotates in a new key, tokens signed with it arrive before the interval is up
+	rotated := newKey(t)
+	set.setKey("rotated", rotated)
+	for i := 0; i < 3; i++ {
+		if _, err := svc.key(ctx, "rotated"); !errors.Is(err, models.ErrInvalidToken) {
+			t.Fatalf("got %v, want ErrInvalidToken while throttled", err)
+		}
+	}
+	if got := set.fetchCount(); got != 1 {
+		t.Fatalf("fetched %d times while throttled, want 1", got)
+	}
+
+	svc.fetchedAt = time.Now().Add(-jwksMinRefresh - time.Second)
+	got, err := svc.key(ctx, "rotated")
+	if err != nil {
+		t.Fatalf("rotated key after the interval: %v", err)
+	}
+	if got.N.Cmp(rotated.N) != 0 {
+		t.Fatal("returned the wrong key")
+	}
+	if got := set.fetchCount(); got != 2 {
+		t.Fatalf("fetched %d times, want 2", got)
+	}
+
+	// a kid nobody has still only costs one fetch per interval
+	svc.fetchedAt = time.Now().Add(-jwksMinRefresh - time.Second)
+	for i := 0; i < 3; i++ {
+		if _, err := svc.key(ctx, "unknown"); !errors.Is(err, models.ErrInvalidToken) {
+			t.Fatalf("got %v, want ErrInvalidToken", err)
+		}
+	}
+	if got := set.fetchCount(); got != 3 {
+		t.Fatalf("fetched %d times, want 3", got)
+	}
+}
+
+func TestKeyRefreshesStaleKeysAndKeepsThemWhenCognitoFails(t *testing.T) {
+	key := newKey(t)
+	svc, set := newTestService(t, key)
+	ctx := context.Background()
+
+	if _, err := svc.key(ctx, "current"); err != nil {
+		t.Fatalf("known key: %v", err)
+	}
+
+	svc.fetchedAt = time.Now().Add(-jwksMaxAge - time.Second)
+	set.setFailing(true)
+	if _, err := svc.key(ctx, "current"); err != nil {
+		t.Fatalf("stale key while Cognito is down: %v", err)
+	}
+	if got := set.fetchCount(); got != 2 {
+		t.Fatalf("fetched %d times, want 2", got)
+	}
+
+	// throttled again, the next attempt waits for the interval
+	if _, err := svc.key(ctx, "current"); err != nil {
+		t.Fatalf("stale key while throttled: %v", err)
+	}
+	if got := set.fetchCount(); got != 2 {
+		t.Fatalf("fetched %d times while throttled, want 2", got)
+	}
+
+	set.setFailing(false)
+	svc.failedAt = time.Now().Add(-jwksMinRefresh - time.Second)
+	if _, err := svc.key(ctx, "current"); err != nil {
+		t.Fatalf("refreshed key: %v", err)
+	}
+	if got := set.fetchCount(); got != 3 {
+		t.Fatalf("fetched %d times, want 3", got)
+	}
+	if time.Since(svc.fetchedAt) > time.Minute {
+		t.Fatal("keys were not marked fresh after the refetch")
+	}
+}
+
+func TestKeyRefetchIsSharedAndOutlivesACancelledCaller(t *testing.T) {
+	key := newKey(t)
+	svc, set := newTestService(t, key)
+	set.arrived = make(chan struct{}, 1)
+	set.gate = make(chan struct{})
+
+	// the first caller starts the fetch and gives up while Cognito is slow to answer
+	cancelled, cancel := context.WithCancel(context.Background())
+	firstErr := make(chan error, 1)
+	go func() {
+		_, err := svc.key(cancelled, "current")
+		firstErr <- err
+	}()
+	<-set.arrived
+
+	if !svc.mu.TryLock() {
+		t.Fatal("keys are locked for the whole fetch")
+	}
+	svc.mu.Unlock()
+
+	second := make(chan error, 1)
+	go func() {
+		_, err := svc.key(context.Background(), "current")
+		second <- err
+	}()
+
+	cancel()
+	if err := <-firstErr; !errors.Is(err, context.Canceled) {
+		t.Fatalf("cancelled caller got %v, want context.Canceled", err)
+	}
+
+	close(set.gate)
+	if err := <-second; err != nil {
+		t.Fatalf("caller waiting on the same fetch: %v", err)
+	}
+	if got := set.fetchCount(); got != 1 {
+		t.Fatalf("fetched %d times, want 1 shared fetch", got)
+	}
+	if _, err := svc.key(context.Background(), "current"); err != nil {
+		t.Fatalf("key after the fetch: %v", err)
+	}
+}
@@ -0,0 +1,41 @@
+// blob stores: S3 in production, a local directory for development
+package blob
+
+import (
+	"context"
+	"fmt"
+
+	"backend/internal/config"
+	ports "backend/internal/core/ports"
+)
+
+const (
+	ProviderS3    = "s3"
+	ProviderLocal = "local"
+)
+
+// NewBlobStore builds the store named by BLOB_STORE, S3 when a bucket is configured and local otherwise
+func NewBlobStore(ctx context.Context, cfg *config.Config) (ports.BlobStore, error) {
+	provider := cfg.Blob.Provider
+	if provider == "" {
+		provider = ProviderLocal
+		if cfg.S3.Bucket != "" {
+			provider = ProviderS3
+		}
+	}
+
+	switch provider {
+	case ProviderS3:
+		if cfg.S3.Bucket == "" || cfg.S3.Region == "" {
+			return nil, fmt.Errorf("S3_BUCKET and S3_REGION are required for the s3 blob store")
+		}
+		if (cfg.S3.AccessKey == "") != (cfg.S3.SecretKey == "") {
+			return nil, fmt.Errorf("S3_ACCESS_KEY and S3_SECRET_KEY must be set together")
+		}
+		return NewS3Store(ctx, cfg.S3.Bucket, cfg.S3.Region, cfg.S3.AccessKey, cfg.S3.SecretKey)
+	case ProviderLocal:
+		return NewLocalStore(cfg.Blob.Dir), nil
+	default:
+		return nil, fmt.Errorf("unknown blob store %q", provider)
+	}
+}
@@ -0,0 +1,15 @@
+package handler
+
+import (
+	"backend/internal/core/models"
+	"errors"
+	"net/http"
+)
+
+// scopedStatus is the status for a service error, 403 when the caller reached outside their branch
+func scopedStatus(err error, status int) int {
+	if errors.Is(err, models.ErrOutOfBranchScope) {
+		return http.StatusForbidden
+	}
+	return status
+}
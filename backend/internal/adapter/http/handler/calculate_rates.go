@@ -14,44 +14,54 @@ import (
 type CalculateRatesHandler struct {
 	svc        ports.CalculateRatesService
 	requestSvc ports.RequestService
+	invoiceSvc ports.InvoiceService
 }
 
-func NewCalculateRatesHandler(svc ports.CalculateRatesService, requestSvc ports.RequestService) *CalculateRatesHandler {
-	return &CalculateRatesHandler{svc: svc, requestSvc: requestSvc}
+func NewCalculateRatesHandler(svc ports.CalculateRatesService, requestSvc ports.RequestService, invoiceSvc ports.InvoiceService) *CalculateRatesHandler {
+	return &CalculateRatesHandler{svc: svc, requestSvc: requestSvc, invoiceSvc: invoiceSvc}
+}
+
+// discountFor returns the discount on the request's invoice, or no discount if it hasn't been invoiced yet
+func (h *CalculateRatesHandler) discountFor(c *gin.Context, request *models.Request) models.Discount {
+	invoice, err := h.invoiceSvc.GetInvoiceByRequestID(c.Request.Context(), request.UUID)
+	if err != nil {
+		return models.Discount{Type: models.DiscountTypeNone}
+	}
+	return invoice.Discount()
 }
 
 func (h *CalculateRatesHandler) CalculateRates(c *gin.Context) {
 	requestID := c.Param("request_id")
 	request, err := h.requestSvc.GetRequestById(c.Request.Context(), uuid.MustParse(requestID))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(scopedStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
 		return
 	}
 
-	amount, transactionFee, serviceFee, subtotal, err := h.svc.CalculateRates(c.Request.Context(), &request)
+	breakdown, err := h.svc.CalculateRates(c.Request.Context(), &request, h.discountFor(c, &request))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(scopedStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"amount": amount, "transactionFee": transactionFee, "serviceFee": serviceFee, "subtotal": subtotal})
+	c.JSON(http.StatusOK, breakdown)
 }
 
 func (h *CalculateRatesHandler) GetRates(c *gin.Context) {
 	requestID := c.Param("request_id")
 	request, err := h.requestSvc.GetRequestById(c.Request.Context(), uuid.MustParse(requestID))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(scopedStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
 		return
 	}
 
-	amount, transactionFee, serviceFee, subtotal, err := h.svc.GetRates(c.Request.Context(), &request)
+	breakdown, err := h.svc.GetRates(c.Request.Context(), &request, h.discountFor(c, &request))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(scopedStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"amount": amount, "transactionFee": transactionFee, "serviceFee": serviceFee, "subtotal": subtotal})
+	c.JSON(http.StatusOK, breakdown)
 }
 
 // UpdateRates updates the rates for a request with optional custom line items
@@ -68,7 +78,7 @@ func (h *CalculateRatesHandler) UpdateRates(c *gin.Context) {
 	requestID := c.Param("request_id")
 	request, err := h.requestSvc.GetRequestById(c.Request.Context(), uuid.MustParse(requestID))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(scopedStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
 		return
 	}
 
@@ -79,17 +89,18 @@ func (h *CalculateRatesHandler) UpdateRates(c *gin.Context) {
 		return
 	}
 
-	amount, transactionFee, serviceFee, subtotal, err := h.svc.UpdateRates(c.Request.Context(), &request, customLineItems)
+	breakdown, err := h.svc.UpdateRates(c.Request.Context(), &request, customLineItems, h.discountFor(c, &request))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(scopedStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"amount":         amount,
-		"transactionFee": transactionFee,
-		"serviceFee":     serviceFee,
-		"subtotal":       subtotal,
+		"amount":         breakdown.Amount,
+		"transactionFee": breakdown.TransactionFee,
+		"serviceFee":     breakdown.ServiceFee,
+		"subtotal":       breakdown.Subtotal,
+		"discount":       breakdown.Discount,
 		"lineItems":      customLineItems,
 	})
 }
@@ -0,0 +1,92 @@
+package handler
+
+import (
+	"backend/internal/core/models"
+	"backend/internal/core/ports"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type CancellationHandler struct {
+	svc ports.CancellationService
+}
+
+func NewCancellationHandler(svc ports.CancellationService) *CancellationHandler {
+	return &CancellationHandler{svc: svc}
+}
+
+func (h *CancellationHandler) GetCancellationPolicy(c *gin.Context) {
+	c.JSON(http.StatusOK, h.svc.GetCancellationPolicy())
+}
+
+// QuoteCancellation shows what cancelling the request now would refund
+func (h *CancellationHandler) QuoteCancellation(c *gin.Context) {
+	requestID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid UUID format"})
+		return
+	}
+
+	quote, err := h.svc.QuoteCancellation(c.Request.Context(), requestID)
+	if err != nil {
+		respondCancellationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, quote)
+}
+
+func (h *CancellationHandler) CancelRequest(c *gin.Context) {
+	requestID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid UUID format"})
+		return
+	}
+
+	cancellation, err := h.svc.CancelRequest(c.Request.Context(), requestID)
+	if err != nil {
+		if cancellation != nil {
+			// cancelled, but the refund didn't fully go through
+			c.JSON(http.StatusBadGateway, gin.H{"error": err.Error(), "cancellation": cancellation})
+			return
+		}
+		respondCancellationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, cancellation)
+}
+
+// RetryCancellationRefund refunds what a cancelled request still owes after its refund failed
+func (h *CancellationHandler) RetryCancellationRefund(c *gin.Context) {
+	requestID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid UUID format"})
+		return
+	}
+
+	cancellation, err := h.svc.RetryCancellationRefund(c.Request.Context(), requestID)
+	if err != nil {
+		if cancellation != nil {
+			c.JSON(http.StatusBadGateway, gin.H{"error": err.Error(), "cancellation": cancellation})
+			return
+		}
+		respondCancellationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, cancellation)
+}
+
+func respondCancellationError(c *gin.Context, err error) {
+	if errors.Is(err, models.ErrRequestAlreadyCancelled) || errors.Is(err, models.ErrRequestNotCancelled) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	log.Printf("Failed to cancel request: %v", err)
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel request"})
+}
@@ -0,0 +1,139 @@
+package handler
+
+import (
+	"backend/internal/core/models"
+	"backend/internal/core/ports"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type ClientHandler struct {
+	svc ports.ClientService
+}
+
+func NewClientHandler(svc ports.ClientService) *ClientHandler {
+	return &ClientHandler{svc: svc}
+}
+
+func (h *ClientHandler) GetAllClients(c *gin.Context) {
+	clients, err := h.svc.GetAllClients(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, clients)
+}
+
+func (h *ClientHandler) GetClientByID(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid UUID format"})
+		return
+	}
+
+	client, err := h.svc.GetClientByID(c.Request.Context(), id)
+	if err != nil {
+		respondClientError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, client)
+}
+
+func (h *ClientHandler) UpdateClient(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid UUID format"})
+		return
+	}
+
+	var update models.ClientUpdate
+	if err := c.ShouldBindJSON(&update); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	client, err := h.svc.UpdateClient(c.Request.Context(), id, update)
+	if err != nil {
+		respondClientError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, client)
+}
+
+func (h *ClientHandler) GetPaymentMethods(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid UUID format"})
+		return
+	}
+
+	methods, err := h.svc.GetPaymentMethods(c.Request.Context(), id)
+	if err != nil {
+		respondClientError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, methods)
+}
+
+func (h *ClientHandler) SetDefaultPaymentMethod(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid UUID format"})
+		return
+	}
+
+	var body struct {
+		PaymentMethodID string `json:"payment_method_id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	client, err := h.svc.SetDefaultPaymentMethod(c.Request.Context(), id, body.PaymentMethodID)
+	if err != nil {
+		respondClientError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, client)
+}
+
+// ChargeInvoice charges the invoice balance to the client's saved card
+func (h *ClientHandler) ChargeInvoice(c *gin.Context) {
+	invoiceID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid UUID format"})
+		return
+	}
+
+	entry, err := h.svc.ChargeInvoice(c.Request.Context(), invoiceID)
+	if err != nil {
+		respondClientError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, entry)
+}
+
+func respondClientError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, models.ErrClientNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, models.ErrOutOfBranchScope):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, models.ErrOffSessionNotApproved), errors.Is(err, models.ErrNoSavedPaymentMethod):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		log.Printf("Client request failed: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -26,7 +26,7 @@ func (h *CustomLineItemsHandler) CreateCustomLineItem(c *gin.Context) {
 	}
 
 	if err := h.svc.CreateCustomLineItem(c.Request.Context(), &customLineItem); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(scopedStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
 		return
 	}
 
@@ -43,7 +43,7 @@ func (h *CustomLineItemsHandler) GetCustomLineItemByID(c *gin.Context) {
 
 	customLineItem, err := h.svc.GetCustomLineItemByID(c.Request.Context(), uuid)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(scopedStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
 		return
 	}
 
@@ -60,7 +60,7 @@ func (h *CustomLineItemsHandler) GetCustomLineItemsByRequestID(c *gin.Context) {
 
 	customLineItems, err := h.svc.GetCustomLineItemsByRequestID(c.Request.Context(), requestUUID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(scopedStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
 		return
 	}
 
@@ -84,7 +84,7 @@ func (h *CustomLineItemsHandler) UpdateCustomLineItem(c *gin.Context) {
 	customLineItem.UUID = uuid
 
 	if err := h.svc.UpdateCustomLineItem(c.Request.Context(), &customLineItem); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(scopedStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
 		return
 	}
 
@@ -100,7 +100,7 @@ func (h *CustomLineItemsHandler) DeleteCustomLineItem(c *gin.Context) {
 	}
 
 	if err := h.svc.DeleteCustomLineItem(c.Request.Context(), parsedUUID); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(scopedStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
 		return
 	}
 
@@ -0,0 +1,83 @@
+package handler
+
+import (
+	"backend/internal/core/models"
+	"backend/internal/core/ports"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type DunningHandler struct {
+	svc ports.DunningService
+}
+
+func NewDunningHandler(svc ports.DunningService) *DunningHandler {
+	return &DunningHandler{svc: svc}
+}
+
+// GetDunningSchedule returns the cadence the branch uses, which is the default one unless
+// the branch has its own
+func (h *DunningHandler) GetDunningSchedule(c *gin.Context) {
+	branchID, err := uuid.Parse(c.Param("branch_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid UUID format"})
+		return
+	}
+
+	schedule, err := h.svc.GetDunningSchedule(c.Request.Context(), branchID)
+	if err != nil {
+		c.JSON(scopedStatus(err, http.StatusNotFound), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, schedule)
+}
+
+func (h *DunningHandler) UpdateDunningSchedule(c *gin.Context) {
+	branchID, err := uuid.Parse(c.Param("branch_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid UUID format"})
+		return
+	}
+
+	var schedule models.DunningSchedule
+	if err := c.ShouldBindJSON(&schedule); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	schedule.BranchID = &branchID
+
+	if err := h.svc.UpdateDunningSchedule(c.Request.Context(), &schedule); err != nil {
+		c.JSON(scopedStatus(err, http.StatusBadRequest), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, schedule)
+}
+
+func (h *DunningHandler) DeleteDunningSchedule(c *gin.Context) {
+	branchID, err := uuid.Parse(c.Param("branch_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid UUID format"})
+		return
+	}
+
+	if err := h.svc.DeleteDunningSchedule(c.Request.Context(), branchID); err != nil {
+		c.JSON(scopedStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Branch now uses the default dunning schedule"})
+}
+
+// SendDueReminders runs the reminder job now instead of waiting for the scheduler
+func (h *DunningHandler) SendDueReminders(c *gin.Context) {
+	if err := h.svc.SendDueReminders(c.Request.Context()); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Payment reminders sent"})
+}
@@ -3,7 +3,9 @@ package handler
 import (
 	ports "backend/internal/core/ports"
 	"encoding/json"
+	"errors"
 	"io"
+	"mime"
 	"net/http"
 	"time"
 
@@ -32,9 +34,54 @@ func NewEmailHandler(svc ports.EmailService, invoiceSvc ports.InvoiceService, st
 	}
 }
 
+// invoiceForRequest picks the invoice an email is about. Requests can have deposits and change
+// orders besides the final invoice, ?invoice_id= selects one of them, otherwise the final
+// invoice is used. The invoice is numbered, if it wasn't already, since the email shows it.
+func (h *EmailHandler) invoiceForRequest(c *gin.Context, requestID uuid.UUID) (*models.Invoice, bool) {
+	var invoice *models.Invoice
+	var err error
+
+	if invoiceID := c.Query("invoice_id"); invoiceID == "" {
+		invoice, err = h.invoiceSvc.GetInvoiceByRequestID(c.Request.Context(), requestID)
+	} else {
+		parsedInvoiceID, parseErr := uuid.Parse(invoiceID)
+		if parseErr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid invoice ID"})
+			return nil, false
+		}
+		invoice, err = h.invoiceSvc.GetInvoiceByID(c.Request.Context(), parsedInvoiceID)
+	}
+	if err != nil {
+		log.Printf("Failed to get invoice: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get invoice"})
+		return nil, false
+	}
+	if invoice.RequestID != requestID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invoice does not belong to this request"})
+		return nil, false
+	}
+
+	if err := h.invoiceSvc.AssignInvoiceNumber(c.Request.Context(), invoice); err != nil {
+		log.Printf("Failed to number invoice: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to number invoice"})
+		return nil, false
+	}
+	return invoice, true
+}
+
+// SendEmail sends the invoice email, an optional JSON body of EmailHeaders adds CC, BCC,
+// reply-to and attachments
 func (h *EmailHandler) SendEmail(c *gin.Context) {
 	requestId := c.Param("request_id")
 
+	var emailHeaders models.EmailHeaders
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&emailHeaders); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+			return
+		}
+	}
+
 	parsedID, err := uuid.Parse(requestId)
 	if err != nil {
 		log.Printf("DEBUG HANDLER: Failed to parse request_id: %v", err)
@@ -42,10 +89,8 @@ func (h *EmailHandler) SendEmail(c *gin.Context) {
 		return
 	}
 
-	invoice, err := h.invoiceSvc.GetInvoiceByRequestID(c.Request.Context(), parsedID)
-	if err != nil {
-		log.Printf("Failed to get invoice: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get invoice"})
+	invoice, ok := h.invoiceForRequest(c, parsedID)
+	if !ok {
 		return
 	}
 
@@ -65,17 +110,14 @@ func (h *EmailHandler) SendEmail(c *gin.Context) {
 	}
 
 	// Send email with payment URL
-	err = h.svc.SendEmailWithPaymentURL(c.Request.Context(), invoice, staffRequirements, checkoutURL)
+	err = h.svc.SendEmailWithPaymentURL(c.Request.Context(), invoice, staffRequirements, checkoutURL, emailHeaders)
 	if err != nil {
 		log.Printf("Failed to send email: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send email"})
 		return
 	}
 
-	now := time.Now().UTC()
-	invoice.LastSent = now
-
-	err = h.invoiceSvc.UpdateInvoice(c.Request.Context(), invoice)
+	err = h.invoiceSvc.MarkInvoiceSent(c.Request.Context(), invoice)
 	if err != nil {
 		log.Printf("Warning: Failed to update email tracking for invoice %s: %v", invoice.UUID, err)
 	}
@@ -111,26 +153,28 @@ func (h *EmailHandler) SendCustomEmail(c *gin.Context) {
 	// Get payment URL from form data (optional)
 	paymentUrl := c.PostForm("paymentUrl")
 
-	// Handle PDF attachment (optional)
-	var pdfData []byte
-	var filename string
-	var hasAttachment bool
-
+	// Handle PDF attachment (optional), it's stored like any other attachment and sent by ID
 	file, header, err := c.Request.FormFile("invoicePDF")
 	if err == nil && file != nil {
 		defer file.Close()
 
 		// Read file data
-		pdfData, err = io.ReadAll(file)
+		pdfData, err := io.ReadAll(file)
 		if err != nil {
 			log.Printf("Failed to read PDF file: %v", err)
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read PDF attachment"})
 			return
 		}
 
-		filename = header.Filename
-		hasAttachment = true
-		log.Printf("PDF attachment received: %s, size: %d bytes", filename, len(pdfData))
+		attachment, err := h.svc.StoreAttachment(c.Request.Context(), header.Filename, header.Header.Get("Content-Type"), pdfData)
+		if err != nil {
+			log.Printf("Failed to store PDF attachment: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store PDF attachment"})
+			return
+		}
+
+		emailHeaders.AttachmentIDs = append(emailHeaders.AttachmentIDs, attachment.UUID)
+		log.Printf("PDF attachment received: %s, size: %d bytes", attachment.Filename, attachment.Size)
 	} else if err != http.ErrMissingFile {
 		log.Printf("Error handling file upload: %v", err)
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Error processing file upload"})
@@ -144,10 +188,8 @@ func (h *EmailHandler) SendCustomEmail(c *gin.Context) {
 		return
 	}
 
-	invoice, err := h.invoiceSvc.GetInvoiceByRequestID(c.Request.Context(), parsedID)
-	if err != nil {
-		log.Printf("Failed to get invoice: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get invoice"})
+	invoice, ok := h.invoiceForRequest(c, parsedID)
+	if !ok {
 		return
 	}
 
@@ -158,24 +200,21 @@ func (h *EmailHandler) SendCustomEmail(c *gin.Context) {
 		return
 	}
 
-	err = h.svc.SendCustomEmail(c.Request.Context(), invoice, staffRequirements, emailContent, emailHeaders, pdfData, filename, paymentUrl)
+	err = h.svc.SendCustomEmail(c.Request.Context(), invoice, staffRequirements, emailContent, emailHeaders, paymentUrl)
 	if err != nil {
 		log.Printf("Failed to send custom email: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send custom email"})
 		return
 	}
 
-	now := time.Now().UTC()
-	invoice.LastSent = now
-
-	err = h.invoiceSvc.UpdateInvoice(c.Request.Context(), invoice)
+	err = h.invoiceSvc.MarkInvoiceSent(c.Request.Context(), invoice)
 	if err != nil {
 		log.Printf("Warning: Failed to update email tracking for invoice %s: %v", invoice.UUID, err)
 	}
 
 	message := "Custom email sent successfully"
-	if hasAttachment {
-		message += " with PDF attachment"
+	if len(emailHeaders.AttachmentIDs) > 0 {
+		message += " with attachments"
 	}
 	if paymentUrl != "" {
 		message += " with payment button"
@@ -195,10 +234,8 @@ func (h *EmailHandler) ScheduleEmail(c *gin.Context) {
 		return
 	}
 
-	invoice, err := h.invoiceSvc.GetInvoiceByRequestID(c.Request.Context(), parsedID)
-	if err != nil {
-		log.Printf("Failed to get invoice: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get invoice"})
+	invoice, ok := h.invoiceForRequest(c, parsedID)
+	if !ok {
 		return
 	}
 
@@ -260,3 +297,220 @@ func (h *EmailHandler) ScheduleEmail(c *gin.Context) {
 		"email_type": emailType,
 	})
 }
+
+// UploadAttachment stores the multipart "file" field and returns its ID for the attachmentIds header
+func (h *EmailHandler) UploadAttachment(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, models.MaxEmailAttachmentSize+(1<<20))
+
+	file, header, err := c.Request.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A file is required"})
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read file"})
+		return
+	}
+
+	attachment, err := h.svc.StoreAttachment(c.Request.Context(), header.Filename, header.Header.Get("Content-Type"), data)
+	if err != nil {
+		log.Printf("Failed to store attachment: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, attachment)
+}
+
+func (h *EmailHandler) DownloadAttachment(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid UUID format"})
+		return
+	}
+
+	attachment, data, err := h.svc.GetAttachment(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, models.ErrEmailAttachmentNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Attachment not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": attachment.Filename}))
+	c.Data(http.StatusOK, attachment.ContentType, data)
+}
+
+func (h *EmailHandler) GetScheduledEmail(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid UUID format"})
+		return
+	}
+
+	email, err := h.svc.GetScheduledEmail(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Scheduled email not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, email)
+}
+
+func (h *EmailHandler) GetScheduledEmailsByRequestID(c *gin.Context) {
+	requestID, err := uuid.Parse(c.Param("request_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid UUID format"})
+		return
+	}
+
+	emails, err := h.svc.GetScheduledEmailsByRequestID(c.Request.Context(), requestID)
+	if err != nil {
+		log.Printf("Failed to get scheduled emails: %v", err)
+		c.JSON(scopedStatus(err, http.StatusInternalServerError), gin.H{"error": "Failed to get scheduled emails"})
+		return
+	}
+
+	c.JSON(http.StatusOK, emails)
+}
+
+// GetScheduledEmailsByBranchID lists a branch's emails, ?status= narrows it to one outbox status
+func (h *EmailHandler) GetScheduledEmailsByBranchID(c *gin.Context) {
+	branchID, err := uuid.Parse(c.Param("branch_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid UUID format"})
+		return
+	}
+
+	emails, err := h.svc.GetScheduledEmailsByBranchID(c.Request.Context(), branchID, c.Query("status"))
+	if err != nil {
+		c.JSON(scopedStatus(err, http.StatusBadRequest), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, emails)
+}
+
+// PreviewScheduledEmail returns the stored HTML exactly as it will be sent
+func (h *EmailHandler) PreviewScheduledEmail(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid UUID format"})
+		return
+	}
+
+	email, err := h.svc.GetScheduledEmail(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Scheduled email not found"})
+		return
+	}
+
+	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(email.Content))
+}
+
+func (h *EmailHandler) UpdateScheduledEmail(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid UUID format"})
+		return
+	}
+
+	var update models.ScheduledEmailUpdate
+	if err := c.ShouldBindJSON(&update); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	email, err := h.svc.UpdateScheduledEmail(c.Request.Context(), id, update)
+	if err != nil {
+		h.scheduledEmailError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, email)
+}
+
+func (h *EmailHandler) CancelScheduledEmail(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid UUID format"})
+		return
+	}
+
+	email, err := h.svc.CancelScheduledEmail(c.Request.Context(), id)
+	if err != nil {
+		h.scheduledEmailError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, email)
+}
+
+func (h *EmailHandler) scheduledEmailError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, models.ErrEmailNotEditable):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, models.ErrEmailNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Scheduled email not found"})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	}
+}
+
+// Webhook receives Mailgun delivery events, the signature is in the JSON body
+func (h *EmailHandler) Webhook(c *gin.Context) {
+	payload, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read request body"})
+		return
+	}
+
+	if err := h.svc.HandleWebhook(c.Request.Context(), payload); err != nil {
+		if errors.Is(err, models.ErrInvalidWebhookSignature) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+		log.Printf("Failed to handle Mailgun webhook: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "success"})
+}
+
+func (h *EmailHandler) GetEmailLogsByRequestID(c *gin.Context) {
+	requestID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid UUID format"})
+		return
+	}
+
+	entries, err := h.svc.GetEmailLogsByRequestID(c.Request.Context(), requestID)
+	if err != nil {
+		c.JSON(scopedStatus(err, http.StatusInternalServerError), gin.H{"error": "Failed to get email history"})
+		return
+	}
+
+	c.JSON(http.StatusOK, entries)
+}
+
+func (h *EmailHandler) GetEmailLogsByInvoiceID(c *gin.Context) {
+	invoiceID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid UUID format"})
+		return
+	}
+
+	entries, err := h.svc.GetEmailLogsByInvoiceID(c.Request.Context(), invoiceID)
+	if err != nil {
+		c.JSON(scopedStatus(err, http.StatusInternalServerError), gin.H{"error": "Failed to get email history"})
+		return
+	}
+
+	c.JSON(http.StatusOK, entries)
+}
@@ -0,0 +1,145 @@
+package handler
+
+import (
+	"backend/internal/core/models"
+	"backend/internal/core/ports"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type EmailTemplateHandler struct {
+	svc ports.EmailTemplateService
+}
+
+func NewEmailTemplateHandler(svc ports.EmailTemplateService) *EmailTemplateHandler {
+	return &EmailTemplateHandler{svc: svc}
+}
+
+// emailTemplateError reports an unknown template name as a 404, anything else as a bad request
+func emailTemplateError(c *gin.Context, err error) {
+	if errors.Is(err, models.ErrUnknownEmailTemplate) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+}
+
+// GetEmailTemplates lists the template in force for each email, for the branch_id query param
+// or the defaults when it's missing
+func (h *EmailTemplateHandler) GetEmailTemplates(c *gin.Context) {
+	branchID, ok := branchIDQuery(c)
+	if !ok {
+		return
+	}
+
+	templates, err := h.svc.GetEmailTemplates(c.Request.Context(), branchID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, templates)
+}
+
+func (h *EmailTemplateHandler) GetEmailTemplate(c *gin.Context) {
+	branchID, ok := branchIDQuery(c)
+	if !ok {
+		return
+	}
+
+	template, err := h.svc.GetEmailTemplate(c.Request.Context(), branchID, c.Param("name"))
+	if err != nil {
+		emailTemplateError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, template)
+}
+
+func (h *EmailTemplateHandler) GetEmailTemplateVersions(c *gin.Context) {
+	branchID, ok := branchIDQuery(c)
+	if !ok {
+		return
+	}
+
+	versions, err := h.svc.GetEmailTemplateVersions(c.Request.Context(), branchID, c.Param("name"))
+	if err != nil {
+		emailTemplateError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, versions)
+}
+
+// SaveEmailTemplate adds a new version, for the branch in the body or the default when it's missing
+func (h *EmailTemplateHandler) SaveEmailTemplate(c *gin.Context) {
+	var template models.EmailTemplate
+	if err := c.ShouldBindJSON(&template); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	template.Name = c.Param("name")
+
+	if err := h.svc.SaveEmailTemplate(c.Request.Context(), &template); err != nil {
+		emailTemplateError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, template)
+}
+
+// PreviewEmailTemplate renders the posted template against sample data without saving it
+func (h *EmailTemplateHandler) PreviewEmailTemplate(c *gin.Context) {
+	var template models.EmailTemplate
+	if err := c.ShouldBindJSON(&template); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	template.Name = c.Param("name")
+
+	rendered, err := h.svc.PreviewEmailTemplate(c.Request.Context(), &template)
+	if err != nil {
+		emailTemplateError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, rendered)
+}
+
+func (h *EmailTemplateHandler) RestoreEmailTemplateVersion(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid UUID format"})
+		return
+	}
+
+	template, err := h.svc.RestoreEmailTemplateVersion(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, template)
+}
+
+// ResetEmailTemplate puts the branch back on the default template
+func (h *EmailTemplateHandler) ResetEmailTemplate(c *gin.Context) {
+	branchID, ok := branchIDQuery(c)
+	if !ok {
+		return
+	}
+	if branchID == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "branch_id is required"})
+		return
+	}
+
+	if err := h.svc.ResetEmailTemplate(c.Request.Context(), *branchID, c.Param("name")); err != nil {
+		emailTemplateError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Branch now uses the default template"})
+}
@@ -0,0 +1,154 @@
+package handler
+
+import (
+	"backend/internal/core/models"
+	"backend/internal/core/ports"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type EventHandler struct {
+	svc ports.EventService
+}
+
+func NewEventHandler(svc ports.EventService) *EventHandler {
+	return &EventHandler{svc: svc}
+}
+
+// GetAllEvents lists events, optionally filtered by the branch_id and status query params and
+// the from and to dates (YYYY-MM-DD) they overlap
+func (h *EventHandler) GetAllEvents(c *gin.Context) {
+	var filter models.EventFilter
+	if branch := c.Query("branch_id"); branch != "" {
+		branchID, err := uuid.Parse(branch)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid UUID format"})
+			return
+		}
+		filter.BranchID = &branchID
+	}
+	filter.Status = c.Query("status")
+	var err error
+	if filter.From, err = dateQuery(c, "from"); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if filter.To, err = dateQuery(c, "to"); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	events, err := h.svc.GetEvents(c.Request.Context(), filter)
+	if err != nil {
+		respondEventError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, events)
+}
+
+func (h *EventHandler) GetEventByID(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid UUID format"})
+		return
+	}
+
+	event, err := h.svc.GetEventByID(c.Request.Context(), id)
+	if err != nil {
+		respondEventError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, event)
+}
+
+func (h *EventHandler) GetEventByRequestID(c *gin.Context) {
+	requestID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid UUID format"})
+		return
+	}
+
+	event, err := h.svc.GetEventByRequestID(c.Request.Context(), requestID)
+	if err != nil {
+		respondEventError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, event)
+}
+
+// ScheduleRequest confirms the request and schedules its event with a shift per staffing window
+func (h *EventHandler) ScheduleRequest(c *gin.Context) {
+	requestID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid UUID format"})
+		return
+	}
+
+	event, err := h.svc.ScheduleRequest(c.Request.Context(), requestID)
+	if err != nil {
+		respondEventError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, event)
+}
+
+func (h *EventHandler) UpdateEventStatus(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid UUID format"})
+		return
+	}
+
+	var update models.EventStatusUpdate
+	if err := c.ShouldBindJSON(&update); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	event, err := h.svc.UpdateEventStatus(c.Request.Context(), id, update)
+	if err != nil {
+		respondEventError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, event)
+}
+
+// dateQuery parses an optional YYYY-MM-DD query param, nil when it isn't set
+func dateQuery(c *gin.Context, param string) (*time.Time, error) {
+	value := c.Query(param)
+	if value == "" {
+		return nil, nil
+	}
+	date, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		return nil, fmt.Errorf("%s must be a date in YYYY-MM-DD format", param)
+	}
+	return &date, nil
+}
+
+// respondEventError maps event and shift errors to statuses
+func respondEventError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, models.ErrEventNotFound), errors.Is(err, models.ErrShiftNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, models.ErrInvalidShiftTimes):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, models.ErrInvalidEventTransition), errors.Is(err, models.ErrEventClosed),
+		errors.Is(err, models.ErrRequestAlreadyCancelled):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		log.Printf("Event request failed: %v", err)
+		c.JSON(scopedStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+	}
+}
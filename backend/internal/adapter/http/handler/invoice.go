@@ -4,7 +4,12 @@ import (
 	"backend/internal/config"
 	"backend/internal/core/models"
 	"backend/internal/core/ports"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"mime"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -30,12 +35,16 @@ func (h *InvoiceHandler) CreateInvoice(c *gin.Context) {
 
 	request, err := h.requestService.GetRequestById(c.Request.Context(), invoice.RequestID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(scopedStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
 		return
 	}
 
 	if err := h.invoiceService.CreateInvoice(c.Request.Context(), &invoice, &request); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		if errors.Is(err, models.ErrFinalInvoiceExists) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(scopedStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
 		return
 	}
 
@@ -52,13 +61,40 @@ func (h *InvoiceHandler) GetInvoiceByID(c *gin.Context) {
 
 	invoice, err := h.invoiceService.GetInvoiceByID(c.Request.Context(), uuid)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(scopedStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
 		return
 	}
 
 	c.JSON(http.StatusOK, invoice)
 }
 
+// GetInvoicePDF renders the invoice PDF. The PDF only changes when the invoice does, so the ETag
+// lets clients skip downloading it again.
+func (h *InvoiceHandler) GetInvoicePDF(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid UUID format"})
+		return
+	}
+
+	invoice, pdf, err := h.invoiceService.GetInvoicePDF(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(scopedStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+
+	sum := sha256.Sum256(pdf)
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+	c.Header("ETag", etag)
+	if c.GetHeader("If-None-Match") == etag {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	c.Header("Content-Disposition", mime.FormatMediaType("inline", map[string]string{"filename": models.InvoicePDFFilename(invoice)}))
+	c.Data(http.StatusOK, "application/pdf", pdf)
+}
+
 func (h *InvoiceHandler) GetInvoiceByRequestID(c *gin.Context) {
 	id := c.Param("id")
 	uuid, err := uuid.Parse(id)
@@ -69,7 +105,7 @@ func (h *InvoiceHandler) GetInvoiceByRequestID(c *gin.Context) {
 
 	invoice, err := h.invoiceService.GetInvoiceByRequestID(c.Request.Context(), uuid)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(scopedStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
 		return
 	}
 
@@ -86,7 +122,7 @@ func (h *InvoiceHandler) GetInvoiceByBranchID(c *gin.Context) {
 
 	invoices, err := h.invoiceService.GetInvoiceByBranchID(c.Request.Context(), uuid)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(scopedStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
 		return
 	}
 
@@ -115,6 +151,10 @@ func (h *InvoiceHandler) UpdateInvoice(c *gin.Context) {
 	existingInvoice.UUID = uuid
 
 	if err := h.invoiceService.UpdateInvoice(c.Request.Context(), existingInvoice); err != nil {
+		if errors.Is(err, models.ErrInvalidInvoiceTransition) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -131,6 +171,10 @@ func (h *InvoiceHandler) DeleteInvoice(c *gin.Context) {
 	}
 
 	if err := h.invoiceService.DeleteInvoice(c.Request.Context(), uuid); err != nil {
+		if errors.Is(err, models.ErrInvoiceNumbered) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -138,6 +182,23 @@ func (h *InvoiceHandler) DeleteInvoice(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Invoice deleted successfully"})
 }
 
+// SearchInvoices matches ?q= against invoice numbers, PO numbers and client names and emails
+func (h *InvoiceHandler) SearchInvoices(c *gin.Context) {
+	query := strings.TrimSpace(c.Query("q"))
+	if query == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A search query is required"})
+		return
+	}
+
+	invoices, err := h.invoiceService.SearchInvoices(c.Request.Context(), query)
+	if err != nil {
+		c.JSON(scopedStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, invoices)
+}
+
 func (h *InvoiceHandler) CheckForOverdueInvoices(c *gin.Context) {
 	branchId := c.Param("branch_id")
 	uuid, err := uuid.Parse(branchId)
@@ -148,13 +209,13 @@ func (h *InvoiceHandler) CheckForOverdueInvoices(c *gin.Context) {
 
 	invoiceResponses, err := h.invoiceService.GetInvoiceByBranchID(c.Request.Context(), uuid)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(scopedStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
 		return
 	}
 
 	var overdueInvoices []models.Invoice
 	for _, invoiceResp := range invoiceResponses {
-		if (invoiceResp.Status == "unpaid" || invoiceResp.Status == "pending") && invoiceResp.DueDate.UTC().Before(time.Now().UTC()) {
+		if models.IsInvoiceOverdue(invoiceResp.Status, invoiceResp.Balance, invoiceResp.DueDate, time.Now().UTC()) {
 			fullInvoice, err := h.invoiceService.GetInvoiceByID(c.Request.Context(), invoiceResp.UUID)
 			if err != nil {
 				continue
@@ -185,6 +246,10 @@ func (h *InvoiceHandler) RecalculateInvoiceWithNewItems(c *gin.Context) {
 
 	updatedInvoice, err := h.invoiceService.RecalculateInvoiceAfterPaymentWithNewItems(c.Request.Context(), invoiceUUID, newCustomLineItems)
 	if err != nil {
+		if errors.Is(err, models.ErrNothingToInvoice) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -194,3 +259,183 @@ func (h *InvoiceHandler) RecalculateInvoiceWithNewItems(c *gin.Context) {
 		"invoice": updatedInvoice,
 	})
 }
+
+// ApplyDiscount applies a percentage or fixed discount, or a promo code, to an unpaid invoice
+func (h *InvoiceHandler) ApplyDiscount(c *gin.Context) {
+	id := c.Param("id")
+	invoiceUUID, err := uuid.Parse(id)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid invoice UUID format"})
+		return
+	}
+
+	var discount models.Discount
+	if err := c.ShouldBindJSON(&discount); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid discount format: " + err.Error()})
+		return
+	}
+
+	updatedInvoice, err := h.invoiceService.ApplyDiscount(c.Request.Context(), invoiceUUID, discount)
+	if err != nil {
+		if errors.Is(err, models.ErrInvoiceNotDraft) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(scopedStatus(err, http.StatusBadRequest), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, updatedInvoice)
+}
+
+func (h *InvoiceHandler) RemoveDiscount(c *gin.Context) {
+	id := c.Param("id")
+	invoiceUUID, err := uuid.Parse(id)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid invoice UUID format"})
+		return
+	}
+
+	updatedInvoice, err := h.invoiceService.RemoveDiscount(c.Request.Context(), invoiceUUID)
+	if err != nil {
+		if errors.Is(err, models.ErrInvoiceNotDraft) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(scopedStatus(err, http.StatusBadRequest), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, updatedInvoice)
+}
+
+// TransitionInvoice moves an invoice to another status, e.g. voiding it or marking it sent by
+// hand. Payment statuses normally follow the payment ledger.
+func (h *InvoiceHandler) TransitionInvoice(c *gin.Context) {
+	invoiceUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid UUID format"})
+		return
+	}
+
+	var body struct {
+		Status string `json:"status" binding:"required"`
+		Reason string `json:"reason"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	invoice, err := h.invoiceService.TransitionInvoice(c.Request.Context(), invoiceUUID, body.Status, body.Reason)
+	if err != nil {
+		if errors.Is(err, models.ErrInvalidInvoiceTransition) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, invoice)
+}
+
+func (h *InvoiceHandler) GetInvoiceStatusHistory(c *gin.Context) {
+	invoiceUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid UUID format"})
+		return
+	}
+
+	history, err := h.invoiceService.GetInvoiceStatusHistory(c.Request.Context(), invoiceUUID)
+	if err != nil {
+		c.JSON(scopedStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, history)
+}
+
+// GetInvoicesByRequestID lists every invoice issued for a request, deposits and change orders
+// included, in the order they were issued
+func (h *InvoiceHandler) GetInvoicesByRequestID(c *gin.Context) {
+	requestUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid UUID format"})
+		return
+	}
+
+	invoices, err := h.invoiceService.GetInvoicesByRequestID(c.Request.Context(), requestUUID)
+	if err != nil {
+		c.JSON(scopedStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, invoices)
+}
+
+func (h *InvoiceHandler) GetRequestInvoiceSummary(c *gin.Context) {
+	requestUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid UUID format"})
+		return
+	}
+
+	summary, err := h.invoiceService.GetRequestInvoiceSummary(c.Request.Context(), requestUUID)
+	if err != nil {
+		c.JSON(scopedStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, summary)
+}
+
+// CreateDepositInvoice takes either a fixed amount or a percent of the request total
+func (h *InvoiceHandler) CreateDepositInvoice(c *gin.Context) {
+	requestUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid UUID format"})
+		return
+	}
+
+	var deposit models.Deposit
+	if err := c.ShouldBindJSON(&deposit); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid deposit format: " + err.Error()})
+		return
+	}
+
+	invoice, err := h.invoiceService.CreateDepositInvoice(c.Request.Context(), requestUUID, deposit)
+	if err != nil {
+		c.JSON(scopedStatus(err, http.StatusBadRequest), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, invoice)
+}
+
+// CreateChangeOrderInvoice bills new line items added to a request on an invoice of their own
+func (h *InvoiceHandler) CreateChangeOrderInvoice(c *gin.Context) {
+	requestUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid UUID format"})
+		return
+	}
+
+	var customLineItems []models.CustomLineItems
+	if err := c.ShouldBindJSON(&customLineItems); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid custom line items format: " + err.Error()})
+		return
+	}
+
+	invoice, err := h.invoiceService.CreateChangeOrderInvoice(c.Request.Context(), requestUUID, customLineItems)
+	if err != nil {
+		if errors.Is(err, models.ErrNothingToInvoice) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, invoice)
+}
@@ -0,0 +1,116 @@
+package handler
+
+import (
+	"backend/internal/core/models"
+	"backend/internal/core/ports"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type PricingPolicyHandler struct {
+	svc ports.PricingPolicyService
+}
+
+func NewPricingPolicyHandler(svc ports.PricingPolicyService) *PricingPolicyHandler {
+	return &PricingPolicyHandler{svc: svc}
+}
+
+func (h *PricingPolicyHandler) CreatePricingPolicy(c *gin.Context) {
+	var policy models.PricingPolicy
+	if err := c.ShouldBindJSON(&policy); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.svc.CreatePricingPolicy(c.Request.Context(), &policy); err != nil {
+		c.JSON(scopedStatus(err, http.StatusBadRequest), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, policy)
+}
+
+// GetPricingPolicies lists policies, optionally filtered by the branch_id query param
+func (h *PricingPolicyHandler) GetPricingPolicies(c *gin.Context) {
+	var branchID *uuid.UUID
+	if branch := c.Query("branch_id"); branch != "" {
+		parsed, err := uuid.Parse(branch)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid UUID format"})
+			return
+		}
+		branchID = &parsed
+	}
+
+	policies, err := h.svc.GetPricingPolicies(c.Request.Context(), branchID)
+	if err != nil {
+		c.JSON(scopedStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, policies)
+}
+
+func (h *PricingPolicyHandler) GetPricingPolicyByID(c *gin.Context) {
+	id := c.Param("id")
+	uuid, err := uuid.Parse(id)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid UUID format"})
+		return
+	}
+
+	policy, err := h.svc.GetPricingPolicyByID(c.Request.Context(), uuid)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Pricing policy not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, policy)
+}
+
+func (h *PricingPolicyHandler) UpdatePricingPolicy(c *gin.Context) {
+	id := c.Param("id")
+	uuid, err := uuid.Parse(id)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid UUID format"})
+		return
+	}
+
+	existingPolicy, err := h.svc.GetPricingPolicyByID(c.Request.Context(), uuid)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Pricing policy not found"})
+		return
+	}
+
+	if err := c.ShouldBindJSON(existingPolicy); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	existingPolicy.UUID = uuid
+
+	if err := h.svc.UpdatePricingPolicy(c.Request.Context(), existingPolicy); err != nil {
+		c.JSON(scopedStatus(err, http.StatusBadRequest), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, existingPolicy)
+}
+
+func (h *PricingPolicyHandler) DeletePricingPolicy(c *gin.Context) {
+	id := c.Param("id")
+	uuid, err := uuid.Parse(id)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid UUID format"})
+		return
+	}
+
+	if err := h.svc.DeletePricingPolicy(c.Request.Context(), uuid); err != nil {
+		c.JSON(scopedStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Pricing policy deleted successfully"})
+}
@@ -0,0 +1,106 @@
+package handler
+
+import (
+	"backend/internal/core/models"
+	"backend/internal/core/ports"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type PromoCodeHandler struct {
+	svc ports.PromoCodeService
+}
+
+func NewPromoCodeHandler(svc ports.PromoCodeService) *PromoCodeHandler {
+	return &PromoCodeHandler{svc: svc}
+}
+
+func (h *PromoCodeHandler) CreatePromoCode(c *gin.Context) {
+	// codes are active unless the body says otherwise
+	promoCode := models.PromoCode{Active: true}
+	if err := c.ShouldBindJSON(&promoCode); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.svc.CreatePromoCode(c.Request.Context(), &promoCode); err != nil {
+		c.JSON(scopedStatus(err, http.StatusBadRequest), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, promoCode)
+}
+
+func (h *PromoCodeHandler) GetPromoCodes(c *gin.Context) {
+	promoCodes, err := h.svc.GetPromoCodes(c.Request.Context())
+	if err != nil {
+		c.JSON(scopedStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, promoCodes)
+}
+
+func (h *PromoCodeHandler) GetPromoCodeByID(c *gin.Context) {
+	id := c.Param("id")
+	uuid, err := uuid.Parse(id)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid UUID format"})
+		return
+	}
+
+	promoCode, err := h.svc.GetPromoCodeByID(c.Request.Context(), uuid)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Promo code not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, promoCode)
+}
+
+func (h *PromoCodeHandler) UpdatePromoCode(c *gin.Context) {
+	id := c.Param("id")
+	uuid, err := uuid.Parse(id)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid UUID format"})
+		return
+	}
+
+	existingPolicy, err := h.svc.GetPromoCodeByID(c.Request.Context(), uuid)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Promo code not found"})
+		return
+	}
+
+	if err := c.ShouldBindJSON(existingPolicy); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	existingPolicy.UUID = uuid
+
+	if err := h.svc.UpdatePromoCode(c.Request.Context(), existingPolicy); err != nil {
+		c.JSON(scopedStatus(err, http.StatusBadRequest), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, existingPolicy)
+}
+
+func (h *PromoCodeHandler) DeletePromoCode(c *gin.Context) {
+	id := c.Param("id")
+	uuid, err := uuid.Parse(id)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid UUID format"})
+		return
+	}
+
+	if err := h.svc.DeletePromoCode(c.Request.Context(), uuid); err != nil {
+		c.JSON(scopedStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Promo code deleted successfully"})
+}
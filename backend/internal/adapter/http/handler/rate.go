@@ -0,0 +1,260 @@
+package handler
+
+import (
+	"backend/internal/core/models"
+	"backend/internal/core/ports"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// RateHandler manages a branch's rate card
+type RateHandler struct {
+	svc ports.RateService
+}
+
+func NewRateHandler(svc ports.RateService) *RateHandler {
+	return &RateHandler{svc: svc}
+}
+
+// GetRatesByBranchID lists the branch's rate history, optionally for one staff_type
+func (h *RateHandler) GetRatesByBranchID(c *gin.Context) {
+	branchID, err := uuid.Parse(c.Param("branch_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid UUID format"})
+		return
+	}
+
+	rates, err := h.svc.GetRatesByBranchID(c.Request.Context(), branchID, c.Query("staff_type"))
+	if err != nil {
+		c.JSON(scopedStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, rates)
+}
+
+func (h *RateHandler) CreateRate(c *gin.Context) {
+	branchID, err := uuid.Parse(c.Param("branch_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid UUID format"})
+		return
+	}
+
+	var rate models.Rate
+	if err := c.ShouldBindJSON(&rate); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	rate.BranchID = branchID
+
+	if err := h.svc.CreateRate(c.Request.Context(), &rate); err != nil {
+		c.JSON(scopedStatus(err, http.StatusBadRequest), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, rate)
+}
+
+func (h *RateHandler) UpdateRate(c *gin.Context) {
+	branchID, err := uuid.Parse(c.Param("branch_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid UUID format"})
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid UUID format"})
+		return
+	}
+
+	existingRate, err := h.svc.GetRateByID(c.Request.Context(), id)
+	if err != nil || existingRate.BranchID != branchID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Rate not found"})
+		return
+	}
+
+	if err := c.ShouldBindJSON(existingRate); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	existingRate.UUID = id
+	existingRate.BranchID = branchID
+
+	if err := h.svc.UpdateRate(c.Request.Context(), existingRate); err != nil {
+		c.JSON(scopedStatus(err, http.StatusBadRequest), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, existingRate)
+}
+
+func (h *RateHandler) DeleteRate(c *gin.Context) {
+	branchID, err := uuid.Parse(c.Param("branch_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid UUID format"})
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid UUID format"})
+		return
+	}
+
+	existingRate, err := h.svc.GetRateByID(c.Request.Context(), id)
+	if err != nil || existingRate.BranchID != branchID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Rate not found"})
+		return
+	}
+
+	if err := h.svc.DeleteRate(c.Request.Context(), id); err != nil {
+		if errors.Is(err, models.ErrRateNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Rate not found"})
+			return
+		}
+		c.JSON(scopedStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Rate deleted successfully"})
+}
+
+// GetDefaultRates lists the fallback rates, ?region= narrows it to one region and an empty
+// region to the global defaults
+func (h *RateHandler) GetDefaultRates(c *gin.Context) {
+	var region *string
+	if value, ok := c.GetQuery("region"); ok {
+		region = &value
+	}
+
+	rates, err := h.svc.GetDefaultRates(c.Request.Context(), region)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, rates)
+}
+
+func (h *RateHandler) CreateDefaultRate(c *gin.Context) {
+	var rate models.DefaultRate
+	if err := c.ShouldBindJSON(&rate); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.svc.CreateDefaultRate(c.Request.Context(), &rate); err != nil {
+		c.JSON(scopedStatus(err, http.StatusBadRequest), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, rate)
+}
+
+func (h *RateHandler) UpdateDefaultRate(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid UUID format"})
+		return
+	}
+
+	existingRate, err := h.svc.GetDefaultRateByID(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Default rate not found"})
+		return
+	}
+
+	if err := c.ShouldBindJSON(existingRate); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	existingRate.UUID = id
+
+	if err := h.svc.UpdateDefaultRate(c.Request.Context(), existingRate); err != nil {
+		c.JSON(scopedStatus(err, http.StatusBadRequest), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, existingRate)
+}
+
+func (h *RateHandler) DeleteDefaultRate(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid UUID format"})
+		return
+	}
+
+	if _, err := h.svc.GetDefaultRateByID(c.Request.Context(), id); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Default rate not found"})
+		return
+	}
+
+	if err := h.svc.DeleteDefaultRate(c.Request.Context(), id); err != nil {
+		c.JSON(scopedStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Default rate deleted successfully"})
+}
+
+// ImportRateCard accepts a CSV rate card either as a multipart "file" upload or as the raw body
+func (h *RateHandler) ImportRateCard(c *gin.Context) {
+	branchID, err := uuid.Parse(c.Param("branch_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid UUID format"})
+		return
+	}
+
+	var body io.Reader = c.Request.Body
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		fileHeader, err := c.FormFile("file")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Missing rate card file"})
+			return
+		}
+		file, err := fileHeader.Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		defer file.Close()
+		body = file
+	}
+
+	rates, err := h.svc.ImportRateCard(c.Request.Context(), branchID, body)
+	if err != nil {
+		c.JSON(scopedStatus(err, http.StatusBadRequest), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"imported": len(rates), "rates": rates})
+}
+
+func (h *RateHandler) ExportRateCard(c *gin.Context) {
+	branchID, err := uuid.Parse(c.Param("branch_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid UUID format"})
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := h.svc.ExportRateCard(c.Request.Context(), branchID, &buf); err != nil {
+		c.JSON(scopedStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="rates-%s.csv"`, branchID))
+	c.Data(http.StatusOK, "text/csv", buf.Bytes())
+}
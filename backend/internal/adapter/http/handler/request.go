@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"errors"
 	"log"
 	"net/http"
 
@@ -57,13 +58,13 @@ func (h *RequestHandler) CreateRequest(c *gin.Context) {
 		PoNumber               string `json:"po_number"`
 		CustomRequirementsText string `json:"custom_requirements_text"`
 		StaffRequirements      []struct {
-			UUID      string  `json:"uuid"`
-			Date      string  `json:"date"`
-			Position  string  `json:"position"`
-			Count     int     `json:"count"`
-			StartTime string  `json:"start_time"`
-			EndTime   string  `json:"end_time"`
-			Rate      float64 `json:"rate"`
+			UUID      string       `json:"uuid"`
+			Date      string       `json:"date"`
+			Position  string       `json:"position"`
+			Count     int          `json:"count"`
+			StartTime string       `json:"start_time"`
+			EndTime   string       `json:"end_time"`
+			Rate      models.Money `json:"rate"`
 		} `json:"staff_requirements"`
 	}
 
@@ -126,6 +127,11 @@ func (h *RequestHandler) CreateRequest(c *gin.Context) {
 	}
 
 	err := h.requestService.CreateRequest(c.Request.Context(), &request, staffRequirements)
+	if errors.Is(err, models.ErrNoApplicableRate) {
+		log.Printf("Error creating request: %v", err)
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		log.Printf("Error creating request: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -143,7 +149,7 @@ func (h *RequestHandler) GetRequestById(c *gin.Context) {
 	requestId := c.Param("id")
 	request, err := h.requestService.GetRequestById(c.Request.Context(), uuid.MustParse(requestId))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(scopedStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
 		return
 	}
 	c.JSON(http.StatusOK, request)
@@ -153,7 +159,7 @@ func (h *RequestHandler) GetRequestById(c *gin.Context) {
 func (h *RequestHandler) GetAllRequests(c *gin.Context) {
 	requests, err := h.requestService.GetAllRequests(c.Request.Context())
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(scopedStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
 		return
 	}
 	c.JSON(http.StatusOK, requests)
@@ -164,7 +170,7 @@ func (h *RequestHandler) GetRequestsByEventId(c *gin.Context) {
 	eventId := c.Param("id")
 	requests, err := h.requestService.GetRequestsByEventId(c.Request.Context(), uuid.MustParse(eventId))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(scopedStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
 		return
 	}
 	c.JSON(http.StatusOK, requests)
@@ -181,7 +187,7 @@ func (h *RequestHandler) GetRequestsByBranchID(c *gin.Context) {
 
 	requests, err := h.requestService.GetRequestsByBranchID(c.Request.Context(), uuid)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(scopedStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
 		return
 	}
 
@@ -217,6 +223,11 @@ func (h *RequestHandler) UpdateRequest(c *gin.Context) {
 		existingRequest.LastName = val
 	}
 	if val, ok := updates["email"].(string); ok {
+		// a new address gets a fresh chance after a bounce
+		if val != existingRequest.Email {
+			existingRequest.EmailInvalid = false
+			existingRequest.EmailInvalidReason = ""
+		}
 		existingRequest.Email = val
 	}
 	if val, ok := updates["company_name"].(string); ok {
@@ -230,7 +241,7 @@ func (h *RequestHandler) UpdateRequest(c *gin.Context) {
 
 	err = h.requestService.UpdateRequest(c.Request.Context(), &existingRequest)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(scopedStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
 		return
 	}
 	c.JSON(http.StatusOK, existingRequest)
@@ -241,7 +252,7 @@ func (h *RequestHandler) DeleteRequest(c *gin.Context) {
 	requestId := c.Param("id")
 	err := h.requestService.DeleteRequest(c.Request.Context(), uuid.MustParse(requestId))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(scopedStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "Request deleted successfully"})
@@ -0,0 +1,109 @@
+package handler
+
+import (
+	"backend/internal/core/models"
+	"backend/internal/core/ports"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type ShiftHandler struct {
+	svc ports.ShiftService
+}
+
+func NewShiftHandler(svc ports.ShiftService) *ShiftHandler {
+	return &ShiftHandler{svc: svc}
+}
+
+func (h *ShiftHandler) GetShiftsByEventID(c *gin.Context) {
+	eventID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid UUID format"})
+		return
+	}
+
+	shifts, err := h.svc.GetShiftsByEventID(c.Request.Context(), eventID)
+	if err != nil {
+		respondEventError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, shifts)
+}
+
+func (h *ShiftHandler) CreateShift(c *gin.Context) {
+	eventID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid UUID format"})
+		return
+	}
+
+	var input models.ShiftInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	shift, err := h.svc.CreateShift(c.Request.Context(), eventID, input)
+	if err != nil {
+		respondEventError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, shift)
+}
+
+func (h *ShiftHandler) GetShiftByID(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid UUID format"})
+		return
+	}
+
+	shift, err := h.svc.GetShiftByID(c.Request.Context(), id)
+	if err != nil {
+		respondEventError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, shift)
+}
+
+func (h *ShiftHandler) UpdateShift(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid UUID format"})
+		return
+	}
+
+	var input models.ShiftInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	shift, err := h.svc.UpdateShift(c.Request.Context(), id, input)
+	if err != nil {
+		respondEventError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, shift)
+}
+
+func (h *ShiftHandler) DeleteShift(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid UUID format"})
+		return
+	}
+
+	if err := h.svc.DeleteShift(c.Request.Context(), id); err != nil {
+		respondEventError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Shift deleted successfully"})
+}
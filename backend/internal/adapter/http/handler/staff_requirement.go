@@ -4,6 +4,7 @@ import (
 	"backend/internal/config"
 	"backend/internal/core/models"
 	"backend/internal/core/ports"
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -30,7 +31,11 @@ func (h *StaffRequirementHandler) CreateStaffRequirement(c *gin.Context) {
 	}
 
 	if err := h.staffRequirementService.CreateStaffRequirement(c.Request.Context(), &staffRequirement); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		if errors.Is(err, models.ErrNoApplicableRate) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(scopedStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
 		return
 	}
 
@@ -47,7 +52,7 @@ func (h *StaffRequirementHandler) GetStaffRequirementById(c *gin.Context) {
 
 	staffRequirement, err := h.staffRequirementService.GetStaffRequirementById(c.Request.Context(), uuid)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(scopedStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
 		return
 	}
 
@@ -64,7 +69,7 @@ func (h *StaffRequirementHandler) GetAllStaffRequirements(c *gin.Context) { // t
 
 	staffRequirements, err := h.staffRequirementService.GetAllStaffRequirementsByRequestID(c.Request.Context(), uuid)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(scopedStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
 		return
 	}
 
@@ -88,7 +93,11 @@ func (h *StaffRequirementHandler) UpdateStaffRequirement(c *gin.Context) {
 	staffRequirement.UUID = uuid
 
 	if err := h.staffRequirementService.UpdateStaffRequirement(c.Request.Context(), &staffRequirement); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		if errors.Is(err, models.ErrNoApplicableRate) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(scopedStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
 		return
 	}
 
@@ -104,7 +113,7 @@ func (h *StaffRequirementHandler) DeleteStaffRequirement(c *gin.Context) {
 	}
 
 	if err := h.staffRequirementService.DeleteStaffRequirement(c.Request.Context(), uuid); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(scopedStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
 		return
 	}
 
@@ -121,7 +130,7 @@ func (h *StaffRequirementHandler) GetStaffRequirementsByRequestID(c *gin.Context
 
 	staffRequirements, err := h.staffRequirementService.GetAllStaffRequirementsByRequestID(c.Request.Context(), uuid)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(scopedStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
 		return
 	}
 
@@ -41,6 +41,14 @@ func (h *StripeHandler) CreatePaymentIntent(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get invoice"})
 		return
 	}
+
+	// the client sees the invoice number on the payment, so the invoice is issued now
+	if err := h.invoiceService.AssignInvoiceNumber(c.Request.Context(), invoice); err != nil {
+		log.Printf("Failed to number invoice: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to number invoice"})
+		return
+	}
+
 	paymentIntent, err := h.stripeService.CreatePaymentIntent(c.Request.Context(), invoice)
 	if err != nil {
 		log.Printf("Failed to create payment intent: %v", err)
@@ -65,6 +73,12 @@ func (h *StripeHandler) CreateCheckoutSession(c *gin.Context) {
 		return
 	}
 
+	if err := h.invoiceService.AssignInvoiceNumber(c.Request.Context(), invoice); err != nil {
+		log.Printf("Failed to number invoice: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to number invoice"})
+		return
+	}
+
 	staffRequirements, err := h.staffRequirementService.GetAllStaffRequirementsByRequestID(c.Request.Context(), invoice.RequestID)
 	if err != nil {
 		log.Printf("Failed to get staff requirements: %v", err)
@@ -97,14 +111,32 @@ func (h *StripeHandler) RefundPayment(c *gin.Context) {
 		return
 	}
 
-	refund, err := h.stripeService.RefundPayment(c.Request.Context(), invoice)
+	refunds, err := h.stripeService.RefundPayment(c.Request.Context(), invoice)
 	if err != nil {
 		log.Printf("Failed to refund payment: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refund payment"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refund payment", "refunds": refunds})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"refunds": refunds})
+}
+
+// GetInvoicePayments lists the payments and refunds recorded against an invoice
+func (h *StripeHandler) GetInvoicePayments(c *gin.Context) {
+	parsedID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid UUID format"})
+		return
+	}
+
+	entries, err := h.stripeService.GetLedgerEntriesByInvoiceID(c.Request.Context(), parsedID)
+	if err != nil {
+		log.Printf("Failed to get payments for invoice %s: %v", parsedID, err)
+		c.JSON(scopedStatus(err, http.StatusInternalServerError), gin.H{"error": "Failed to get invoice payments"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"refund": refund})
+	c.JSON(http.StatusOK, entries)
 }
 
 // Webhook handles incoming events from Stripe.
@@ -0,0 +1,174 @@
+package handler
+
+import (
+	"backend/internal/core/models"
+	"backend/internal/core/ports"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type SurchargeHandler struct {
+	svc ports.SurchargeService
+}
+
+func NewSurchargeHandler(svc ports.SurchargeService) *SurchargeHandler {
+	return &SurchargeHandler{svc: svc}
+}
+
+// branchIDQuery parses the optional branch_id query param
+func branchIDQuery(c *gin.Context) (*uuid.UUID, bool) {
+	branch := c.Query("branch_id")
+	if branch == "" {
+		return nil, true
+	}
+
+	parsed, err := uuid.Parse(branch)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid UUID format"})
+		return nil, false
+	}
+	return &parsed, true
+}
+
+func (h *SurchargeHandler) CreateSurchargeRule(c *gin.Context) {
+	// rules are active unless the body says otherwise
+	rule := models.SurchargeRule{Active: true}
+	if err := c.ShouldBindJSON(&rule); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.svc.CreateSurchargeRule(c.Request.Context(), &rule); err != nil {
+		c.JSON(scopedStatus(err, http.StatusBadRequest), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, rule)
+}
+
+// GetSurchargeRules lists rules, optionally filtered by the branch_id query param
+func (h *SurchargeHandler) GetSurchargeRules(c *gin.Context) {
+	branchID, ok := branchIDQuery(c)
+	if !ok {
+		return
+	}
+
+	rules, err := h.svc.GetSurchargeRules(c.Request.Context(), branchID)
+	if err != nil {
+		c.JSON(scopedStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, rules)
+}
+
+func (h *SurchargeHandler) GetSurchargeRuleByID(c *gin.Context) {
+	id := c.Param("id")
+	uuid, err := uuid.Parse(id)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid UUID format"})
+		return
+	}
+
+	rule, err := h.svc.GetSurchargeRuleByID(c.Request.Context(), uuid)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Surcharge rule not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, rule)
+}
+
+func (h *SurchargeHandler) UpdateSurchargeRule(c *gin.Context) {
+	id := c.Param("id")
+	uuid, err := uuid.Parse(id)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid UUID format"})
+		return
+	}
+
+	existingRule, err := h.svc.GetSurchargeRuleByID(c.Request.Context(), uuid)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Surcharge rule not found"})
+		return
+	}
+
+	if err := c.ShouldBindJSON(existingRule); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	existingRule.UUID = uuid
+
+	if err := h.svc.UpdateSurchargeRule(c.Request.Context(), existingRule); err != nil {
+		c.JSON(scopedStatus(err, http.StatusBadRequest), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, existingRule)
+}
+
+func (h *SurchargeHandler) DeleteSurchargeRule(c *gin.Context) {
+	id := c.Param("id")
+	uuid, err := uuid.Parse(id)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid UUID format"})
+		return
+	}
+
+	if err := h.svc.DeleteSurchargeRule(c.Request.Context(), uuid); err != nil {
+		c.JSON(scopedStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Surcharge rule deleted successfully"})
+}
+
+func (h *SurchargeHandler) CreateHoliday(c *gin.Context) {
+	var holiday models.Holiday
+	if err := c.ShouldBindJSON(&holiday); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.svc.CreateHoliday(c.Request.Context(), &holiday); err != nil {
+		c.JSON(scopedStatus(err, http.StatusBadRequest), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, holiday)
+}
+
+// GetHolidays lists the holiday calendar, optionally for a single branch via the branch_id query param
+func (h *SurchargeHandler) GetHolidays(c *gin.Context) {
+	branchID, ok := branchIDQuery(c)
+	if !ok {
+		return
+	}
+
+	holidays, err := h.svc.GetHolidays(c.Request.Context(), branchID)
+	if err != nil {
+		c.JSON(scopedStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, holidays)
+}
+
+func (h *SurchargeHandler) DeleteHoliday(c *gin.Context) {
+	id := c.Param("id")
+	uuid, err := uuid.Parse(id)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid UUID format"})
+		return
+	}
+
+	if err := h.svc.DeleteHoliday(c.Request.Context(), uuid); err != nil {
+		c.JSON(scopedStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Holiday deleted successfully"})
+}
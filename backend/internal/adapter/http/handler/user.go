@@ -0,0 +1,203 @@
+package handler
+
+import (
+	"backend/internal/adapter/http/middleware"
+	"backend/internal/core/models"
+	"backend/internal/core/ports"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type UserHandler struct {
+	svc ports.UserService
+}
+
+func NewUserHandler(svc ports.UserService) *UserHandler {
+	return &UserHandler{svc: svc}
+}
+
+// GetAllUsers lists the directory, optionally filtered by the branch_id and role query params.
+// Deactivated users are left out unless include_deactivated=true.
+func (h *UserHandler) GetAllUsers(c *gin.Context) {
+	var filter models.UserFilter
+	if branch := c.Query("branch_id"); branch != "" {
+		branchID, err := uuid.Parse(branch)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid UUID format"})
+			return
+		}
+		filter.BranchID = &branchID
+	}
+	filter.Role = c.Query("role")
+	if include := c.Query("include_deactivated"); include != "" {
+		parsed, err := strconv.ParseBool(include)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "include_deactivated must be true or false"})
+			return
+		}
+		filter.IncludeDeactivated = parsed
+	}
+
+	users, err := h.svc.ListUsers(c.Request.Context(), filter)
+	if err != nil {
+		respondUserError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, users)
+}
+
+func (h *UserHandler) GetUserByID(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid UUID format"})
+		return
+	}
+
+	user, err := h.svc.GetUserByID(c.Request.Context(), id)
+	if err != nil {
+		respondUserError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
+func (h *UserHandler) InviteUser(c *gin.Context) {
+	actor, ok := middleware.CurrentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var invite models.UserInvite
+	if err := c.ShouldBindJSON(&invite); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := h.svc.InviteUser(c.Request.Context(), actor, invite)
+	if err != nil {
+		respondUserError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, user)
+}
+
+// UpdateUserAccess changes a user's role and branch
+func (h *UserHandler) UpdateUserAccess(c *gin.Context) {
+	actor, ok := middleware.CurrentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid UUID format"})
+		return
+	}
+
+	var update models.UserAccessUpdate
+	if err := c.ShouldBindJSON(&update); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := h.svc.UpdateAccess(c.Request.Context(), actor, id, update)
+	if err != nil {
+		respondUserError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
+func (h *UserHandler) DeactivateUser(c *gin.Context) {
+	h.setActive(c, false)
+}
+
+func (h *UserHandler) ReactivateUser(c *gin.Context) {
+	h.setActive(c, true)
+}
+
+func (h *UserHandler) setActive(c *gin.Context, active bool) {
+	actor, ok := middleware.CurrentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid UUID format"})
+		return
+	}
+
+	var user *models.User
+	if active {
+		user, err = h.svc.ReactivateUser(c.Request.Context(), actor, id)
+	} else {
+		user, err = h.svc.DeactivateUser(c.Request.Context(), actor, id)
+	}
+	if err != nil {
+		respondUserError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
+// GetProfile returns the signed in user and what their role lets them do
+func (h *UserHandler) GetProfile(c *gin.Context) {
+	user, ok := middleware.CurrentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	c.JSON(http.StatusOK, h.svc.GetProfile(c.Request.Context(), user))
+}
+
+func (h *UserHandler) UpdateProfile(c *gin.Context) {
+	user, ok := middleware.CurrentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var update models.ProfileUpdate
+	if err := c.ShouldBindJSON(&update); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	profile, err := h.svc.UpdateProfile(c.Request.Context(), user, update)
+	if err != nil {
+		respondUserError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, profile)
+}
+
+func respondUserError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, models.ErrUserNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, models.ErrInvalidRole):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, models.ErrRoleNotAllowed), errors.Is(err, models.ErrCannotChangeSelf),
+		errors.Is(err, models.ErrOutOfBranchScope):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, models.ErrUserExists):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		log.Printf("User request failed: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -1,72 +1,134 @@
 package middleware
 
 import (
-	"context"
+	"log"
 	"net/http"
+	"strings"
 
 	"backend/internal/core/models"
 
 	"github.com/gin-gonic/gin"
 )
 
+// gin context key the signed in user is kept under
+const userContextKey = "user"
+
 func (m *MiddlewareService) AuthMiddleware() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
-		// get user from session
-		user, ok := m.sm.Get(ctx, "user").(models.User)
+		user, ok := m.currentUser(ctx)
 		if !ok {
 			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 			return
 		}
-		// check if user is in database and check their role
-		foundUser, err := m.userRepo.GetUserByID(context.Background(), user.UUID)
-		if err != nil {
-			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
-			return
-		}
-		user = *foundUser
 		// set user information in context for use in protected routes
-		ctx.Set("user", user)
+		setUser(ctx, user)
 		ctx.Next()
-
 	}
 }
 
-// LaxAuthMiddleware verifies the JWT token from the cookie and checks user status in database. Does not abort if user is not authenticated
+// LaxAuthMiddleware sets the user when there is one. Does not abort if user is not authenticated
 func (m *MiddlewareService) LaxAuthMiddleware() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
-		user, ok := m.sm.Get(ctx, "user").(models.User)
+		if user, ok := m.currentUser(ctx); ok {
+			setUser(ctx, user)
+		}
+		ctx.Next()
+	}
+}
+
+// RequirePermission lets the request through only if the signed in user's role grants every
+// permission given. It runs after AuthMiddleware.
+func (m *MiddlewareService) RequirePermission(permissions ...models.Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, ok := CurrentUser(c)
 		if !ok {
-			ctx.Next()
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 			return
 		}
-		// check if user is in db
-		foundUser, err := m.userRepo.GetUserByID(context.Background(), user.UUID)
-		if err != nil {
-			ctx.Next()
-			return
+		for _, permission := range permissions {
+			if !user.Can(permission) {
+				c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Forbidden", "missing_permission": permission})
+				return
+			}
 		}
-		user = *foundUser
-
-		// set user information in context for use in protected routes
-		ctx.Set("user", user)
-		ctx.Next()
+		c.Next()
 	}
 }
 
 // AdminAccess restricts routes so only users with admin/superadmin/account executive role can access
 func (m *MiddlewareService) AdminAccess() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		if m.cfg.App.Env == "production" {
-			if isAuthorized, exists := c.Get("authorized"); !exists || !isAuthorized.(bool) {
-				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
-				return
-			}
-			role, _ := c.Get("role")
-			if role != "admin" && role != "superadmin" && role != "account-executive" {
-				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
-				return
-			}
+		user, ok := CurrentUser(c)
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+		if user.Role != models.RoleAdmin && user.Role != models.RoleSuperAdmin && user.Role != models.RoleAccountExecutive {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
+			return
 		}
 		c.Next()
 	}
 }
+
+// CurrentUser is the user AuthMiddleware or LaxAuthMiddleware signed in
+func CurrentUser(c *gin.Context) (*models.User, bool) {
+	value, ok := c.Get(userContextKey)
+	if !ok {
+		return nil, false
+	}
+	user, ok := value.(*models.User)
+	return user, ok
+}
+
+// currentUser verifies the Cognito token in the Authorization header and looks its user up in
+// the database, so a changed role or a deactivated user takes effect straight away
+func (m *MiddlewareService) currentUser(ctx *gin.Context) (*models.User, bool) {
+	token, ok := strings.CutPrefix(ctx.GetHeader("Authorization"), "Bearer ")
+	if !ok || token == "" || m.tokenSvc == nil || m.userRepo == nil {
+		return nil, false
+	}
+
+	payload, err := m.tokenSvc.VerifyToken(ctx.Request.Context(), strings.TrimSpace(token))
+	if err != nil {
+		log.Printf("Rejected token: %v", err)
+		return nil, false
+	}
+
+	user, err := m.userRepo.GetUserByCognitoSub(ctx.Request.Context(), payload.Subject)
+	if err != nil {
+		log.Printf("Failed to look up user for token: %v", err)
+		return nil, false
+	}
+	if user != nil {
+		// deactivated users keep valid Cognito tokens until they expire
+		return user, user.Active()
+	}
+
+	// users from before cognito_sub are linked on their first sign in, by verified email only
+	if payload.TokenUse != "id" || !payload.EmailVerified || payload.Email == "" {
+		return nil, false
+	}
+	user, err = m.userRepo.GetUserByEmail(ctx.Request.Context(), payload.Email)
+	if err != nil || user == nil || user.CognitoSub != nil || !user.Active() {
+		return nil, false
+	}
+	linked, err := m.userRepo.LinkCognitoSub(ctx.Request.Context(), user.UUID, payload.Subject)
+	if err != nil || !linked {
+		return nil, false
+	}
+	log.Printf("Linked user %s to Cognito user %s", user.UUID, payload.Subject)
+	user.CognitoSub = &payload.Subject
+	return user, true
+}
+
+// setUser keeps the user for handlers and, unless they act across branches, limits everything
+// the request reads or changes to their branch
+func setUser(ctx *gin.Context, user *models.User) {
+	ctx.Set(userContextKey, user)
+	reqCtx := models.WithActor(ctx.Request.Context(), user.Email)
+	if !user.AllBranches() {
+		reqCtx = models.WithBranchScope(reqCtx, user.BranchID)
+	}
+	ctx.Request = ctx.Request.WithContext(reqCtx)
+}
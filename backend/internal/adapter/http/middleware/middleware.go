@@ -2,6 +2,7 @@ package middleware
 
 import (
 	"backend/internal/config"
+	"backend/internal/core/models"
 	"backend/internal/core/ports"
 
 	gin_adapter "github.com/39george/scs_gin_adapter"
@@ -12,6 +13,7 @@ type Middleware interface {
 	AuthMiddleware() gin.HandlerFunc
 	LaxAuthMiddleware() gin.HandlerFunc
 	AdminAccess() gin.HandlerFunc
+	RequirePermission(permissions ...models.Permission) gin.HandlerFunc
 	CORS() gin.HandlerFunc
 }
 
@@ -7,6 +7,7 @@ import (
 	"backend/internal/adapter/http/handler"
 	"backend/internal/adapter/http/middleware"
 	"backend/internal/config"
+	"backend/internal/core/models"
 )
 
 // Router is a wrapper for HTTP router
@@ -19,12 +20,11 @@ func NewRouter(
 	sessionAdapter *gin_adapter.GinAdapter,
 	// presignedUrlHandler *handler.PresignedUrlHandler,
 	// branchesHandler *handler.BranchHandler,
-	// userHandler *handler.UserHandler,
 	requestHandler *handler.RequestHandler,
 	geolocationHandler *handler.GeolocationHandler,
 	invoiceHandler *handler.InvoiceHandler,
-	// eventHandler *handler.EventHandler,
-	// shiftHandler *handler.ShiftHandler,
+	eventHandler *handler.EventHandler,
+	shiftHandler *handler.ShiftHandler,
 	// shiftAssignmentHandler *handler.ShiftAssignmentHandler,
 	staffRequirementHandler *handler.StaffRequirementHandler,
 	// uniformHandler *handler.UniformHandler,
@@ -35,6 +35,15 @@ func NewRouter(
 	customLineItemsHandler *handler.CustomLineItemsHandler,
 	calculateRatesHandler *handler.CalculateRatesHandler,
 	cronHandler *handler.CronHandler,
+	pricingPolicyHandler *handler.PricingPolicyHandler,
+	promoCodeHandler *handler.PromoCodeHandler,
+	surchargeHandler *handler.SurchargeHandler,
+	rateHandler *handler.RateHandler,
+	dunningHandler *handler.DunningHandler,
+	emailTemplateHandler *handler.EmailTemplateHandler,
+	cancellationHandler *handler.CancellationHandler,
+	clientHandler *handler.ClientHandler,
+	userHandler *handler.UserHandler,
 ) *Router {
 	if cfg.App.Env != "development" {
 		gin.SetMode(gin.ReleaseMode)
@@ -54,7 +63,18 @@ func NewRouter(
 		c.JSON(200, gin.H{"status": "healthy"})
 	})
 
-	apiGroup := router.Group("api")
+	// the booking form and the Stripe and Mailgun webhooks are the only routes open without
+	// signing in, webhooks check their own signatures
+	publicGroup := router.Group("api")
+	{
+		publicGroup.POST("/requests", requestHandler.CreateRequest)
+		publicGroup.POST("/stripe/webhook", stripeHandler.Webhook)
+		publicGroup.POST("/emails/webhook", emailHandler.Webhook)
+	}
+
+	// everything else needs a signed in user whose role grants the route's permission
+	can := middleware.RequirePermission
+	apiGroup := router.Group("api", middleware.AuthMiddleware())
 	{
 		// authGroup := apiGroup.Group("/auth")
 		// {
@@ -74,57 +94,86 @@ func NewRouter(
 		// 	branchGroup.PUT(":id", branchesHandler.UpdateBranch)
 		// 	branchGroup.DELETE(":id", branchesHandler.DeleteBranch)
 		// }
-		// userGroup := apiGroup.Group("/users")
-		// {
-		// 	userGroup.GET("", userHandler.GetAllUsers)
-		// 	userGroup.GET(":id", userHandler.GetUserById)
-		// 	userGroup.POST("", userHandler.CreateUser)
-		// 	userGroup.PUT(":id", userHandler.UpdateUser)
-		// 	userGroup.DELETE(":id", userHandler.DeleteUser)
-		// }
+		// every signed in user sees and edits their own profile
+		apiGroup.GET("/me", userHandler.GetProfile)
+		apiGroup.PUT("/me", userHandler.UpdateProfile)
+		userGroup := apiGroup.Group("/users")
+		{
+			userGroup.GET("", can(models.PermissionUsersRead), userHandler.GetAllUsers)
+			userGroup.GET(":id", can(models.PermissionUsersRead), userHandler.GetUserByID)
+			userGroup.POST("", can(models.PermissionUsersManage), userHandler.InviteUser)
+			userGroup.PUT(":id/access", can(models.PermissionUsersManage), userHandler.UpdateUserAccess)
+			userGroup.POST(":id/deactivate", can(models.PermissionUsersManage), userHandler.DeactivateUser)
+			userGroup.POST(":id/reactivate", can(models.PermissionUsersManage), userHandler.ReactivateUser)
+		}
 		requestGroup := apiGroup.Group("/requests")
 		{
-			requestGroup.POST("", requestHandler.CreateRequest)
-			requestGroup.GET("", requestHandler.GetAllRequests)
-			requestGroup.GET(":id", requestHandler.GetRequestById)
-			requestGroup.GET(":id/events", requestHandler.GetRequestsByEventId)
-			requestGroup.GET("/branch/:branch_id", requestHandler.GetRequestsByBranchID)
-			requestGroup.PUT(":id", requestHandler.UpdateRequest)
-			requestGroup.DELETE(":id", requestHandler.DeleteRequest)
+			requestGroup.GET("", can(models.PermissionRequestsRead), requestHandler.GetAllRequests)
+			requestGroup.GET(":id", can(models.PermissionRequestsRead), requestHandler.GetRequestById)
+			requestGroup.GET(":id/events", can(models.PermissionRequestsRead), requestHandler.GetRequestsByEventId)
+			requestGroup.GET(":id/emails", can(models.PermissionEmailsRead), emailHandler.GetEmailLogsByRequestID)
+			requestGroup.GET(":id/invoices", can(models.PermissionInvoicesRead), invoiceHandler.GetInvoicesByRequestID)
+			requestGroup.GET(":id/invoice-summary", can(models.PermissionInvoicesRead), invoiceHandler.GetRequestInvoiceSummary)
+			requestGroup.POST(":id/invoices/deposit", can(models.PermissionInvoicesWrite), invoiceHandler.CreateDepositInvoice)
+			requestGroup.POST(":id/invoices/change-order", can(models.PermissionInvoicesWrite), invoiceHandler.CreateChangeOrderInvoice)
+			requestGroup.GET(":id/event", can(models.PermissionEventsRead), eventHandler.GetEventByRequestID)
+			requestGroup.POST(":id/event", can(models.PermissionEventsWrite), eventHandler.ScheduleRequest)
+			requestGroup.GET(":id/cancellation", can(models.PermissionRequestsRead), cancellationHandler.QuoteCancellation)
+			requestGroup.POST(":id/cancel", can(models.PermissionRequestsCancel), cancellationHandler.CancelRequest)
+			requestGroup.POST(":id/cancel/refund", can(models.PermissionPaymentsRefund), cancellationHandler.RetryCancellationRefund)
+			requestGroup.GET("/cancellation-policy", can(models.PermissionRequestsRead), cancellationHandler.GetCancellationPolicy)
+			requestGroup.GET("/branch/:branch_id", can(models.PermissionRequestsRead), requestHandler.GetRequestsByBranchID)
+			requestGroup.PUT(":id", can(models.PermissionRequestsWrite), requestHandler.UpdateRequest)
+			requestGroup.DELETE(":id", can(models.PermissionRequestsDelete), requestHandler.DeleteRequest)
 		}
 		geolocationGroup := apiGroup.Group("/geolocation")
 		{
-			geolocationGroup.GET("/geocode", geolocationHandler.GeoCodeAddress)
-			geolocationGroup.GET("/nearest-branch", geolocationHandler.FindClosestBranch)
+			geolocationGroup.GET("/geocode", can(models.PermissionRequestsRead), geolocationHandler.GeoCodeAddress)
+			geolocationGroup.GET("/nearest-branch", can(models.PermissionRequestsRead), geolocationHandler.FindClosestBranch)
 		}
 		invoicesGroup := apiGroup.Group("/invoices")
 		{
-			invoicesGroup.GET("", invoiceHandler.GetInvoiceByRequestID)
-			invoicesGroup.GET("/request/:id", invoiceHandler.GetInvoiceByRequestID)
-			invoicesGroup.GET(":id", invoiceHandler.GetInvoiceByID)
-			invoicesGroup.GET("/branch/:branch_id", invoiceHandler.GetInvoiceByBranchID)
-			invoicesGroup.GET("/overdue/:branch_id", invoiceHandler.CheckForOverdueInvoices)
-			invoicesGroup.POST("", invoiceHandler.CreateInvoice)
-			invoicesGroup.PUT(":id", invoiceHandler.UpdateInvoice)
-			invoicesGroup.DELETE(":id", invoiceHandler.DeleteInvoice)
-			invoicesGroup.POST(":id/recalculate", invoiceHandler.RecalculateInvoiceWithNewItems)
-		}
-		// eventGroup := apiGroup.Group("/events")
-		// {
-		// 	eventGroup.GET("", eventHandler.GetAllEvents)
-		// 	eventGroup.GET(":id", eventHandler.GetEventById)
-		// 	eventGroup.POST("", eventHandler.CreateEvent)
-		// 	eventGroup.PUT(":id", eventHandler.UpdateEvent)
-		// 	eventGroup.DELETE(":id", eventHandler.DeleteEvent)
-		// }
-		// shiftGroup := apiGroup.Group("/shifts")
-		// {
-		// 	shiftGroup.GET("", shiftHandler.GetAllShifts)
-		// 	shiftGroup.GET(":id", shiftHandler.GetShiftById)
-		// 	shiftGroup.POST("", shiftHandler.CreateShift)
-		// 	shiftGroup.PUT(":id", shiftHandler.UpdateShift)
-		// 	shiftGroup.DELETE(":id", shiftHandler.DeleteShift)
-		// }
+			invoicesGroup.GET("", can(models.PermissionInvoicesRead), invoiceHandler.GetInvoiceByRequestID)
+			invoicesGroup.GET("/request/:id", can(models.PermissionInvoicesRead), invoiceHandler.GetInvoiceByRequestID)
+			invoicesGroup.GET("/search", can(models.PermissionInvoicesRead), invoiceHandler.SearchInvoices)
+			invoicesGroup.GET(":id", can(models.PermissionInvoicesRead), invoiceHandler.GetInvoiceByID)
+			invoicesGroup.GET("/branch/:branch_id", can(models.PermissionInvoicesRead), invoiceHandler.GetInvoiceByBranchID)
+			invoicesGroup.GET("/overdue/:branch_id", can(models.PermissionInvoicesRead), invoiceHandler.CheckForOverdueInvoices)
+			invoicesGroup.POST("", can(models.PermissionInvoicesWrite), invoiceHandler.CreateInvoice)
+			invoicesGroup.PUT(":id", can(models.PermissionInvoicesWrite), invoiceHandler.UpdateInvoice)
+			invoicesGroup.DELETE(":id", can(models.PermissionInvoicesDelete), invoiceHandler.DeleteInvoice)
+			invoicesGroup.POST(":id/recalculate", can(models.PermissionInvoicesWrite), invoiceHandler.RecalculateInvoiceWithNewItems)
+			invoicesGroup.PUT(":id/discount", can(models.PermissionInvoicesWrite), invoiceHandler.ApplyDiscount)
+			invoicesGroup.DELETE(":id/discount", can(models.PermissionInvoicesWrite), invoiceHandler.RemoveDiscount)
+			invoicesGroup.GET(":id/emails", can(models.PermissionEmailsRead), emailHandler.GetEmailLogsByInvoiceID)
+			invoicesGroup.GET(":id/pdf", can(models.PermissionInvoicesRead), invoiceHandler.GetInvoicePDF)
+			invoicesGroup.GET(":id/payments", can(models.PermissionInvoicesRead), stripeHandler.GetInvoicePayments)
+			invoicesGroup.POST(":id/status", can(models.PermissionInvoicesWrite), invoiceHandler.TransitionInvoice)
+			invoicesGroup.GET(":id/history", can(models.PermissionInvoicesRead), invoiceHandler.GetInvoiceStatusHistory)
+			invoicesGroup.POST(":id/charge", can(models.PermissionPaymentsCharge), clientHandler.ChargeInvoice)
+		}
+		clientGroup := apiGroup.Group("/clients")
+		{
+			clientGroup.GET("", can(models.PermissionClientsRead), clientHandler.GetAllClients)
+			clientGroup.GET(":id", can(models.PermissionClientsRead), clientHandler.GetClientByID)
+			clientGroup.PUT(":id", can(models.PermissionClientsWrite), clientHandler.UpdateClient)
+			clientGroup.GET(":id/payment-methods", can(models.PermissionClientsRead), clientHandler.GetPaymentMethods)
+			clientGroup.PUT(":id/payment-methods/default", can(models.PermissionClientsWrite), clientHandler.SetDefaultPaymentMethod)
+		}
+		eventGroup := apiGroup.Group("/events")
+		{
+			eventGroup.GET("", can(models.PermissionEventsRead), eventHandler.GetAllEvents)
+			eventGroup.GET(":id", can(models.PermissionEventsRead), eventHandler.GetEventByID)
+			eventGroup.PUT(":id/status", can(models.PermissionEventsWrite), eventHandler.UpdateEventStatus)
+			eventGroup.GET(":id/shifts", can(models.PermissionEventsRead), shiftHandler.GetShiftsByEventID)
+			eventGroup.POST(":id/shifts", can(models.PermissionEventsWrite), shiftHandler.CreateShift)
+		}
+		shiftGroup := apiGroup.Group("/shifts")
+		{
+			shiftGroup.GET(":id", can(models.PermissionEventsRead), shiftHandler.GetShiftByID)
+			shiftGroup.PUT(":id", can(models.PermissionEventsWrite), shiftHandler.UpdateShift)
+			shiftGroup.DELETE(":id", can(models.PermissionEventsWrite), shiftHandler.DeleteShift)
+		}
 		// shiftAssignmentGroup := apiGroup.Group("/shift-assignments")
 		// {
 		// 	shiftAssignmentGroup.GET("", shiftAssignmentHandler.GetAllShiftAssignments)
@@ -135,12 +184,12 @@ func NewRouter(
 		// }
 		staffRequirementGroup := apiGroup.Group("/staff-requirements")
 		{
-			staffRequirementGroup.GET("", staffRequirementHandler.GetAllStaffRequirements)
-			staffRequirementGroup.GET(":id", staffRequirementHandler.GetStaffRequirementById)
-			staffRequirementGroup.GET("/request/:id", staffRequirementHandler.GetStaffRequirementsByRequestID)
-			staffRequirementGroup.POST("", staffRequirementHandler.CreateStaffRequirement)
-			staffRequirementGroup.PUT(":id", staffRequirementHandler.UpdateStaffRequirement)
-			staffRequirementGroup.DELETE(":id", staffRequirementHandler.DeleteStaffRequirement)
+			staffRequirementGroup.GET("", can(models.PermissionStaffingRead), staffRequirementHandler.GetAllStaffRequirements)
+			staffRequirementGroup.GET(":id", can(models.PermissionStaffingRead), staffRequirementHandler.GetStaffRequirementById)
+			staffRequirementGroup.GET("/request/:id", can(models.PermissionStaffingRead), staffRequirementHandler.GetStaffRequirementsByRequestID)
+			staffRequirementGroup.POST("", can(models.PermissionStaffingWrite), staffRequirementHandler.CreateStaffRequirement)
+			staffRequirementGroup.PUT(":id", can(models.PermissionStaffingWrite), staffRequirementHandler.UpdateStaffRequirement)
+			staffRequirementGroup.DELETE(":id", can(models.PermissionStaffingWrite), staffRequirementHandler.DeleteStaffRequirement)
 		}
 		// uniformGroup := apiGroup.Group("/uniforms")
 		// {
@@ -152,35 +201,97 @@ func NewRouter(
 		// }
 		emailGroup := apiGroup.Group("/emails")
 		{
-			emailGroup.POST("/send/:request_id", emailHandler.SendEmail)
-			emailGroup.POST("/send-custom/:request_id", emailHandler.SendCustomEmail)
-			emailGroup.POST("/schedule/:request_id", emailHandler.ScheduleEmail)
+			emailGroup.POST("/send/:request_id", can(models.PermissionEmailsSend), emailHandler.SendEmail)
+			emailGroup.POST("/send-custom/:request_id", can(models.PermissionEmailsSend), emailHandler.SendCustomEmail)
+			emailGroup.POST("/schedule/:request_id", can(models.PermissionEmailsSend), emailHandler.ScheduleEmail)
+			emailGroup.POST("/attachments", can(models.PermissionEmailsSend), emailHandler.UploadAttachment)
+			emailGroup.GET("/attachments/:id", can(models.PermissionEmailsRead), emailHandler.DownloadAttachment)
+			emailGroup.GET("/scheduled/request/:request_id", can(models.PermissionEmailsRead), emailHandler.GetScheduledEmailsByRequestID)
+			emailGroup.GET("/scheduled/branch/:branch_id", can(models.PermissionEmailsRead), emailHandler.GetScheduledEmailsByBranchID)
+			emailGroup.GET("/scheduled/:id", can(models.PermissionEmailsRead), emailHandler.GetScheduledEmail)
+			emailGroup.GET("/scheduled/:id/preview", can(models.PermissionEmailsRead), emailHandler.PreviewScheduledEmail)
+			emailGroup.PUT("/scheduled/:id", can(models.PermissionEmailsSend), emailHandler.UpdateScheduledEmail)
+			emailGroup.DELETE("/scheduled/:id", can(models.PermissionEmailsSend), emailHandler.CancelScheduledEmail)
 		}
 		stripeGroup := apiGroup.Group("/stripe")
 		{
-			stripeGroup.POST("/create-checkout-session/:invoiceID", stripeHandler.CreateCheckoutSession)
-			stripeGroup.POST("/create-payment-intent/:invoiceID", stripeHandler.CreatePaymentIntent)
-			stripeGroup.POST("/refund-payment/:invoiceID", stripeHandler.RefundPayment)
-			stripeGroup.POST("/webhook", stripeHandler.Webhook)
+			stripeGroup.POST("/create-checkout-session/:invoiceID", can(models.PermissionPaymentsCollect), stripeHandler.CreateCheckoutSession)
+			stripeGroup.POST("/create-payment-intent/:invoiceID", can(models.PermissionPaymentsCollect), stripeHandler.CreatePaymentIntent)
+			stripeGroup.POST("/refund-payment/:invoiceID", can(models.PermissionPaymentsRefund), stripeHandler.RefundPayment)
 		}
 		customLineItemsGroup := apiGroup.Group("/custom-line-items")
 		{
-			customLineItemsGroup.POST("", customLineItemsHandler.CreateCustomLineItem)
-			customLineItemsGroup.GET(":id", customLineItemsHandler.GetCustomLineItemByID)
-			customLineItemsGroup.GET("/request/:id", customLineItemsHandler.GetCustomLineItemsByRequestID)
-			customLineItemsGroup.PUT(":id", customLineItemsHandler.UpdateCustomLineItem)
-			customLineItemsGroup.DELETE(":uuid", customLineItemsHandler.DeleteCustomLineItem)
+			customLineItemsGroup.POST("", can(models.PermissionInvoicesWrite), customLineItemsHandler.CreateCustomLineItem)
+			customLineItemsGroup.GET(":id", can(models.PermissionInvoicesRead), customLineItemsHandler.GetCustomLineItemByID)
+			customLineItemsGroup.GET("/request/:id", can(models.PermissionInvoicesRead), customLineItemsHandler.GetCustomLineItemsByRequestID)
+			customLineItemsGroup.PUT(":id", can(models.PermissionInvoicesWrite), customLineItemsHandler.UpdateCustomLineItem)
+			customLineItemsGroup.DELETE(":uuid", can(models.PermissionInvoicesWrite), customLineItemsHandler.DeleteCustomLineItem)
 		}
 		ratesGroup := apiGroup.Group("/rates")
 		{
-			ratesGroup.GET("/calculate/:request_id", calculateRatesHandler.CalculateRates)
-			ratesGroup.GET("/:request_id", calculateRatesHandler.GetRates)
-			ratesGroup.PUT("/:request_id", calculateRatesHandler.UpdateRates)
+			ratesGroup.GET("/calculate/:request_id", can(models.PermissionInvoicesRead), calculateRatesHandler.CalculateRates)
+			ratesGroup.GET("/:request_id", can(models.PermissionInvoicesRead), calculateRatesHandler.GetRates)
+			ratesGroup.PUT("/:request_id", can(models.PermissionInvoicesWrite), calculateRatesHandler.UpdateRates)
+			ratesGroup.GET("/branch/:branch_id", can(models.PermissionPricingRead), rateHandler.GetRatesByBranchID)
+			ratesGroup.POST("/branch/:branch_id", can(models.PermissionPricingWrite), rateHandler.CreateRate)
+			ratesGroup.GET("/branch/:branch_id/export", can(models.PermissionPricingRead), rateHandler.ExportRateCard)
+			ratesGroup.POST("/branch/:branch_id/import", can(models.PermissionPricingWrite), rateHandler.ImportRateCard)
+			ratesGroup.PUT("/branch/:branch_id/:id", can(models.PermissionPricingWrite), rateHandler.UpdateRate)
+			ratesGroup.DELETE("/branch/:branch_id/:id", can(models.PermissionPricingWrite), rateHandler.DeleteRate)
+			// regional and global fallbacks cover every branch, only superadmins change them
+			ratesGroup.GET("/defaults", can(models.PermissionPricingRead), rateHandler.GetDefaultRates)
+			ratesGroup.POST("/defaults", can(models.PermissionPricingWrite), rateHandler.CreateDefaultRate)
+			ratesGroup.PUT("/defaults/:id", can(models.PermissionPricingWrite), rateHandler.UpdateDefaultRate)
+			ratesGroup.DELETE("/defaults/:id", can(models.PermissionPricingWrite), rateHandler.DeleteDefaultRate)
+		}
+		pricingPolicyGroup := apiGroup.Group("/pricing-policies")
+		{
+			pricingPolicyGroup.GET("", can(models.PermissionPricingRead), pricingPolicyHandler.GetPricingPolicies)
+			pricingPolicyGroup.GET(":id", can(models.PermissionPricingRead), pricingPolicyHandler.GetPricingPolicyByID)
+			pricingPolicyGroup.POST("", can(models.PermissionPricingWrite), pricingPolicyHandler.CreatePricingPolicy)
+			pricingPolicyGroup.PUT(":id", can(models.PermissionPricingWrite), pricingPolicyHandler.UpdatePricingPolicy)
+			pricingPolicyGroup.DELETE(":id", can(models.PermissionPricingWrite), pricingPolicyHandler.DeletePricingPolicy)
+		}
+		promoCodeGroup := apiGroup.Group("/promo-codes")
+		{
+			promoCodeGroup.GET("", can(models.PermissionPricingRead), promoCodeHandler.GetPromoCodes)
+			promoCodeGroup.GET(":id", can(models.PermissionPricingRead), promoCodeHandler.GetPromoCodeByID)
+			promoCodeGroup.POST("", can(models.PermissionPricingWrite), promoCodeHandler.CreatePromoCode)
+			promoCodeGroup.PUT(":id", can(models.PermissionPricingWrite), promoCodeHandler.UpdatePromoCode)
+			promoCodeGroup.DELETE(":id", can(models.PermissionPricingWrite), promoCodeHandler.DeletePromoCode)
+		}
+		surchargeGroup := apiGroup.Group("/surcharges")
+		{
+			surchargeGroup.GET("/rules", can(models.PermissionPricingRead), surchargeHandler.GetSurchargeRules)
+			surchargeGroup.GET("/rules/:id", can(models.PermissionPricingRead), surchargeHandler.GetSurchargeRuleByID)
+			surchargeGroup.POST("/rules", can(models.PermissionPricingWrite), surchargeHandler.CreateSurchargeRule)
+			surchargeGroup.PUT("/rules/:id", can(models.PermissionPricingWrite), surchargeHandler.UpdateSurchargeRule)
+			surchargeGroup.DELETE("/rules/:id", can(models.PermissionPricingWrite), surchargeHandler.DeleteSurchargeRule)
+			surchargeGroup.GET("/holidays", can(models.PermissionPricingRead), surchargeHandler.GetHolidays)
+			surchargeGroup.POST("/holidays", can(models.PermissionPricingWrite), surchargeHandler.CreateHoliday)
+			surchargeGroup.DELETE("/holidays/:id", can(models.PermissionPricingWrite), surchargeHandler.DeleteHoliday)
+		}
+		dunningGroup := apiGroup.Group("/dunning")
+		{
+			dunningGroup.GET("/branch/:branch_id", can(models.PermissionInvoicesRead), dunningHandler.GetDunningSchedule)
+			dunningGroup.PUT("/branch/:branch_id", can(models.PermissionDunningManage), dunningHandler.UpdateDunningSchedule)
+			dunningGroup.DELETE("/branch/:branch_id", can(models.PermissionDunningManage), dunningHandler.DeleteDunningSchedule)
+			dunningGroup.POST("/run", can(models.PermissionDunningManage), dunningHandler.SendDueReminders)
+		}
+		emailTemplateGroup := apiGroup.Group("/email-templates")
+		{
+			emailTemplateGroup.GET("", can(models.PermissionEmailsRead), emailTemplateHandler.GetEmailTemplates)
+			emailTemplateGroup.GET("/:name", can(models.PermissionEmailsRead), emailTemplateHandler.GetEmailTemplate)
+			emailTemplateGroup.GET("/:name/versions", can(models.PermissionEmailsRead), emailTemplateHandler.GetEmailTemplateVersions)
+			emailTemplateGroup.POST("/:name", can(models.PermissionEmailTemplates), emailTemplateHandler.SaveEmailTemplate)
+			emailTemplateGroup.POST("/:name/preview", can(models.PermissionEmailTemplates), emailTemplateHandler.PreviewEmailTemplate)
+			emailTemplateGroup.POST("/versions/:id/restore", can(models.PermissionEmailTemplates), emailTemplateHandler.RestoreEmailTemplateVersion)
+			emailTemplateGroup.DELETE("/:name", can(models.PermissionEmailTemplates), emailTemplateHandler.ResetEmailTemplate)
 		}
 		adminRoutes := apiGroup.Group("/admin")
 		{
-			adminRoutes.POST("/cron/run", cronHandler.Run)
-			adminRoutes.POST("/cron/stop", cronHandler.Stop)
+			adminRoutes.POST("/cron/run", can(models.PermissionSystemManage), cronHandler.Run)
+			adminRoutes.POST("/cron/stop", can(models.PermissionSystemManage), cronHandler.Stop)
 		}
 	}
 
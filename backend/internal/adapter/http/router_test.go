@@ -0,0 +1,265 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	stdhttp "net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	gin_adapter "github.com/39george/scs_gin_adapter"
+	"github.com/alexedwards/scs/v2"
+	"github.com/google/uuid"
+
+	"backend/internal/adapter/http/handler"
+	"backend/internal/adapter/http/middleware"
+	"backend/internal/config"
+	"backend/internal/core/models"
+)
+
+// fakeTokens accepts the tokens it was given, each standing for its payload
+type fakeTokens map[string]*models.TokenPayload
+
+func (f fakeTokens) VerifyToken(ctx context.Context, token string) (*models.TokenPayload, error) {
+	payload, ok := f[token]
+	if !ok {
+		return nil, models.ErrInvalidToken
+	}
+	return payload, nil
+}
+
+// fakeUsers is a user table keyed by Cognito sub and email, only what the auth middleware reads is real
+type fakeUsers struct {
+	bySub   map[string]*models.User
+	byEmail map[string]*models.User
+}
+
+func (f *fakeUsers) GetUserByID(ctx context.Context, id uuid.UUID) (*models.User, error) {
+	return nil, nil
+}
+
+func (f *fakeUsers) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	return f.byEmail[email], nil
+}
+
+func (f *fakeUsers) GetUserByCognitoSub(ctx context.Context, sub string) (*models.User, error) {
+	return f.bySub[sub], nil
+}
+
+func (f *fakeUsers) LinkCognitoSub(ctx context.Context, id uuid.UUID, sub string) (bool, error) {
+	for _, user := range f.byEmail {
+		if user.UUID == id && user.CognitoSub == nil {
+			f.bySub[sub] = user
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (f *fakeUsers) ListUsers(ctx context.Context, filter models.UserFilter) ([]models.User, error) {
+	return nil, nil
+}
+
+func (f *fakeUsers) CreateUser(ctx context.Context, user *models.User) error { return nil }
+
+func (f *fakeUsers) UpdateUser(ctx context.Context, user *models.User) error { return nil }
+
+func (f *fakeUsers) DeleteUser(ctx context.Context, id uuid.UUID) error { return nil }
+
+// newTestRouter builds the real route table over handlers without services. Requests that get
+// past the middleware are only sent where the handler answers before touching its service.
+func newTestRouter(t *testing.T, users map[string]*models.User) *Router {
+	t.Helper()
+
+	tokens := fakeTokens{}
+	repo := &fakeUsers{bySub: map[string]*models.User{}, byEmail: map[string]*models.User{}}
+	for token, user := range users {
+		sub := "sub-" + token
+		tokens[token] = &models.TokenPayload{
+			Subject:       sub,
+			Email:         user.Email,
+			EmailVerified: true,
+			TokenUse:      "id",
+			ExpiresAt:     time.Now().Add(time.Hour),
+		}
+		if user.CognitoSub != nil {
+			repo.bySub[sub] = user
+		}
+		repo.byEmail[user.Email] = user
+	}
+
+	cfg := &config.Config{App: &config.App{Env: "test"}}
+	sessions := gin_adapter.New(scs.New())
+	mw := middleware.NewMiddlewareService(cfg, sessions, repo, tokens, nil).(*middleware.MiddlewareService)
+
+	return NewRouter(
+		cfg,
+		sessions,
+		&handler.RequestHandler{},
+		&handler.GeolocationHandler{},
+		&handler.InvoiceHandler{},
+		&handler.EventHandler{},
+		&handler.ShiftHandler{},
+		&handler.StaffRequirementHandler{},
+		mw,
+		&handler.EmailHandler{},
+		&handler.StripeHandler{},
+		&handler.CustomLineItemsHandler{},
+		&handler.CalculateRatesHandler{},
+		&handler.CronHandler{},
+		&handler.PricingPolicyHandler{},
+		&handler.PromoCodeHandler{},
+		&handler.SurchargeHandler{},
+		&handler.RateHandler{},
+		&handler.DunningHandler{},
+		&handler.EmailTemplateHandler{},
+		&handler.CancellationHandler{},
+		&handler.ClientHandler{},
+		&handler.UserHandler{},
+	)
+}
+
+func testUser(role string, linked bool) *models.User {
+	user := &models.User{
+		UUID:     uuid.New(),
+		Email:    role + "@example.com",
+		BranchID: uuid.New(),
+		Role:     role,
+	}
+	if linked {
+		sub := "linked"
+		user.CognitoSub = &sub
+	}
+	return user
+}
+
+func serve(router *Router, method, path, token, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestPublicRoutesNeedNoToken(t *testing.T) {
+	router := newTestRouter(t, nil)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		want   int
+	}{
+		{"health", stdhttp.MethodGet, "/health", "", stdhttp.StatusOK},
+		{"booking form", stdhttp.MethodPost, "/api/requests", "{", stdhttp.StatusBadRequest},
+		{"stripe webhook", stdhttp.MethodPost, "/api/stripe/webhook", "{}", stdhttp.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serve(router, tt.method, tt.path, "", tt.body)
+			if rec.Code != tt.want {
+				t.Fatalf("%s %s = %d, want %d: %s", tt.method, tt.path, rec.Code, tt.want, rec.Body)
+			}
+		})
+	}
+}
+
+func TestProtectedRoutesRejectMissingOrBadTokens(t *testing.T) {
+	deactivatedAt := time.Now()
+	deactivated := testUser(models.RoleAdmin, true)
+	deactivated.DeactivatedAt = &deactivatedAt
+
+	router := newTestRouter(t, map[string]*models.User{"deactivated": deactivated})
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"no token", ""},
+		{"unknown token", "forged"},
+		{"deactivated user", "deactivated"},
+	}
+	for _, tt := range tests {
+		for _, path := range []string{"/api/me", "/api/requests", "/api/invoices/search"} {
+			t.Run(tt.name+" "+path, func(t *testing.T) {
+				rec := serve(router, stdhttp.MethodGet, path, tt.token, "")
+				if rec.Code != stdhttp.StatusUnauthorized {
+					t.Fatalf("GET %s = %d, want 401: %s", path, rec.Code, rec.Body)
+				}
+			})
+		}
+	}
+
+	t.Run("header without Bearer", func(t *testing.T) {
+		req := httptest.NewRequest(stdhttp.MethodGet, "/api/requests", nil)
+		req.Header.Set("Authorization", "deactivated")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Code != stdhttp.StatusUnauthorized {
+			t.Fatalf("GET /api/requests = %d, want 401", rec.Code)
+		}
+	})
+}
+
+func TestRolesWithoutThePermissionAreForbidden(t *testing.T) {
+	router := newTestRouter(t, map[string]*models.User{
+		"staff":     testUser(models.RoleStaff, true),
+		"executive": testUser(models.RoleAccountExecutive, true),
+		"admin":     testUser(models.RoleAdmin, true),
+	})
+
+	tests := []struct {
+		token      string
+		method     string
+		path       string
+		permission models.Permission
+	}{
+		{"staff", stdhttp.MethodGet, "/api/invoices/not-a-uuid", models.PermissionInvoicesRead},
+		{"staff", stdhttp.MethodPost, "/api/users", models.PermissionUsersManage},
+		{"executive", stdhttp.MethodDelete, "/api/requests/" + uuid.NewString(), models.PermissionRequestsDelete},
+		{"executive", stdhttp.MethodPost, "/api/requests/" + uuid.NewString() + "/cancel/refund", models.PermissionPaymentsRefund},
+		{"executive", stdhttp.MethodPost, "/api/pricing-policies", models.PermissionPricingWrite},
+		{"admin", stdhttp.MethodPost, "/api/admin/cron/run", models.PermissionSystemManage},
+	}
+	for _, tt := range tests {
+		t.Run(tt.token+" "+tt.method+" "+tt.path, func(t *testing.T) {
+			rec := serve(router, tt.method, tt.path, tt.token, "{}")
+			if rec.Code != stdhttp.StatusForbidden {
+				t.Fatalf("%s %s = %d, want 403: %s", tt.method, tt.path, rec.Code, rec.Body)
+			}
+			var body struct {
+				MissingPermission models.Permission `json:"missing_permission"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatalf("failed to decode body: %v", err)
+			}
+			if body.MissingPermission != tt.permission {
+				t.Fatalf("missing_permission = %q, want %q", body.MissingPermission, tt.permission)
+			}
+		})
+	}
+}
+
+func TestRolesWithThePermissionReachTheHandler(t *testing.T) {
+	router := newTestRouter(t, map[string]*models.User{
+		"executive": testUser(models.RoleAccountExecutive, true),
+		// signed up before Cognito subs were stored, linked on this first request
+		"legacy": testUser(models.RoleAdmin, false),
+	})
+
+	for _, token := range []string{"executive", "legacy"} {
+		t.Run(token, func(t *testing.T) {
+			// the handler rejects the id before it needs its service
+			rec := serve(router, stdhttp.MethodGet, "/api/invoices/not-a-uuid", token, "")
+			if rec.Code != stdhttp.StatusBadRequest {
+				t.Fatalf("GET /api/invoices/not-a-uuid = %d, want 400: %s", rec.Code, rec.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"backend/internal/core/models"
+)
+
+// FileTransport writes each email to a .eml file instead of sending it, so the whole email
+// flow can run without a mail provider. The files open in any mail client.
+type FileTransport struct {
+	dir string
+}
+
+func NewFileTransport(dir string) *FileTransport {
+	return &FileTransport{dir: dir}
+}
+
+func (t *FileTransport) Send(ctx context.Context, message *models.MailMessage) (string, error) {
+	if err := os.MkdirAll(t.dir, 0o755); err != nil {
+		return "", fmt.Errorf("failed to create email directory: %w", err)
+	}
+
+	now := time.Now()
+	messageID := newMessageID(message.From)
+	data, err := buildMIME(message, messageID, now)
+	if err != nil {
+		return "", fmt.Errorf("failed to build email: %w", err)
+	}
+
+	name := fmt.Sprintf("%s-%s.eml", now.UTC().Format("20060102T150405"), strings.Trim(messageID, "<>"))
+	if err := os.WriteFile(filepath.Join(t.dir, name), data, 0o644); err != nil {
+		return "", fmt.Errorf("failed to write email: %w", err)
+	}
+
+	return messageID, nil
+}
@@ -0,0 +1,137 @@
+package mail
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/mailgun/mailgun-go/v4"
+
+	"backend/internal/core/models"
+)
+
+// MailgunTransport sends through the Mailgun API and parses its delivery webhooks
+type MailgunTransport struct {
+	mg *mailgun.MailgunImpl
+}
+
+func NewMailgunTransport(domain, apiKey, webhookSigningKey string) *MailgunTransport {
+	mg := mailgun.NewMailgun(domain, apiKey)
+	mg.SetWebhookSigningKey(webhookSigningKey)
+	return &MailgunTransport{mg: mg}
+}
+
+func (t *MailgunTransport) Send(ctx context.Context, message *models.MailMessage) (string, error) {
+	mgMessage := mailgun.NewMessage(message.From, message.Subject, message.Text, message.To...)
+	if message.HTML != "" {
+		mgMessage.SetHTML(message.HTML)
+	}
+	if message.ReplyTo != "" {
+		mgMessage.SetReplyTo(message.ReplyTo)
+	}
+	for _, cc := range message.CC {
+		mgMessage.AddCC(cc)
+	}
+	for _, bcc := range message.BCC {
+		mgMessage.AddBCC(bcc)
+	}
+	for _, attachment := range message.Attachments {
+		mgMessage.AddBufferAttachment(attachment.Filename, attachment.Data)
+	}
+
+	_, messageID, err := t.mg.Send(ctx, mgMessage)
+	if err != nil {
+		return "", fmt.Errorf("mailgun send error: %w", err)
+	}
+	return messageID, nil
+}
+
+// webhooks older than this are refused so a captured request can't be replayed later
+const webhookMaxAge = 15 * time.Minute
+
+type mailgunWebhookEvent struct {
+	ID        string  `json:"id"`
+	Event     string  `json:"event"`
+	Timestamp float64 `json:"timestamp"`
+	Recipient string  `json:"recipient"`
+	Severity  string  `json:"severity"`
+	Reason    string  `json:"reason"`
+	URL       string  `json:"url"`
+	Message   struct {
+		Headers struct {
+			MessageID string `json:"message-id"`
+		} `json:"headers"`
+	} `json:"message"`
+	DeliveryStatus struct {
+		Description string `json:"description"`
+		Message     string `json:"message"`
+	} `json:"delivery-status"`
+}
+
+// ParseWebhookEvent verifies a Mailgun webhook and turns it into an email event. Event types
+// we don't track, like accepted or unsubscribed, come back as nil.
+func (t *MailgunTransport) ParseWebhookEvent(payload []byte) (*models.EmailEvent, error) {
+	if t.mg.WebhookSigningKey() == "" {
+		return nil, fmt.Errorf("MAILGUN_WEBHOOK_SIGNING_KEY environment variable not set")
+	}
+
+	var webhook struct {
+		Signature mailgun.Signature   `json:"signature"`
+		EventData mailgunWebhookEvent `json:"event-data"`
+	}
+	if err := json.Unmarshal(payload, &webhook); err != nil {
+		return nil, fmt.Errorf("error parsing webhook JSON: %w", err)
+	}
+
+	verified, err := t.mg.VerifyWebhookSignature(webhook.Signature)
+	if err != nil || !verified {
+		return nil, models.ErrInvalidWebhookSignature
+	}
+
+	timestamp, err := strconv.ParseInt(webhook.Signature.TimeStamp, 10, 64)
+	if err != nil || time.Since(time.Unix(timestamp, 0)) > webhookMaxAge {
+		return nil, models.ErrInvalidWebhookSignature
+	}
+
+	data := webhook.EventData
+	event := &models.EmailEvent{
+		MailgunEventID: data.ID,
+		Recipient:      data.Recipient,
+		Severity:       data.Severity,
+		Reason:         data.Reason,
+		URL:            data.URL,
+		OccurredAt:     time.Unix(0, int64(data.Timestamp*float64(time.Second))).UTC(),
+		MessageID:      data.Message.Headers.MessageID,
+	}
+
+	switch data.Event {
+	case "delivered":
+		event.Event = models.EmailEventDelivered
+	case "opened":
+		event.Event = models.EmailEventOpened
+	case "clicked":
+		event.Event = models.EmailEventClicked
+	case "complained":
+		event.Event = models.EmailEventComplained
+	case "failed":
+		event.Event = models.EmailEventFailed
+		if data.Severity == "permanent" {
+			event.Event = models.EmailEventBounced
+		}
+		if detail := data.DeliveryStatus.Description; detail != "" {
+			event.Reason = detail
+		} else if detail := data.DeliveryStatus.Message; detail != "" {
+			event.Reason = detail
+		}
+	default:
+		return nil, nil
+	}
+
+	if event.MailgunEventID == "" || event.MessageID == "" {
+		return nil, fmt.Errorf("webhook event is missing its id or message id")
+	}
+
+	return event, nil
+}
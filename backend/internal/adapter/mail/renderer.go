@@ -0,0 +1,155 @@
+package mail
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	"github.com/google/uuid"
+
+	"backend/internal/core/models"
+	ports "backend/internal/core/ports"
+)
+
+//go:embed templates/*.tmpl
+var defaultTemplates embed.FS
+
+var templateFuncs = map[string]interface{}{
+	"money": func(amount models.Money) string { return "$" + amount.String() },
+	"date":  func(t time.Time) string { return t.Format("January 2, 2006") },
+	"clock": func(t time.Time) string { return t.Format("3:04 PM") },
+	"hours": func(hours float64) string { return fmt.Sprintf("%.2f", hours) },
+}
+
+// TemplateRenderer renders emails from the branch's saved templates, falling back to the
+// defaults embedded in the binary. The HTML part goes through html/template so anything a
+// user typed is escaped.
+type TemplateRenderer struct {
+	repo ports.EmailTemplateRepository
+}
+
+func NewTemplateRenderer(repo ports.EmailTemplateRepository) *TemplateRenderer {
+	return &TemplateRenderer{repo: repo}
+}
+
+func (r *TemplateRenderer) Render(ctx context.Context, branchID *uuid.UUID, name string, view interface{}) (*models.RenderedEmail, error) {
+	var template *models.EmailTemplate
+	if r.repo != nil {
+		saved, err := r.repo.GetActiveEmailTemplate(ctx, branchID, name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get email template: %w", err)
+		}
+		template = saved
+	}
+
+	if template == nil {
+		builtIn, err := r.DefaultTemplate(name)
+		if err != nil {
+			return nil, err
+		}
+		template = builtIn
+	}
+
+	return r.RenderTemplate(template, view)
+}
+
+func (r *TemplateRenderer) RenderTemplate(template *models.EmailTemplate, view interface{}) (*models.RenderedEmail, error) {
+	subject, err := executeText(template.Name+".subject", template.Subject, view)
+	if err != nil {
+		return nil, err
+	}
+
+	html, err := executeHTML(template.Name+".html", template.HTML, view)
+	if err != nil {
+		return nil, err
+	}
+
+	text := ""
+	if strings.TrimSpace(template.Text) != "" {
+		text, err = executeText(template.Name+".text", template.Text, view)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &models.RenderedEmail{
+		// subjects are a single line whatever the template looks like
+		Subject: strings.Join(strings.Fields(subject), " "),
+		HTML:    html,
+		Text:    strings.TrimSpace(text) + "\n",
+	}, nil
+}
+
+func (r *TemplateRenderer) DefaultTemplate(name string) (*models.EmailTemplate, error) {
+	if !isTemplateName(name) {
+		return nil, models.ErrUnknownEmailTemplate
+	}
+
+	read := func(part string) (string, error) {
+		data, err := defaultTemplates.ReadFile(fmt.Sprintf("templates/%s.%s.tmpl", name, part))
+		if err != nil {
+			return "", fmt.Errorf("failed to read default %s template: %w", name, err)
+		}
+		return string(data), nil
+	}
+
+	subject, err := read("subject")
+	if err != nil {
+		return nil, err
+	}
+	html, err := read("html")
+	if err != nil {
+		return nil, err
+	}
+	text, err := read("txt")
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.EmailTemplate{
+		Name:    name,
+		Subject: subject,
+		HTML:    html,
+		Text:    text,
+	}, nil
+}
+
+func isTemplateName(name string) bool {
+	for _, known := range models.EmailTemplateNames {
+		if name == known {
+			return true
+		}
+	}
+	return false
+}
+
+func executeHTML(name, source string, view interface{}) (string, error) {
+	tmpl, err := htmltemplate.New(name).Funcs(templateFuncs).Option("missingkey=error").Parse(source)
+	if err != nil {
+		return "", fmt.Errorf("invalid %s template: %w", name, err)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, view); err != nil {
+		return "", fmt.Errorf("failed to render %s template: %w", name, err)
+	}
+	return buf.String(), nil
+}
+
+func executeText(name, source string, view interface{}) (string, error) {
+	tmpl, err := texttemplate.New(name).Funcs(templateFuncs).Option("missingkey=error").Parse(source)
+	if err != nil {
+		return "", fmt.Errorf("invalid %s template: %w", name, err)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, view); err != nil {
+		return "", fmt.Errorf("failed to render %s template: %w", name, err)
+	}
+	return buf.String(), nil
+}
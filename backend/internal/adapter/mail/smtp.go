@@ -0,0 +1,133 @@
+package mail
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"time"
+
+	"backend/internal/core/models"
+)
+
+// smtpTimeout bounds a whole send, from dialling to QUIT, so a server that stops answering can't
+// hold up the outbox
+const smtpTimeout = 30 * time.Second
+
+// SMTPTransport sends through any SMTP server, e.g. MailHog or Mailpit when running locally.
+// STARTTLS is used whenever the server offers it.
+type SMTPTransport struct {
+	addr     string
+	username string
+	password string
+	host     string
+}
+
+func NewSMTPTransport(host, port, username, password string) *SMTPTransport {
+	if port == "" {
+		port = "587"
+	}
+	return &SMTPTransport{
+		addr:     net.JoinHostPort(host, port),
+		username: username,
+		password: password,
+		host:     host,
+	}
+}
+
+func (t *SMTPTransport) Send(ctx context.Context, message *models.MailMessage) (string, error) {
+	messageID := newMessageID(message.From)
+	data, err := buildMIME(message, messageID, time.Now())
+	if err != nil {
+		return "", fmt.Errorf("failed to build email: %w", err)
+	}
+
+	from, err := mail.ParseAddress(message.From)
+	if err != nil {
+		return "", fmt.Errorf("invalid from address %q: %w", message.From, err)
+	}
+
+	var recipients []string
+	for _, list := range [][]string{message.To, message.CC, message.BCC} {
+		for _, recipient := range list {
+			address, err := mail.ParseAddress(recipient)
+			if err != nil {
+				return "", fmt.Errorf("invalid recipient %q: %w", recipient, err)
+			}
+			recipients = append(recipients, address.Address)
+		}
+	}
+
+	var auth smtp.Auth
+	if t.username != "" {
+		auth = smtp.PlainAuth("", t.username, t.password, t.host)
+	}
+
+	if err := t.sendMail(ctx, auth, from.Address, recipients, data); err != nil {
+		return "", fmt.Errorf("smtp send error: %w", err)
+	}
+
+	return messageID, nil
+}
+
+// sendMail does what smtp.SendMail does, but gives up once ctx is done or smtpTimeout has passed
+func (t *SMTPTransport) sendMail(ctx context.Context, auth smtp.Auth, from string, to []string, data []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, smtpTimeout)
+	defer cancel()
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", t.addr)
+	if err != nil {
+		return err
+	}
+	deadline, _ := ctx.Deadline()
+	if err := conn.SetDeadline(deadline); err != nil {
+		conn.Close()
+		return err
+	}
+	// cancelling ctx interrupts whatever read or write is in flight
+	stop := context.AfterFunc(ctx, func() { conn.SetDeadline(time.Now()) })
+	defer stop()
+
+	client, err := smtp.NewClient(conn, t.host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: t.host}); err != nil {
+			return err
+		}
+	}
+	if auth != nil {
+		if ok, _ := client.Extension("AUTH"); !ok {
+			return fmt.Errorf("server doesn't support AUTH")
+		}
+		if err := client.Auth(auth); err != nil {
+			return err
+		}
+	}
+	if err := client.Mail(from); err != nil {
+		return err
+	}
+	for _, recipient := range to {
+		if err := client.Rcpt(recipient); err != nil {
+			return err
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
@@ -0,0 +1,53 @@
+package mail
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"backend/internal/core/models"
+)
+
+func TestSMTPSendGivesUpOnASilentServer(t *testing.T) {
+	// accepts connections but never sends the greeting
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer listener.Close()
+	go func() {
+		var conns []net.Conn
+		defer func() {
+			for _, conn := range conns {
+				conn.Close()
+			}
+		}()
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conns = append(conns, conn)
+		}
+	}()
+
+	host, port, _ := net.SplitHostPort(listener.Addr().String())
+	transport := NewSMTPTransport(host, port, "", "")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err = transport.Send(ctx, &models.MailMessage{
+		From:    "billing@example.com",
+		To:      []string{"client@example.com"},
+		Subject: "Invoice",
+		Text:    "hello",
+	})
+	if err == nil {
+		t.Fatal("Send to a silent server succeeded")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("Send took %v to give up", elapsed)
+	}
+}
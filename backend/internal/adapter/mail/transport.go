@@ -0,0 +1,35 @@
+// mail transports: Mailgun in production, SMTP or .eml files for staging and local development
+package mail
+
+import (
+	"fmt"
+
+	"backend/internal/config"
+	ports "backend/internal/core/ports"
+)
+
+const (
+	ProviderMailgun = "mailgun"
+	ProviderSMTP    = "smtp"
+	ProviderFile    = "file"
+)
+
+// NewTransport builds the transport named by EMAIL_PROVIDER
+func NewTransport(cfg *config.Email) (ports.MailTransport, error) {
+	switch cfg.Provider {
+	case ProviderMailgun, "":
+		if cfg.Mailgun.Domain == "" || cfg.Mailgun.APIKey == "" {
+			return nil, fmt.Errorf("MAILGUN_DOMAIN and MAILGUN_API_KEY are required for the mailgun email provider")
+		}
+		return NewMailgunTransport(cfg.Mailgun.Domain, cfg.Mailgun.APIKey, cfg.Mailgun.WebhookSigningKey), nil
+	case ProviderSMTP:
+		if cfg.SMTP.Host == "" {
+			return nil, fmt.Errorf("SMTP_HOST is required for the smtp email provider")
+		}
+		return NewSMTPTransport(cfg.SMTP.Host, cfg.SMTP.Port, cfg.SMTP.Username, cfg.SMTP.Password), nil
+	case ProviderFile:
+		return NewFileTransport(cfg.FileDir), nil
+	default:
+		return nil, fmt.Errorf("unknown email provider %q", cfg.Provider)
+	}
+}
@@ -0,0 +1,195 @@
+// a small PDF writer for invoices. It only uses the standard Helvetica fonts, text and lines,
+// and writes no dates or random IDs, so the same input always gives byte-identical output.
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// US Letter in points
+const (
+	pageWidth  = 612.0
+	pageHeight = 792.0
+)
+
+const (
+	fontRegular = "F1"
+	fontBold    = "F2"
+)
+
+type page struct {
+	content bytes.Buffer
+}
+
+func (p *page) text(x, y float64, font string, size float64, s string) {
+	fmt.Fprintf(&p.content, "BT /%s %s Tf %s %s Td (%s) Tj ET\n", font, num(size), num(x), num(y), escapeText(s))
+}
+
+// textRight draws s so that it ends at x
+func (p *page) textRight(x, y float64, font string, size float64, s string) {
+	p.text(x-textWidth(font, size, s), y, font, size, s)
+}
+
+func (p *page) line(x1, y1, x2, y2, width float64) {
+	fmt.Fprintf(&p.content, "%s w %s %s m %s %s l S\n", num(width), num(x1), num(y1), num(x2), num(y2))
+}
+
+// fillRect draws a filled rectangle in a shade of gray, 0 is black and 1 is white
+func (p *page) fillRect(x, y, w, h, gray float64) {
+	fmt.Fprintf(&p.content, "q %s g %s %s %s %s re f Q\n", num(gray), num(x), num(y), num(w), num(h))
+}
+
+type document struct {
+	pages []*page
+}
+
+func (d *document) addPage() *page {
+	p := &page{}
+	d.pages = append(d.pages, p)
+	return p
+}
+
+// bytes writes the document. Object numbers are fixed: catalog, page tree, the two fonts, then
+// a page and its content stream for every page.
+func (d *document) bytes() []byte {
+	var out bytes.Buffer
+	var offsets []int
+
+	writeObject := func(body string) {
+		offsets = append(offsets, out.Len())
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	out.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	const firstPageObject = 5
+	kids := make([]string, len(d.pages))
+	for i := range d.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", firstPageObject+2*i)
+	}
+
+	writeObject("<< /Type /Catalog /Pages 2 0 R >>")
+	writeObject(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.pages)))
+	writeObject("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	writeObject("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+
+	for i, p := range d.pages {
+		writeObject(fmt.Sprintf(
+			"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %s %s] /Resources << /Font << /%s 3 0 R /%s 4 0 R >> >> /Contents %d 0 R >>",
+			num(pageWidth), num(pageHeight), fontRegular, fontBold, firstPageObject+2*i+1,
+		))
+		content := p.content.Bytes()
+		writeObject(fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(content), content))
+	}
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+
+	return out.Bytes()
+}
+
+// num formats a coordinate without trailing zeros, PDF has no exponent notation
+func num(v float64) string {
+	s := fmt.Sprintf("%.2f", v)
+	s = strings.TrimRight(s, "0")
+	return strings.TrimSuffix(s, ".")
+}
+
+// escapeText converts s to WinAnsi and escapes it for a PDF string literal. Characters outside
+// Latin-1 can't be shown by the standard fonts and become '?'.
+func escapeText(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		c := winAnsi(r)
+		switch c {
+		case '\\', '(', ')':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+func winAnsi(r rune) byte {
+	switch {
+	case r == '\t':
+		return ' '
+	case r >= 0x20 && r <= 0x7e, r >= 0xa0 && r <= 0xff:
+		return byte(r)
+	}
+
+	// the typographic characters people paste from word processors
+	switch r {
+	case '€':
+		return 0x80
+	case '‘':
+		return 0x91
+	case '’':
+		return 0x92
+	case '“':
+		return 0x93
+	case '”':
+		return 0x94
+	case '•':
+		return 0x95
+	case '–':
+		return 0x96
+	case '—':
+		return 0x97
+	case '…':
+		return 0x85
+	}
+	return '?'
+}
+
+// textWidth measures s in points using the Helvetica metrics
+func textWidth(font string, size float64, s string) float64 {
+	widths := &helveticaWidths
+	if font == fontBold {
+		widths = &helveticaBoldWidths
+	}
+
+	total := 0
+	for _, r := range s {
+		c := winAnsi(r)
+		if c >= 32 && c <= 126 {
+			total += widths[c-32]
+		} else {
+			total += 556
+		}
+	}
+	return float64(total) * size / 1000
+}
+
+// wrap splits s into lines no wider than width, breaking on spaces and keeping blank lines
+func wrap(font string, size float64, s string, width float64) []string {
+	var lines []string
+	for _, paragraph := range strings.Split(strings.ReplaceAll(s, "\r\n", "\n"), "\n") {
+		words := strings.Fields(paragraph)
+		if len(words) == 0 {
+			lines = append(lines, "")
+			continue
+		}
+
+		current := words[0]
+		for _, word := range words[1:] {
+			candidate := current + " " + word
+			if textWidth(font, size, candidate) > width {
+				lines = append(lines, current)
+				current = word
+				continue
+			}
+			current = candidate
+		}
+		lines = append(lines, current)
+	}
+	return lines
+}
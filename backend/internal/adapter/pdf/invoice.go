@@ -0,0 +1,312 @@
+package pdf
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"backend/internal/core/models"
+)
+
+const (
+	margin     = 50.0
+	contentTop = pageHeight - margin
+	// room kept free at the bottom for the page number
+	contentBottom = margin + 20
+
+	bodySize  = 10.0
+	smallSize = 8.0
+	lineGap   = 14.0
+
+	// right edges of the table's number columns
+	colQuantity = 360.0
+	colRate     = 460.0
+	colAmount   = pageWidth - margin
+)
+
+// InvoiceRenderer lays out the invoice PDF
+type InvoiceRenderer struct{}
+
+func NewInvoiceRenderer() *InvoiceRenderer {
+	return &InvoiceRenderer{}
+}
+
+// layout tracks the write position and starts a new page when the current one is full
+type layout struct {
+	doc  *document
+	page *page
+	y    float64
+}
+
+func (l *layout) newPage() {
+	l.page = l.doc.addPage()
+	l.y = contentTop
+}
+
+// need moves to a new page unless height points are left on this one
+func (l *layout) need(height float64) {
+	if l.y-height < contentBottom {
+		l.newPage()
+	}
+}
+
+func (l *layout) textLine(font string, size float64, s string) {
+	l.need(lineGap)
+	l.page.text(margin, l.y, font, size, s)
+	l.y -= lineGap
+}
+
+func (l *layout) paragraph(font string, size float64, s string) {
+	for _, line := range wrap(font, size, s, pageWidth-2*margin) {
+		l.textLine(font, size, line)
+	}
+}
+
+func (r *InvoiceRenderer) RenderInvoicePDF(doc *models.InvoiceDocument) ([]byte, error) {
+	invoice := doc.Invoice
+	if invoice == nil {
+		return nil, fmt.Errorf("invoice is nil")
+	}
+
+	l := &layout{doc: &document{}}
+	l.newPage()
+
+	r.header(l, invoice)
+	r.lineItems(l, doc)
+	r.totals(l, invoice)
+
+	if strings.TrimSpace(invoice.Notes) != "" {
+		l.y -= lineGap
+		l.textLine(fontBold, bodySize, "Notes")
+		l.paragraph(fontRegular, bodySize, invoice.Notes)
+	}
+
+	if strings.TrimSpace(doc.TermsAndConditions) != "" {
+		l.y -= lineGap
+		l.textLine(fontBold, bodySize, "Terms and Conditions")
+		l.paragraph(fontRegular, smallSize, doc.TermsAndConditions)
+	}
+
+	// page numbers go on last, once the page count is known
+	total := len(l.doc.pages)
+	for i, p := range l.doc.pages {
+		p.textRight(pageWidth-margin, margin, fontRegular, smallSize, fmt.Sprintf("Page %d of %d", i+1, total))
+	}
+
+	return l.doc.bytes(), nil
+}
+
+func (r *InvoiceRenderer) header(l *layout, invoice *models.Invoice) {
+	request := invoice.Request
+
+	l.page.text(margin, l.y, fontBold, 20, title(invoice))
+	l.page.textRight(pageWidth-margin, l.y, fontBold, 14, "Evershift")
+	l.y -= 18
+	l.page.textRight(pageWidth-margin, l.y, fontRegular, bodySize, "support@evershift.co")
+	l.y -= 2 * lineGap
+
+	details := [][2]string{
+		{"Invoice #", invoice.Number()},
+		{"Due Date", invoice.DueDate.Format("January 2, 2006")},
+	}
+	if invoice.PaymentTerms != "" {
+		details = append(details, [2]string{"Payment Terms", invoice.PaymentTerms})
+	}
+	if invoice.PONumber != "" {
+		details = append(details, [2]string{"PO Number", invoice.PONumber})
+	}
+	if request.ClosestBranchName != "" {
+		details = append(details, [2]string{"Branch", request.ClosestBranchName})
+	}
+
+	billTo := []string{strings.TrimSpace(request.FirstName + " " + request.LastName)}
+	if request.IsCompany && request.CompanyName != "" {
+		billTo = append(billTo, request.CompanyName)
+	}
+	for _, value := range []string{request.Email, request.PhoneNumber} {
+		if value != "" {
+			billTo = append(billTo, value)
+		}
+	}
+	if invoice.ShipTo != "" {
+		billTo = append(billTo, wrap(fontRegular, bodySize, "Event: "+invoice.ShipTo, 250)...)
+	}
+
+	top := l.y
+	l.page.text(margin, l.y, fontBold, bodySize, "Bill To")
+	l.y -= lineGap
+	for _, line := range billTo {
+		l.page.text(margin, l.y, fontRegular, bodySize, line)
+		l.y -= lineGap
+	}
+	left := l.y
+
+	l.y = top
+	for _, detail := range details {
+		l.page.text(340, l.y, fontBold, bodySize, detail[0])
+		l.page.textRight(pageWidth-margin, l.y, fontRegular, bodySize, detail[1])
+		l.y -= lineGap
+	}
+
+	if left < l.y {
+		l.y = left
+	}
+	l.y -= lineGap
+}
+
+func (r *InvoiceRenderer) tableHeader(l *layout) {
+	l.page.fillRect(margin, l.y-4, pageWidth-2*margin, lineGap+2, 0.93)
+	l.page.text(margin+4, l.y, fontBold, bodySize, "Description")
+	l.page.textRight(colQuantity, l.y, fontBold, bodySize, "Qty")
+	l.page.textRight(colRate, l.y, fontBold, bodySize, "Rate")
+	l.page.textRight(colAmount-4, l.y, fontBold, bodySize, "Amount")
+	l.y -= lineGap + 4
+}
+
+// row writes a table row, repeating the table header when it spills onto a new page
+func (r *InvoiceRenderer) row(l *layout, height float64) {
+	before := l.page
+	l.need(height)
+	if l.page != before {
+		r.tableHeader(l)
+	}
+}
+
+func (r *InvoiceRenderer) lineItems(l *layout, doc *models.InvoiceDocument) {
+	l.need(3 * lineGap)
+	r.tableHeader(l)
+
+	// deposits and change orders bill part of the request, listing every staff line on them
+	// would read as if all of it were due
+	if invoice := doc.Invoice; invoice.Kind == models.InvoiceKindDeposit || invoice.Kind == models.InvoiceKindChangeOrder {
+		r.row(l, lineGap)
+		l.page.text(margin+4, l.y, fontRegular, bodySize, invoice.KindLabel())
+		l.page.textRight(colQuantity, l.y, fontRegular, bodySize, "1")
+		l.page.textRight(colRate, l.y, fontRegular, bodySize, money(invoice.Subtotal))
+		l.page.textRight(colAmount-4, l.y, fontRegular, bodySize, money(invoice.Subtotal))
+		l.y -= lineGap
+		l.page.line(margin, l.y+lineGap-4, pageWidth-margin, l.y+lineGap-4, 0.5)
+		return
+	}
+
+	// stored order can change between loads, the PDF must not
+	staff := append([]models.StaffRequirement(nil), doc.StaffRequirements...)
+	sort.SliceStable(staff, func(i, j int) bool {
+		if !staff[i].StartTime.Equal(staff[j].StartTime) {
+			return staff[i].StartTime.Before(staff[j].StartTime)
+		}
+		if staff[i].Position != staff[j].Position {
+			return staff[i].Position < staff[j].Position
+		}
+		return staff[i].UUID.String() < staff[j].UUID.String()
+	})
+
+	for _, req := range staff {
+		r.row(l, 2*lineGap)
+		l.page.text(margin+4, l.y, fontBold, bodySize, req.Position)
+		l.page.textRight(colQuantity, l.y, fontRegular, bodySize, strconv.Itoa(req.Count))
+		l.page.textRight(colRate, l.y, fontRegular, bodySize, money(req.Rate)+" / hr")
+		l.page.textRight(colAmount-4, l.y, fontRegular, bodySize, money(req.Amount))
+		l.y -= lineGap - 2
+		l.page.text(margin+4, l.y, fontRegular, smallSize, fmt.Sprintf("%s (%s - %s)",
+			req.Date.Format("January 2, 2006"), req.StartTime.Format("3:04 PM"), req.EndTime.Format("3:04 PM")))
+		l.y -= lineGap
+
+		charges := append([]models.StaffRequirementCharge(nil), req.Charges...)
+		sort.SliceStable(charges, func(i, j int) bool { return charges[i].Description < charges[j].Description })
+		for _, charge := range charges {
+			if charge.Type == models.ChargeTypeBase {
+				continue
+			}
+			r.row(l, lineGap)
+			l.page.text(margin+16, l.y, fontRegular, smallSize, fmt.Sprintf("%s (+%s%%, %.2f hrs)",
+				charge.Description, strconv.FormatFloat(charge.Percent, 'f', -1, 64), charge.Hours))
+			l.page.textRight(colAmount-4, l.y, fontRegular, smallSize, "incl. "+money(charge.Amount))
+			l.y -= lineGap
+		}
+	}
+
+	items := append([]models.CustomLineItems(nil), doc.CustomLineItems...)
+	sort.SliceStable(items, func(i, j int) bool {
+		if !items[i].CreatedAt.Equal(items[j].CreatedAt) {
+			return items[i].CreatedAt.Before(items[j].CreatedAt)
+		}
+		return items[i].UUID.String() < items[j].UUID.String()
+	})
+
+	for _, item := range items {
+		lines := wrap(fontRegular, bodySize, item.Description, colQuantity-margin-60)
+		r.row(l, float64(len(lines))*lineGap)
+		l.page.textRight(colQuantity, l.y, fontRegular, bodySize, strconv.Itoa(item.Quantity))
+		l.page.textRight(colRate, l.y, fontRegular, bodySize, money(item.Rate))
+		l.page.textRight(colAmount-4, l.y, fontRegular, bodySize, money(item.Total))
+		for _, line := range lines {
+			l.page.text(margin+4, l.y, fontRegular, bodySize, line)
+			l.y -= lineGap
+		}
+	}
+
+	l.page.line(margin, l.y+lineGap-4, pageWidth-margin, l.y+lineGap-4, 0.5)
+}
+
+func (r *InvoiceRenderer) totals(l *layout, invoice *models.Invoice) {
+	rows := [][2]string{{"Subtotal", money(invoice.Subtotal)}}
+	if invoice.DiscountAmount > 0 {
+		rows = append(rows, [2]string{discountLabel(invoice), "-" + money(invoice.DiscountAmount)})
+	}
+	rows = append(rows,
+		[2]string{"Service Fee", money(invoice.ServiceFee)},
+		[2]string{"Transaction Fee", money(invoice.TransactionFee)},
+	)
+	if invoice.PriorInvoiced > 0 {
+		rows = append(rows,
+			[2]string{"Request Total", money(invoice.Amount + invoice.PriorInvoiced)},
+			[2]string{"Less Prior Invoices", "-" + money(invoice.PriorInvoiced)},
+		)
+	}
+	rows = append(rows, [2]string{"Total", money(invoice.Amount)})
+	if invoice.AmountPaid > 0 {
+		rows = append(rows, [2]string{"Amount Paid", "-" + money(invoice.AmountPaid)})
+	}
+	rows = append(rows, [2]string{"Balance Due", money(invoice.Balance)})
+
+	l.need(float64(len(rows)) * lineGap)
+	for _, row := range rows {
+		font := fontRegular
+		if row[0] == "Total" || row[0] == "Balance Due" {
+			font = fontBold
+		}
+		l.page.textRight(colRate, l.y, font, bodySize, row[0]+":")
+		l.page.textRight(colAmount-4, l.y, font, bodySize, row[1])
+		l.y -= lineGap
+	}
+}
+
+// title is the heading at the top of the first page
+func title(invoice *models.Invoice) string {
+	switch invoice.Kind {
+	case models.InvoiceKindDeposit:
+		return "DEPOSIT INVOICE"
+	case models.InvoiceKindChangeOrder:
+		return "CHANGE ORDER"
+	default:
+		return "INVOICE"
+	}
+}
+
+func money(amount models.Money) string {
+	return "$" + amount.String()
+}
+
+// discountLabel matches the wording of the invoice email
+func discountLabel(invoice *models.Invoice) string {
+	switch {
+	case invoice.PromoCode != "":
+		return fmt.Sprintf("Discount (%s)", invoice.PromoCode)
+	case invoice.DiscountType == models.DiscountTypePercentage:
+		return fmt.Sprintf("Discount (%s%%)", strconv.FormatFloat(invoice.DiscountPercent, 'f', -1, 64))
+	default:
+		return "Discount"
+	}
+}
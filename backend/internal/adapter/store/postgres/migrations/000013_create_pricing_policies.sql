@@ -0,0 +1,31 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE EXTENSION IF NOT EXISTS "uuid-ossp";
+
+CREATE TABLE pricing_policies (
+    uuid UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    name TEXT NOT NULL,
+    branch_id UUID REFERENCES branches(uuid),
+    client_email TEXT NOT NULL DEFAULT '',
+    transaction_fee_percent NUMERIC(6, 3) NOT NULL CHECK (transaction_fee_percent >= 0),
+    service_fee_percent NUMERIC(6, 3) NOT NULL CHECK (service_fee_percent >= 0),
+    minimum_service_fee NUMERIC(10, 2) NOT NULL DEFAULT 0,
+    minimum_total NUMERIC(10, 2) NOT NULL DEFAULT 0,
+    rounding TEXT NOT NULL DEFAULT 'nearest_cent',
+    effective_from TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    effective_to TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX idx_pricing_policies_lookup ON pricing_policies (branch_id, client_email, effective_from);
+
+-- Global default matching the fees that were previously hard-coded in the calculator
+INSERT INTO pricing_policies (name, transaction_fee_percent, service_fee_percent, effective_from)
+VALUES ('Default', 3.5, 30, '2000-01-01');
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS pricing_policies;
+-- +goose StatementEnd
@@ -0,0 +1,28 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE EXTENSION IF NOT EXISTS "uuid-ossp";
+
+ALTER TABLE invoices ADD COLUMN discount_amount NUMERIC(10, 2) NOT NULL DEFAULT 0;
+ALTER TABLE invoices ADD COLUMN promo_code TEXT NOT NULL DEFAULT '';
+
+CREATE TABLE promo_codes (
+    uuid UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    code TEXT NOT NULL UNIQUE,
+    discount_type TEXT NOT NULL CHECK (discount_type IN ('percentage', 'fixed')),
+    discount_value NUMERIC(10, 3) NOT NULL CHECK (discount_value > 0),
+    branch_id UUID REFERENCES branches(uuid),
+    valid_from TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    valid_to TIMESTAMPTZ,
+    max_redemptions INTEGER NOT NULL DEFAULT 0,
+    redemptions INTEGER NOT NULL DEFAULT 0,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS promo_codes;
+ALTER TABLE invoices DROP COLUMN IF EXISTS promo_code;
+ALTER TABLE invoices DROP COLUMN IF EXISTS discount_amount;
+-- +goose StatementEnd
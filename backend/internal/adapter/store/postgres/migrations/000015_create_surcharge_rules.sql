@@ -0,0 +1,51 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE EXTENSION IF NOT EXISTS "uuid-ossp";
+
+CREATE TABLE surcharge_rules (
+    uuid UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    name TEXT NOT NULL,
+    type TEXT NOT NULL CHECK (type IN ('overtime', 'night', 'holiday', 'lead_time')),
+    branch_id UUID REFERENCES branches(uuid),
+    percent NUMERIC(6, 3) NOT NULL CHECK (percent >= 0),
+    threshold_hours NUMERIC(6, 2) NOT NULL DEFAULT 0,
+    start_hour INTEGER NOT NULL DEFAULT 0 CHECK (start_hour BETWEEN 0 AND 23),
+    end_hour INTEGER NOT NULL DEFAULT 0 CHECK (end_hour BETWEEN 0 AND 23),
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE TABLE holidays (
+    uuid UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    branch_id UUID REFERENCES branches(uuid),
+    date DATE NOT NULL,
+    name TEXT NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX idx_holidays_date ON holidays (date, branch_id);
+
+CREATE TABLE staff_requirement_charges (
+    uuid UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    staff_requirement_id UUID NOT NULL REFERENCES staff_requirements(uuid) ON DELETE CASCADE,
+    type TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    hours NUMERIC(8, 2) NOT NULL DEFAULT 0,
+    percent NUMERIC(6, 3) NOT NULL DEFAULT 0,
+    amount NUMERIC(10, 2) NOT NULL DEFAULT 0
+);
+
+CREATE INDEX idx_staff_requirement_charges_requirement ON staff_requirement_charges (staff_requirement_id);
+
+-- Last minute booking fee from the terms and conditions
+INSERT INTO surcharge_rules (name, type, percent, threshold_hours)
+VALUES ('Last Minute Booking', 'lead_time', 20, 24);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS staff_requirement_charges;
+DROP TABLE IF EXISTS holidays;
+DROP TABLE IF EXISTS surcharge_rules;
+-- +goose StatementEnd
@@ -0,0 +1,53 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE EXTENSION IF NOT EXISTS "uuid-ossp";
+
+ALTER TABLE branches ADD COLUMN region TEXT NOT NULL DEFAULT '';
+
+UPDATE branches SET region = 'West' WHERE uuid IN (
+    '00000000-0000-0000-0000-000000000001', -- Los Angeles
+    '00000000-0000-0000-0000-000000000006', -- Orange County
+    '00000000-0000-0000-0000-000000000008', -- San Francisco
+    '00000000-0000-0000-0000-000000000010', -- Las Vegas
+    '00000000-0000-0000-0000-000000000011', -- Salt Lake City
+    '00000000-0000-0000-0000-000000000012', -- Seattle
+    '00000000-0000-0000-0000-000000000019', -- Phoenix
+    '00000000-0000-0000-0000-000000000020'  -- San Diego
+);
+UPDATE branches SET region = 'Northeast' WHERE uuid IN (
+    '00000000-0000-0000-0000-000000000002', -- New York City
+    '00000000-0000-0000-0000-000000000015'  -- Boston
+);
+UPDATE branches SET region = 'Midwest' WHERE uuid IN (
+    '00000000-0000-0000-0000-000000000007'  -- Chicago
+);
+UPDATE branches SET region = 'South' WHERE uuid IN (
+    '00000000-0000-0000-0000-000000000003', -- Atlanta
+    '00000000-0000-0000-0000-000000000004', -- Houston
+    '00000000-0000-0000-0000-000000000005', -- Washington
+    '00000000-0000-0000-0000-000000000009', -- Miami
+    '00000000-0000-0000-0000-000000000013', -- Orlando
+    '00000000-0000-0000-0000-000000000014', -- Charlotte
+    '00000000-0000-0000-0000-000000000016', -- Dallas
+    '00000000-0000-0000-0000-000000000017', -- Austin
+    '00000000-0000-0000-0000-000000000018', -- Tampa
+    '00000000-0000-0000-0000-000000000021'  -- New Orleans
+);
+
+-- Fallback rates for branches without their own rate for a staff type. An empty region is
+-- the global default.
+CREATE TABLE default_rates (
+    uuid UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    region TEXT NOT NULL DEFAULT '',
+    staff_type VARCHAR(50) NOT NULL,
+    hourly_rate DECIMAL(10,2) NOT NULL,
+    created_at TIMESTAMP DEFAULT NOW(),
+    UNIQUE(region, staff_type)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS default_rates;
+ALTER TABLE branches DROP COLUMN IF EXISTS region;
+-- +goose StatementEnd
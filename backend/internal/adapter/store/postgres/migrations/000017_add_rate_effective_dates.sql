@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE rates DROP CONSTRAINT IF EXISTS rates_branch_id_staff_type_key;
+
+ALTER TABLE rates ADD COLUMN effective_from DATE NOT NULL DEFAULT '2000-01-01';
+ALTER TABLE rates ADD COLUMN effective_to DATE;
+ALTER TABLE rates ADD COLUMN updated_at TIMESTAMP DEFAULT NOW();
+
+ALTER TABLE rates ADD CONSTRAINT rates_effective_dates_check CHECK (effective_to IS NULL OR effective_to > effective_from);
+
+CREATE UNIQUE INDEX idx_rates_branch_staff_type_effective_from ON rates (branch_id, staff_type, effective_from);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_rates_branch_staff_type_effective_from;
+ALTER TABLE rates DROP CONSTRAINT IF EXISTS rates_effective_dates_check;
+ALTER TABLE rates DROP COLUMN IF EXISTS updated_at;
+ALTER TABLE rates DROP COLUMN IF EXISTS effective_to;
+ALTER TABLE rates DROP COLUMN IF EXISTS effective_from;
+-- +goose StatementEnd
@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE EXTENSION IF NOT EXISTS "uuid-ossp";
+
+-- Days relative to an invoice's due date on which a payment reminder goes out, negative days
+-- are before the due date. A schedule with no branch is the default for every branch.
+CREATE TABLE dunning_schedules (
+    uuid UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    branch_id UUID REFERENCES branches(uuid),
+    offset_days INTEGER[] NOT NULL,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW(),
+    UNIQUE(branch_id)
+);
+
+INSERT INTO dunning_schedules (branch_id, offset_days) VALUES (NULL, '{-3,0,7,14}');
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS dunning_schedules;
+-- +goose StatementEnd
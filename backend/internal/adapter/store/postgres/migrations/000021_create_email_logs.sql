@@ -0,0 +1,55 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE EXTENSION IF NOT EXISTS "uuid-ossp";
+
+-- One row per email handed to Mailgun, keyed by the message ID Mailgun returns so webhook
+-- events can be matched back to it
+CREATE TABLE email_logs (
+    uuid UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    message_id TEXT NOT NULL UNIQUE,
+    request_id UUID REFERENCES requests(uuid) ON DELETE SET NULL,
+    invoice_id UUID REFERENCES invoices(uuid) ON DELETE SET NULL,
+    outbox_email_id UUID REFERENCES email_outbox(id) ON DELETE SET NULL,
+    kind TEXT NOT NULL,
+    recipient TEXT NOT NULL,
+    subject TEXT NOT NULL DEFAULT '',
+    status TEXT NOT NULL DEFAULT 'sent',
+    sent_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    delivered_at TIMESTAMP,
+    opened_at TIMESTAMP,
+    clicked_at TIMESTAMP,
+    bounced_at TIMESTAMP,
+    complained_at TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_email_logs_request_id ON email_logs (request_id);
+CREATE INDEX idx_email_logs_invoice_id ON email_logs (invoice_id);
+
+-- Raw webhook events, Mailgun retries deliveries so the event ID keeps them from doubling up
+CREATE TABLE email_events (
+    uuid UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    email_log_id UUID NOT NULL REFERENCES email_logs(uuid) ON DELETE CASCADE,
+    mailgun_event_id TEXT NOT NULL UNIQUE,
+    event TEXT NOT NULL,
+    recipient TEXT NOT NULL DEFAULT '',
+    severity TEXT NOT NULL DEFAULT '',
+    reason TEXT NOT NULL DEFAULT '',
+    url TEXT NOT NULL DEFAULT '',
+    occurred_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_email_events_email_log_id ON email_events (email_log_id);
+
+ALTER TABLE requests ADD COLUMN email_invalid BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE requests ADD COLUMN email_invalid_reason TEXT NOT NULL DEFAULT '';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE requests DROP COLUMN IF EXISTS email_invalid_reason;
+ALTER TABLE requests DROP COLUMN IF EXISTS email_invalid;
+DROP TABLE IF EXISTS email_events;
+DROP TABLE IF EXISTS email_logs;
+-- +goose StatementEnd
@@ -0,0 +1,30 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE EXTENSION IF NOT EXISTS "uuid-ossp";
+
+-- Every save adds a version, the highest version for a branch and name is the one in use. A
+-- template with no branch is the default, and the app ships built-in templates for when no
+-- default has been saved either.
+CREATE TABLE email_templates (
+    uuid UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    branch_id UUID REFERENCES branches(uuid),
+    name TEXT NOT NULL CHECK (name IN ('invoice', 'follow_up', 'payment_confirmation', 'custom')),
+    version INTEGER NOT NULL,
+    subject TEXT NOT NULL,
+    html TEXT NOT NULL,
+    text TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX idx_email_templates_version
+    ON email_templates (COALESCE(branch_id, '00000000-0000-0000-0000-000000000000'), name, version);
+
+-- plain text alternative for scheduled emails
+ALTER TABLE email_outbox ADD COLUMN text_content TEXT NOT NULL DEFAULT '';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE email_outbox DROP COLUMN IF EXISTS text_content;
+DROP TABLE IF EXISTS email_templates;
+-- +goose StatementEnd
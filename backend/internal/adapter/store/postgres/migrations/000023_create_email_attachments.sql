@@ -0,0 +1,24 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE EXTENSION IF NOT EXISTS "uuid-ossp";
+
+-- Attachment files live in the blob store under a key derived from their checksum, so the same
+-- PDF uploaded twice is only stored once. Emails reference attachments by ID.
+CREATE TABLE email_attachments (
+    uuid UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    filename TEXT NOT NULL,
+    content_type TEXT NOT NULL,
+    size BIGINT NOT NULL,
+    checksum TEXT NOT NULL,
+    storage_key TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+ALTER TABLE email_outbox ADD COLUMN attachment_ids TEXT[];
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE email_outbox DROP COLUMN IF EXISTS attachment_ids;
+DROP TABLE IF EXISTS email_attachments;
+-- +goose StatementEnd
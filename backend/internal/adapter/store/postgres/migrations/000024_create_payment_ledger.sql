@@ -0,0 +1,35 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE EXTENSION IF NOT EXISTS "uuid-ossp";
+
+-- Append-only record of money moving through Stripe. Invoice amount_paid, balance and status
+-- are derived from it, and the unique event ID makes a replayed webhook a no-op.
+CREATE TABLE payment_ledger (
+    uuid UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    invoice_id UUID NOT NULL REFERENCES invoices(uuid) ON DELETE CASCADE,
+    stripe_event_id TEXT NOT NULL UNIQUE,
+    kind TEXT NOT NULL CHECK (kind IN ('payment', 'refund')),
+    amount NUMERIC(12, 2) NOT NULL CHECK (amount > 0),
+    payment_intent_id TEXT NOT NULL DEFAULT '',
+    checkout_session_id TEXT NOT NULL DEFAULT '',
+    charge_id TEXT NOT NULL DEFAULT '',
+    refund_id TEXT NOT NULL DEFAULT '',
+    occurred_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_payment_ledger_invoice ON payment_ledger (invoice_id);
+CREATE INDEX idx_payment_ledger_payment_intent ON payment_ledger (payment_intent_id);
+CREATE INDEX idx_payment_ledger_charge ON payment_ledger (charge_id);
+
+-- payments taken before the ledger existed, so deriving the totals doesn't wipe them out
+INSERT INTO payment_ledger (invoice_id, stripe_event_id, kind, amount, payment_intent_id, occurred_at)
+SELECT uuid, 'legacy:' || uuid, 'payment', amount_paid, COALESCE(payment_intent, ''), NOW()
+FROM invoices
+WHERE amount_paid > 0;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS payment_ledger;
+-- +goose StatementEnd
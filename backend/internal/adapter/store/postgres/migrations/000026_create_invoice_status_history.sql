@@ -0,0 +1,43 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE EXTENSION IF NOT EXISTS "uuid-ossp";
+
+CREATE TABLE invoice_status_history (
+    uuid UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    invoice_id UUID NOT NULL REFERENCES invoices(uuid) ON DELETE CASCADE,
+    from_status TEXT NOT NULL DEFAULT '',
+    to_status TEXT NOT NULL,
+    actor TEXT NOT NULL,
+    reason TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_invoice_status_history_invoice ON invoice_status_history (invoice_id, created_at);
+
+-- "pending" and "unpaid" predate the lifecycle, an invoice that has been emailed is sent and
+-- anything else is still a draft
+UPDATE invoices SET status = 'sent'
+WHERE (status IS NULL OR status NOT IN ('draft', 'sent', 'partially_paid', 'paid', 'overdue', 'void', 'refunded'))
+  AND last_sent IS NOT NULL AND last_sent > '0001-01-01';
+
+UPDATE invoices SET status = 'draft'
+WHERE status IS NULL OR status NOT IN ('draft', 'sent', 'partially_paid', 'paid', 'overdue', 'void', 'refunded');
+
+INSERT INTO invoice_status_history (invoice_id, to_status, actor, reason)
+SELECT uuid, status, 'system', 'status at migration'
+FROM invoices;
+
+ALTER TABLE invoices ALTER COLUMN status SET DEFAULT 'draft';
+ALTER TABLE invoices ALTER COLUMN status SET NOT NULL;
+ALTER TABLE invoices ADD CONSTRAINT invoices_status_check
+    CHECK (status IN ('draft', 'sent', 'partially_paid', 'paid', 'overdue', 'void', 'refunded'));
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE invoices DROP CONSTRAINT IF EXISTS invoices_status_check;
+ALTER TABLE invoices ALTER COLUMN status DROP NOT NULL;
+ALTER TABLE invoices ALTER COLUMN status DROP DEFAULT;
+UPDATE invoices SET status = 'pending' WHERE status IN ('draft', 'sent', 'overdue');
+DROP TABLE IF EXISTS invoice_status_history;
+-- +goose StatementEnd
@@ -0,0 +1,31 @@
+-- +goose Up
+-- +goose StatementBegin
+-- every invoice so far billed its whole request, which is what a final invoice does
+ALTER TABLE invoices ADD COLUMN kind TEXT NOT NULL DEFAULT 'final'
+    CHECK (kind IN ('deposit', 'change_order', 'final'));
+ALTER TABLE invoices ADD COLUMN sequence INTEGER;
+-- deposits and change orders a final invoice deducts from the request total
+ALTER TABLE invoices ADD COLUMN prior_invoiced NUMERIC(12, 2) NOT NULL DEFAULT 0;
+
+UPDATE invoices SET sequence = numbered.n
+FROM (
+    SELECT uuid, ROW_NUMBER() OVER (PARTITION BY request_id ORDER BY due_date, uuid) AS n
+    FROM invoices
+) AS numbered
+WHERE invoices.uuid = numbered.uuid;
+
+ALTER TABLE invoices ALTER COLUMN sequence SET NOT NULL;
+CREATE UNIQUE INDEX idx_invoices_request_sequence ON invoices (request_id, sequence);
+
+-- which of the request's invoices a scheduled email is about
+ALTER TABLE email_outbox ADD COLUMN invoice_id UUID REFERENCES invoices(uuid) ON DELETE SET NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE email_outbox DROP COLUMN IF EXISTS invoice_id;
+DROP INDEX IF EXISTS idx_invoices_request_sequence;
+ALTER TABLE invoices DROP COLUMN IF EXISTS prior_invoiced;
+ALTER TABLE invoices DROP COLUMN IF EXISTS sequence;
+ALTER TABLE invoices DROP COLUMN IF EXISTS kind;
+-- +goose StatementEnd
@@ -0,0 +1,79 @@
+-- +goose Up
+-- +goose StatementBegin
+-- short code that prefixes the branch's invoice numbers, e.g. LA-2026-000123
+ALTER TABLE branches ADD COLUMN code TEXT;
+
+UPDATE branches SET code = CASE uuid
+    WHEN '00000000-0000-0000-0000-000000000001' THEN 'LA'
+    WHEN '00000000-0000-0000-0000-000000000002' THEN 'NYC'
+    WHEN '00000000-0000-0000-0000-000000000003' THEN 'ATL'
+    WHEN '00000000-0000-0000-0000-000000000004' THEN 'HOU'
+    WHEN '00000000-0000-0000-0000-000000000005' THEN 'DC'
+    WHEN '00000000-0000-0000-0000-000000000006' THEN 'OC'
+    WHEN '00000000-0000-0000-0000-000000000007' THEN 'CHI'
+    WHEN '00000000-0000-0000-0000-000000000008' THEN 'SF'
+    WHEN '00000000-0000-0000-0000-000000000009' THEN 'MIA'
+    WHEN '00000000-0000-0000-0000-000000000010' THEN 'LV'
+    WHEN '00000000-0000-0000-0000-000000000011' THEN 'SLC'
+    WHEN '00000000-0000-0000-0000-000000000012' THEN 'SEA'
+    WHEN '00000000-0000-0000-0000-000000000013' THEN 'ORL'
+    WHEN '00000000-0000-0000-0000-000000000014' THEN 'CLT'
+    WHEN '00000000-0000-0000-0000-000000000015' THEN 'BOS'
+    WHEN '00000000-0000-0000-0000-000000000016' THEN 'DAL'
+    WHEN '00000000-0000-0000-0000-000000000017' THEN 'AUS'
+    WHEN '00000000-0000-0000-0000-000000000018' THEN 'TPA'
+    WHEN '00000000-0000-0000-0000-000000000019' THEN 'PHX'
+    WHEN '00000000-0000-0000-0000-000000000020' THEN 'SD'
+    WHEN '00000000-0000-0000-0000-000000000021' THEN 'NOLA'
+END;
+
+UPDATE branches SET code = UPPER(LEFT(REGEXP_REPLACE(name, '[^A-Za-z]', '', 'g'), 3))
+WHERE code IS NULL;
+
+-- the last number handed out per branch code and year. Allocating bumps the row inside the
+-- issuing transaction, so a rolled back issue gives its number back and numbers stay gapless.
+CREATE TABLE invoice_number_sequences (
+    branch_code TEXT NOT NULL,
+    year INTEGER NOT NULL,
+    last_number INTEGER NOT NULL,
+    PRIMARY KEY (branch_code, year)
+);
+
+ALTER TABLE invoices ADD COLUMN invoice_number TEXT UNIQUE;
+
+-- number the invoices that have already gone out to clients, in the order they were sent
+CREATE TEMPORARY TABLE numbered_invoices ON COMMIT DROP AS
+SELECT
+    numbered.uuid,
+    numbered.branch_code,
+    numbered.year,
+    ROW_NUMBER() OVER (PARTITION BY numbered.branch_code, numbered.year ORDER BY numbered.issued_at, numbered.uuid) AS n
+FROM (
+    SELECT
+        i.uuid,
+        COALESCE(NULLIF(b.code, ''), 'EVS') AS branch_code,
+        EXTRACT(YEAR FROM CASE WHEN i.last_sent > '2000-01-01' THEN i.last_sent ELSE i.due_date END)::INTEGER AS year,
+        CASE WHEN i.last_sent > '2000-01-01' THEN i.last_sent ELSE i.due_date END AS issued_at
+    FROM invoices i
+    JOIN requests r ON r.uuid = i.request_id
+    LEFT JOIN branches b ON b.uuid = r.closest_branch_id
+    WHERE i.status <> 'draft' AND (i.status <> 'void' OR i.last_sent > '2000-01-01')
+) AS numbered;
+
+UPDATE invoices
+SET invoice_number = numbered_invoices.branch_code || '-' || numbered_invoices.year || '-' || LPAD(numbered_invoices.n::TEXT, 6, '0')
+FROM numbered_invoices
+WHERE invoices.uuid = numbered_invoices.uuid;
+
+INSERT INTO invoice_number_sequences (branch_code, year, last_number)
+SELECT branch_code, year, MAX(n)
+FROM numbered_invoices
+GROUP BY branch_code, year;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE invoices DROP COLUMN IF EXISTS invoice_number;
+DROP TABLE IF EXISTS invoice_number_sequences;
+ALTER TABLE branches DROP COLUMN IF EXISTS code;
+-- +goose StatementEnd
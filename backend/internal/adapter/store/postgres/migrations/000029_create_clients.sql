@@ -0,0 +1,50 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE EXTENSION IF NOT EXISTS "uuid-ossp";
+
+-- whoever pays for requests, matched by lowercased email, and the Stripe customer their
+-- payments and saved cards are kept under
+CREATE TABLE clients (
+    uuid UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    email TEXT NOT NULL UNIQUE,
+    name TEXT NOT NULL DEFAULT '',
+    company_name TEXT NOT NULL DEFAULT '',
+    phone_number TEXT NOT NULL DEFAULT '',
+    stripe_customer_id TEXT NOT NULL DEFAULT '',
+    default_payment_method_id TEXT NOT NULL DEFAULT '',
+    off_session_approved BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX idx_clients_stripe_customer ON clients (stripe_customer_id) WHERE stripe_customer_id <> '';
+
+-- one client per email seen so far, named after their latest request
+INSERT INTO clients (email, name, company_name, phone_number)
+SELECT DISTINCT ON (LOWER(TRIM(email)))
+    LOWER(TRIM(email)),
+    TRIM(COALESCE(first_name, '') || ' ' || COALESCE(last_name, '')),
+    CASE WHEN is_company THEN COALESCE(company_name, '') ELSE '' END,
+    COALESCE(phone_number, '')
+FROM requests
+WHERE TRIM(COALESCE(email, '')) <> ''
+ORDER BY LOWER(TRIM(email)), date_requested DESC;
+
+ALTER TABLE requests ADD COLUMN client_id UUID REFERENCES clients(uuid);
+CREATE INDEX idx_requests_client ON requests (client_id);
+
+UPDATE requests SET client_id = clients.uuid
+FROM clients
+WHERE clients.email = LOWER(TRIM(requests.email));
+
+-- the automatic charge after an event is tried once, a declined card is followed up by hand
+ALTER TABLE invoices ADD COLUMN auto_charge_attempted_at TIMESTAMP;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE invoices DROP COLUMN IF EXISTS auto_charge_attempted_at;
+DROP INDEX IF EXISTS idx_requests_client;
+ALTER TABLE requests DROP COLUMN IF EXISTS client_id;
+DROP TABLE IF EXISTS clients;
+-- +goose StatementEnd
@@ -0,0 +1,22 @@
+-- +goose Up
+-- +goose StatementBegin
+-- roles now decide what a user may do, so they have to be one the API knows. Users created
+-- by the sign up Lambda as plain 'user' become staff, the least privileged staff role.
+UPDATE users SET role = LOWER(TRIM(role)) WHERE role IS NOT NULL;
+UPDATE users SET role = 'account-executive' WHERE role IN ('account_executive', 'accountexecutive', 'ae');
+UPDATE users SET role = 'superadmin' WHERE role IN ('super_admin', 'super-admin');
+UPDATE users SET role = 'staff'
+WHERE role IS NULL OR role NOT IN ('superadmin', 'admin', 'account-executive', 'staff', 'client');
+
+ALTER TABLE users ALTER COLUMN role SET DEFAULT 'staff';
+ALTER TABLE users ALTER COLUMN role SET NOT NULL;
+ALTER TABLE users ADD CONSTRAINT users_role_check
+    CHECK (role IN ('superadmin', 'admin', 'account-executive', 'staff', 'client'));
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_role_check;
+ALTER TABLE users ALTER COLUMN role DROP NOT NULL;
+ALTER TABLE users ALTER COLUMN role DROP DEFAULT;
+-- +goose StatementEnd
@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+-- the Cognito user the row belongs to, tokens are matched on it. Users created before the
+-- column are linked by verified email the first time they sign in.
+ALTER TABLE users ADD COLUMN cognito_sub TEXT UNIQUE;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE users DROP COLUMN IF EXISTS cognito_sub;
+-- +goose StatementEnd
@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+-- invited users are added through the directory before they sign up, deactivated users keep
+-- their row but can no longer sign in
+ALTER TABLE users
+    ADD COLUMN invited_at TIMESTAMPTZ,
+    ADD COLUMN deactivated_at TIMESTAMPTZ;
+
+CREATE INDEX idx_users_branch_id ON users (branch_id);
+CREATE INDEX idx_users_lower_email ON users (LOWER(email));
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_users_lower_email;
+DROP INDEX IF EXISTS idx_users_branch_id;
+ALTER TABLE users
+    DROP COLUMN IF EXISTS deactivated_at,
+    DROP COLUMN IF EXISTS invited_at;
+-- +goose StatementEnd
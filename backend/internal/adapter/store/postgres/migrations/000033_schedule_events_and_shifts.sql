@@ -0,0 +1,30 @@
+-- +goose Up
+-- +goose StatementBegin
+-- a confirmed request is scheduled as one event, the index keeps the sweep and a manual
+-- confirmation from both creating it
+CREATE UNIQUE INDEX idx_events_request_id ON events (request_id);
+CREATE INDEX idx_events_branch_start ON events (branch_id, start_date);
+
+UPDATE events SET status = 'scheduled'
+WHERE status IS NULL OR status NOT IN ('scheduled', 'in_progress', 'completed', 'cancelled');
+ALTER TABLE events
+    ALTER COLUMN status SET DEFAULT 'scheduled',
+    ALTER COLUMN status SET NOT NULL,
+    ADD CONSTRAINT events_status_check CHECK (status IN ('scheduled', 'in_progress', 'completed', 'cancelled'));
+
+-- how many staff the shift's window needs, across positions
+ALTER TABLE shifts ADD COLUMN staff_needed INTEGER NOT NULL DEFAULT 0;
+CREATE INDEX idx_shifts_event_id ON shifts (event_id, date, start_time);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_shifts_event_id;
+ALTER TABLE shifts DROP COLUMN IF EXISTS staff_needed;
+ALTER TABLE events
+    DROP CONSTRAINT IF EXISTS events_status_check,
+    ALTER COLUMN status DROP NOT NULL,
+    ALTER COLUMN status DROP DEFAULT;
+DROP INDEX IF EXISTS idx_events_branch_start;
+DROP INDEX IF EXISTS idx_events_request_id;
+-- +goose StatementEnd
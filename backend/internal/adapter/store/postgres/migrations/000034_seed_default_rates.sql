@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Global defaults so a branch without a rate for a staff type can still be priced, close to the
+-- average of the branch rate cards. Regional defaults are added through the API.
+INSERT INTO default_rates (region, staff_type, hourly_rate)
+VALUES
+('', 'Brand Ambassadors', 19.50),
+('', 'Bartenders', 19.00),
+('', 'Production Assistants', 24.00),
+('', 'Catering Staff', 22.00),
+('', 'Model Staff', 36.00),
+('', 'Registration Staff', 20.50),
+('', 'Convention Staff', 22.50)
+ON CONFLICT (region, staff_type) DO NOTHING;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DELETE FROM default_rates WHERE region = '' AND staff_type IN (
+    'Brand Ambassadors', 'Bartenders', 'Production Assistants', 'Catering Staff',
+    'Model Staff', 'Registration Staff', 'Convention Staff'
+);
+-- +goose StatementEnd
@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+-- What cancelling the request still has to refund, it goes down as refunds go through so a
+-- refund that fails can be retried for the rest
+ALTER TABLE requests ADD COLUMN cancellation_refund_due DECIMAL(10,2) NOT NULL DEFAULT 0;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE requests DROP COLUMN IF EXISTS cancellation_refund_due;
+-- +goose StatementEnd
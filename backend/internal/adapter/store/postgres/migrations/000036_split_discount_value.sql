@@ -0,0 +1,40 @@
+-- +goose Up
+-- +goose StatementBegin
+-- discount_value was a percentage or a dollar amount depending on discount_type. Fixed
+-- discounts are money and get an exact column of their own, percentages keep three decimals.
+ALTER TABLE invoices ADD COLUMN discount_percent NUMERIC(6,3) NOT NULL DEFAULT 0;
+ALTER TABLE invoices ADD COLUMN discount_fixed DECIMAL(10,2) NOT NULL DEFAULT 0;
+UPDATE invoices SET discount_percent = COALESCE(discount_value, 0) WHERE discount_type = 'percentage';
+UPDATE invoices SET discount_fixed = ROUND(COALESCE(discount_value, 0), 2) WHERE discount_type = 'fixed';
+ALTER TABLE invoices DROP COLUMN discount_value;
+
+ALTER TABLE promo_codes ADD COLUMN discount_percent NUMERIC(6,3) NOT NULL DEFAULT 0;
+ALTER TABLE promo_codes ADD COLUMN discount_fixed DECIMAL(10,2) NOT NULL DEFAULT 0;
+UPDATE promo_codes SET discount_percent = discount_value WHERE discount_type = 'percentage';
+UPDATE promo_codes SET discount_fixed = ROUND(discount_value, 2) WHERE discount_type = 'fixed';
+ALTER TABLE promo_codes DROP COLUMN discount_value;
+ALTER TABLE promo_codes ADD CONSTRAINT promo_codes_discount_check CHECK (
+    (discount_type = 'percentage' AND discount_percent > 0 AND discount_percent <= 100 AND discount_fixed = 0)
+    OR (discount_type = 'fixed' AND discount_fixed > 0 AND discount_percent = 0)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE promo_codes DROP CONSTRAINT IF EXISTS promo_codes_discount_check;
+ALTER TABLE promo_codes ADD COLUMN discount_value NUMERIC(10, 3);
+UPDATE promo_codes SET discount_value = CASE WHEN discount_type = 'percentage' THEN discount_percent ELSE discount_fixed END;
+ALTER TABLE promo_codes ALTER COLUMN discount_value SET NOT NULL;
+ALTER TABLE promo_codes ADD CHECK (discount_value > 0);
+ALTER TABLE promo_codes DROP COLUMN discount_percent;
+ALTER TABLE promo_codes DROP COLUMN discount_fixed;
+
+ALTER TABLE invoices ADD COLUMN discount_value NUMERIC;
+UPDATE invoices SET discount_value = CASE
+    WHEN discount_type = 'percentage' THEN discount_percent
+    WHEN discount_type = 'fixed' THEN discount_fixed
+    ELSE 0
+END;
+ALTER TABLE invoices DROP COLUMN discount_percent;
+ALTER TABLE invoices DROP COLUMN discount_fixed;
+-- +goose StatementEnd
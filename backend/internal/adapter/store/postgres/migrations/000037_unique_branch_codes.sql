@@ -0,0 +1,49 @@
+-- +goose Up
+-- +goose StatementBegin
+-- codes made from the first three letters of branch names can collide, e.g. San Diego and San
+-- Jose, and two branches on one code would share a run of invoice numbers. Every code after the
+-- first gets a number on the end, so do codes that are empty or the EVS fallback, picking one no
+-- branch and no existing invoice number uses yet.
+UPDATE branches SET code = UPPER(TRIM(code)) WHERE code IS NOT NULL;
+
+DO $$
+DECLARE
+    duplicate RECORD;
+    base TEXT;
+    candidate TEXT;
+    n INTEGER;
+BEGIN
+    FOR duplicate IN
+        SELECT uuid, code
+        FROM (
+            SELECT uuid, code, ROW_NUMBER() OVER (PARTITION BY code ORDER BY uuid) AS rank
+            FROM branches
+        ) AS ranked
+        WHERE code IS NULL OR code = '' OR code = 'EVS' OR rank > 1
+        ORDER BY code, uuid
+    LOOP
+        base := COALESCE(NULLIF(duplicate.code, ''), 'BR');
+        n := 2;
+        LOOP
+            candidate := base || n;
+            EXIT WHEN NOT EXISTS (SELECT 1 FROM branches WHERE code = candidate)
+                AND NOT EXISTS (SELECT 1 FROM invoice_number_sequences WHERE branch_code = candidate);
+            n := n + 1;
+        END LOOP;
+        UPDATE branches SET code = candidate WHERE uuid = duplicate.uuid;
+    END LOOP;
+END $$;
+
+ALTER TABLE branches ALTER COLUMN code SET NOT NULL;
+ALTER TABLE branches ADD CONSTRAINT branches_code_key UNIQUE (code);
+-- upper case keeps the unique constraint from letting LA and la both through, EVS is what
+-- requests without a branch are numbered under
+ALTER TABLE branches ADD CONSTRAINT branches_code_check CHECK (code <> '' AND code = UPPER(code) AND code <> 'EVS');
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE branches DROP CONSTRAINT IF EXISTS branches_code_check;
+ALTER TABLE branches DROP CONSTRAINT IF EXISTS branches_code_key;
+ALTER TABLE branches ALTER COLUMN code DROP NOT NULL;
+-- +goose StatementEnd
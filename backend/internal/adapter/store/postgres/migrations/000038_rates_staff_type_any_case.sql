@@ -0,0 +1,26 @@
+-- +goose Up
+-- +goose StatementBegin
+-- rates are looked up by staff type in any case, so Bartender and bartender starting on the same
+-- day are one rate twice. The most recently updated one is the one kept.
+DELETE FROM rates
+WHERE uuid IN (
+    SELECT uuid
+    FROM (
+        SELECT uuid, ROW_NUMBER() OVER (
+            PARTITION BY branch_id, LOWER(staff_type), effective_from
+            ORDER BY updated_at DESC NULLS LAST, created_at DESC, uuid
+        ) AS rank
+        FROM rates
+    ) AS ranked
+    WHERE rank > 1
+);
+
+DROP INDEX IF EXISTS idx_rates_branch_staff_type_effective_from;
+CREATE UNIQUE INDEX idx_rates_branch_staff_type_effective_from ON rates (branch_id, LOWER(staff_type), effective_from);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_rates_branch_staff_type_effective_from;
+CREATE UNIQUE INDEX idx_rates_branch_staff_type_effective_from ON rates (branch_id, staff_type, effective_from);
+-- +goose StatementEnd
@@ -0,0 +1,149 @@
+package repository
+
+import (
+	"backend/internal/core/models"
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// requestsInScope limits a query to rows whose request is in the context's branch. column
+// holds the request's uuid, e.g. "invoices.request_id".
+func requestsInScope(ctx context.Context, column string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		branchID, ok := models.BranchScope(ctx)
+		if !ok {
+			return db
+		}
+		return db.Where(column+" IN (SELECT uuid FROM requests WHERE closest_branch_id = ?)", branchID)
+	}
+}
+
+// branchInScope limits a query to rows of the context's branch, column holds the branch's uuid
+func branchInScope(ctx context.Context, column string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		branchID, ok := models.BranchScope(ctx)
+		if !ok {
+			return db
+		}
+		return db.Where(column+" = ?", branchID)
+	}
+}
+
+// sharedOrBranchInScope limits a query to rows of the context's branch and rows every branch
+// shares, column holds the branch's uuid and is NULL on shared rows. Only contexts that reach
+// every branch change shared rows, see checkSharedBranchScope.
+func sharedOrBranchInScope(ctx context.Context, column string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		branchID, ok := models.BranchScope(ctx)
+		if !ok {
+			return db
+		}
+		return db.Where("("+column+" = ? OR "+column+" IS NULL)", branchID)
+	}
+}
+
+// clientsInScope limits a query to clients with a request in the context's branch. column holds
+// the client's uuid, e.g. "clients.uuid".
+func clientsInScope(ctx context.Context, column string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		branchID, ok := models.BranchScope(ctx)
+		if !ok {
+			return db
+		}
+		return db.Where(column+" IN (SELECT client_id FROM requests WHERE closest_branch_id = ?)", branchID)
+	}
+}
+
+// eventsInScope limits a query to rows whose event is in the context's branch. column holds the
+// event's uuid, e.g. "shifts.event_id".
+func eventsInScope(ctx context.Context, column string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		branchID, ok := models.BranchScope(ctx)
+		if !ok {
+			return db
+		}
+		return db.Where(column+" IN (SELECT uuid FROM events WHERE branch_id = ?)", branchID)
+	}
+}
+
+// checkBranchScope fails with ErrOutOfBranchScope when the context may not reach the branch
+func checkBranchScope(ctx context.Context, branchID uuid.UUID) error {
+	if !models.InBranchScope(ctx, branchID) {
+		return models.ErrOutOfBranchScope
+	}
+	return nil
+}
+
+// checkSharedBranchScope is checkBranchScope for rows that may be shared by every branch, a nil
+// branch is only reachable by contexts without a scope
+func checkSharedBranchScope(ctx context.Context, branchID *uuid.UUID) error {
+	if branchID == nil {
+		if _, ok := models.BranchScope(ctx); ok {
+			return models.ErrOutOfBranchScope
+		}
+		return nil
+	}
+	return checkBranchScope(ctx, *branchID)
+}
+
+// deleteInBranchScope deletes the row with the uuid if it is on the context's branch. A row that
+// is there but out of reach, e.g. one every branch shares, fails with ErrOutOfBranchScope.
+func deleteInBranchScope(ctx context.Context, db *gorm.DB, model any, id uuid.UUID) error {
+	result := db.WithContext(ctx).Scopes(branchInScope(ctx, "branch_id")).Where("uuid = ?", id).Delete(model)
+	if result.Error != nil || result.RowsAffected > 0 {
+		return result.Error
+	}
+
+	var count int64
+	if err := db.WithContext(ctx).Model(model).Where("uuid = ?", id).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return models.ErrOutOfBranchScope
+	}
+	return nil
+}
+
+// checkRequestScope fails with ErrOutOfBranchScope when the request is outside the context's
+// branch, it is used before writing rows that belong to a request
+func checkRequestScope(ctx context.Context, db *gorm.DB, requestID uuid.UUID) error {
+	if _, ok := models.BranchScope(ctx); !ok {
+		return nil
+	}
+
+	var count int64
+	err := db.WithContext(ctx).Model(&models.Request{}).
+		Scopes(requestsInScope(ctx, "uuid")).
+		Where("uuid = ?", requestID).
+		Count(&count).Error
+	if err != nil {
+		return fmt.Errorf("failed to check request %s: %w", requestID, err)
+	}
+	if count == 0 {
+		return models.ErrOutOfBranchScope
+	}
+	return nil
+}
+
+// checkInvoiceScope is checkRequestScope for rows that belong to an invoice
+func checkInvoiceScope(ctx context.Context, db *gorm.DB, invoiceID uuid.UUID) error {
+	if _, ok := models.BranchScope(ctx); !ok {
+		return nil
+	}
+
+	var count int64
+	err := db.WithContext(ctx).Model(&models.Invoice{}).
+		Scopes(requestsInScope(ctx, "request_id")).
+		Where("uuid = ?", invoiceID).
+		Count(&count).Error
+	if err != nil {
+		return fmt.Errorf("failed to check invoice %s: %w", invoiceID, err)
+	}
+	if count == 0 {
+		return models.ErrOutOfBranchScope
+	}
+	return nil
+}
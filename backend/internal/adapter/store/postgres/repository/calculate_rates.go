@@ -1,10 +1,12 @@
 // where all the calculations actually happen
 // subtotal = base rate * number of staff
-// transaction fee = 3% of subtotal
-// service fee = (subtotal + transaction fee) * 1.5
-// amount = subtotal + transaction fee + service fee
+// discount = percentage or fixed discount taken off the subtotal
+// transaction fee = policy transaction fee % of (subtotal - discount)
+// service fee = (subtotal - discount + transaction fee) * policy service fee %
+// amount = subtotal - discount + transaction fee + service fee
 
-// rates will be calculated differently for each staff type and event location
+// fee percentages, minimums and rounding come from the pricing policy that applies to
+// the request's branch and client at the time the request was made
 package repository
 
 import (
@@ -12,32 +14,39 @@ import (
 	ports "backend/internal/core/ports"
 	"context"
 	"errors"
+	"fmt"
+	"time"
 
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
+// used when no pricing policy row applies, matches the seeded global default
+var defaultPricingPolicy = models.PricingPolicy{
+	Name:                  "Default",
+	TransactionFeePercent: 3.5,
+	ServiceFeePercent:     30,
+	Rounding:              models.RoundingNearestCent,
+}
+
 type RateCalculatorRepository struct {
 	rateStore RateStore
 }
 
-// Getting the rate for a given staff type and location
+// Getting the staff requirements, line items and pricing policy for a request
 type RateStore interface {
-	GetRate(ctx context.Context, staffType string, location string) (float64, error)
 	GetAllStaffRequirementsByRequestID(ctx context.Context, id uuid.UUID) ([]models.StaffRequirement, error)
 	GetCustomLineItemsByRequestID(ctx context.Context, id uuid.UUID) ([]models.CustomLineItems, error)
 	UpdateCustomLineItem(ctx context.Context, customLineItem *models.CustomLineItems) error
 	CreateCustomLineItem(ctx context.Context, customLineItem *models.CustomLineItems) error
+	GetApplicablePricingPolicy(ctx context.Context, branchID uuid.UUID, clientEmail string, at time.Time) (*models.PricingPolicy, error)
 }
 
 // RateStoreAdapter adapts StaffRequirementRepository to RateStore interface
 type RateStoreAdapter struct {
 	staffRepo           ports.StaffRequirementRepository
 	customLineItemsRepo ports.CustomLineItemsRepository
-}
-
-func (r *RateStoreAdapter) GetRate(ctx context.Context, staffType string, location string) (float64, error) {
-	staffRepo := r.staffRepo.(*StaffRequirementRepository)
-	return staffRepo.GetRate(ctx, staffType, location)
+	pricingPolicyRepo   ports.PricingPolicyRepository
 }
 
 func (r *RateStoreAdapter) GetAllStaffRequirementsByRequestID(ctx context.Context, id uuid.UUID) ([]models.StaffRequirement, error) {
@@ -56,48 +65,48 @@ func (r *RateStoreAdapter) CreateCustomLineItem(ctx context.Context, customLineI
 	return r.customLineItemsRepo.CreateCustomLineItem(ctx, customLineItem)
 }
 
-func NewRateCalculatorRepository(staffRepo ports.StaffRequirementRepository, customLineItemsRepo ports.CustomLineItemsRepository) *RateCalculatorRepository {
+func (r *RateStoreAdapter) GetApplicablePricingPolicy(ctx context.Context, branchID uuid.UUID, clientEmail string, at time.Time) (*models.PricingPolicy, error) {
+	return r.pricingPolicyRepo.GetApplicablePricingPolicy(ctx, branchID, clientEmail, at)
+}
+
+func NewRateCalculatorRepository(staffRepo ports.StaffRequirementRepository, customLineItemsRepo ports.CustomLineItemsRepository, pricingPolicyRepo ports.PricingPolicyRepository) *RateCalculatorRepository {
 	adapter := &RateStoreAdapter{
 		staffRepo:           staffRepo,
 		customLineItemsRepo: customLineItemsRepo,
+		pricingPolicyRepo:   pricingPolicyRepo,
 	}
 	return &RateCalculatorRepository{rateStore: adapter}
 }
 
-func (r *RateCalculatorRepository) CalculateRates(ctx context.Context, request *models.Request) (float64, float64, float64, float64, error) {
+func (r *RateCalculatorRepository) CalculateRates(ctx context.Context, request *models.Request, discount models.Discount) (*models.RateBreakdown, error) {
 	if request == nil {
-		return 0, 0, 0, 0, errors.New("request is nil")
+		return nil, errors.New("request is nil")
 	}
 
 	// grab uuid of request, get all staff requirements by id by using GetAllStaffRequirementsByRequestID
 	requestUUID := request.UUID
 	listOfStaff, err := r.rateStore.GetAllStaffRequirementsByRequestID(ctx, requestUUID)
 	if err != nil {
-		return 0, 0, 0, 0, err
+		return nil, err
 	}
 
 	// Sum the pre-calculated amount from each staff requirement for the subtotal
-	subtotal := 0.00
+	var subtotal models.Money
 
 	for _, staff := range listOfStaff {
 		subtotal += staff.Amount
 	}
 
-	transactionFee := subtotal * 0.035              // 3.5% of subtotal
-	serviceFee := (subtotal + transactionFee) * 0.3 // 30% of (subtotal + transaction fee)
-
-	totalAmount := subtotal + transactionFee + serviceFee
-
-	return totalAmount, transactionFee, serviceFee, subtotal, nil
+	return r.CalculateFees(ctx, request, subtotal, discount)
 }
 
-func (r *RateCalculatorRepository) GetRates(ctx context.Context, request *models.Request) (float64, float64, float64, float64, error) {
-	return r.CalculateRates(ctx, request)
+func (r *RateCalculatorRepository) GetRates(ctx context.Context, request *models.Request, discount models.Discount) (*models.RateBreakdown, error) {
+	return r.CalculateRates(ctx, request, discount)
 }
 
-func (r *RateCalculatorRepository) UpdateRates(ctx context.Context, request *models.Request, customLineItems []models.CustomLineItems) (float64, float64, float64, float64, error) {
+func (r *RateCalculatorRepository) UpdateRates(ctx context.Context, request *models.Request, customLineItems []models.CustomLineItems, discount models.Discount) (*models.RateBreakdown, error) {
 	if request == nil {
-		return 0, 0, 0, 0, errors.New("request is nil")
+		return nil, errors.New("request is nil")
 	}
 
 	// Get request UUID
@@ -106,18 +115,18 @@ func (r *RateCalculatorRepository) UpdateRates(ctx context.Context, request *mod
 	// Get all staff requirements for this request
 	listOfStaff, err := r.rateStore.GetAllStaffRequirementsByRequestID(ctx, requestUUID)
 	if err != nil {
-		return 0, 0, 0, 0, err
+		return nil, err
 	}
 
 	// Calculate staff-based subtotal
-	staffSubtotal := 0.00
+	var staffSubtotal models.Money
 	for _, staff := range listOfStaff {
 		staffSubtotal += staff.Amount
 	}
 
 	// Handle custom line items
 	// First save/update them in the database if they're provided
-	customLineItemsSubtotal := 0.00
+	var customLineItemsSubtotal models.Money
 
 	if customLineItems != nil && len(customLineItems) > 0 {
 		// For each custom line item, set the request ID and calculate total
@@ -131,7 +140,7 @@ func (r *RateCalculatorRepository) UpdateRates(ctx context.Context, request *mod
 			}
 
 			// Calculate total (quantity * rate)
-			customLineItems[i].Total = float64(customLineItems[i].Quantity) * customLineItems[i].Rate
+			customLineItems[i].Total = customLineItems[i].Rate.Mul(int64(customLineItems[i].Quantity))
 
 			// Add to subtotal
 			customLineItemsSubtotal += customLineItems[i].Total
@@ -140,7 +149,7 @@ func (r *RateCalculatorRepository) UpdateRates(ctx context.Context, request *mod
 			if err := r.rateStore.UpdateCustomLineItem(ctx, &customLineItems[i]); err != nil {
 				// If it fails to update, try creating it
 				if err := r.rateStore.CreateCustomLineItem(ctx, &customLineItems[i]); err != nil {
-					return 0, 0, 0, 0, err
+					return nil, err
 				}
 			}
 		}
@@ -149,12 +158,94 @@ func (r *RateCalculatorRepository) UpdateRates(ctx context.Context, request *mod
 	// Calculate final subtotal (staff + custom line items)
 	subtotal := staffSubtotal + customLineItemsSubtotal
 
-	// Calculate fees
-	transactionFee := subtotal * 0.035              // 3.5% of subtotal
-	serviceFee := (subtotal + transactionFee) * 0.3 // 30% of (subtotal + transaction fee)
+	return r.CalculateFees(ctx, request, subtotal, discount)
+}
+
+// CalculateRequestTotal prices the whole request, staff and every custom line item already saved
+func (r *RateCalculatorRepository) CalculateRequestTotal(ctx context.Context, request *models.Request, discount models.Discount) (*models.RateBreakdown, error) {
+	if request == nil {
+		return nil, errors.New("request is nil")
+	}
+
+	listOfStaff, err := r.rateStore.GetAllStaffRequirementsByRequestID(ctx, request.UUID)
+	if err != nil {
+		return nil, err
+	}
+
+	customLineItems, err := r.rateStore.GetCustomLineItemsByRequestID(ctx, request.UUID)
+	if err != nil {
+		return nil, err
+	}
+
+	var subtotal models.Money
+	for _, staff := range listOfStaff {
+		subtotal += staff.Amount
+	}
+	for _, item := range customLineItems {
+		subtotal += item.Total
+	}
 
-	// Calculate total amount
-	totalAmount := subtotal + transactionFee + serviceFee
+	return r.CalculateFees(ctx, request, subtotal, discount)
+}
+
+// CalculateFees applies the discount and then the request's pricing policy to a subtotal
+func (r *RateCalculatorRepository) CalculateFees(ctx context.Context, request *models.Request, subtotal models.Money, discount models.Discount) (*models.RateBreakdown, error) {
+	if request == nil {
+		return nil, errors.New("request is nil")
+	}
+
+	policy, err := r.pricingPolicyFor(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	discountAmount := discount.Amount(subtotal)
+	discountedSubtotal := subtotal - discountAmount
+
+	transactionFee, serviceFee := applyPricingPolicy(discountedSubtotal, policy)
+
+	return &models.RateBreakdown{
+		Subtotal:       subtotal,
+		Discount:       discountAmount,
+		TransactionFee: transactionFee,
+		ServiceFee:     serviceFee,
+		Amount:         discountedSubtotal + transactionFee + serviceFee,
+	}, nil
+}
+
+// pricingPolicyFor finds the policy in force when the request was made, falling back to the
+// built-in default when no policy has been configured
+func (r *RateCalculatorRepository) pricingPolicyFor(ctx context.Context, request *models.Request) (*models.PricingPolicy, error) {
+	at := request.DateRequested
+	if at.IsZero() {
+		at = time.Now().UTC()
+	}
+
+	policy, err := r.rateStore.GetApplicablePricingPolicy(ctx, request.ClosestBranchID, request.Email, at)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		fallback := defaultPricingPolicy
+		return &fallback, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pricing policy: %w", err)
+	}
+
+	return policy, nil
+}
+
+// applyPricingPolicy returns the transaction and service fees for a subtotal. Any shortfall
+// against the policy's minimum total is added to the service fee.
+func applyPricingPolicy(subtotal models.Money, policy *models.PricingPolicy) (models.Money, models.Money) {
+	transactionFee := subtotal.Percent(policy.TransactionFeePercent, policy.Rounding)
+	serviceFee := (subtotal + transactionFee).Percent(policy.ServiceFeePercent, policy.Rounding)
+
+	if serviceFee < policy.MinimumServiceFee {
+		serviceFee = policy.MinimumServiceFee
+	}
+
+	if total := subtotal + transactionFee + serviceFee; subtotal > 0 && total < policy.MinimumTotal {
+		serviceFee += policy.MinimumTotal - total
+	}
 
-	return totalAmount, transactionFee, serviceFee, subtotal, nil
+	return transactionFee, serviceFee
 }
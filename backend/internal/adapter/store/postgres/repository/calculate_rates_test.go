@@ -0,0 +1,116 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"backend/internal/core/models"
+)
+
+// policyStore hands out one pricing policy, or none so the built-in default applies
+type policyStore struct {
+	policy *models.PricingPolicy
+}
+
+func (s *policyStore) GetAllStaffRequirementsByRequestID(ctx context.Context, id uuid.UUID) ([]models.StaffRequirement, error) {
+	return nil, nil
+}
+
+func (s *policyStore) GetCustomLineItemsByRequestID(ctx context.Context, id uuid.UUID) ([]models.CustomLineItems, error) {
+	return nil, nil
+}
+
+func (s *policyStore) UpdateCustomLineItem(ctx context.Context, customLineItem *models.CustomLineItems) error {
+	return nil
+}
+
+func (s *policyStore) CreateCustomLineItem(ctx context.Context, customLineItem *models.CustomLineItems) error {
+	return nil
+}
+
+func (s *policyStore) GetApplicablePricingPolicy(ctx context.Context, branchID uuid.UUID, clientEmail string, at time.Time) (*models.PricingPolicy, error) {
+	if s.policy == nil {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return s.policy, nil
+}
+
+func TestCalculateFeesWithTheDefaultPolicy(t *testing.T) {
+	calculator := &RateCalculatorRepository{rateStore: &policyStore{}}
+
+	// $1000 less 10%: 3.5% of $900 is $31.50, 30% of $931.50 is $279.45
+	breakdown, err := calculator.CalculateFees(context.Background(), &models.Request{}, 100000,
+		models.Discount{Type: models.DiscountTypePercentage, Percent: 10})
+	if err != nil {
+		t.Fatalf("CalculateFees: %v", err)
+	}
+	want := models.RateBreakdown{
+		Subtotal:       100000,
+		Discount:       10000,
+		TransactionFee: 3150,
+		ServiceFee:     27945,
+		Amount:         121095,
+	}
+	if *breakdown != want {
+		t.Fatalf("breakdown = %+v, want %+v", *breakdown, want)
+	}
+}
+
+func FuzzCalculateFeesAddsUp(f *testing.F) {
+	f.Add(int64(100000), uint8(1), int32(10000), int32(3500), int32(30000), int64(0), int64(0), uint8(0))
+	f.Add(int64(12345), uint8(2), int32(2550), int32(2900), int32(15000), int64(5000), int64(0), uint8(1))
+	f.Add(int64(999), uint8(0), int32(0), int32(0), int32(0), int64(0), int64(50000), uint8(2))
+	f.Add(int64(5000), uint8(2), int32(900000), int32(3333), int32(12345), int64(100), int64(100), uint8(3))
+	f.Fuzz(func(t *testing.T, subtotal int64, discountKind uint8, discountValue, transactionFee, serviceFee int32, minimumServiceFee, minimumTotal int64, rounding uint8) {
+		// amounts up to $10M, percentages and discounts in thousandths up to 1000%
+		subtotal %= 1_000_000_000
+		if subtotal < 0 {
+			subtotal = -subtotal
+		}
+		policy := &models.PricingPolicy{
+			TransactionFeePercent: float64(transactionFee%1_000_000) / 1000,
+			ServiceFeePercent:     float64(serviceFee%1_000_000) / 1000,
+			MinimumServiceFee:     models.MoneyFromCents(minimumServiceFee % 10_000_000),
+			MinimumTotal:          models.MoneyFromCents(minimumTotal % 10_000_000),
+			Rounding: []string{
+				models.RoundingNearestCent, models.RoundingUpCent, models.RoundingNearestDollar, models.RoundingUpDollar,
+			}[rounding%4],
+		}
+		if policy.TransactionFeePercent < 0 {
+			policy.TransactionFeePercent = -policy.TransactionFeePercent
+		}
+		if policy.ServiceFeePercent < 0 {
+			policy.ServiceFeePercent = -policy.ServiceFeePercent
+		}
+		discount := models.Discount{Type: []string{models.DiscountTypeNone, models.DiscountTypePercentage, models.DiscountTypeFixed}[discountKind%3]}
+		switch discount.Type {
+		case models.DiscountTypePercentage:
+			discount.Percent = float64(discountValue%1_000_000) / 1000
+		case models.DiscountTypeFixed:
+			discount.Fixed = models.MoneyFromCents(int64(discountValue))
+		}
+
+		calculator := &RateCalculatorRepository{rateStore: &policyStore{policy: policy}}
+		breakdown, err := calculator.CalculateFees(context.Background(), &models.Request{}, models.MoneyFromCents(subtotal), discount)
+		if err != nil {
+			t.Fatalf("CalculateFees: %v", err)
+		}
+
+		if breakdown.Subtotal+breakdown.TransactionFee+breakdown.ServiceFee-breakdown.Discount != breakdown.Amount {
+			t.Fatalf("subtotal + fees - discount != amount in %+v", *breakdown)
+		}
+		if breakdown.Discount < 0 || breakdown.Discount > breakdown.Subtotal {
+			t.Fatalf("discount outside 0..subtotal in %+v", *breakdown)
+		}
+		if breakdown.ServiceFee < policy.MinimumServiceFee {
+			t.Fatalf("service fee under the %d minimum in %+v", policy.MinimumServiceFee, *breakdown)
+		}
+		if breakdown.Subtotal > breakdown.Discount && breakdown.Amount < policy.MinimumTotal {
+			t.Fatalf("amount under the %d minimum in %+v", policy.MinimumTotal, *breakdown)
+		}
+	})
+}
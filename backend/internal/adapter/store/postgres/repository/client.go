@@ -0,0 +1,158 @@
+package repository
+
+import (
+	"backend/internal/core/models"
+	ports "backend/internal/core/ports"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ClientRepository struct {
+	db *gorm.DB
+}
+
+func NewClientRepository(db *gorm.DB) ports.ClientRepository {
+	return &ClientRepository{db: db}
+}
+
+func (r *ClientRepository) GetClientByID(ctx context.Context, id uuid.UUID) (*models.Client, error) {
+	var client models.Client
+	err := r.db.WithContext(ctx).Scopes(clientsInScope(ctx, "uuid")).Where("uuid = ?", id).First(&client).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client %s: %w", id, err)
+	}
+	return &client, nil
+}
+
+func (r *ClientRepository) GetClientForRequest(ctx context.Context, requestID uuid.UUID) (*models.Client, error) {
+	var client *models.Client
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := checkRequestScope(ctx, tx, requestID); err != nil {
+			return err
+		}
+
+		var request models.Request
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("uuid = ?", requestID).First(&request).Error
+		if err != nil {
+			return fmt.Errorf("failed to get request %s: %w", requestID, err)
+		}
+
+		if request.ClientID != nil {
+			client = &models.Client{}
+			return tx.Where("uuid = ?", *request.ClientID).First(client).Error
+		}
+
+		client, err = linkClient(tx, &request)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+func (r *ClientRepository) GetAllClients(ctx context.Context) ([]models.Client, error) {
+	var clients []models.Client
+	err := r.db.WithContext(ctx).Scopes(clientsInScope(ctx, "uuid")).Order("email").Find(&clients).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get clients: %w", err)
+	}
+	return clients, nil
+}
+
+// UpdateClient saves the editable details, the Stripe fields only change through their setters
+func (r *ClientRepository) UpdateClient(ctx context.Context, client *models.Client) error {
+	return r.db.WithContext(ctx).
+		Model(client).
+		Scopes(clientsInScope(ctx, "uuid")).
+		Select("name", "company_name", "phone_number", "off_session_approved", "updated_at").
+		Updates(client).Error
+}
+
+func (r *ClientRepository) SetStripeCustomerID(ctx context.Context, clientID uuid.UUID, customerID string) (string, error) {
+	// two checkouts racing to create the customer keep whichever was stored first
+	err := r.db.WithContext(ctx).Model(&models.Client{}).
+		Where("uuid = ? AND stripe_customer_id = ''", clientID).
+		Updates(map[string]interface{}{"stripe_customer_id": customerID, "updated_at": time.Now().UTC()}).Error
+	if err != nil {
+		return "", fmt.Errorf("failed to store Stripe customer for client %s: %w", clientID, err)
+	}
+
+	var stored models.Client
+	if err := r.db.WithContext(ctx).Select("stripe_customer_id").Where("uuid = ?", clientID).First(&stored).Error; err != nil {
+		return "", fmt.Errorf("failed to get client %s: %w", clientID, err)
+	}
+	return stored.StripeCustomerID, nil
+}
+
+func (r *ClientRepository) SetDefaultPaymentMethod(ctx context.Context, clientID uuid.UUID, paymentMethodID string) error {
+	return r.db.WithContext(ctx).Model(&models.Client{}).
+		Where("uuid = ?", clientID).
+		Updates(map[string]interface{}{"default_payment_method_id": paymentMethodID, "updated_at": time.Now().UTC()}).Error
+}
+
+func (r *ClientRepository) GetInvoicesDueForAutoCharge(ctx context.Context, eventEndedBefore time.Time) ([]models.Invoice, error) {
+	var invoices []models.Invoice
+	err := r.db.WithContext(ctx).
+		Preload("Request").
+		Joins("JOIN requests ON requests.uuid = invoices.request_id").
+		Joins("JOIN clients ON clients.uuid = requests.client_id").
+		Where("clients.off_session_approved AND clients.stripe_customer_id <> ''").
+		Where("requests.cancelled_at IS NULL AND requests.end_date < ?", eventEndedBefore).
+		Where("invoices.auto_charge_attempted_at IS NULL AND invoices.balance > 0").
+		Where("invoices.status IN ?", []string{models.InvoiceStatusSent, models.InvoiceStatusPartiallyPaid, models.InvoiceStatusOverdue}).
+		Order("requests.end_date").
+		Find(&invoices).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to query invoices due for automatic charge: %w", err)
+	}
+	return invoices, nil
+}
+
+func (r *ClientRepository) MarkAutoChargeAttempted(ctx context.Context, invoiceID uuid.UUID, at time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&models.Invoice{}).
+		Where("uuid = ? AND auto_charge_attempted_at IS NULL", invoiceID).
+		Update("auto_charge_attempted_at", at)
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to mark automatic charge for invoice %s: %w", invoiceID, result.Error)
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// linkClient finds the client with the request's email, creating it from the request if there
+// is none, and links the request to it inside the caller's transaction
+func linkClient(tx *gorm.DB, request *models.Request) (*models.Client, error) {
+	if models.NormalizeClientEmail(request.Email) == "" {
+		return nil, fmt.Errorf("request %s has no email to match a client on", request.UUID)
+	}
+
+	candidate := models.NewClientFromRequest(request)
+	err := tx.Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "email"}}, DoNothing: true}).
+		Create(candidate).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client: %w", err)
+	}
+
+	var client models.Client
+	if err := tx.Where("email = ?", candidate.Email).First(&client).Error; err != nil {
+		return nil, fmt.Errorf("failed to get client %s: %w", candidate.Email, err)
+	}
+
+	request.ClientID = &client.UUID
+	if request.UUID != uuid.Nil {
+		err := tx.Model(&models.Request{}).Where("uuid = ?", request.UUID).Update("client_id", client.UUID).Error
+		if err != nil {
+			return nil, fmt.Errorf("failed to link request %s to client: %w", request.UUID, err)
+		}
+	}
+	return &client, nil
+}
@@ -9,24 +9,30 @@ import (
 	"log"
 	"time"
 
-	"github.com/mailgun/mailgun-go/v4"
+	"github.com/google/uuid"
 	"github.com/redis/go-redis/v9"
 	"github.com/robfig/cron"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// how many outbox emails one poll sends at most
+const outboxBatchSize = 50
+
 type CronRepository struct {
 	db        *gorm.DB
 	redis     *redis.Client
 	emailRepo *EmailRepository
+	outbox    ports.EmailOutboxRepository
 	scheduler *cron.Cron
 }
 
-func NewCronRepository(db *gorm.DB, redis *redis.Client, emailRepo *EmailRepository) ports.CronRepository {
+func NewCronRepository(db *gorm.DB, redis *redis.Client, emailRepo *EmailRepository, outbox ports.EmailOutboxRepository) ports.CronRepository {
 	return &CronRepository{
 		db:        db,
 		redis:     redis,
 		emailRepo: emailRepo,
+		outbox:    outbox,
 		scheduler: cron.New(),
 	}
 }
@@ -34,29 +40,54 @@ func NewCronRepository(db *gorm.DB, redis *redis.Client, emailRepo *EmailReposit
 func (r *CronRepository) Run() error {
 	log.Println("[CRON] Starting scheduled email processor...")
 
-	// Check if Redis is available
-	if r.redis == nil {
-		log.Println("[CRON] Warning: Redis not available, skipping scheduled email processing")
-		return nil
-	}
-
 	err := r.scheduler.AddFunc("@every 1m", func() {
 		ctx := context.Background()
 		if err := r.ProcessScheduledEmails(ctx); err != nil {
 			log.Printf("[CRON] Error processing scheduled emails: %v", err)
 		}
 	})
-
 	if err != nil {
 		return fmt.Errorf("failed to add cron job: %w", err)
 	}
 
+	// Redis is optional, when it's there emails are picked up as soon as they are due
+	// instead of waiting for the next outbox poll
+	if r.redis != nil {
+		if err := r.importLegacyRedisEmails(context.Background()); err != nil {
+			log.Printf("[CRON] Warning: failed to import Redis scheduled emails: %v", err)
+		}
+
+		err = r.scheduler.AddFunc("@every 10s", func() {
+			ctx := context.Background()
+			if err := r.processRedisDueEmails(ctx); err != nil {
+				log.Printf("[CRON] Error processing Redis scheduled emails: %v", err)
+			}
+		})
+		if err != nil {
+			return fmt.Errorf("failed to add cron job: %w", err)
+		}
+	} else {
+		log.Println("[CRON] Redis not available, polling the email outbox only")
+	}
+
 	r.scheduler.Start()
 	log.Println("[CRON] Scheduler started successfully")
 
 	return nil
 }
 
+func (r *CronRepository) Schedule(spec string, name string, job func(ctx context.Context) error) error {
+	err := r.scheduler.AddFunc(spec, func() {
+		if err := job(context.Background()); err != nil {
+			log.Printf("[CRON] Error running %s: %v", name, err)
+		}
+	})
+	if err != nil {
+		return fmt.Errorf("failed to add cron job %s: %w", name, err)
+	}
+	return nil
+}
+
 func (r *CronRepository) Stop() error {
 	if r.scheduler != nil {
 		log.Println("[CRON] Stopping scheduler...")
@@ -66,131 +97,148 @@ func (r *CronRepository) Stop() error {
 	return nil
 }
 
+// ProcessScheduledEmails sends every due email in the outbox, one batch at a time
 func (r *CronRepository) ProcessScheduledEmails(ctx context.Context) error {
-	// Check if Redis is available
-	if r.redis == nil {
-		return nil
-	}
+	for {
+		emails, err := r.outbox.ClaimDueEmails(ctx, outboxBatchSize)
+		if err != nil {
+			return err
+		}
+		if len(emails) == 0 {
+			return nil
+		}
 
-	now := time.Now().UTC()
+		r.sendClaimedEmails(ctx, emails)
 
-	emailIDs, err := r.redis.ZRangeByScore(ctx, "scheduled_emails", &redis.ZRangeBy{
+		if len(emails) < outboxBatchSize {
+			return nil
+		}
+	}
+}
+
+// processRedisDueEmails claims the emails Redis says are due. Anything Redis misses is still
+// picked up by the outbox poll.
+func (r *CronRepository) processRedisDueEmails(ctx context.Context) error {
+	members, err := r.redis.ZRangeByScore(ctx, scheduledEmailsKey, &redis.ZRangeBy{
 		Min: "0",
-		Max: fmt.Sprintf("%d", now.Unix()),
+		Max: fmt.Sprintf("%d", time.Now().UTC().Unix()),
 	}).Result()
-
 	if err != nil {
 		return fmt.Errorf("failed to query scheduled emails: %w", err)
 	}
-
-	if len(emailIDs) == 0 {
+	if len(members) == 0 {
 		return nil
 	}
 
-	log.Printf("[CRON] Found %d scheduled emails to send", len(emailIDs))
-
-	successCount := 0
-	errorCount := 0
-
-	for _, emailID := range emailIDs {
-		if err := r.processIndividualEmail(ctx, emailID); err != nil {
-			log.Printf("[CRON] Failed to send email %s: %v", emailID, err)
-			errorCount++
-		} else {
-			log.Printf("[CRON] Successfully sent email %s", emailID)
-			successCount++
+	var ids []uuid.UUID
+	for _, member := range members {
+		if id, err := uuid.Parse(member); err == nil {
+			ids = append(ids, id)
 		}
 	}
 
-	log.Printf("[CRON] Processed %d/%d emails successfully, %d failed",
-		successCount, len(emailIDs), errorCount)
-
-	return nil
-}
-
-func (r *CronRepository) processIndividualEmail(ctx context.Context, emailID string) error {
-	key := "scheduled_email:" + emailID
-	emailData, err := r.redis.Get(ctx, key).Result()
+	emails, err := r.outbox.ClaimEmails(ctx, ids)
 	if err != nil {
-		return fmt.Errorf("failed to get email data: %w", err)
-	}
-
-	var email models.Email
-	if err := json.Unmarshal([]byte(emailData), &email); err != nil {
-		return fmt.Errorf("failed to unmarshal email data: %w", err)
-	}
-
-	if email.SendAt.After(time.Now().UTC()) {
-		return fmt.Errorf("email not yet due: scheduled for %s", email.SendAt.Format(time.RFC3339))
-	}
-
-	if err := r.sendEmailViaMailgun(ctx, &email); err != nil {
-		return fmt.Errorf("failed to send via mailgun: %w", err)
+		return err
 	}
 
-	if err := r.cleanupEmailFromRedis(ctx, emailID); err != nil {
-		log.Printf("[CRON] Warning: Failed to cleanup email %s from Redis: %v", emailID, err)
+	// retries are driven by the outbox, so the hints are done with either way
+	for _, member := range members {
+		r.redis.ZRem(ctx, scheduledEmailsKey, member)
 	}
 
+	r.sendClaimedEmails(ctx, emails)
 	return nil
 }
 
-func (r *CronRepository) sendEmailViaMailgun(ctx context.Context, email *models.Email) error {
-	if r.emailRepo.mg == nil || r.emailRepo.domain == "" || r.emailRepo.from == "" {
-		return fmt.Errorf("mailgun configuration missing")
-	}
+func (r *CronRepository) sendClaimedEmails(ctx context.Context, emails []models.Email) {
+	log.Printf("[CRON] Found %d scheduled emails to send", len(emails))
 
-	var invoice models.Invoice
-	err := r.db.WithContext(ctx).
-		Preload("Request").
-		Where("request_id = ?", email.RequestID).
-		First(&invoice).Error
+	successCount := 0
+	errorCount := 0
 
-	if err != nil {
-		return fmt.Errorf("failed to get invoice for request %s: %w", email.RequestID, err)
-	}
+	for i := range emails {
+		email := &emails[i]
+		if err := r.sendOutboxEmail(ctx, email); err != nil {
+			log.Printf("[CRON] Failed to send email %s (attempt %d/%d): %v", email.ID, email.Attempts, email.MaxAttempts, err)
+			if err := r.outbox.MarkEmailFailed(ctx, email, err); err != nil {
+				log.Printf("[CRON] Failed to record failure for email %s: %v", email.ID, err)
+			}
+			errorCount++
+			continue
+		}
 
-	clientEmail := invoice.Request.Email
-	if clientEmail == "" {
-		return fmt.Errorf("client email is empty for request %s", email.RequestID)
+		if err := r.outbox.MarkEmailSent(ctx, email.ID); err != nil {
+			log.Printf("[CRON] Failed to mark email %s as sent: %v", email.ID, err)
+		}
+		log.Printf("[CRON] Successfully sent email %s", email.ID)
+		successCount++
 	}
 
-	message := mailgun.NewMessage(r.emailRepo.from, email.Subject, "", clientEmail)
-	message.SetHTML(email.Content)
+	log.Printf("[CRON] Processed %d/%d emails successfully, %d failed",
+		successCount, len(emails), errorCount)
+}
 
-	if email.ReplyTo != "" {
-		message.SetReplyTo(email.ReplyTo)
+// importLegacyRedisEmails moves emails scheduled before the outbox existed, which only live in
+// Redis, into the outbox so they are sent with retries like everything else
+func (r *CronRepository) importLegacyRedisEmails(ctx context.Context) error {
+	members, err := r.redis.ZRange(ctx, scheduledEmailsKey, 0, -1).Result()
+	if err != nil {
+		return err
 	}
 
-	for _, cc := range email.CC {
-		if cc != "" {
-			message.AddCC(cc)
+	imported := 0
+	for _, member := range members {
+		key := "scheduled_email:" + member
+		emailData, err := r.redis.Get(ctx, key).Result()
+		if err == redis.Nil {
+			continue
+		}
+		if err != nil {
+			return err
 		}
-	}
 
-	for _, bcc := range email.BCC {
-		if bcc != "" {
-			message.AddBCC(bcc)
+		var email models.Email
+		if err := json.Unmarshal([]byte(emailData), &email); err != nil {
+			log.Printf("[CRON] Skipping unreadable Redis email %s: %v", member, err)
+			continue
 		}
-	}
 
-	_, _, err = r.emailRepo.mg.Send(ctx, message)
-	if err != nil {
-		return fmt.Errorf("mailgun send error: %w", err)
+		email.Status = models.EmailStatusPending
+		email.MaxAttempts = models.DefaultEmailMaxAttempts
+		email.NextAttemptAt = email.SendAt
+		if err := r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&email).Error; err != nil {
+			return fmt.Errorf("failed to import email %s: %w", member, err)
+		}
+
+		r.redis.Del(ctx, key)
+		imported++
 	}
 
+	if imported > 0 {
+		log.Printf("[CRON] Imported %d Redis scheduled emails into the outbox", imported)
+	}
 	return nil
 }
 
-func (r *CronRepository) cleanupEmailFromRedis(ctx context.Context, emailID string) error {
-	if err := r.redis.ZRem(ctx, "scheduled_emails", emailID).Err(); err != nil {
-		return fmt.Errorf("failed to remove from scheduled_emails: %w", err)
-	}
+func (r *CronRepository) sendOutboxEmail(ctx context.Context, email *models.Email) error {
+	var invoice models.Invoice
+	query := r.db.WithContext(ctx).Preload("Request")
+	if email.InvoiceID != nil {
+		query = query.Where("uuid = ?", *email.InvoiceID)
+	} else {
+		// emails scheduled before they recorded their invoice were about the final invoice
+		query = query.Where("request_id = ?", email.RequestID).
+			Order(clause.Expr{
+				SQL:  "CASE WHEN kind = ? AND status <> ? THEN 0 ELSE 1 END, sequence DESC",
+				Vars: []interface{}{models.InvoiceKindFinal, models.InvoiceStatusVoid},
+			})
+	}
+	err := query.First(&invoice).Error
 
-	key := "scheduled_email:" + emailID
-	if err := r.redis.Del(ctx, key).Err(); err != nil {
-		return fmt.Errorf("failed to delete email data: %w", err)
+	if err != nil {
+		return fmt.Errorf("failed to get invoice for request %s: %w", email.RequestID, err)
 	}
 
-	return nil
+	return r.emailRepo.SendOutboxEmail(ctx, email, &invoice)
 }
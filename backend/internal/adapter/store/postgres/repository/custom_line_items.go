@@ -23,6 +23,10 @@ func (r *CustomLineItemsRepository) CreateCustomLineItem(ctx context.Context, cu
 		customLineItem.UUID = uuid.New()
 	}
 
+	if err := checkRequestScope(ctx, r.db, customLineItem.RequestID); err != nil {
+		return err
+	}
+
 	if err := r.db.WithContext(ctx).Create(customLineItem).Error; err != nil {
 		return err
 	}
@@ -31,7 +35,7 @@ func (r *CustomLineItemsRepository) CreateCustomLineItem(ctx context.Context, cu
 }
 
 func (r *CustomLineItemsRepository) DeleteCustomLineItem(ctx context.Context, id uuid.UUID) error {
-	if err := r.db.WithContext(ctx).Delete(&models.CustomLineItems{}, id).Error; err != nil {
+	if err := r.db.WithContext(ctx).Scopes(requestsInScope(ctx, "request_id")).Delete(&models.CustomLineItems{}, id).Error; err != nil {
 		return err
 	}
 
@@ -40,7 +44,7 @@ func (r *CustomLineItemsRepository) DeleteCustomLineItem(ctx context.Context, id
 
 func (r *CustomLineItemsRepository) GetCustomLineItemByID(ctx context.Context, id uuid.UUID) (models.CustomLineItems, error) {
 	var customLineItem models.CustomLineItems
-	if err := r.db.WithContext(ctx).First(&customLineItem, id).Error; err != nil {
+	if err := r.db.WithContext(ctx).Scopes(requestsInScope(ctx, "request_id")).First(&customLineItem, id).Error; err != nil {
 		return models.CustomLineItems{}, err
 	}
 
@@ -49,7 +53,7 @@ func (r *CustomLineItemsRepository) GetCustomLineItemByID(ctx context.Context, i
 
 func (r *CustomLineItemsRepository) GetCustomLineItemsByRequestID(ctx context.Context, requestID uuid.UUID) ([]models.CustomLineItems, error) {
 	var customLineItems []models.CustomLineItems
-	if err := r.db.WithContext(ctx).Where("request_id = ?", requestID).Find(&customLineItems).Error; err != nil {
+	if err := r.db.WithContext(ctx).Scopes(requestsInScope(ctx, "request_id")).Where("request_id = ?", requestID).Find(&customLineItems).Error; err != nil {
 		return nil, err
 	}
 
@@ -57,6 +61,15 @@ func (r *CustomLineItemsRepository) GetCustomLineItemsByRequestID(ctx context.Co
 }
 
 func (r *CustomLineItemsRepository) UpdateCustomLineItem(ctx context.Context, customLineItem *models.CustomLineItems) error {
+	// the item has to be in the caller's branch both before and after the change
+	var existing models.CustomLineItems
+	if err := r.db.WithContext(ctx).Scopes(requestsInScope(ctx, "request_id")).First(&existing, customLineItem.UUID).Error; err != nil {
+		return err
+	}
+	if err := checkRequestScope(ctx, r.db, customLineItem.RequestID); err != nil {
+		return err
+	}
+
 	if err := r.db.WithContext(ctx).Save(customLineItem).Error; err != nil {
 		return err
 	}
@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"backend/internal/core/models"
+	ports "backend/internal/core/ports"
+)
+
+// invoices in these statuses never get reminders
+var closedInvoiceStatuses = []string{models.InvoiceStatusPaid, models.InvoiceStatusRefunded, models.InvoiceStatusVoid}
+
+type DunningRepository struct {
+	db *gorm.DB
+}
+
+func NewDunningRepository(db *gorm.DB) ports.DunningRepository {
+	return &DunningRepository{db: db}
+}
+
+func (r *DunningRepository) GetDunningSchedule(ctx context.Context, branchID uuid.UUID) (*models.DunningSchedule, error) {
+	if err := checkBranchScope(ctx, branchID); err != nil {
+		return nil, err
+	}
+
+	var schedule models.DunningSchedule
+	err := r.db.WithContext(ctx).
+		Where("branch_id = ? OR branch_id IS NULL", branchID).
+		Order("branch_id NULLS LAST").
+		First(&schedule).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &schedule, nil
+}
+
+func (r *DunningRepository) UpsertDunningSchedule(ctx context.Context, schedule *models.DunningSchedule) error {
+	if err := checkSharedBranchScope(ctx, schedule.BranchID); err != nil {
+		return err
+	}
+	if schedule.UUID == uuid.Nil {
+		schedule.UUID = uuid.New()
+	}
+
+	// RETURNING picks up the existing row's uuid and created_at when the branch already had one
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "branch_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"offset_days", "enabled", "updated_at"}),
+	}, clause.Returning{}).Create(schedule).Error
+}
+
+func (r *DunningRepository) DeleteDunningSchedule(ctx context.Context, branchID uuid.UUID) error {
+	if err := checkBranchScope(ctx, branchID); err != nil {
+		return err
+	}
+	return r.db.WithContext(ctx).Where("branch_id = ?", branchID).Delete(&models.DunningSchedule{}).Error
+}
+
+func (r *DunningRepository) GetOpenInvoices(ctx context.Context) ([]models.Invoice, error) {
+	var invoices []models.Invoice
+	err := r.db.WithContext(ctx).
+		Preload("Request").
+		Scopes(requestsInScope(ctx, "request_id")).
+		Where("status NOT IN ?", closedInvoiceStatuses).
+		Where("balance > 0").
+		// unsent invoices are still being priced, there is nothing to chase yet
+		Where("last_sent IS NOT NULL AND last_sent > ?", time.Time{}).
+		Order("due_date").
+		Find(&invoices).Error
+	if err != nil {
+		return nil, err
+	}
+	return invoices, nil
+}
+
+// ClaimReminder is a compare and swap on the follow up date, of the instances running the job
+// at once only the first to move the date sends the reminder
+func (r *DunningRepository) ClaimReminder(ctx context.Context, invoiceID uuid.UUID, previous *time.Time, sentAt time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&models.Invoice{}).
+		Where("uuid = ? AND follow_up_date IS NOT DISTINCT FROM ?", invoiceID, previous).
+		Updates(map[string]interface{}{
+			"follow_up_count": gorm.Expr("follow_up_count + 1"),
+			"follow_up_date":  sentAt.UTC(),
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+func (r *DunningRepository) ReleaseReminder(ctx context.Context, invoiceID uuid.UUID, previous *time.Time, sentAt time.Time) error {
+	return r.db.WithContext(ctx).Model(&models.Invoice{}).
+		// the date column dropped the time of day
+		Where("uuid = ? AND follow_up_date = ?", invoiceID, sentAt.UTC().Format(dateFormat)).
+		Updates(map[string]interface{}{
+			"follow_up_count": gorm.Expr("GREATEST(follow_up_count - 1, 0)"),
+			"follow_up_date":  previous,
+		}).Error
+}
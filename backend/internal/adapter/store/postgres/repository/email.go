@@ -1,389 +1,297 @@
 package repository
 
-// where the email sending logic as well as the HTML template is stored
+// where the email sending logic is stored, the emails themselves come from the templates
+// in the mail adapter
 
 import (
 	"backend/internal/core/models"
+	"backend/internal/core/ports"
 	"context"
-	"encoding/json"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"log"
+	"mime"
+	"path/filepath"
 	"strings"
 	"time"
 
-	"github.com/redis/go-redis/v9"
-
 	"github.com/google/uuid"
-	"github.com/mailgun/mailgun-go/v4"
+	"github.com/redis/go-redis/v9"
 )
 
+// replies go here unless the sender picks another address
+const defaultReplyTo = "Evershift Support <support@evershift.co>"
+
 type EmailRepository struct {
-	mg     *mailgun.MailgunImpl
-	domain string
-	from   string
-	redis  *redis.Client
+	transport   ports.MailTransport
+	from        string
+	renderer    ports.EmailRenderer
+	outbox      ports.EmailOutboxRepository
+	logs        ports.EmailLogRepository
+	attachments ports.EmailAttachmentRepository
+	blobs       ports.BlobStore
+	invoicePDFs ports.InvoicePDFRepository
 }
 
-func NewEmailRepository(domain, from, apiKey string, redis *redis.Client) *EmailRepository {
-
-	mg := mailgun.NewMailgun(domain, apiKey)
+// NewEmailRepository sends through the given transport, a nil transport leaves email disabled
+func NewEmailRepository(transport ports.MailTransport, from string, renderer ports.EmailRenderer, outbox ports.EmailOutboxRepository, logs ports.EmailLogRepository, attachments ports.EmailAttachmentRepository, blobs ports.BlobStore, invoicePDFs ports.InvoicePDFRepository) *EmailRepository {
 	return &EmailRepository{
-		mg:     mg,
-		domain: domain,
-		from:   from,
-		redis:  redis,
+		transport:   transport,
+		from:        from,
+		renderer:    renderer,
+		outbox:      outbox,
+		logs:        logs,
+		attachments: attachments,
+		blobs:       blobs,
+		invoicePDFs: invoicePDFs,
 	}
 }
 
-type RedisEmailScheduler struct {
-	rdb *redis.Client
+func (r *EmailRepository) checkConfigured() error {
+	if r.transport == nil || r.from == "" {
+		return fmt.Errorf("email configuration missing")
+	}
+	return nil
 }
 
-func (r *EmailRepository) SendEmail(ctx context.Context, invoice *models.Invoice, staffRequirements []models.StaffRequirement) error {
-	if r.domain == "" || r.from == "" {
-		return fmt.Errorf("mailgun configuration missing")
+// send hands the message to the transport and logs it under the message ID it returns. A failed
+// log write doesn't fail the send, the email is already on its way.
+func (r *EmailRepository) send(ctx context.Context, message *models.MailMessage, entry models.EmailLog) error {
+	message.From = r.from
+	messageID, err := r.transport.Send(ctx, message)
+	if err != nil {
+		return err
 	}
 
-	clientName := invoice.Request.FirstName + " " + invoice.Request.LastName
-	clientEmail := invoice.Request.Email
+	if r.logs != nil {
+		entry.MessageID = messageID
+		if err := r.logs.CreateEmailLog(ctx, &entry); err != nil {
+			log.Printf("Failed to log email %s: %v", messageID, err)
+		}
+	}
+	return nil
+}
+
+// invoiceEmailLog is the log entry for an email about an invoice
+func invoiceEmailLog(invoice *models.Invoice, kind, recipient, subject string) models.EmailLog {
+	requestID := invoice.RequestID
+	invoiceID := invoice.UUID
+	return models.EmailLog{
+		RequestID: &requestID,
+		InvoiceID: &invoiceID,
+		Kind:      kind,
+		Recipient: recipient,
+		Subject:   subject,
+	}
+}
 
-	requestID := invoice.RequestID.String()
+// newMessage builds the message every send path uses, so headers and attachments are handled
+// the same way whether the email goes out now, later or as a reminder
+func newMessage(to string, rendered *models.RenderedEmail, headers models.EmailHeaders, attachments []models.MailAttachment) *models.MailMessage {
+	message := &models.MailMessage{
+		To:          []string{to},
+		CC:          nonEmpty(headers.CC),
+		BCC:         nonEmpty(headers.BCC),
+		ReplyTo:     headers.ReplyTo,
+		Subject:     rendered.Subject,
+		HTML:        rendered.HTML,
+		Text:        rendered.Text,
+		Attachments: attachments,
+	}
+	if headers.Subject != "" {
+		message.Subject = headers.Subject
+	}
+	if message.ReplyTo == "" {
+		message.ReplyTo = defaultReplyTo
+	}
+	return message
+}
 
-	if clientEmail == "" {
-		return fmt.Errorf("client email is empty - cannot send email")
+// StoreAttachment saves an uploaded file for emails to reference. The blob key is the file's
+// checksum, so uploading the same PDF again doesn't store it twice.
+func (r *EmailRepository) StoreAttachment(ctx context.Context, filename, contentType string, data []byte) (*models.EmailAttachment, error) {
+	if r.blobs == nil || r.attachments == nil {
+		return nil, fmt.Errorf("attachment storage is not configured")
 	}
 
-	staffRequirementsWithRates := staffRequirements
-	quantityOfEachStaffType := make(map[string]int)
-	for _, req := range staffRequirementsWithRates {
-		quantityOfEachStaffType[req.Position] += req.Count
+	if contentType == "" || contentType == "application/octet-stream" {
+		if byExtension := mime.TypeByExtension(filepath.Ext(filename)); byExtension != "" {
+			contentType = byExtension
+		}
+	}
+	if contentType == "" {
+		contentType = "application/octet-stream"
 	}
 
-	stripeURL := "" // This will be passed as a parameter or generated
+	sum := sha256.Sum256(data)
+	checksum := hex.EncodeToString(sum[:])
+	attachment := &models.EmailAttachment{
+		Filename:    filepath.Base(filename),
+		ContentType: contentType,
+		Size:        int64(len(data)),
+		Checksum:    checksum,
+		StorageKey:  "email-attachments/" + checksum,
+	}
 
-	staffRowsHTML := r.generateStaffRows(staffRequirementsWithRates)
-	htmlBody := r.generateEmailHTML(invoice, clientName, staffRowsHTML, stripeURL, requestID)
+	if err := r.blobs.Put(ctx, attachment.StorageKey, contentType, data); err != nil {
+		return nil, err
+	}
+	if err := r.attachments.CreateEmailAttachment(ctx, attachment); err != nil {
+		return nil, fmt.Errorf("failed to save attachment: %w", err)
+	}
 
-	subject := fmt.Sprintf("Request #%s from Evershift", requestID)
+	return attachment, nil
+}
 
-	message := r.mg.NewMessage(r.from, subject, "", clientEmail)
-	message.SetHtml(htmlBody)
-	message.SetReplyTo("Evershift Support <support@evershift.co>")
+func (r *EmailRepository) GetAttachment(ctx context.Context, id uuid.UUID) (*models.EmailAttachment, []byte, error) {
+	if r.blobs == nil || r.attachments == nil {
+		return nil, nil, fmt.Errorf("attachment storage is not configured")
+	}
 
-	_, _, err := r.mg.Send(ctx, message)
+	attachment, err := r.attachments.GetEmailAttachmentByID(ctx, id)
 	if err != nil {
-		return fmt.Errorf("mailgun send error: %w", err)
+		return nil, nil, err
 	}
-	return nil
+
+	data, err := r.blobs.Get(ctx, attachment.StorageKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	return attachment, data, nil
 }
 
-func (r *EmailRepository) SendEmailWithPaymentURL(ctx context.Context, invoice *models.Invoice, staffRequirements []models.StaffRequirement, paymentURL string) error {
-	if r.domain == "" || r.from == "" {
-		return fmt.Errorf("mailgun configuration missing")
+// loadAttachments fetches the files behind the IDs, a missing one fails the send instead of
+// quietly dropping it
+func (r *EmailRepository) loadAttachments(ctx context.Context, ids []uuid.UUID) ([]models.MailAttachment, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	if r.blobs == nil || r.attachments == nil {
+		return nil, fmt.Errorf("attachment storage is not configured")
 	}
 
-	clientName := invoice.Request.FirstName + " " + invoice.Request.LastName
-	clientEmail := invoice.Request.Email
+	stored, err := r.attachments.GetEmailAttachmentsByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
 
-	requestID := invoice.RequestID.String()
+	attachments := make([]models.MailAttachment, 0, len(stored))
+	for _, attachment := range stored {
+		data, err := r.blobs.Get(ctx, attachment.StorageKey)
+		if err != nil {
+			return nil, err
+		}
+		attachments = append(attachments, models.MailAttachment{
+			Filename:    attachment.Filename,
+			ContentType: attachment.ContentType,
+			Data:        data,
+		})
+	}
+	return attachments, nil
+}
 
-	if clientEmail == "" {
-		return fmt.Errorf("client email is empty - cannot send email")
+// withInvoicePDF adds the invoice PDF to the attachments the sender picked, invoice and reminder
+// emails always carry it
+func (r *EmailRepository) withInvoicePDF(ctx context.Context, invoice *models.Invoice, attachments []models.MailAttachment) ([]models.MailAttachment, error) {
+	if r.invoicePDFs == nil {
+		return attachments, nil
 	}
 
-	staffRowsHTML := r.generateStaffRows(staffRequirements)
-	htmlBody := r.generateEmailHTML(invoice, clientName, staffRowsHTML, paymentURL, requestID)
+	pdf, err := r.invoicePDFs.GenerateInvoicePDF(ctx, invoice)
+	if err != nil {
+		return nil, err
+	}
 
-	subject := fmt.Sprintf("Request #%s from Evershift", requestID)
+	// copied so reminders in the same batch don't share one backing array
+	withPDF := make([]models.MailAttachment, 0, len(attachments)+1)
+	withPDF = append(withPDF, attachments...)
+	return append(withPDF, models.MailAttachment{
+		Filename:    models.InvoicePDFFilename(invoice),
+		ContentType: "application/pdf",
+		Data:        pdf,
+	}), nil
+}
 
-	message := r.mg.NewMessage(r.from, subject, "", clientEmail)
-	message.SetHTML(htmlBody)
-	message.SetReplyTo("Evershift Support <support@evershift.co>")
+type RedisEmailScheduler struct {
+	rdb *redis.Client
+}
 
-	_, _, err := r.mg.Send(ctx, message)
-	if err != nil {
-		return fmt.Errorf("mailgun send error: %w", err)
-	}
-	return nil
+func (r *EmailRepository) SendEmail(ctx context.Context, invoice *models.Invoice, staffRequirements []models.StaffRequirement, headers models.EmailHeaders) error {
+	return r.SendEmailWithPaymentURL(ctx, invoice, staffRequirements, "", headers)
 }
 
-func (r *EmailRepository) SendCustomEmail(ctx context.Context, invoice *models.Invoice, staffRequirements []models.StaffRequirement, emailContent string, headers models.EmailHeaders, attachmentData []byte, filename string, paymentURL string) error {
-	if r.domain == "" || r.from == "" {
-		return fmt.Errorf("mailgun configuration missing")
+func (r *EmailRepository) SendEmailWithPaymentURL(ctx context.Context, invoice *models.Invoice, staffRequirements []models.StaffRequirement, paymentURL string, headers models.EmailHeaders) error {
+	if err := r.checkConfigured(); err != nil {
+		return err
 	}
 
 	clientEmail := invoice.Request.Email
-	requestID := invoice.RequestID.String()
-
 	if clientEmail == "" {
 		return fmt.Errorf("client email is empty - cannot send email")
 	}
 
-	subject := headers.Subject
-	if subject == "" {
-		subject = fmt.Sprintf("Request #%s from Evershift", requestID)
+	view := models.NewInvoiceEmailView(invoice, staffRequirements, paymentURL)
+	rendered, err := r.renderer.Render(ctx, &invoice.Request.ClosestBranchID, models.EmailTemplateInvoice, view)
+	if err != nil {
+		return err
 	}
 
-	cleanContent := strings.TrimSpace(emailContent)
-	cleanContent = strings.ReplaceAll(cleanContent, "\n\n\n\n", "\n\n")
-	cleanContent = strings.ReplaceAll(cleanContent, "\n\n\n", "\n\n")
-
-	paragraphs := strings.Split(cleanContent, "\n\n")
-	var htmlParagraphs []string
-
-	for _, paragraph := range paragraphs {
-		if strings.TrimSpace(paragraph) != "" {
-			htmlParagraph := strings.ReplaceAll(strings.TrimSpace(paragraph), "\n", "<br>")
-			htmlParagraphs = append(htmlParagraphs, fmt.Sprintf("<p>%s</p>", htmlParagraph))
-		}
+	attachments, err := r.loadAttachments(ctx, headers.AttachmentIDs)
+	if err != nil {
+		return err
 	}
-
-	htmlEmailContent := strings.Join(htmlParagraphs, "")
-	finalEmailContent := fmt.Sprintf(`
-<!DOCTYPE html>
-<html>
-<head>
-	<style>
-		body { 
-			font-family: Arial, sans-serif; 
-			line-height: 1.6; 
-			color: #333; 
-			max-width: 600px; 
-			margin: 0 auto; 
-			padding: 20px;
-		}
-		p {
-			margin: 0 0 16px 0;
-		}
-		.content {
-			margin-bottom: 20px;
-		}
-	</style>
-</head>
-<body>
-	<div class="content">%s</div>
-	%%s
-</body>
-</html>`, htmlEmailContent)
-
-	// If a payment URL is provided, append a payment button to the email content
-	if paymentURL != "" {
-		paymentButtonHTML := fmt.Sprintf(`
-<div style="margin: 20px 0; text-align: center;">
-	<a href="%s" 
-	   style="display: inline-block; 
-	          background-color: #22c55e; 
-	          color: white; 
-	          padding: 12px 24px; 
-	          text-decoration: none; 
-	          border-radius: 6px; 
-	          font-weight: bold; 
-	          border: none;">
-		Pay Invoice - %s
-	</a>
-</div>
-<p style="color: #6b7280; font-size: 12px; text-align: center; margin-top: 10px;">
-	Click the button above to pay your invoice securely online
-</p>`, paymentURL, r.formatCurrency(invoice.Balance))
-
-		finalEmailContent = fmt.Sprintf(finalEmailContent, paymentButtonHTML)
-	} else {
-		finalEmailContent = fmt.Sprintf(finalEmailContent, "")
+	attachments, err = r.withInvoicePDF(ctx, invoice, attachments)
+	if err != nil {
+		return err
 	}
 
-	message := r.mg.NewMessage(r.from, subject, "", clientEmail)
-	message.SetHtml(finalEmailContent)
+	message := newMessage(clientEmail, rendered, headers, attachments)
+	return r.send(ctx, message, invoiceEmailLog(invoice, models.EmailKindInvoice, clientEmail, message.Subject))
+}
 
-	if headers.ReplyTo != "" {
-		message.SetReplyTo(headers.ReplyTo)
-	} else {
-		message.SetReplyTo("Evershift Support <support@evershift.co>")
+func (r *EmailRepository) SendCustomEmail(ctx context.Context, invoice *models.Invoice, staffRequirements []models.StaffRequirement, emailContent string, headers models.EmailHeaders, paymentURL string) error {
+	if err := r.checkConfigured(); err != nil {
+		return err
 	}
 
-	for _, cc := range headers.CC {
-		if cc != "" {
-			message.AddCC(cc)
-		}
-	}
-
-	for _, bcc := range headers.BCC {
-		if bcc != "" {
-			message.AddBCC(bcc)
-		}
+	clientEmail := invoice.Request.Email
+	if clientEmail == "" {
+		return fmt.Errorf("client email is empty - cannot send email")
 	}
 
-	if len(attachmentData) > 0 && filename != "" {
-		message.AddBufferAttachment(filename, attachmentData)
+	view := models.NewCustomEmailView(invoice, emailContent, paymentURL)
+	rendered, err := r.renderer.Render(ctx, &invoice.Request.ClosestBranchID, models.EmailTemplateCustom, view)
+	if err != nil {
+		return err
 	}
 
-	_, _, err := r.mg.Send(ctx, message)
+	attachments, err := r.loadAttachments(ctx, headers.AttachmentIDs)
 	if err != nil {
-		return fmt.Errorf("mailgun send error: %w", err)
+		return err
 	}
-	return nil
-}
 
-func (r *EmailRepository) formatCurrency(amount float64) string {
-	return fmt.Sprintf("$%.2f", amount)
+	message := newMessage(clientEmail, rendered, headers, attachments)
+	return r.send(ctx, message, invoiceEmailLog(invoice, models.EmailKindCustom, clientEmail, message.Subject))
 }
 
-func (r *EmailRepository) formatDate(date time.Time) string {
-	return date.Format("January 2, 2006")
-}
-
-func (r *EmailRepository) generateStaffRows(staffRequirements []models.StaffRequirement) string {
-	var rows strings.Builder
-	for _, req := range staffRequirements {
-		rows.WriteString(fmt.Sprintf(`
-		<tr>
-			<td>
-				<strong>%s</strong><br>
-				<small>%s (%s - %s)</small>
-			</td>
-			<td class="amount">%d</td>
-			<td class="amount">$%.2f / hr</td>
-			<td class="amount">%s</td>
-		</tr>`,
-			req.Position,
-			req.Date.Format("January 2, 2006"),
-			req.StartTime,
-			req.EndTime,
-			req.Count,
-			req.Rate,
-			r.formatCurrency(float64(req.Count)*req.Rate),
-		))
-	}
-	return rows.String()
-}
-
-func (r *EmailRepository) generateEmailHTML(invoice *models.Invoice, clientName, staffRowsHTML, paymentURL, requestID string) string {
-	notesHTML := ""
-	if invoice.Notes != "" {
-		notesHTML = fmt.Sprintf(`<div class="notes"><h3>Notes</h3><p>%s</p></div>`, invoice.Notes)
-	}
-
-	paymentButtonHTML := ""
-	if paymentURL != "" {
-		paymentButtonHTML = fmt.Sprintf(`
-		<div class="payment-section" style="text-align: center; margin: 30px 0;">
-			<a href="%s" class="payment-button" style="display: inline-block; background-color: #635BFF; color: white; padding: 15px 30px; text-decoration: none; border-radius: 6px; font-weight: bold; font-size: 16px;">
-				Pay Invoice Online
-			</a>
-			<p style="margin-top: 10px; font-size: 14px; color: #666;">
-				Click the button above to pay securely with Stripe
-			</p>
-		</div>`, paymentURL)
-	}
-
-	return fmt.Sprintf(`
-<!DOCTYPE html>
-<html>
-<head>
-  <style>
-    body { font-family: Arial, sans-serif; line-height: 1.6; color: #333; }
-    .container { max-width: 600px; margin: 0 auto; padding: 20px; }
-    .header { text-align: center; padding-bottom: 20px; border-bottom: 1px solid #eee; }
-    .invoice-details { margin: 20px 0; }
-    .invoice-table { width: 100%%; border-collapse: collapse; margin: 20px 0; }
-    .invoice-table th, .invoice-table td { padding: 10px; text-align: left; border-bottom: 1px solid #eee; }
-    .invoice-table th { background-color: #f8f8f8; }
-    .amount { text-align: right; }
-    .total { font-weight: bold; }
-    .payment-button:hover { background-color: #5A52E5 !important; }
-    .footer { margin-top: 30px; text-align: center; font-size: 12px; color: #777; }
-  </style>
-</head>
-<body>
-  <div class="container">
-    <div class="header">
-      <h1>Invoice from Evershift</h1>
-      <p>Request #%s</p>
-      <p>Branch: %s</p>
-    </div>
-    
-    <div class="invoice-details">
-      <p><strong>To:</strong> %s</p>
-      <p><strong>Email:</strong> %s</p>
-      <p><strong>Due Date:</strong> %s</p>
-      <p><strong>Payment Terms:</strong> %s</p>
-    </div>
-    
-    <table class="invoice-table">
-      <thead>
-        <tr>
-          <th>Description</th>
-          <th class="amount">Quantity</th>
-          <th class="amount">Rate</th>
-          <th class="amount">Amount</th>
-        </tr>
-      </thead>
-      <tbody>
-        %s
-      </tbody>
-      <tfoot>
-        <tr>
-          <td colspan="3" class="amount">Subtotal:</td>
-          <td class="amount">%s</td>
-        </tr>
-        <tr>
-          <td colspan="3" class="amount">Service Fee:</td>
-          <td class="amount">%s</td>
-        </tr>
-        <tr>
-          <td colspan="3" class="amount">Transaction Fee (3.5%%):</td>
-          <td class="amount">%s</td>
-        </tr>
-        <tr class="total">
-          <td colspan="3" class="amount">Total Amount:</td>
-          <td class="amount">%s</td>
-        </tr>
-        <tr class="total">
-          <td colspan="3" class="amount">Balance Due:</td>
-          <td class="amount">%s</td>
-        </tr>
-      </tfoot>
-    </table>
-    
-    %s
-    
-    %s
-    
-    <div class="footer">
-      <p>If you have any questions about this invoice, please contact us at support@evershift.co</p>
-      <p>Thank you for your business!</p>
-    </div>
-  </div>
-</body>
-</html>`,
-		requestID,
-		invoice.Request.ClosestBranchName,
-		clientName,
-		invoice.Request.Email,
-		r.formatDate(invoice.DueDate),
-		invoice.PaymentTerms,
-		staffRowsHTML,
-		r.formatCurrency(invoice.Subtotal),
-		r.formatCurrency(invoice.ServiceFee),
-		r.formatCurrency(invoice.TransactionFee),
-		r.formatCurrency(invoice.Amount),
-		r.formatCurrency(invoice.Balance),
-		notesHTML,
-		paymentButtonHTML,
-	)
-}
+func (r *EmailRepository) SendFollowUpEmails(ctx context.Context, invoices []models.Invoice, paymentURLs map[string]string, headers models.EmailHeaders) error {
+	if err := r.checkConfigured(); err != nil {
+		return err
+	}
 
-func (r *EmailRepository) SendFollowUpEmails(ctx context.Context, invoices []models.Invoice, paymentURLs map[string]string) error {
-	if r.domain == "" || r.from == "" {
-		return fmt.Errorf("mailgun configuration missing")
+	// the same files go on every reminder, so they're only fetched once
+	attachments, err := r.loadAttachments(ctx, headers.AttachmentIDs)
+	if err != nil {
+		return err
 	}
 
 	var errors []string
 	successCount := 0
+	now := time.Now().UTC()
 
 	for _, invoice := range invoices {
-		clientName := invoice.Request.FirstName + " " + invoice.Request.LastName
 		clientEmail := invoice.Request.Email
-		requestID := invoice.RequestID.String()
 
 		if clientEmail == "" {
 			errors = append(errors, fmt.Sprintf("Invoice %s: no client email", invoice.UUID))
@@ -392,14 +300,21 @@ func (r *EmailRepository) SendFollowUpEmails(ctx context.Context, invoices []mod
 
 		paymentURL := paymentURLs[invoice.UUID.String()]
 
-		subject := fmt.Sprintf("Follow-up: Outstanding Invoice #%s from Evershift", requestID)
-		htmlBody := r.generateFollowUpEmailHTML(&invoice, clientName, requestID, paymentURL)
+		view := models.NewFollowUpEmailView(&invoice, paymentURL, now)
+		rendered, err := r.renderer.Render(ctx, &invoice.Request.ClosestBranchID, models.EmailTemplateFollowUp, view)
+		if err != nil {
+			errors = append(errors, fmt.Sprintf("Invoice %s: %v", invoice.UUID, err))
+			continue
+		}
 
-		message := r.mg.NewMessage(r.from, subject, "", clientEmail)
-		message.SetHTML(htmlBody)
-		message.SetReplyTo("Evershift Support <support@evershift.co>")
+		withPDF, err := r.withInvoicePDF(ctx, &invoice, attachments)
+		if err != nil {
+			errors = append(errors, fmt.Sprintf("Invoice %s: %v", invoice.UUID, err))
+			continue
+		}
 
-		_, _, err := r.mg.Send(ctx, message)
+		message := newMessage(clientEmail, rendered, headers, withPDF)
+		err = r.send(ctx, message, invoiceEmailLog(&invoice, models.EmailKindFollowUp, clientEmail, message.Subject))
 		if err != nil {
 			errors = append(errors, fmt.Sprintf("Invoice %s: %v", invoice.UUID, err))
 		} else {
@@ -414,193 +329,152 @@ func (r *EmailRepository) SendFollowUpEmails(ctx context.Context, invoices []mod
 	return nil
 }
 
-func (r *EmailRepository) generateFollowUpEmailHTML(invoice *models.Invoice, clientName, requestID, paymentURL string) string {
-	daysPastDue := int(time.Now().UTC().Sub(invoice.DueDate.UTC()).Hours() / 24)
-
-	paymentButtonHTML := ""
-	if paymentURL != "" {
-		paymentButtonHTML = fmt.Sprintf(`
-		<div class="payment-section" style="text-align: center; margin: 30px 0;">
-			<a href="%s" class="payment-button" style="display: inline-block; background-color: #0070f3; color: white; padding: 15px 30px; text-decoration: none; border-radius: 6px; font-weight: bold; font-size: 16px;">
-				Pay Invoice - %s
-			</a>
-			<p style="margin-top: 10px; font-size: 14px; color: #666;">
-				Secure payment powered by Stripe
-			</p>
-		</div>`, paymentURL, r.formatCurrency(invoice.Balance))
-	}
-
-	return fmt.Sprintf(`
-<!DOCTYPE html>
-<html>
-<head>
-  <style>
-    body { font-family: Arial, sans-serif; line-height: 1.6; color: #333; }
-    .container { max-width: 600px; margin: 0 auto; padding: 20px; }
-    .header { text-align: center; padding-bottom: 20px; border-bottom: 1px solid #eee; }
-    .reminder { background-color: #f8f9fa; border: 1px solid #e9ecef; padding: 20px; border-radius: 5px; margin: 20px 0; }
-    .amount-due { font-size: 20px; color: #0070f3; font-weight: bold; text-align: center; margin: 20px 0; }
-    .payment-button:hover { background-color: #0056b3 !important; }
-    .footer { margin-top: 30px; text-align: center; font-size: 12px; color: #777; }
-  </style>
-</head>
-<body>
-  <div class="container">
-    <div class="header">
-      <h1>🔔 Friendly Payment Reminder</h1>
-      <p>Invoice #%s</p>
-    </div>
-    
-    <div class="reminder">
-      <h2>Hi %s!</h2>
-      <p>We hope you're doing well! We wanted to reach out regarding your invoice which became due %d days ago.</p>
-      <p>We understand that things can get busy, so we wanted to send a friendly reminder about your outstanding payment.</p>
-    </div>
-    
-    <div class="amount-due">
-      Outstanding Balance: %s
-    </div>
-    
-    %s
-    
-    <div style="margin: 20px 0;">
-      <h3>Invoice Summary:</h3>
-      <p><strong>Invoice Number:</strong> #%s</p>
-      <p><strong>Due Date:</strong> %s</p>
-      <p><strong>Days Past Due:</strong> %d</p>
-      <p><strong>Amount:</strong> %s</p>
-    </div>
-    
-    <div style="margin: 20px 0; padding: 15px; background-color: #e8f4fd; border-radius: 5px;">
-      <p><strong>Need help or have questions?</strong></p>
-      <p>If you're experiencing any issues with payment or have questions about this invoice, please don't hesitate to reach out to us at <a href="mailto:support@evershift.co">support@evershift.co</a>. We're here to help!</p>
-    </div>
-    
-    <div style="margin: 20px 0;">
-      <p>Thank you for choosing Evershift for your staffing needs. We truly appreciate your business and look forward to continuing to serve you.</p>
-      <p>Have a wonderful day!</p>
-      <p><strong>The Evershift Team</strong></p>
-    </div>
-    
-    <div class="footer">
-      <p>This is a friendly reminder for your outstanding invoice.</p>
-      <p>Evershift | <a href="mailto:support@evershift.co">support@evershift.co</a></p>
-    </div>
-  </div>
-</body>
-</html>`,
-		requestID,
-		clientName,
-		daysPastDue,
-		r.formatCurrency(invoice.Balance),
-		paymentButtonHTML,
-		requestID,
-		r.formatDate(invoice.DueDate),
-		daysPastDue,
-		r.formatCurrency(invoice.Balance),
-	)
-}
+func (r *EmailRepository) SendPaymentConfirmationEmail(ctx context.Context, invoice *models.Invoice, amountPaid models.Money, paidAt time.Time) error {
+	if err := r.checkConfigured(); err != nil {
+		return err
+	}
 
-func (r *EmailRepository) ScheduleEmail(ctx context.Context, invoice *models.Invoice, staffRequirements []models.StaffRequirement, sendAt time.Time, customContent string, headers models.EmailHeaders, paymentURL string) error {
-	if r.domain == "" || r.from == "" {
-		return fmt.Errorf("mailgun configuration missing")
+	clientEmail := invoice.Request.Email
+	if clientEmail == "" {
+		return fmt.Errorf("client email is empty - cannot send email")
 	}
 
-	// Check if Redis is available for scheduling
-	if r.redis == nil {
-		log.Printf("Warning: Redis not available, cannot schedule email for request %s", invoice.RequestID.String())
-		return fmt.Errorf("email scheduling unavailable (Redis not connected)")
+	view := models.NewPaymentConfirmationEmailView(invoice, amountPaid, paidAt)
+	rendered, err := r.renderer.Render(ctx, &invoice.Request.ClosestBranchID, models.EmailTemplatePaymentConfirmation, view)
+	if err != nil {
+		return err
 	}
 
-	var subject, htmlBody, replyTo string
-	var cc, bcc []string
+	message := newMessage(clientEmail, rendered, models.EmailHeaders{}, nil)
+	return r.send(ctx, message, invoiceEmailLog(invoice, models.EmailKindPaymentConfirmation, clientEmail, message.Subject))
+}
+
+// ScheduleEmail renders the email now and stores it in the outbox. Attachments are kept as IDs
+// and only fetched when the email goes out.
+func (r *EmailRepository) ScheduleEmail(ctx context.Context, invoice *models.Invoice, staffRequirements []models.StaffRequirement, sendAt time.Time, customContent string, headers models.EmailHeaders, paymentURL string) error {
+	if err := r.checkConfigured(); err != nil {
+		return err
+	}
 
+	var rendered *models.RenderedEmail
+	var err error
 	if customContent != "" {
-		subject = headers.Subject
-		if subject == "" {
-			subject = fmt.Sprintf("Request #%s from Evershift", invoice.RequestID.String())
-		}
+		view := models.NewCustomEmailView(invoice, customContent, paymentURL)
+		rendered, err = r.renderer.Render(ctx, &invoice.Request.ClosestBranchID, models.EmailTemplateCustom, view)
+	} else {
+		view := models.NewInvoiceEmailView(invoice, staffRequirements, paymentURL)
+		rendered, err = r.renderer.Render(ctx, &invoice.Request.ClosestBranchID, models.EmailTemplateInvoice, view)
+	}
+	if err != nil {
+		return err
+	}
 
-		htmlBody = customContent
-		if paymentURL != "" {
-			paymentButtonHTML := fmt.Sprintf(`
-<div style="margin: 20px 0; text-align: center;">
-	<a href="%s" 
-	   style="display: inline-block; 
-	          background-color: #22c55e; 
-	          color: white; 
-	          padding: 12px 24px; 
-	          text-decoration: none; 
-	          border-radius: 6px; 
-	          font-weight: bold; 
-	          border: none;">
-		Pay Invoice - %s
-	</a>
-</div>
-<p style="color: #6b7280; font-size: 12px; text-align: center; margin-top: 10px;">
-	Click the button above to pay your invoice securely online
-</p>`, paymentURL, r.formatCurrency(invoice.Balance))
-
-			htmlBody = customContent + paymentButtonHTML
+	// the invoice PDF is stored as of now, like the rendered email, and sent by ID
+	if customContent == "" && r.invoicePDFs != nil {
+		pdf, err := r.invoicePDFs.GenerateInvoicePDF(ctx, invoice)
+		if err != nil {
+			return err
+		}
+		attachment, err := r.StoreAttachment(ctx, models.InvoicePDFFilename(invoice), "application/pdf", pdf)
+		if err != nil {
+			return err
 		}
+		headers.AttachmentIDs = append(append([]uuid.UUID(nil), headers.AttachmentIDs...), attachment.UUID)
+	}
 
-		replyTo = headers.ReplyTo
-		if replyTo == "" {
-			replyTo = "Evershift Support <support@evershift.co>"
+	// catch a bad attachment ID now rather than when the outbox tries to send it
+	if len(headers.AttachmentIDs) > 0 {
+		if r.attachments == nil {
+			return fmt.Errorf("attachment storage is not configured")
+		}
+		if _, err := r.attachments.GetEmailAttachmentsByIDs(ctx, headers.AttachmentIDs); err != nil {
+			return err
 		}
-		cc = headers.CC
-		bcc = headers.BCC
-	} else {
-		clientName := invoice.Request.FirstName + " " + invoice.Request.LastName
-		requestID := invoice.RequestID.String()
-		staffRowsHTML := r.generateStaffRows(staffRequirements)
-		htmlBody = r.generateEmailHTML(invoice, clientName, staffRowsHTML, paymentURL, requestID)
-		subject = fmt.Sprintf("Request #%s from Evershift", requestID)
-		replyTo = "Evershift Support <support@evershift.co>"
 	}
 
+	message := newMessage(invoice.Request.Email, rendered, headers, nil)
+	invoiceID := invoice.UUID
 	email := models.Email{
-		RequestID: invoice.RequestID,
-		ID:        uuid.New(),
-		Subject:   subject,
-		Content:   htmlBody,
-		ReplyTo:   replyTo,
-		CC:        cc,
-		BCC:       bcc,
-		SendAt:    sendAt,
-		CreatedAt: time.Now().UTC(),
-		UpdatedAt: time.Now().UTC(),
+		RequestID:     invoice.RequestID,
+		InvoiceID:     &invoiceID,
+		Subject:       message.Subject,
+		Content:       message.HTML,
+		TextContent:   message.Text,
+		ReplyTo:       message.ReplyTo,
+		CC:            message.CC,
+		BCC:           message.BCC,
+		AttachmentIDs: headers.AttachmentIDStrings(),
+		SendAt:        sendAt,
 	}
 
-	key := "scheduled_email:" + email.ID.String()
-	jsonData, err := json.Marshal(email)
-	if err != nil {
-		return fmt.Errorf("failed to marshal email data: %w", err)
+	if err := r.outbox.EnqueueEmail(ctx, &email); err != nil {
+		return err
 	}
 
-	log.Printf("DEBUG: About to store email with key: %s", key)
-	log.Printf("DEBUG: Email ID: %s", email.ID.String())
-	log.Printf("DEBUG: Send timestamp: %d", sendAt.Unix())
-	log.Printf("DEBUG: Using custom content: %v", customContent != "")
+	log.Printf("Scheduled email %s for request %s at %s", email.ID, invoice.RequestID, sendAt.Format(time.RFC3339))
 
-	expiration := time.Until(sendAt) + (48 * time.Hour)
-	err = r.redis.Set(ctx, key, jsonData, expiration).Err()
-	if err != nil {
-		return fmt.Errorf("failed to store email data in Redis: %w", err)
+	return nil
+}
+
+// SendOutboxEmail delivers an email from the outbox to the client on its invoice
+func (r *EmailRepository) SendOutboxEmail(ctx context.Context, email *models.Email, invoice *models.Invoice) error {
+	if err := r.checkConfigured(); err != nil {
+		return err
 	}
 
-	log.Printf("DEBUG: Stored email data in Redis, expires in: %v", expiration)
+	clientEmail := invoice.Request.Email
+	if clientEmail == "" {
+		return fmt.Errorf("client email is empty for request %s", email.RequestID)
+	}
 
-	err = r.redis.ZAdd(ctx, "scheduled_emails", redis.Z{
-		Score:  float64(sendAt.Unix()),
-		Member: email.ID.String(),
-	}).Err()
+	attachmentIDs := make([]uuid.UUID, 0, len(email.AttachmentIDs))
+	for _, id := range email.AttachmentIDs {
+		parsed, err := uuid.Parse(id)
+		if err != nil {
+			return fmt.Errorf("invalid attachment id %q on email %s", id, email.ID)
+		}
+		attachmentIDs = append(attachmentIDs, parsed)
+	}
 
+	attachments, err := r.loadAttachments(ctx, attachmentIDs)
 	if err != nil {
-		return fmt.Errorf("failed to add email to scheduled queue: %w", err)
+		return err
 	}
 
-	log.Printf("DEBUG: Added to sorted set with score: %f", float64(sendAt.Unix()))
+	message := &models.MailMessage{
+		To:          []string{clientEmail},
+		CC:          nonEmpty(email.CC),
+		BCC:         nonEmpty(email.BCC),
+		ReplyTo:     email.ReplyTo,
+		Subject:     email.Subject,
+		HTML:        email.Content,
+		Text:        email.TextContent,
+		Attachments: attachments,
+	}
 
-	return nil
+	entry := invoiceEmailLog(invoice, models.EmailKindScheduled, clientEmail, email.Subject)
+	outboxID := email.ID
+	entry.OutboxEmailID = &outboxID
+
+	return r.send(ctx, message, entry)
+}
+
+// ParseWebhookEvent hands a delivery webhook to the transport, only some providers have them
+func (r *EmailRepository) ParseWebhookEvent(payload []byte) (*models.EmailEvent, error) {
+	parser, ok := r.transport.(ports.MailWebhookParser)
+	if !ok {
+		return nil, fmt.Errorf("email provider does not support delivery webhooks")
+	}
+	return parser.ParseWebhookEvent(payload)
+}
+
+// nonEmpty drops blank addresses left by empty form fields
+func nonEmpty(addresses []string) []string {
+	var result []string
+	for _, address := range addresses {
+		if strings.TrimSpace(address) != "" {
+			result = append(result, address)
+		}
+	}
+	return result
 }
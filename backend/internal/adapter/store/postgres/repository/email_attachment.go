@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"backend/internal/core/models"
+	ports "backend/internal/core/ports"
+)
+
+type EmailAttachmentRepository struct {
+	db *gorm.DB
+}
+
+func NewEmailAttachmentRepository(db *gorm.DB) ports.EmailAttachmentRepository {
+	return &EmailAttachmentRepository{db: db}
+}
+
+func (r *EmailAttachmentRepository) CreateEmailAttachment(ctx context.Context, attachment *models.EmailAttachment) error {
+	if attachment.UUID == uuid.Nil {
+		attachment.UUID = uuid.New()
+	}
+	return r.db.WithContext(ctx).Create(attachment).Error
+}
+
+func (r *EmailAttachmentRepository) GetEmailAttachmentByID(ctx context.Context, id uuid.UUID) (*models.EmailAttachment, error) {
+	var attachment models.EmailAttachment
+	err := r.db.WithContext(ctx).Where("uuid = ?", id).First(&attachment).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, models.ErrEmailAttachmentNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &attachment, nil
+}
+
+func (r *EmailAttachmentRepository) GetEmailAttachmentsByIDs(ctx context.Context, ids []uuid.UUID) ([]models.EmailAttachment, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	var found []models.EmailAttachment
+	if err := r.db.WithContext(ctx).Where("uuid IN ?", ids).Find(&found).Error; err != nil {
+		return nil, err
+	}
+
+	byID := make(map[uuid.UUID]models.EmailAttachment, len(found))
+	for _, attachment := range found {
+		byID[attachment.UUID] = attachment
+	}
+
+	attachments := make([]models.EmailAttachment, 0, len(ids))
+	for _, id := range ids {
+		attachment, ok := byID[id]
+		if !ok {
+			return nil, models.ErrEmailAttachmentNotFound
+		}
+		attachments = append(attachments, attachment)
+	}
+	return attachments, nil
+}
@@ -0,0 +1,128 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"backend/internal/core/models"
+	ports "backend/internal/core/ports"
+)
+
+type EmailLogRepository struct {
+	db *gorm.DB
+}
+
+func NewEmailLogRepository(db *gorm.DB) ports.EmailLogRepository {
+	return &EmailLogRepository{db: db}
+}
+
+// normalizeMessageID strips the angle brackets Send returns, webhooks report the bare ID
+func normalizeMessageID(id string) string {
+	return strings.Trim(strings.TrimSpace(id), "<>")
+}
+
+func (r *EmailLogRepository) CreateEmailLog(ctx context.Context, entry *models.EmailLog) error {
+	if entry.UUID == uuid.Nil {
+		entry.UUID = uuid.New()
+	}
+	if entry.SentAt.IsZero() {
+		entry.SentAt = time.Now().UTC()
+	}
+	if entry.Status == "" {
+		entry.Status = models.EmailEventSent
+	}
+	entry.MessageID = normalizeMessageID(entry.MessageID)
+
+	return r.db.WithContext(ctx).Create(entry).Error
+}
+
+func (r *EmailLogRepository) RecordEmailEvent(ctx context.Context, event *models.EmailEvent) (*models.EmailLog, error) {
+	var entry models.EmailLog
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("message_id = ?", normalizeMessageID(event.MessageID)).
+			First(&entry).Error
+		if err != nil {
+			return err
+		}
+
+		if event.UUID == uuid.Nil {
+			event.UUID = uuid.New()
+		}
+		event.EmailLogID = entry.UUID
+
+		result := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "mailgun_event_id"}},
+			DoNothing: true,
+		}).Create(event)
+		if result.Error != nil {
+			return result.Error
+		}
+		// already seen this event, Mailgun is retrying the webhook
+		if result.RowsAffected == 0 {
+			return nil
+		}
+
+		entry.Apply(event)
+		return tx.Save(&entry).Error
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &entry, nil
+}
+
+func (r *EmailLogRepository) GetEmailLogsByRequestID(ctx context.Context, requestID uuid.UUID) ([]models.EmailLog, error) {
+	if err := checkRequestScope(ctx, r.db, requestID); err != nil {
+		return nil, err
+	}
+
+	var entries []models.EmailLog
+	err := r.db.WithContext(ctx).
+		Preload("Events", func(db *gorm.DB) *gorm.DB { return db.Order("occurred_at") }).
+		Where("request_id = ?", requestID).
+		Order("sent_at DESC").
+		Find(&entries).Error
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+func (r *EmailLogRepository) GetEmailLogsByInvoiceID(ctx context.Context, invoiceID uuid.UUID) ([]models.EmailLog, error) {
+	if err := checkInvoiceScope(ctx, r.db, invoiceID); err != nil {
+		return nil, err
+	}
+
+	var entries []models.EmailLog
+	err := r.db.WithContext(ctx).
+		Preload("Events", func(db *gorm.DB) *gorm.DB { return db.Order("occurred_at") }).
+		Where("invoice_id = ?", invoiceID).
+		Order("sent_at DESC").
+		Find(&entries).Error
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+func (r *EmailLogRepository) MarkRequestEmailInvalid(ctx context.Context, requestID uuid.UUID, recipient, reason string) error {
+	// the bounce can be for a cc, or for an address the request has since moved away from
+	return r.db.WithContext(ctx).Model(&models.Request{}).
+		Where("uuid = ? AND LOWER(TRIM(email)) = LOWER(?)", requestID, strings.TrimSpace(recipient)).
+		Updates(map[string]interface{}{
+			"email_invalid":        true,
+			"email_invalid_reason": reason,
+		}).Error
+}
@@ -0,0 +1,324 @@
+package repository
+
+// postgres backed outbox for scheduled emails. Rows are claimed with FOR UPDATE SKIP LOCKED so
+// any number of API instances can process the outbox without sending an email twice. Redis,
+// when connected, only holds a sorted set of due times so emails can be picked up between
+// outbox polls.
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"backend/internal/core/models"
+	ports "backend/internal/core/ports"
+)
+
+const (
+	scheduledEmailsKey = "scheduled_emails"
+
+	// an email stuck in sending this long belongs to an instance that died mid send
+	staleSendingAfter = 10 * time.Minute
+
+	emailRetryBaseDelay = time.Minute
+	emailRetryMaxDelay  = 6 * time.Hour
+)
+
+type EmailOutboxRepository struct {
+	db    *gorm.DB
+	redis *redis.Client
+}
+
+func NewEmailOutboxRepository(db *gorm.DB, redis *redis.Client) ports.EmailOutboxRepository {
+	return &EmailOutboxRepository{db: db, redis: redis}
+}
+
+func (r *EmailOutboxRepository) EnqueueEmail(ctx context.Context, email *models.Email) error {
+	now := time.Now().UTC()
+	if email.ID == uuid.Nil {
+		email.ID = uuid.New()
+	}
+	if email.MaxAttempts == 0 {
+		email.MaxAttempts = models.DefaultEmailMaxAttempts
+	}
+	email.Status = models.EmailStatusPending
+	email.Attempts = 0
+	email.NextAttemptAt = email.SendAt
+	email.CreatedAt = now
+	email.UpdatedAt = now
+
+	if err := r.db.WithContext(ctx).Create(email).Error; err != nil {
+		return fmt.Errorf("failed to add email to outbox: %w", err)
+	}
+
+	// the outbox row is the source of truth, the Redis hint is best effort
+	if r.redis != nil {
+		err := r.redis.ZAdd(ctx, scheduledEmailsKey, redis.Z{
+			Score:  float64(email.SendAt.Unix()),
+			Member: email.ID.String(),
+		}).Err()
+		if err != nil {
+			log.Printf("Warning: failed to add email %s to Redis schedule: %v", email.ID, err)
+		}
+	}
+
+	return nil
+}
+
+func (r *EmailOutboxRepository) ClaimDueEmails(ctx context.Context, limit int) ([]models.Email, error) {
+	return r.claim(ctx, limit, nil)
+}
+
+func (r *EmailOutboxRepository) ClaimEmails(ctx context.Context, ids []uuid.UUID) ([]models.Email, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	return r.claim(ctx, len(ids), ids)
+}
+
+func (r *EmailOutboxRepository) claim(ctx context.Context, limit int, ids []uuid.UUID) ([]models.Email, error) {
+	var emails []models.Email
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now().UTC()
+		staleBefore := now.Add(-staleSendingAfter)
+
+		// a stale email that already used its last attempt may have gone out before its sender
+		// died, so it's given up on rather than sent again
+		err := tx.Model(&models.Email{}).
+			Where("status = ? AND updated_at < ? AND attempts >= max_attempts", models.EmailStatusSending, staleBefore).
+			Updates(map[string]interface{}{
+				"status":     models.EmailStatusDead,
+				"last_error": "sender stopped responding on the last attempt",
+				"updated_at": now,
+			}).Error
+		if err != nil {
+			return err
+		}
+
+		query := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("((status IN ? AND next_attempt_at <= ?) OR (status = ? AND updated_at < ? AND attempts < max_attempts))",
+				[]string{models.EmailStatusPending, models.EmailStatusFailed}, now,
+				models.EmailStatusSending, staleBefore).
+			Order("next_attempt_at").
+			Limit(limit)
+		if ids != nil {
+			query = query.Where("id IN ?", ids)
+		}
+
+		if err := query.Find(&emails).Error; err != nil {
+			return err
+		}
+		if len(emails) == 0 {
+			return nil
+		}
+
+		claimed := make([]uuid.UUID, len(emails))
+		for i := range emails {
+			claimed[i] = emails[i].ID
+			emails[i].Status = models.EmailStatusSending
+			emails[i].Attempts++
+			emails[i].UpdatedAt = now
+		}
+
+		return tx.Model(&models.Email{}).
+			Where("id IN ?", claimed).
+			Updates(map[string]interface{}{
+				"status":     models.EmailStatusSending,
+				"attempts":   gorm.Expr("attempts + 1"),
+				"updated_at": now,
+			}).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim emails from outbox: %w", err)
+	}
+
+	return emails, nil
+}
+
+func (r *EmailOutboxRepository) MarkEmailSent(ctx context.Context, id uuid.UUID) error {
+	now := time.Now().UTC()
+	err := r.db.WithContext(ctx).Model(&models.Email{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"status":     models.EmailStatusSent,
+			"sent_at":    now,
+			"last_error": "",
+			"updated_at": now,
+		}).Error
+	if err != nil {
+		return err
+	}
+
+	if r.redis != nil {
+		r.redis.ZRem(ctx, scheduledEmailsKey, id.String())
+	}
+	return nil
+}
+
+func (r *EmailOutboxRepository) MarkEmailFailed(ctx context.Context, email *models.Email, sendErr error) error {
+	now := time.Now().UTC()
+	updates := map[string]interface{}{
+		"last_error": sendErr.Error(),
+		"updated_at": now,
+	}
+
+	if email.Attempts >= email.MaxAttempts {
+		updates["status"] = models.EmailStatusDead
+	} else {
+		updates["status"] = models.EmailStatusFailed
+		updates["next_attempt_at"] = now.Add(emailRetryDelay(email.Attempts))
+	}
+
+	return r.db.WithContext(ctx).Model(&models.Email{}).Where("id = ?", email.ID).Updates(updates).Error
+}
+
+// emailRetryDelay doubles the wait after each failed attempt, 1m, 2m, 4m... up to 6h
+func emailRetryDelay(attempts int) time.Duration {
+	delay := emailRetryBaseDelay
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= emailRetryMaxDelay {
+			return emailRetryMaxDelay
+		}
+	}
+	return delay
+}
+
+func (r *EmailOutboxRepository) GetEmailByID(ctx context.Context, id uuid.UUID) (*models.Email, error) {
+	var email models.Email
+	if err := r.db.WithContext(ctx).Scopes(requestsInScope(ctx, "request_id")).Where("id = ?", id).First(&email).Error; err != nil {
+		return nil, err
+	}
+	return &email, nil
+}
+
+func (r *EmailOutboxRepository) GetEmailsByRequestID(ctx context.Context, requestID uuid.UUID) ([]models.Email, error) {
+	if err := checkRequestScope(ctx, r.db, requestID); err != nil {
+		return nil, err
+	}
+
+	var emails []models.Email
+	if err := r.db.WithContext(ctx).Where("request_id = ?", requestID).Order("send_at").Find(&emails).Error; err != nil {
+		return nil, err
+	}
+	return emails, nil
+}
+
+func (r *EmailOutboxRepository) GetEmailsByBranchID(ctx context.Context, branchID uuid.UUID, status string) ([]models.Email, error) {
+	if err := checkBranchScope(ctx, branchID); err != nil {
+		return nil, err
+	}
+
+	var emails []models.Email
+	query := r.db.WithContext(ctx).
+		Joins("JOIN requests ON requests.uuid = email_outbox.request_id").
+		Where("requests.closest_branch_id = ?", branchID)
+	if status != "" {
+		query = query.Where("email_outbox.status = ?", status)
+	}
+	if err := query.Order("email_outbox.send_at").Find(&emails).Error; err != nil {
+		return nil, err
+	}
+	return emails, nil
+}
+
+func (r *EmailOutboxRepository) UpdateScheduledEmail(ctx context.Context, id uuid.UUID, update models.ScheduledEmailUpdate) (*models.Email, error) {
+	var email models.Email
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := lockEditableEmail(ctx, tx, id, &email); err != nil {
+			return err
+		}
+
+		if update.SendAt != nil {
+			email.SendAt = update.SendAt.UTC()
+			email.NextAttemptAt = email.SendAt
+			// a manual reschedule starts the retries over
+			email.Status = models.EmailStatusPending
+			email.Attempts = 0
+			email.LastError = ""
+		}
+		if update.Content != nil {
+			email.Content = *update.Content
+			// the old text part no longer matches, the HTML goes out on its own
+			email.TextContent = ""
+		}
+		if update.Headers != nil {
+			if update.Headers.Subject != "" {
+				email.Subject = update.Headers.Subject
+			}
+			if update.Headers.ReplyTo != "" {
+				email.ReplyTo = update.Headers.ReplyTo
+			}
+			email.CC = update.Headers.CC
+			email.BCC = update.Headers.BCC
+			email.AttachmentIDs = update.Headers.AttachmentIDStrings()
+		}
+		email.UpdatedAt = time.Now().UTC()
+
+		return tx.Save(&email).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if update.SendAt != nil && r.redis != nil {
+		r.redis.ZAdd(ctx, scheduledEmailsKey, redis.Z{
+			Score:  float64(email.SendAt.Unix()),
+			Member: email.ID.String(),
+		})
+	}
+
+	return &email, nil
+}
+
+func (r *EmailOutboxRepository) CancelEmail(ctx context.Context, id uuid.UUID) (*models.Email, error) {
+	var email models.Email
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := lockEditableEmail(ctx, tx, id, &email); err != nil {
+			return err
+		}
+
+		email.Status = models.EmailStatusCancelled
+		email.UpdatedAt = time.Now().UTC()
+		return tx.Save(&email).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if r.redis != nil {
+		r.redis.ZRem(ctx, scheduledEmailsKey, id.String())
+	}
+
+	return &email, nil
+}
+
+// lockEditableEmail locks the email row so the cron can't claim it mid edit. If the cron got
+// there first the row comes back as sending and the edit is refused.
+func lockEditableEmail(ctx context.Context, tx *gorm.DB, id uuid.UUID, email *models.Email) error {
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Scopes(requestsInScope(ctx, "request_id")).
+		Where("id = ?", id).
+		First(email).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return models.ErrEmailNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	if !email.Editable() {
+		return fmt.Errorf("email is %s: %w", email.Status, models.ErrEmailNotEditable)
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"backend/internal/core/models"
+	ports "backend/internal/core/ports"
+)
+
+type EmailTemplateRepository struct {
+	db *gorm.DB
+}
+
+func NewEmailTemplateRepository(db *gorm.DB) ports.EmailTemplateRepository {
+	return &EmailTemplateRepository{db: db}
+}
+
+// branchScope matches the branch's own rows, or the default rows for a nil branch
+func branchScope(branchID *uuid.UUID) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if branchID == nil {
+			return db.Where("branch_id IS NULL")
+		}
+		return db.Where("branch_id = ?", *branchID)
+	}
+}
+
+func (r *EmailTemplateRepository) CreateEmailTemplate(ctx context.Context, template *models.EmailTemplate) error {
+	if template.UUID == uuid.Nil {
+		template.UUID = uuid.New()
+	}
+
+	// the unique index turns a race between two saves into an error instead of a duplicate version
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var latest int
+		err := tx.Model(&models.EmailTemplate{}).
+			Scopes(branchScope(template.BranchID)).
+			Where("name = ?", template.Name).
+			Select("COALESCE(MAX(version), 0)").
+			Scan(&latest).Error
+		if err != nil {
+			return err
+		}
+
+		template.Version = latest + 1
+		return tx.Create(template).Error
+	})
+}
+
+func (r *EmailTemplateRepository) GetEmailTemplateByID(ctx context.Context, id uuid.UUID) (*models.EmailTemplate, error) {
+	var template models.EmailTemplate
+	if err := r.db.WithContext(ctx).Where("uuid = ?", id).First(&template).Error; err != nil {
+		return nil, err
+	}
+	return &template, nil
+}
+
+func (r *EmailTemplateRepository) GetEmailTemplateVersions(ctx context.Context, branchID *uuid.UUID, name string) ([]models.EmailTemplate, error) {
+	var templates []models.EmailTemplate
+	err := r.db.WithContext(ctx).
+		Scopes(branchScope(branchID)).
+		Where("name = ?", name).
+		Order("version DESC").
+		Find(&templates).Error
+	if err != nil {
+		return nil, err
+	}
+	return templates, nil
+}
+
+func (r *EmailTemplateRepository) GetActiveEmailTemplate(ctx context.Context, branchID *uuid.UUID, name string) (*models.EmailTemplate, error) {
+	query := r.db.WithContext(ctx).Where("name = ?", name)
+	if branchID == nil {
+		query = query.Where("branch_id IS NULL")
+	} else {
+		query = query.Where("branch_id = ? OR branch_id IS NULL", *branchID)
+	}
+
+	var template models.EmailTemplate
+	err := query.Order("branch_id NULLS LAST").Order("version DESC").First(&template).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &template, nil
+}
+
+func (r *EmailTemplateRepository) DeleteEmailTemplates(ctx context.Context, branchID uuid.UUID, name string) error {
+	return r.db.WithContext(ctx).
+		Where("branch_id = ? AND name = ?", branchID, name).
+		Delete(&models.EmailTemplate{}).Error
+}
@@ -0,0 +1,154 @@
+package repository
+
+import (
+	"backend/internal/core/models"
+	ports "backend/internal/core/ports"
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type EventRepository struct {
+	db *gorm.DB
+}
+
+func NewEventRepository(db *gorm.DB) ports.EventRepository {
+	return &EventRepository{db: db}
+}
+
+// shifts come back in the order they are worked
+func orderedShifts(db *gorm.DB) *gorm.DB {
+	return db.Order("date, start_time, end_time")
+}
+
+func (r *EventRepository) CreateEvent(ctx context.Context, event *models.Event) (bool, error) {
+	if err := checkBranchScope(ctx, event.BranchID); err != nil {
+		return false, err
+	}
+	if event.UUID == uuid.Nil {
+		event.UUID = uuid.New()
+	}
+
+	created := false
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := checkRequestScope(ctx, tx, event.RequestID); err != nil {
+			return err
+		}
+
+		// the unique request_id index lets only one of two concurrent schedulings through
+		result := tx.Omit(clause.Associations).
+			Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "request_id"}}, DoNothing: true}).
+			Create(event)
+		if result.Error != nil {
+			return fmt.Errorf("failed to create event for request %s: %w", event.RequestID, result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+		created = true
+
+		for i := range event.Shifts {
+			shift := &event.Shifts[i]
+			shift.EventID = event.UUID
+			if shift.UUID == uuid.Nil {
+				shift.UUID = uuid.New()
+			}
+		}
+		if len(event.Shifts) > 0 {
+			if err := tx.Omit(clause.Associations).Create(&event.Shifts).Error; err != nil {
+				return fmt.Errorf("failed to create shifts for event %s: %w", event.UUID, err)
+			}
+		}
+		return nil
+	})
+	return created, err
+}
+
+func (r *EventRepository) GetEventByID(ctx context.Context, id uuid.UUID) (*models.Event, error) {
+	var event models.Event
+	err := r.db.WithContext(ctx).
+		Preload("Shifts", orderedShifts).
+		Scopes(branchInScope(ctx, "branch_id")).
+		Where("uuid = ?", id).
+		First(&event).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get event %s: %w", id, err)
+	}
+	return &event, nil
+}
+
+func (r *EventRepository) GetEventByRequestID(ctx context.Context, requestID uuid.UUID) (*models.Event, error) {
+	var event models.Event
+	err := r.db.WithContext(ctx).
+		Preload("Shifts", orderedShifts).
+		Scopes(branchInScope(ctx, "branch_id")).
+		Where("request_id = ?", requestID).
+		First(&event).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get event for request %s: %w", requestID, err)
+	}
+	return &event, nil
+}
+
+func (r *EventRepository) GetEvents(ctx context.Context, filter models.EventFilter) ([]models.Event, error) {
+	query := r.db.WithContext(ctx).Scopes(branchInScope(ctx, "branch_id"))
+	if filter.BranchID != nil {
+		query = query.Where("branch_id = ?", *filter.BranchID)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.From != nil {
+		query = query.Where("end_date >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("start_date <= ?", *filter.To)
+	}
+
+	var events []models.Event
+	if err := query.Order("start_date, start_hour").Find(&events).Error; err != nil {
+		return nil, fmt.Errorf("failed to get events: %w", err)
+	}
+	return events, nil
+}
+
+func (r *EventRepository) UpdateEventStatus(ctx context.Context, id uuid.UUID, from, to string, notes *string) (bool, error) {
+	updates := map[string]interface{}{"status": to}
+	if notes != nil {
+		updates["notes"] = *notes
+	}
+
+	// only moves from the status the caller saw, so two changes at once can't skip a step
+	result := r.db.WithContext(ctx).Model(&models.Event{}).
+		Scopes(branchInScope(ctx, "branch_id")).
+		Where("uuid = ? AND status = ?", id, from).
+		Updates(updates)
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to update event %s: %w", id, result.Error)
+	}
+	return result.RowsAffected > 0, nil
+}
+
+func (r *EventRepository) GetRequestsAwaitingEvent(ctx context.Context) ([]uuid.UUID, error) {
+	var requestIDs []uuid.UUID
+	err := r.db.WithContext(ctx).Model(&models.Request{}).
+		Scopes(branchInScope(ctx, "closest_branch_id")).
+		Where("cancelled_at IS NULL").
+		Where("EXISTS (SELECT 1 FROM invoices WHERE invoices.request_id = requests.uuid AND invoices.amount_paid > 0)").
+		Where("NOT EXISTS (SELECT 1 FROM events WHERE events.request_id = requests.uuid)").
+		Pluck("uuid", &requestIDs).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get requests awaiting an event: %w", err)
+	}
+	return requestIDs, nil
+}
@@ -10,6 +10,7 @@ import (
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type InvoiceRepository struct {
@@ -29,47 +30,137 @@ func (r *InvoiceRepository) CreateInvoice(ctx context.Context, invoice *models.I
 		invoice.UUID = uuid.New()
 	}
 
-	amount, transactionFee, serviceFee, subtotal, err := r.rateCalculator.CalculateRates(ctx, request)
+	if err := checkRequestScope(ctx, r.db, request.UUID); err != nil {
+		return err
+	}
+
+	// keep a discount the caller already set, otherwise start with none
+	if invoice.DiscountType == "" {
+		invoice.DiscountType = models.DiscountTypeNone
+		invoice.DiscountPercent = 0
+		invoice.DiscountFixed = 0
+	}
+
+	breakdown, err := r.rateCalculator.CalculateRates(ctx, request, invoice.Discount())
 	if err != nil {
 		return fmt.Errorf("failed to calculate rates: %w", err)
 	}
 
 	invoice.RequestID = request.UUID
+	invoice.Kind = models.InvoiceKindFinal
+	invoice.PriorInvoiced = 0
 	invoice.DueDate = request.StartDate
-	invoice.Subtotal = subtotal
-	invoice.DiscountType = "none"
-	invoice.DiscountValue = 0
-	invoice.TransactionFee = transactionFee
-	invoice.ServiceFee = serviceFee
-	invoice.Amount = amount
-	invoice.Balance = amount
-	invoice.Status = "pending"
+	invoice.ApplyBreakdown(breakdown)
+	invoice.Balance = breakdown.Amount
+	invoice.Status = models.InvoiceStatusDraft
 	invoice.PaymentTerms = "Due on receipt"
 	invoice.Notes = ""
 	invoice.ShipTo = request.EventLocation
 	invoice.POEditCounter = 0
-	invoice.PONumber = fmt.Sprintf("PO-%s", request.UUID.String()[:8])
+	// the PO number is the client's to give, the invoice number identifies the invoice
+	invoice.PONumber = ""
+	invoice.InvoiceNumber = nil
 
-	return r.db.WithContext(ctx).Create(invoice).Error
+	return r.insertInvoice(ctx, invoice)
+}
+
+func (r *InvoiceRepository) IssueInvoice(ctx context.Context, invoice *models.Invoice) error {
+	if invoice.UUID == uuid.Nil {
+		invoice.UUID = uuid.New()
+	}
+	invoice.Status = models.InvoiceStatusDraft
+
+	return r.insertInvoice(ctx, invoice)
+}
+
+// insertInvoice gives the invoice the request's next sequence number and stores it along with
+// its first status history entry
+func (r *InvoiceRepository) insertInvoice(ctx context.Context, invoice *models.Invoice) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// the request row lock serialises numbering across concurrent invoices for one request
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Select("uuid").
+			Where("uuid = ?", invoice.RequestID).
+			First(&models.Request{}).Error
+		if err != nil {
+			return fmt.Errorf("failed to lock request %s: %w", invoice.RequestID, err)
+		}
+
+		if invoice.Kind == models.InvoiceKindFinal {
+			var finals int64
+			err := tx.Model(&models.Invoice{}).
+				Where("request_id = ? AND kind = ? AND status <> ?", invoice.RequestID, models.InvoiceKindFinal, models.InvoiceStatusVoid).
+				Count(&finals).Error
+			if err != nil {
+				return fmt.Errorf("failed to check for a final invoice: %w", err)
+			}
+			if finals > 0 {
+				return models.ErrFinalInvoiceExists
+			}
+		}
+
+		err = tx.Model(&models.Invoice{}).
+			Where("request_id = ?", invoice.RequestID).
+			Select("COALESCE(MAX(sequence), 0) + 1").
+			Scan(&invoice.Sequence).Error
+		if err != nil {
+			return fmt.Errorf("failed to number invoice: %w", err)
+		}
+
+		if err := tx.Omit("Request").Create(invoice).Error; err != nil {
+			return err
+		}
+		return recordInvoiceStatusChange(tx, &models.InvoiceStatusChange{
+			InvoiceID: invoice.UUID,
+			ToStatus:  invoice.Status,
+			Actor:     models.ActorFromContext(ctx),
+			Reason:    fmt.Sprintf("%s invoice created", strings.ReplaceAll(invoice.Kind, "_", " ")),
+		})
+	})
 }
 
 func (r *InvoiceRepository) GetInvoiceByID(ctx context.Context, id uuid.UUID) (*models.Invoice, error) {
 	var invoice models.Invoice
-	err := r.db.WithContext(ctx).Preload("Request").Where("uuid = ?", id).First(&invoice).Error
+	err := r.db.WithContext(ctx).Preload("Request").Scopes(requestsInScope(ctx, "invoices.request_id")).Where("uuid = ?", id).First(&invoice).Error
 	if err != nil {
 		return nil, err
 	}
 	return &invoice, nil
 }
 
+// GetInvoiceByRequestID returns the request's live final invoice, or its latest invoice when
+// there is no final one
 func (r *InvoiceRepository) GetInvoiceByRequestID(ctx context.Context, requestID uuid.UUID) (*models.Invoice, error) {
 	var invoice models.Invoice
-	if err := r.db.WithContext(ctx).Preload("Request").Where("request_id = ?", requestID).First(&invoice).Error; err != nil {
+	err := r.db.WithContext(ctx).
+		Preload("Request").
+		Where("request_id = ?", requestID).
+		Scopes(requestsInScope(ctx, "invoices.request_id")).
+		Order(clause.Expr{
+			SQL:  "CASE WHEN kind = ? AND status <> ? THEN 0 ELSE 1 END, sequence DESC",
+			Vars: []interface{}{models.InvoiceKindFinal, models.InvoiceStatusVoid},
+		}).
+		First(&invoice).Error
+	if err != nil {
 		return nil, err
 	}
 	return &invoice, nil
 }
 
+func (r *InvoiceRepository) GetInvoicesByRequestID(ctx context.Context, requestID uuid.UUID) ([]models.Invoice, error) {
+	var invoices []models.Invoice
+	err := r.db.WithContext(ctx).
+		Preload("Request").
+		Where("request_id = ?", requestID).
+		Scopes(requestsInScope(ctx, "invoices.request_id")).
+		Order("sequence").
+		Find(&invoices).Error
+	if err != nil {
+		return nil, err
+	}
+	return invoices, nil
+}
+
 func (r *InvoiceRepository) GetInvoiceByBranchID(ctx context.Context, branchID uuid.UUID) ([]models.InvoiceResponse, error) {
 	var tempResults []struct {
 		models.Invoice
@@ -80,6 +171,10 @@ func (r *InvoiceRepository) GetInvoiceByBranchID(ctx context.Context, branchID u
 		RequestClosestBranchName string
 	}
 
+	if err := checkBranchScope(ctx, branchID); err != nil {
+		return nil, err
+	}
+
 	err := r.db.WithContext(ctx).Table("invoices").
 		Select("invoices.*, requests.first_name as request_first_name, requests.last_name as request_last_name, requests.is_company as request_is_company, requests.company_name as request_company_name, requests.closest_branch_name as request_closest_branch_name").
 		Joins("JOIN requests ON requests.uuid = invoices.request_id").
@@ -102,6 +197,9 @@ func (r *InvoiceRepository) GetInvoiceByBranchID(ctx context.Context, branchID u
 		finalResponse = append(finalResponse, models.InvoiceResponse{
 			UUID:       res.Invoice.UUID,
 			RequestID:  res.Invoice.RequestID,
+			Kind:       res.Invoice.Kind,
+			Sequence:   res.Invoice.Sequence,
+			Number:     res.Invoice.InvoiceNumber,
 			DueDate:    res.Invoice.DueDate,
 			Amount:     res.Invoice.Amount,
 			Balance:    res.Invoice.Balance,
@@ -115,12 +213,65 @@ func (r *InvoiceRepository) GetInvoiceByBranchID(ctx context.Context, branchID u
 }
 
 func (r *InvoiceRepository) UpdateInvoice(ctx context.Context, invoice *models.Invoice) error {
-	// Get existing invoice to check PO edit counter
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		existing, err := lockInvoice(ctx, tx, invoice.UUID)
+		if err != nil {
+			return err
+		}
+		return saveInvoice(tx, invoice, existing)
+	})
+}
+
+func (r *InvoiceRepository) ApplyDiscount(ctx context.Context, invoice *models.Invoice) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		existing, err := lockInvoice(ctx, tx, invoice.UUID)
+		if err != nil {
+			return err
+		}
+
+		totals, err := ledgerTotals(tx, existing.UUID)
+		if err != nil {
+			return err
+		}
+		// checked again on the locked row in case the invoice went out since it was read
+		if existing.Status != models.InvoiceStatusDraft || totals.Paid > 0 {
+			return models.ErrInvoiceNotDraft
+		}
+
+		// the code on the locked row is the one to give back, whatever the caller read before
+		if existing.PromoCode != invoice.PromoCode {
+			if invoice.PromoCode != "" {
+				if err := redeemPromoCode(tx, invoice.PromoCode); err != nil {
+					return err
+				}
+			}
+			if existing.PromoCode != "" {
+				if err := releasePromoCode(tx, existing.PromoCode); err != nil {
+					return fmt.Errorf("failed to release promo code: %w", err)
+				}
+			}
+		}
+
+		return saveInvoice(tx, invoice, existing)
+	})
+}
+
+// lockInvoice reads the invoice for update, the lock keeps a payment landing mid edit from being
+// lost from the balance
+func lockInvoice(ctx context.Context, tx *gorm.DB, id uuid.UUID) (*models.Invoice, error) {
 	var existing models.Invoice
-	if err := r.db.WithContext(ctx).Where("uuid = ?", invoice.UUID).First(&existing).Error; err != nil {
-		return err
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Scopes(requestsInScope(ctx, "invoices.request_id")).
+		Where("uuid = ?", id).
+		First(&existing).Error
+	if err != nil {
+		return nil, err
 	}
+	return &existing, nil
+}
 
+// saveInvoice writes the caller's edits over the locked row, keeping what only other paths change
+func saveInvoice(tx *gorm.DB, invoice, existing *models.Invoice) error {
 	// Only check PO edit limit if they're actually updating the PO number
 	if invoice.PONumber != existing.PONumber {
 		if existing.POEditCounter >= 1 {
@@ -129,18 +280,89 @@ func (r *InvoiceRepository) UpdateInvoice(ctx context.Context, invoice *models.I
 		invoice.POEditCounter = 1
 	}
 
-	return r.db.WithContext(ctx).Save(invoice).Error
+	// what has been paid only comes from the ledger, the balance follows the new amount
+	totals, err := ledgerTotals(tx, existing.UUID)
+	if err != nil {
+		return err
+	}
+	invoice.Status = existing.Status
+	invoice.PaymentIntent = existing.PaymentIntent
+	invoice.AmountPaid = totals.Paid - totals.Refunded
+	invoice.Balance = invoice.Amount - invoice.AmountPaid
+	if existing.Status == models.InvoiceStatusVoid {
+		invoice.Balance = 0
+	}
+
+	// status only changes through TransitionInvoiceStatus, so it has a history entry, the
+	// number is only ever allocated, the automatic charge is only ever claimed and payments
+	// are only ever recorded in the ledger
+	return tx.Omit("status", "invoice_number", "auto_charge_attempted_at", "amount_paid", "payment_intent").Save(invoice).Error
 }
 
+// DeleteInvoice only deletes invoices that were never numbered, a numbered invoice has to stay
+// so the branch's numbers have no gaps
 func (r *InvoiceRepository) DeleteInvoice(ctx context.Context, id uuid.UUID) error {
-	return r.db.WithContext(ctx).Delete(&models.Invoice{}, id).Error
+	result := r.db.WithContext(ctx).Scopes(requestsInScope(ctx, "invoices.request_id")).Where("invoice_number IS NULL").Delete(&models.Invoice{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		var count int64
+		err := r.db.WithContext(ctx).Model(&models.Invoice{}).Scopes(requestsInScope(ctx, "invoices.request_id")).Where("uuid = ?", id).Count(&count).Error
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			return models.ErrInvoiceNumbered
+		}
+	}
+	return nil
+}
+
+// AssignInvoiceNumber gives the invoice its number if it doesn't have one yet
+func (r *InvoiceRepository) AssignInvoiceNumber(ctx context.Context, invoice *models.Invoice) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		number, err := assignInvoiceNumber(tx, invoice.UUID)
+		if err != nil {
+			return err
+		}
+		invoice.InvoiceNumber = &number
+		return nil
+	})
+}
+
+// SearchInvoices finds invoices by invoice number, PO number or client
+func (r *InvoiceRepository) SearchInvoices(ctx context.Context, query string) ([]models.Invoice, error) {
+	pattern := "%" + strings.TrimSpace(query) + "%"
+
+	var invoices []models.Invoice
+	err := r.db.WithContext(ctx).
+		Preload("Request").
+		Joins("JOIN requests ON requests.uuid = invoices.request_id").
+		Where(`invoices.invoice_number ILIKE ? OR invoices.po_number ILIKE ? OR requests.email ILIKE ?
+			OR requests.company_name ILIKE ? OR (requests.first_name || ' ' || requests.last_name) ILIKE ?`,
+			pattern, pattern, pattern, pattern, pattern).
+		Scopes(requestsInScope(ctx, "invoices.request_id")).
+		Order("invoices.invoice_number NULLS LAST").
+		Limit(50).
+		Find(&invoices).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to search invoices: %w", err)
+	}
+	return invoices, nil
 }
 
 func (r *InvoiceRepository) CheckForOverdueInvoices(ctx context.Context) ([]models.Invoice, error) {
 	var overdueInvoices []models.Invoice
-	err := r.db.WithContext(ctx).Table("invoices").
-		Where("status = ? AND due_date < ?", "unpaid", time.Now().UTC()).
-		Scan(&overdueInvoices).Error
+	err := r.db.WithContext(ctx).
+		Preload("Request").
+		Where("status = ? OR (status IN ? AND due_date < ? AND balance > 0)",
+			models.InvoiceStatusOverdue,
+			[]string{models.InvoiceStatusSent, models.InvoiceStatusPartiallyPaid},
+			time.Now().UTC()).
+		Scopes(requestsInScope(ctx, "invoices.request_id")).
+		Order("due_date").
+		Find(&overdueInvoices).Error
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to query overdue invoices: %w", err)
@@ -148,3 +370,106 @@ func (r *InvoiceRepository) CheckForOverdueInvoices(ctx context.Context) ([]mode
 
 	return overdueInvoices, nil
 }
+
+func (r *InvoiceRepository) TransitionInvoiceStatus(ctx context.Context, change *models.InvoiceStatusChange) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// compare-and-set, a change that raced this one leaves nothing to update
+		result := tx.Model(&models.Invoice{}).
+			Where("uuid = ? AND status = ?", change.InvoiceID, change.FromStatus).
+			Update("status", change.ToStatus)
+		if result.Error != nil {
+			return fmt.Errorf("failed to update invoice status: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("%w: invoice %s is no longer %s", models.ErrInvalidInvoiceTransition, change.InvoiceID, change.FromStatus)
+		}
+
+		return recordInvoiceStatusChange(tx, change)
+	})
+}
+
+func (r *InvoiceRepository) GetInvoiceStatusHistory(ctx context.Context, invoiceID uuid.UUID) ([]models.InvoiceStatusChange, error) {
+	query := r.db.WithContext(ctx).Where("invoice_id = ?", invoiceID)
+	if _, ok := models.BranchScope(ctx); ok {
+		inScope := r.db.Model(&models.Invoice{}).Select("uuid").Scopes(requestsInScope(ctx, "invoices.request_id"))
+		query = query.Where("invoice_id IN (?)", inScope)
+	}
+
+	var history []models.InvoiceStatusChange
+	err := query.Order("created_at").Find(&history).Error
+	if err != nil {
+		return nil, err
+	}
+	return history, nil
+}
+
+// recordInvoiceStatusChange appends to the status history inside the caller's transaction, every
+// repository that moves an invoice's status goes through it
+func recordInvoiceStatusChange(tx *gorm.DB, change *models.InvoiceStatusChange) error {
+	if change.UUID == uuid.Nil {
+		change.UUID = uuid.New()
+	}
+	if change.Actor == "" {
+		change.Actor = models.ActorUnknown
+	}
+	if err := tx.Create(change).Error; err != nil {
+		return fmt.Errorf("failed to record invoice status change: %w", err)
+	}
+
+	// leaving draft is when an invoice is issued, a draft voided before going out is never numbered
+	if change.ToStatus != models.InvoiceStatusDraft && change.ToStatus != models.InvoiceStatusVoid {
+		if _, err := assignInvoiceNumber(tx, change.InvoiceID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// assignInvoiceNumber allocates the next number for the invoice's branch and year inside the
+// caller's transaction. The upsert locks the sequence row until the transaction ends, so
+// concurrent issues queue up and a rollback hands the number back.
+func assignInvoiceNumber(tx *gorm.DB, invoiceID uuid.UUID) (string, error) {
+	var current struct {
+		InvoiceNumber *string
+		BranchCode    string
+	}
+	err := tx.Raw(`
+		SELECT i.invoice_number, COALESCE(NULLIF(b.code, ''), ?) AS branch_code
+		FROM invoices i
+		JOIN requests r ON r.uuid = i.request_id
+		LEFT JOIN branches b ON b.uuid = r.closest_branch_id
+		WHERE i.uuid = ?
+		FOR UPDATE OF i`, models.DefaultBranchCode, invoiceID).
+		Scan(&current).Error
+	if err != nil {
+		return "", fmt.Errorf("failed to lock invoice %s: %w", invoiceID, err)
+	}
+	if current.InvoiceNumber != nil && *current.InvoiceNumber != "" {
+		return *current.InvoiceNumber, nil
+	}
+	if current.BranchCode == "" {
+		return "", fmt.Errorf("invoice %s not found", invoiceID)
+	}
+
+	year := time.Now().UTC().Year()
+	var next int
+	err = tx.Raw(`
+		INSERT INTO invoice_number_sequences (branch_code, year, last_number)
+		VALUES (?, ?, 1)
+		ON CONFLICT (branch_code, year)
+		DO UPDATE SET last_number = invoice_number_sequences.last_number + 1
+		RETURNING last_number`, current.BranchCode, year).
+		Scan(&next).Error
+	if err != nil {
+		return "", fmt.Errorf("failed to allocate invoice number: %w", err)
+	}
+
+	number := models.FormatInvoiceNumber(current.BranchCode, year, next)
+	err = tx.Model(&models.Invoice{}).
+		Where("uuid = ?", invoiceID).
+		Update("invoice_number", number).Error
+	if err != nil {
+		return "", fmt.Errorf("failed to number invoice %s: %w", invoiceID, err)
+	}
+	return number, nil
+}
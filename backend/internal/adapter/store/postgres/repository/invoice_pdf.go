@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"backend/internal/core/models"
+	ports "backend/internal/core/ports"
+	"context"
+	"fmt"
+)
+
+type InvoicePDFRepository struct {
+	staffRepo           ports.StaffRequirementRepository
+	customLineItemsRepo ports.CustomLineItemsRepository
+	renderer            ports.InvoicePDFRenderer
+	// used when the invoice doesn't carry its own terms
+	termsAndConditions string
+}
+
+func NewInvoicePDFRepository(staffRepo ports.StaffRequirementRepository, customLineItemsRepo ports.CustomLineItemsRepository, renderer ports.InvoicePDFRenderer, termsAndConditions string) ports.InvoicePDFRepository {
+	return &InvoicePDFRepository{
+		staffRepo:           staffRepo,
+		customLineItemsRepo: customLineItemsRepo,
+		renderer:            renderer,
+		termsAndConditions:  termsAndConditions,
+	}
+}
+
+func (r *InvoicePDFRepository) GenerateInvoicePDF(ctx context.Context, invoice *models.Invoice) ([]byte, error) {
+	staffRequirements, err := r.staffRepo.GetAllStaffRequirementsByRequestID(ctx, invoice.RequestID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get staff requirements: %w", err)
+	}
+
+	customLineItems, err := r.customLineItemsRepo.GetCustomLineItemsByRequestID(ctx, invoice.RequestID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get custom line items: %w", err)
+	}
+
+	terms := invoice.TermsAndConditions
+	if terms == "" {
+		terms = r.termsAndConditions
+	}
+
+	pdf, err := r.renderer.RenderInvoicePDF(&models.InvoiceDocument{
+		Invoice:            invoice,
+		StaffRequirements:  staffRequirements,
+		CustomLineItems:    customLineItems,
+		TermsAndConditions: terms,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to render invoice pdf: %w", err)
+	}
+	return pdf, nil
+}
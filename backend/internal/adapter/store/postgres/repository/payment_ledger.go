@@ -0,0 +1,202 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"backend/internal/core/models"
+	ports "backend/internal/core/ports"
+)
+
+type PaymentLedgerRepository struct {
+	db *gorm.DB
+}
+
+func NewPaymentLedgerRepository(db *gorm.DB) ports.PaymentLedgerRepository {
+	return &PaymentLedgerRepository{db: db}
+}
+
+func (r *PaymentLedgerRepository) RecordPayment(ctx context.Context, entry *models.PaymentLedgerEntry) (*models.Invoice, bool, error) {
+	entry.Kind = models.LedgerEntryPayment
+	return r.record(ctx, entry, nil)
+}
+
+func (r *PaymentLedgerRepository) RecordRefund(ctx context.Context, entry *models.PaymentLedgerEntry, chargeRefundedTotal models.Money) (*models.Invoice, bool, error) {
+	entry.Kind = models.LedgerEntryRefund
+
+	// the charge's refunds recorded so far are read under the invoice lock, so two refund
+	// events for the same charge can't both claim the same amount
+	refundDelta := func(tx *gorm.DB) (models.Money, error) {
+		var recorded models.Money
+		err := tx.Model(&models.PaymentLedgerEntry{}).
+			Where("kind = ? AND charge_id = ?", models.LedgerEntryRefund, entry.ChargeID).
+			Select("COALESCE(SUM(amount), 0)").
+			Scan(&recorded).Error
+		if err != nil {
+			return 0, err
+		}
+		return chargeRefundedTotal - recorded, nil
+	}
+
+	return r.record(ctx, entry, refundDelta)
+}
+
+// record appends the entry and re-derives the invoice from the ledger, all under a lock on the
+// invoice row. amount, when given, works out the entry's amount inside the transaction and an
+// amount of zero or less means there is nothing new to record.
+func (r *PaymentLedgerRepository) record(ctx context.Context, entry *models.PaymentLedgerEntry, amount func(tx *gorm.DB) (models.Money, error)) (*models.Invoice, bool, error) {
+	if entry.UUID == uuid.Nil {
+		entry.UUID = uuid.New()
+	}
+	if entry.OccurredAt.IsZero() {
+		entry.OccurredAt = time.Now().UTC()
+	}
+
+	var invoice models.Invoice
+	recorded := false
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("uuid = ?", entry.InvoiceID).
+			First(&invoice).Error
+		if err != nil {
+			return fmt.Errorf("failed to get invoice %s: %w", entry.InvoiceID, err)
+		}
+
+		if amount != nil {
+			entry.Amount, err = amount(tx)
+			if err != nil {
+				return fmt.Errorf("failed to work out ledger amount: %w", err)
+			}
+		}
+		if entry.Amount <= 0 {
+			return nil
+		}
+
+		result := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "stripe_event_id"}},
+			DoNothing: true,
+		}).Create(entry)
+		if result.Error != nil {
+			return fmt.Errorf("failed to record %s: %w", entry.Kind, result.Error)
+		}
+		// a replayed event, the invoice already reflects it
+		if result.RowsAffected == 0 {
+			return nil
+		}
+
+		totals, err := ledgerTotals(tx, invoice.UUID)
+		if err != nil {
+			return err
+		}
+
+		previousStatus := invoice.Status
+		invoice.ApplyLedger(totals)
+		updates := map[string]interface{}{
+			"amount_paid": invoice.AmountPaid,
+			"balance":     invoice.Balance,
+			"status":      invoice.Status,
+		}
+		if entry.Kind == models.LedgerEntryPayment && entry.PaymentIntentID != "" {
+			invoice.PaymentIntent = entry.PaymentIntentID
+			updates["payment_intent"] = entry.PaymentIntentID
+		}
+
+		if err := tx.Model(&models.Invoice{}).Where("uuid = ?", invoice.UUID).Updates(updates).Error; err != nil {
+			return fmt.Errorf("failed to update invoice %s: %w", invoice.UUID, err)
+		}
+
+		if invoice.Status != previousStatus {
+			err := recordInvoiceStatusChange(tx, &models.InvoiceStatusChange{
+				InvoiceID:  invoice.UUID,
+				FromStatus: previousStatus,
+				ToStatus:   invoice.Status,
+				Actor:      models.ActorFromContext(ctx),
+				Reason:     fmt.Sprintf("%s of %s recorded", entry.Kind, entry.Amount),
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		recorded = true
+		return nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+
+	return &invoice, recorded, nil
+}
+
+// ledgerTotals sums the invoice's ledger inside the caller's transaction, which should hold the
+// lock on the invoice row
+func ledgerTotals(tx *gorm.DB, invoiceID uuid.UUID) (models.LedgerTotals, error) {
+	var totals models.LedgerTotals
+	err := tx.Model(&models.PaymentLedgerEntry{}).
+		Where("invoice_id = ?", invoiceID).
+		Select("COALESCE(SUM(amount) FILTER (WHERE kind = ?), 0) AS paid, COALESCE(SUM(amount) FILTER (WHERE kind = ?), 0) AS refunded",
+			models.LedgerEntryPayment, models.LedgerEntryRefund).
+		Scan(&totals).Error
+	if err != nil {
+		return totals, fmt.Errorf("failed to total ledger: %w", err)
+	}
+	return totals, nil
+}
+
+func (r *PaymentLedgerRepository) GetInvoiceIDByPaymentIntent(ctx context.Context, paymentIntentID string) (*uuid.UUID, error) {
+	var entry models.PaymentLedgerEntry
+	err := r.db.WithContext(ctx).
+		Where("payment_intent_id = ? AND kind = ?", paymentIntentID, models.LedgerEntryPayment).
+		First(&entry).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &entry.InvoiceID, nil
+}
+
+func (r *PaymentLedgerRepository) GetLedgerEntriesByInvoiceID(ctx context.Context, invoiceID uuid.UUID) ([]models.PaymentLedgerEntry, error) {
+	if err := checkInvoiceScope(ctx, r.db, invoiceID); err != nil {
+		return nil, err
+	}
+
+	var entries []models.PaymentLedgerEntry
+	err := r.db.WithContext(ctx).
+		Where("invoice_id = ?", invoiceID).
+		Order("occurred_at, created_at").
+		Find(&entries).Error
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+func (r *PaymentLedgerRepository) GetRefundablePayments(ctx context.Context, invoiceID uuid.UUID) ([]models.RefundablePayment, error) {
+	if err := checkInvoiceScope(ctx, r.db, invoiceID); err != nil {
+		return nil, err
+	}
+
+	var payments []models.RefundablePayment
+	err := r.db.WithContext(ctx).Model(&models.PaymentLedgerEntry{}).
+		Select("payment_intent_id, "+
+			"SUM(CASE WHEN kind = ? THEN amount ELSE -amount END) AS refundable, "+
+			"MAX(occurred_at) FILTER (WHERE kind = ?) AS last_paid_at",
+			models.LedgerEntryPayment, models.LedgerEntryPayment).
+		Where("invoice_id = ? AND payment_intent_id <> ''", invoiceID).
+		Group("payment_intent_id").
+		Having("SUM(CASE WHEN kind = ? THEN amount ELSE -amount END) > 0", models.LedgerEntryPayment).
+		Scan(&payments).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get refundable payments: %w", err)
+	}
+	return payments, nil
+}
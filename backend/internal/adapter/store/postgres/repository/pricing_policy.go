@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"backend/internal/core/models"
+	ports "backend/internal/core/ports"
+)
+
+type PricingPolicyRepository struct {
+	db *gorm.DB
+}
+
+func NewPricingPolicyRepository(db *gorm.DB) ports.PricingPolicyRepository {
+	return &PricingPolicyRepository{db: db}
+}
+
+func (r *PricingPolicyRepository) CreatePricingPolicy(ctx context.Context, policy *models.PricingPolicy) error {
+	if err := checkSharedBranchScope(ctx, policy.BranchID); err != nil {
+		return err
+	}
+	if policy.UUID == uuid.Nil {
+		policy.UUID = uuid.New()
+	}
+
+	return r.db.WithContext(ctx).Create(policy).Error
+}
+
+func (r *PricingPolicyRepository) GetPricingPolicyByID(ctx context.Context, id uuid.UUID) (*models.PricingPolicy, error) {
+	var policy models.PricingPolicy
+	if err := r.db.WithContext(ctx).Scopes(sharedOrBranchInScope(ctx, "branch_id")).Where("uuid = ?", id).First(&policy).Error; err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+func (r *PricingPolicyRepository) GetPricingPolicies(ctx context.Context, branchID *uuid.UUID) ([]models.PricingPolicy, error) {
+	if branchID != nil {
+		if err := checkBranchScope(ctx, *branchID); err != nil {
+			return nil, err
+		}
+	}
+
+	var policies []models.PricingPolicy
+	query := r.db.WithContext(ctx).Scopes(sharedOrBranchInScope(ctx, "branch_id")).Order("effective_from DESC")
+	if branchID != nil {
+		query = query.Where("branch_id = ?", *branchID)
+	}
+	if err := query.Find(&policies).Error; err != nil {
+		return nil, err
+	}
+	return policies, nil
+}
+
+func (r *PricingPolicyRepository) UpdatePricingPolicy(ctx context.Context, policy *models.PricingPolicy) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// branch users change their own branch's policies, the shared ones are left to superadmins
+		var existing models.PricingPolicy
+		if err := tx.Where("uuid = ?", policy.UUID).First(&existing).Error; err != nil {
+			return err
+		}
+		if err := checkSharedBranchScope(ctx, existing.BranchID); err != nil {
+			return err
+		}
+		if err := checkSharedBranchScope(ctx, policy.BranchID); err != nil {
+			return err
+		}
+		return tx.Save(policy).Error
+	})
+}
+
+func (r *PricingPolicyRepository) DeletePricingPolicy(ctx context.Context, id uuid.UUID) error {
+	return deleteInBranchScope(ctx, r.db, &models.PricingPolicy{}, id)
+}
+
+func (r *PricingPolicyRepository) GetApplicablePricingPolicy(ctx context.Context, branchID uuid.UUID, clientEmail string, at time.Time) (*models.PricingPolicy, error) {
+	var policy models.PricingPolicy
+	err := r.db.WithContext(ctx).
+		Where("branch_id = ? OR branch_id IS NULL", branchID).
+		Where("client_email = '' OR LOWER(client_email) = LOWER(?)", clientEmail).
+		Where("effective_from <= ? AND (effective_to IS NULL OR effective_to > ?)", at, at).
+		Order("client_email <> '' DESC").
+		Order("branch_id IS NOT NULL DESC").
+		Order("effective_from DESC").
+		First(&policy).Error
+	if err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"backend/internal/core/models"
+	ports "backend/internal/core/ports"
+)
+
+type PromoCodeRepository struct {
+	db *gorm.DB
+}
+
+func NewPromoCodeRepository(db *gorm.DB) ports.PromoCodeRepository {
+	return &PromoCodeRepository{db: db}
+}
+
+func (r *PromoCodeRepository) CreatePromoCode(ctx context.Context, promoCode *models.PromoCode) error {
+	if err := checkSharedBranchScope(ctx, promoCode.BranchID); err != nil {
+		return err
+	}
+	if promoCode.UUID == uuid.Nil {
+		promoCode.UUID = uuid.New()
+	}
+
+	return r.db.WithContext(ctx).Create(promoCode).Error
+}
+
+func (r *PromoCodeRepository) GetPromoCodeByID(ctx context.Context, id uuid.UUID) (*models.PromoCode, error) {
+	var promoCode models.PromoCode
+	if err := r.db.WithContext(ctx).Scopes(sharedOrBranchInScope(ctx, "branch_id")).Where("uuid = ?", id).First(&promoCode).Error; err != nil {
+		return nil, err
+	}
+	return &promoCode, nil
+}
+
+// codes are matched case insensitively so clients don't have to type them exactly
+func (r *PromoCodeRepository) GetPromoCodeByCode(ctx context.Context, code string) (*models.PromoCode, error) {
+	var promoCode models.PromoCode
+	err := r.db.WithContext(ctx).
+		Scopes(sharedOrBranchInScope(ctx, "branch_id")).
+		Where("UPPER(code) = ?", strings.ToUpper(code)).
+		First(&promoCode).Error
+	if err != nil {
+		return nil, err
+	}
+	return &promoCode, nil
+}
+
+func (r *PromoCodeRepository) GetPromoCodes(ctx context.Context) ([]models.PromoCode, error) {
+	var promoCodes []models.PromoCode
+	if err := r.db.WithContext(ctx).Scopes(sharedOrBranchInScope(ctx, "branch_id")).Order("created_at DESC").Find(&promoCodes).Error; err != nil {
+		return nil, err
+	}
+	return promoCodes, nil
+}
+
+func (r *PromoCodeRepository) UpdatePromoCode(ctx context.Context, promoCode *models.PromoCode) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// branch users change their own branch's codes, the shared ones are left to superadmins
+		var existing models.PromoCode
+		if err := tx.Where("uuid = ?", promoCode.UUID).First(&existing).Error; err != nil {
+			return err
+		}
+		if err := checkSharedBranchScope(ctx, existing.BranchID); err != nil {
+			return err
+		}
+		if err := checkSharedBranchScope(ctx, promoCode.BranchID); err != nil {
+			return err
+		}
+		return tx.Save(promoCode).Error
+	})
+}
+
+func (r *PromoCodeRepository) DeletePromoCode(ctx context.Context, id uuid.UUID) error {
+	return deleteInBranchScope(ctx, r.db, &models.PromoCode{}, id)
+}
+
+// redeemPromoCode counts a redemption, it fails if the code has hit its redemption limit
+func redeemPromoCode(tx *gorm.DB, code string) error {
+	// the limit is checked in the UPDATE itself so two invoices can't both take the last redemption
+	result := tx.Model(&models.PromoCode{}).
+		Where("UPPER(code) = ? AND (max_redemptions = 0 OR redemptions < max_redemptions)", strings.ToUpper(code)).
+		UpdateColumn("redemptions", gorm.Expr("redemptions + 1"))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("promo code has reached its redemption limit")
+	}
+	return nil
+}
+
+// releasePromoCode gives back a redemption when the code comes off an invoice
+func releasePromoCode(tx *gorm.DB, code string) error {
+	return tx.Model(&models.PromoCode{}).
+		Where("UPPER(code) = ? AND redemptions > 0", strings.ToUpper(code)).
+		UpdateColumn("redemptions", gorm.Expr("redemptions - 1")).Error
+}
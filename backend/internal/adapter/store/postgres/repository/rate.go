@@ -0,0 +1,231 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"backend/internal/core/models"
+	ports "backend/internal/core/ports"
+)
+
+const dateFormat = "2006-01-02"
+
+type RateRepository struct {
+	db *gorm.DB
+}
+
+func NewRateRepository(db *gorm.DB) ports.RateRepository {
+	return &RateRepository{db: db}
+}
+
+// CreateRates adds rates to a branch's rate card in one transaction. A new rate closes the
+// open-ended rate it supersedes, any other overlap with existing history is rejected.
+func (r *RateRepository) CreateRates(ctx context.Context, rates []models.Rate) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for i := range rates {
+			if rates[i].UUID == uuid.Nil {
+				rates[i].UUID = uuid.New()
+			}
+			if err := checkBranchScope(ctx, rates[i].BranchID); err != nil {
+				return err
+			}
+
+			// close the open-ended rate that was in force when this one starts
+			err := tx.Model(&models.Rate{}).
+				Where("branch_id = ? AND LOWER(staff_type) = LOWER(?)", rates[i].BranchID, rates[i].StaffType).
+				Where("effective_from < ? AND effective_to IS NULL", rates[i].EffectiveFrom.Format(dateFormat)).
+				Update("effective_to", rates[i].EffectiveFrom.Format(dateFormat)).Error
+			if err != nil {
+				return fmt.Errorf("failed to close previous rate: %w", err)
+			}
+
+			if err := checkRateOverlap(tx, &rates[i]); err != nil {
+				return err
+			}
+
+			if err := tx.Create(&rates[i]).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *RateRepository) GetRateByID(ctx context.Context, id uuid.UUID) (*models.Rate, error) {
+	var rate models.Rate
+	if err := r.db.WithContext(ctx).Scopes(branchInScope(ctx, "branch_id")).Where("uuid = ?", id).First(&rate).Error; err != nil {
+		return nil, err
+	}
+	return &rate, nil
+}
+
+func (r *RateRepository) GetRatesByBranchID(ctx context.Context, branchID uuid.UUID, staffType string) ([]models.Rate, error) {
+	if err := checkBranchScope(ctx, branchID); err != nil {
+		return nil, err
+	}
+
+	var rates []models.Rate
+	query := r.db.WithContext(ctx).Where("branch_id = ?", branchID)
+	if staffType != "" {
+		query = query.Where("LOWER(staff_type) = LOWER(?)", staffType)
+	}
+	if err := query.Order("staff_type").Order("effective_from DESC").Find(&rates).Error; err != nil {
+		return nil, err
+	}
+	return rates, nil
+}
+
+func (r *RateRepository) UpdateRate(ctx context.Context, rate *models.Rate) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// the rate has to be on the caller's branch card both before and after the change
+		var existing models.Rate
+		if err := tx.Scopes(branchInScope(ctx, "branch_id")).Where("uuid = ?", rate.UUID).First(&existing).Error; err != nil {
+			return err
+		}
+		if err := checkBranchScope(ctx, rate.BranchID); err != nil {
+			return err
+		}
+
+		if err := checkRateOverlap(tx, rate); err != nil {
+			return err
+		}
+		return tx.Save(rate).Error
+	})
+}
+
+// DeleteRate fails with ErrRateNotFound when there is no such rate, and with
+// ErrOutOfBranchScope when it is on another branch's card
+func (r *RateRepository) DeleteRate(ctx context.Context, id uuid.UUID) error {
+	result := r.db.WithContext(ctx).Scopes(branchInScope(ctx, "branch_id")).Where("uuid = ?", id).Delete(&models.Rate{})
+	if result.Error != nil || result.RowsAffected > 0 {
+		return result.Error
+	}
+
+	var count int64
+	if err := r.db.WithContext(ctx).Model(&models.Rate{}).Where("uuid = ?", id).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return models.ErrOutOfBranchScope
+	}
+	return models.ErrRateNotFound
+}
+
+func (r *RateRepository) GetBranchRate(ctx context.Context, branchID uuid.UUID, staffType string, at time.Time) (*models.Rate, error) {
+	var rate models.Rate
+	err := r.db.WithContext(ctx).
+		Where("branch_id = ? AND LOWER(staff_type) = LOWER(?)", branchID, staffType).
+		Where("effective_from <= ? AND (effective_to IS NULL OR effective_to > ?)", at.Format(dateFormat), at.Format(dateFormat)).
+		Order("effective_from DESC").
+		First(&rate).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &rate, nil
+}
+
+func (r *RateRepository) GetRegionalDefaultRate(ctx context.Context, branchID uuid.UUID, staffType string) (*models.DefaultRate, error) {
+	var rate models.DefaultRate
+	err := r.db.WithContext(ctx).
+		Joins("JOIN branches ON branches.region = default_rates.region").
+		Where("branches.uuid = ? AND default_rates.region <> ''", branchID).
+		Where("LOWER(default_rates.staff_type) = LOWER(?)", staffType).
+		First(&rate).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &rate, nil
+}
+
+func (r *RateRepository) GetGlobalDefaultRate(ctx context.Context, staffType string) (*models.DefaultRate, error) {
+	var rate models.DefaultRate
+	err := r.db.WithContext(ctx).
+		Where("region = '' AND LOWER(staff_type) = LOWER(?)", staffType).
+		First(&rate).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &rate, nil
+}
+
+func (r *RateRepository) GetDefaultRates(ctx context.Context, region *string) ([]models.DefaultRate, error) {
+	var rates []models.DefaultRate
+	query := r.db.WithContext(ctx).Order("region").Order("staff_type")
+	if region != nil {
+		query = query.Where("region = ?", *region)
+	}
+	if err := query.Find(&rates).Error; err != nil {
+		return nil, err
+	}
+	return rates, nil
+}
+
+func (r *RateRepository) GetDefaultRateByID(ctx context.Context, id uuid.UUID) (*models.DefaultRate, error) {
+	var rate models.DefaultRate
+	if err := r.db.WithContext(ctx).Where("uuid = ?", id).First(&rate).Error; err != nil {
+		return nil, err
+	}
+	return &rate, nil
+}
+
+func (r *RateRepository) CreateDefaultRate(ctx context.Context, rate *models.DefaultRate) error {
+	if err := checkSharedBranchScope(ctx, nil); err != nil {
+		return err
+	}
+	if rate.UUID == uuid.Nil {
+		rate.UUID = uuid.New()
+	}
+	return r.db.WithContext(ctx).Create(rate).Error
+}
+
+func (r *RateRepository) UpdateDefaultRate(ctx context.Context, rate *models.DefaultRate) error {
+	if err := checkSharedBranchScope(ctx, nil); err != nil {
+		return err
+	}
+	return r.db.WithContext(ctx).
+		Model(rate).
+		Select("region", "staff_type", "hourly_rate").
+		Updates(rate).Error
+}
+
+func (r *RateRepository) DeleteDefaultRate(ctx context.Context, id uuid.UUID) error {
+	if err := checkSharedBranchScope(ctx, nil); err != nil {
+		return err
+	}
+	return r.db.WithContext(ctx).Where("uuid = ?", id).Delete(&models.DefaultRate{}).Error
+}
+
+// checkRateOverlap makes sure no other rate for the same branch and staff type is in force
+// during any part of the rate's effective period. Staff types match regardless of case, the
+// same way rates are looked up.
+func checkRateOverlap(tx *gorm.DB, rate *models.Rate) error {
+	query := tx.Model(&models.Rate{}).
+		Where("branch_id = ? AND LOWER(staff_type) = LOWER(?) AND uuid <> ?", rate.BranchID, rate.StaffType, rate.UUID).
+		Where("effective_to IS NULL OR effective_to > ?", rate.EffectiveFrom.Format(dateFormat))
+	if rate.EffectiveTo != nil {
+		query = query.Where("effective_from < ?", rate.EffectiveTo.Format(dateFormat))
+	}
+
+	var count int64
+	if err := query.Count(&count).Error; err != nil {
+		return fmt.Errorf("failed to check rate history: %w", err)
+	}
+	if count > 0 {
+		return fmt.Errorf("a %s rate is already in force on %s", rate.StaffType, rate.EffectiveFrom.Format(dateFormat))
+	}
+	return nil
+}
@@ -8,6 +8,7 @@ import (
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 
 	"backend/internal/core/models"
 	ports "backend/internal/core/ports"
@@ -26,13 +27,22 @@ func (r *RequestRepository) CreateRequest(ctx context.Context, request *models.R
 		request.UUID = uuid.New()
 	}
 
-	request.DateRequested = time.Now().UTC()
+	if request.DateRequested.IsZero() {
+		request.DateRequested = time.Now().UTC()
+	}
 
 	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
 		if err := tx.Create(request).Error; err != nil {
 			return err
 		}
 
+		// requests are billed to the client with the same email
+		if models.NormalizeClientEmail(request.Email) != "" {
+			if _, err := linkClient(tx, request); err != nil {
+				return err
+			}
+		}
+
 		// Create staff requirements
 		for _, staffReq := range staff {
 			staffReq.RequestID = request.UUID
@@ -58,13 +68,13 @@ func (r *RequestRepository) CreateRequest(ctx context.Context, request *models.R
 
 func (r *RequestRepository) GetRequestById(ctx context.Context, id uuid.UUID) (models.Request, error) {
 	var request models.Request
-	err := r.db.WithContext(ctx).Where("uuid = ?", id).First(&request).Error
+	err := r.db.WithContext(ctx).Scopes(requestsInScope(ctx, "uuid")).Where("uuid = ?", id).First(&request).Error
 	return request, err
 }
 
 func (r *RequestRepository) GetAllRequests(ctx context.Context) ([]models.Request, error) {
 	var requests []models.Request
-	err := r.db.WithContext(ctx).Find(&requests).Error
+	err := r.db.WithContext(ctx).Scopes(requestsInScope(ctx, "uuid")).Find(&requests).Error
 	return requests, err
 }
 
@@ -73,11 +83,16 @@ func (r *RequestRepository) GetRequestsByEventId(ctx context.Context, eventId uu
 	err := r.db.WithContext(ctx).
 		Joins("JOIN events ON events.request_id = requests.uuid").
 		Where("events.uuid = ?", eventId).
+		Scopes(requestsInScope(ctx, "requests.uuid")).
 		Find(&requests).Error
 	return requests, err
 }
 
 func (r *RequestRepository) GetRequestsByBranchID(ctx context.Context, branchID uuid.UUID) ([]models.Request, error) {
+	if err := checkBranchScope(ctx, branchID); err != nil {
+		return nil, err
+	}
+
 	var requests []models.Request
 	err := r.db.WithContext(ctx).Model(&models.Request{}).
 		Joins("JOIN branches ON branches.uuid = requests.closest_branch_id").
@@ -93,9 +108,106 @@ func (r *RequestRepository) GetRequestsByBranchID(ctx context.Context, branchID
 }
 
 func (r *RequestRepository) UpdateRequest(ctx context.Context, request *models.Request) error {
-	return r.db.WithContext(ctx).Save(request).Error
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// branch users can't edit another branch's request or hand theirs to another branch
+		if err := checkRequestScope(ctx, tx, request.UUID); err != nil {
+			return err
+		}
+		if err := checkBranchScope(ctx, request.ClosestBranchID); err != nil {
+			return err
+		}
+
+		// the client follows the email, a changed address moves the request to that client
+		if models.NormalizeClientEmail(request.Email) != "" {
+			if _, err := linkClient(tx, request); err != nil {
+				return err
+			}
+		}
+		// cancellation only changes through CancelRequest and its refunds
+		return tx.Omit("cancelled_at", "cancellation_refund_due").Save(request).Error
+	})
 }
 
 func (r *RequestRepository) DeleteRequest(ctx context.Context, id uuid.UUID) error {
-	return r.db.WithContext(ctx).Where("uuid = ?", id).Delete(&models.Request{}).Error
+	return r.db.WithContext(ctx).Scopes(requestsInScope(ctx, "uuid")).Where("uuid = ?", id).Delete(&models.Request{}).Error
+}
+
+func (r *RequestRepository) CancelRequest(ctx context.Context, id uuid.UUID, cancelledAt time.Time, refundDue models.Money) (bool, error) {
+	cancelled := false
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// only the first cancellation wins, so a retried call can't refund twice
+		result := tx.Model(&models.Request{}).
+			Where("uuid = ? AND cancelled_at IS NULL", id).
+			Scopes(requestsInScope(ctx, "uuid")).
+			Updates(map[string]interface{}{
+				"cancelled_at":            cancelledAt,
+				"cancellation_refund_due": refundDue,
+			})
+		if result.Error != nil {
+			return fmt.Errorf("failed to cancel request %s: %w", id, result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+
+		var invoices []models.Invoice
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("request_id = ?", id).
+			Find(&invoices).Error
+		if err != nil {
+			return fmt.Errorf("failed to get invoices for request %s: %w", id, err)
+		}
+
+		// a fully refunded invoice is already final and stays as it is
+		for _, invoice := range invoices {
+			if !models.CanTransitionInvoice(invoice.Status, models.InvoiceStatusVoid) {
+				continue
+			}
+
+			err := tx.Model(&models.Invoice{}).
+				Where("uuid = ?", invoice.UUID).
+				Updates(map[string]interface{}{
+					"status":  models.InvoiceStatusVoid,
+					"balance": 0,
+				}).Error
+			if err != nil {
+				return fmt.Errorf("failed to void invoice %s: %w", invoice.UUID, err)
+			}
+
+			err = recordInvoiceStatusChange(tx, &models.InvoiceStatusChange{
+				InvoiceID:  invoice.UUID,
+				FromStatus: invoice.Status,
+				ToStatus:   models.InvoiceStatusVoid,
+				Actor:      models.ActorFromContext(ctx),
+				Reason:     "request cancelled",
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		// the request's event is called off with it, one that already ran stays completed
+		err = tx.Model(&models.Event{}).
+			Where("request_id = ? AND status IN ?", id, []string{models.EventStatusScheduled, models.EventStatusInProgress}).
+			Update("status", models.EventStatusCancelled).Error
+		if err != nil {
+			return fmt.Errorf("failed to cancel event for request %s: %w", id, err)
+		}
+
+		cancelled = true
+		return nil
+	})
+
+	return cancelled, err
+}
+
+func (r *RequestRepository) SettleCancellationRefund(ctx context.Context, id uuid.UUID, refunded models.Money) error {
+	err := r.db.WithContext(ctx).Model(&models.Request{}).
+		Where("uuid = ?", id).
+		Update("cancellation_refund_due", gorm.Expr("GREATEST(cancellation_refund_due - ?, 0)", refunded)).Error
+	if err != nil {
+		return fmt.Errorf("failed to settle cancellation refund for request %s: %w", id, err)
+	}
+	return nil
 }
@@ -0,0 +1,116 @@
+package repository
+
+import (
+	"backend/internal/core/models"
+	ports "backend/internal/core/ports"
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ShiftRepository struct {
+	db *gorm.DB
+}
+
+func NewShiftRepository(db *gorm.DB) ports.ShiftRepository {
+	return &ShiftRepository{db: db}
+}
+
+func (r *ShiftRepository) CreateShift(ctx context.Context, shift *models.Shift) error {
+	if err := r.checkEventScope(ctx, shift.EventID); err != nil {
+		return err
+	}
+	if shift.UUID == uuid.Nil {
+		shift.UUID = uuid.New()
+	}
+	return r.db.WithContext(ctx).Omit(clause.Associations).Create(shift).Error
+}
+
+func (r *ShiftRepository) GetShiftByID(ctx context.Context, id uuid.UUID) (*models.Shift, error) {
+	var shift models.Shift
+	err := r.db.WithContext(ctx).Scopes(eventsInScope(ctx, "event_id")).Where("uuid = ?", id).First(&shift).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get shift %s: %w", id, err)
+	}
+	return &shift, nil
+}
+
+func (r *ShiftRepository) GetShiftsByEventID(ctx context.Context, eventID uuid.UUID) ([]models.Shift, error) {
+	var shifts []models.Shift
+	err := r.db.WithContext(ctx).
+		Scopes(eventsInScope(ctx, "event_id"), orderedShifts).
+		Where("event_id = ?", eventID).
+		Find(&shifts).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get shifts for event %s: %w", eventID, err)
+	}
+	return shifts, nil
+}
+
+func (r *ShiftRepository) UpdateShift(ctx context.Context, shift *models.Shift) error {
+	// a shift stays with its event, only its window changes
+	result := r.db.WithContext(ctx).Model(&models.Shift{}).
+		Scopes(eventsInScope(ctx, "event_id")).
+		Where("uuid = ?", shift.UUID).
+		Updates(map[string]interface{}{
+			"date":         shift.Date,
+			"start_time":   shift.StartTime,
+			"end_time":     shift.EndTime,
+			"staff_needed": shift.StaffNeeded,
+		})
+	if result.Error != nil {
+		return fmt.Errorf("failed to update shift %s: %w", shift.UUID, result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return models.ErrOutOfBranchScope
+	}
+	return nil
+}
+
+func (r *ShiftRepository) DeleteShift(ctx context.Context, id uuid.UUID) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var count int64
+		err := tx.Model(&models.Shift{}).Scopes(eventsInScope(ctx, "event_id")).Where("uuid = ?", id).Count(&count).Error
+		if err != nil {
+			return fmt.Errorf("failed to check shift %s: %w", id, err)
+		}
+		if count == 0 {
+			return models.ErrOutOfBranchScope
+		}
+
+		if err := tx.Where("shift_id = ?", id).Delete(&models.ShiftAssignment{}).Error; err != nil {
+			return fmt.Errorf("failed to delete assignments for shift %s: %w", id, err)
+		}
+		if err := tx.Where("uuid = ?", id).Delete(&models.Shift{}).Error; err != nil {
+			return fmt.Errorf("failed to delete shift %s: %w", id, err)
+		}
+		return nil
+	})
+}
+
+// checkEventScope fails with ErrOutOfBranchScope when the event is outside the context's branch
+func (r *ShiftRepository) checkEventScope(ctx context.Context, eventID uuid.UUID) error {
+	if _, ok := models.BranchScope(ctx); !ok {
+		return nil
+	}
+
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.Event{}).
+		Scopes(branchInScope(ctx, "branch_id")).
+		Where("uuid = ?", eventID).
+		Count(&count).Error
+	if err != nil {
+		return fmt.Errorf("failed to check event %s: %w", eventID, err)
+	}
+	if count == 0 {
+		return models.ErrOutOfBranchScope
+	}
+	return nil
+}
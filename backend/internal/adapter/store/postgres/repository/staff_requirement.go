@@ -24,6 +24,10 @@ func (r *StaffRequirementRepository) CreateStaffRequirement(ctx context.Context,
 		staffRequirement.UUID = uuid.New()
 	}
 
+	if err := checkRequestScope(ctx, r.db, staffRequirement.RequestID); err != nil {
+		return err
+	}
+
 	// log.Printf("Creating staff requirement: %+v", staffRequirement)
 
 	err := r.db.WithContext(ctx).Create(staffRequirement).Error
@@ -38,38 +42,51 @@ func (r *StaffRequirementRepository) CreateStaffRequirement(ctx context.Context,
 
 func (r *StaffRequirementRepository) GetStaffRequirementById(ctx context.Context, id uuid.UUID) (models.StaffRequirement, error) {
 	var staffRequirement models.StaffRequirement
-	err := r.db.WithContext(ctx).Where("uuid = ?", id).First(&staffRequirement).Error
+	err := r.db.WithContext(ctx).Scopes(requestsInScope(ctx, "request_id")).Where("uuid = ?", id).First(&staffRequirement).Error
 	return staffRequirement, err
 }
 
 func (r *StaffRequirementRepository) GetAllStaffRequirementsByRequestID(ctx context.Context, requestID uuid.UUID) ([]models.StaffRequirement, error) {
 	var staffRequirements []models.StaffRequirement
-	err := r.db.WithContext(ctx).Where("request_id = ?", requestID).Find(&staffRequirements).Error
+	err := r.db.WithContext(ctx).Preload("Charges").Scopes(requestsInScope(ctx, "request_id")).Where("request_id = ?", requestID).Find(&staffRequirements).Error
 	return staffRequirements, err
 }
 
 func (r *StaffRequirementRepository) UpdateStaffRequirement(ctx context.Context, staffRequirement *models.StaffRequirement) error {
-	return r.db.WithContext(ctx).Save(staffRequirement).Error // update using GORM Save method
-}
+	// the line has to be in the caller's branch both before and after the change
+	var existing models.StaffRequirement
+	if err := r.db.WithContext(ctx).Scopes(requestsInScope(ctx, "request_id")).Where("uuid = ?", staffRequirement.UUID).First(&existing).Error; err != nil {
+		return err
+	}
+	if err := checkRequestScope(ctx, r.db, staffRequirement.RequestID); err != nil {
+		return err
+	}
 
-func (r *StaffRequirementRepository) DeleteStaffRequirement(ctx context.Context, id uuid.UUID) error {
-	return r.db.WithContext(ctx).Delete(&models.StaffRequirement{}, id).Error // delete using GORM Delete method
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Charges").Save(staffRequirement).Error; err != nil {
+			return err
+		}
+
+		// the line was repriced, its old charges are replaced rather than added to
+		if err := tx.Where("staff_requirement_id = ?", staffRequirement.UUID).Delete(&models.StaffRequirementCharge{}).Error; err != nil {
+			return err
+		}
+		if len(staffRequirement.Charges) == 0 {
+			return nil
+		}
+		for i := range staffRequirement.Charges {
+			staffRequirement.Charges[i].StaffRequirementID = staffRequirement.UUID
+		}
+		return tx.Create(&staffRequirement.Charges).Error
+	})
 }
 
-func (r *StaffRequirementRepository) GetRate(ctx context.Context, staffType string, location string) (float64, error) {
-	var rate models.Rate
-	err := r.db.WithContext(ctx).
-		Where("staff_type = ? AND branch_id = (SELECT closest_branch_id FROM requests WHERE event_location = ? LIMIT 1)",
-			staffType, location).
-		First(&rate).Error
-	if err != nil {
-		return 0, err
-	}
-	return rate.HourlyRate, nil
+func (r *StaffRequirementRepository) DeleteStaffRequirement(ctx context.Context, id uuid.UUID) error {
+	return r.db.WithContext(ctx).Scopes(requestsInScope(ctx, "request_id")).Delete(&models.StaffRequirement{}, id).Error // delete using GORM Delete method
 }
 
 func (r *StaffRequirementRepository) GetStaffRequirementByRequestID(ctx context.Context, id uuid.UUID) (models.StaffRequirement, error) {
 	var staffRequirement models.StaffRequirement
-	err := r.db.WithContext(ctx).Where("request_id = ?", id).First(&staffRequirement).Error
+	err := r.db.WithContext(ctx).Scopes(requestsInScope(ctx, "request_id")).Where("request_id = ?", id).First(&staffRequirement).Error
 	return staffRequirement, err
 }
@@ -4,27 +4,41 @@ import (
 	"backend/internal/core/models"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"os"
+	"time"
 
 	ports "backend/internal/core/ports"
 
+	"github.com/google/uuid"
 	"github.com/stripe/stripe-go/v82"
+	"github.com/stripe/stripe-go/v82/charge"
 	"github.com/stripe/stripe-go/v82/checkout/session"
+	"github.com/stripe/stripe-go/v82/coupon"
+	"github.com/stripe/stripe-go/v82/customer"
 	"github.com/stripe/stripe-go/v82/paymentintent"
 	"github.com/stripe/stripe-go/v82/refund"
 	"github.com/stripe/stripe-go/v82/webhook"
 	"gorm.io/gorm"
 )
 
+// charge_type metadata on payment intents made without the client present, so the webhook can
+// tell them from checkout payments, which it records from the checkout session instead
+const offSessionChargeType = "off_session"
+
 type StripeRepository struct {
-	db *gorm.DB
+	db      *gorm.DB
+	ledger  ports.PaymentLedgerRepository
+	clients ports.ClientRepository
 }
 
-func NewStripeRepository(db *gorm.DB) ports.StripeRepository {
+func NewStripeRepository(db *gorm.DB, ledger ports.PaymentLedgerRepository, clients ports.ClientRepository) ports.StripeRepository {
 	return &StripeRepository{
-		db: db,
+		db:      db,
+		ledger:  ledger,
+		clients: clients,
 	}
 }
 
@@ -35,13 +49,15 @@ func (r *StripeRepository) CreatePaymentIntent(ctx context.Context, invoice *mod
 	}
 
 	params := &stripe.PaymentIntentParams{
-		Amount:   stripe.Int64(int64(paymentAmount * 100)),
+		Amount:   stripe.Int64(paymentAmount.Cents()),
 		Currency: stripe.String(string(stripe.CurrencyUSD)),
 		AutomaticPaymentMethods: &stripe.PaymentIntentAutomaticPaymentMethodsParams{
 			Enabled: stripe.Bool(true),
 		},
+		Description: stripe.String("Invoice " + invoice.Number()),
 		Metadata: map[string]string{
-			"invoice_id": invoice.UUID.String(),
+			"invoice_id":     invoice.UUID.String(),
+			"invoice_number": invoice.Number(),
 		},
 	}
 
@@ -60,21 +76,33 @@ func (r *StripeRepository) CreatePaymentIntent(ctx context.Context, invoice *mod
 
 func (r *StripeRepository) CreateCheckoutSession(ctx context.Context, invoice *models.Invoice, staffRequirements []models.StaffRequirement) (string, error) {
 	var lineItems []*stripe.CheckoutSessionLineItemParams
+	var discounts []*stripe.CheckoutSessionDiscountParams
 
 	checkoutAmount := invoice.Balance
 	if checkoutAmount <= 0 {
 		checkoutAmount = invoice.Amount
 	}
 
-	if invoice.Balance > 0 && invoice.AmountPaid > 0 {
+	// deposits, change orders and a final invoice reduced by them don't add up line by line,
+	// so they're charged as a single line for the amount due
+	partial := invoice.Kind != models.InvoiceKindFinal || invoice.PriorInvoiced > 0
+
+	if (invoice.Balance > 0 && invoice.AmountPaid > 0) || partial {
+		name := "Remaining Balance"
+		description := fmt.Sprintf("Additional charges for Invoice #%s", invoice.Number())
+		if invoice.AmountPaid == 0 {
+			name = invoice.KindLabel()
+			description = fmt.Sprintf("%s, Invoice #%s", invoice.KindLabel(), invoice.Number())
+		}
+
 		balanceItem := &stripe.CheckoutSessionLineItemParams{
 			PriceData: &stripe.CheckoutSessionLineItemPriceDataParams{
 				Currency: stripe.String(string(stripe.CurrencyUSD)),
 				ProductData: &stripe.CheckoutSessionLineItemPriceDataProductDataParams{
-					Name:        stripe.String("Remaining Balance"),
-					Description: stripe.String(fmt.Sprintf("Additional charges for Invoice #%s", invoice.RequestID.String())),
+					Name:        stripe.String(name),
+					Description: stripe.String(description),
 				},
-				UnitAmount: stripe.Int64(int64(checkoutAmount * 100)),
+				UnitAmount: stripe.Int64(checkoutAmount.Cents()),
 			},
 			Quantity: stripe.Int64(1),
 		}
@@ -89,13 +117,33 @@ func (r *StripeRepository) CreateCheckoutSession(ctx context.Context, invoice *m
 						Name:        stripe.String("Event Staff Services"),
 						Description: stripe.String("Staff services for your event"),
 					},
-					UnitAmount: stripe.Int64(int64(invoice.Subtotal * 100)),
+					UnitAmount: stripe.Int64(invoice.Subtotal.Cents()),
 				},
 				Quantity: stripe.Int64(1),
 			}
 			lineItems = append(lineItems, staffServicesItem)
 		}
 
+		// checkout line items can't be negative, so the discount goes on as a one-off coupon
+		// which Stripe shows as its own line under the subtotal
+		if invoice.DiscountAmount > 0 {
+			discountName := "Discount"
+			if invoice.PromoCode != "" {
+				discountName = fmt.Sprintf("Discount (%s)", invoice.PromoCode)
+			}
+
+			discountCoupon, err := coupon.New(&stripe.CouponParams{
+				Name:      stripe.String(discountName),
+				AmountOff: stripe.Int64(invoice.DiscountAmount.Cents()),
+				Currency:  stripe.String(string(stripe.CurrencyUSD)),
+				Duration:  stripe.String(string(stripe.CouponDurationOnce)),
+			})
+			if err != nil {
+				return "", fmt.Errorf("failed to create discount coupon: %w", err)
+			}
+			discounts = append(discounts, &stripe.CheckoutSessionDiscountParams{Coupon: stripe.String(discountCoupon.ID)})
+		}
+
 		if invoice.ServiceFee > 0 {
 			serviceFeeItem := &stripe.CheckoutSessionLineItemParams{
 				PriceData: &stripe.CheckoutSessionLineItemPriceDataParams{
@@ -104,7 +152,7 @@ func (r *StripeRepository) CreateCheckoutSession(ctx context.Context, invoice *m
 						Name:        stripe.String("Service Fee"),
 						Description: stripe.String("Administrative service fee"),
 					},
-					UnitAmount: stripe.Int64(int64(invoice.ServiceFee * 100)),
+					UnitAmount: stripe.Int64(invoice.ServiceFee.Cents()),
 				},
 				Quantity: stripe.Int64(1),
 			}
@@ -117,9 +165,9 @@ func (r *StripeRepository) CreateCheckoutSession(ctx context.Context, invoice *m
 					Currency: stripe.String(string(stripe.CurrencyUSD)),
 					ProductData: &stripe.CheckoutSessionLineItemPriceDataProductDataParams{
 						Name:        stripe.String("Transaction Fee"),
-						Description: stripe.String("Payment processing fee (3.5%)"),
+						Description: stripe.String("Payment processing fee"),
 					},
-					UnitAmount: stripe.Int64(int64(invoice.TransactionFee * 100)),
+					UnitAmount: stripe.Int64(invoice.TransactionFee.Cents()),
 				},
 				Quantity: stripe.Int64(1),
 			}
@@ -132,9 +180,9 @@ func (r *StripeRepository) CreateCheckoutSession(ctx context.Context, invoice *m
 					Currency: stripe.String(string(stripe.CurrencyUSD)),
 					ProductData: &stripe.CheckoutSessionLineItemPriceDataProductDataParams{
 						Name:        stripe.String("Invoice Payment"),
-						Description: stripe.String(fmt.Sprintf("Payment for Invoice #%s", invoice.RequestID.String())),
+						Description: stripe.String(fmt.Sprintf("Payment for Invoice #%s", invoice.Number())),
 					},
-					UnitAmount: stripe.Int64(int64(checkoutAmount * 100)),
+					UnitAmount: stripe.Int64(checkoutAmount.Cents()),
 				},
 				Quantity: stripe.Int64(1),
 			}
@@ -143,19 +191,47 @@ func (r *StripeRepository) CreateCheckoutSession(ctx context.Context, invoice *m
 	}
 
 	if len(lineItems) == 0 {
-		return "", fmt.Errorf("no line items available for checkout session - invoice amount: %.2f, balance: %.2f", invoice.Amount, invoice.Balance)
+		return "", fmt.Errorf("no line items available for checkout session - invoice amount: %s, balance: %s", invoice.Amount, invoice.Balance)
+	}
+
+	// checkout is attached to the client's Stripe customer so repeat clients pay as themselves
+	// and can reuse the cards they saved
+	client, err := r.clients.GetClientForRequest(ctx, invoice.RequestID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get client: %w", err)
+	}
+	customerID, err := r.EnsureCustomer(ctx, client)
+	if err != nil {
+		return "", err
 	}
 
 	params := &stripe.CheckoutSessionParams{
-		SuccessURL:    stripe.String("http://localhost:8080/"),
-		Mode:          stripe.String(string(stripe.CheckoutSessionModePayment)),
-		LineItems:     lineItems,
-		CustomerEmail: stripe.String(invoice.Request.Email),
+		SuccessURL: stripe.String("http://localhost:8080/"),
+		Mode:       stripe.String(string(stripe.CheckoutSessionModePayment)),
+		LineItems:  lineItems,
+		Customer:   stripe.String(customerID),
+		Discounts:  discounts,
+		PaymentIntentData: &stripe.CheckoutSessionPaymentIntentDataParams{
+			Description: stripe.String("Invoice " + invoice.Number()),
+			Metadata: map[string]string{
+				"invoice_id":     invoice.UUID.String(),
+				"invoice_number": invoice.Number(),
+			},
+		},
 		Metadata: map[string]string{
-			"invoice_id": invoice.UUID.String(),
-			"request_id": invoice.RequestID.String(),
+			"invoice_id":     invoice.UUID.String(),
+			"invoice_number": invoice.Number(),
 		},
 	}
+	if client.OffSessionApproved {
+		// approved accounts agree to later charges, so the card is always kept for them
+		params.PaymentIntentData.SetupFutureUsage = stripe.String(string(stripe.PaymentIntentSetupFutureUsageOffSession))
+	} else {
+		// everyone else can choose to save their card for next time
+		params.SavedPaymentMethodOptions = &stripe.CheckoutSessionSavedPaymentMethodOptionsParams{
+			PaymentMethodSave: stripe.String(string(stripe.CheckoutSessionSavedPaymentMethodOptionsPaymentMethodSaveEnabled)),
+		}
+	}
 
 	session, err := session.New(params)
 	if err != nil {
@@ -165,20 +241,188 @@ func (r *StripeRepository) CreateCheckoutSession(ctx context.Context, invoice *m
 	return session.URL, nil
 }
 
-func (r *StripeRepository) RefundPayment(ctx context.Context, invoice *models.Invoice) (string, error) {
+// EnsureCustomer returns the client's Stripe customer, creating it the first time
+func (r *StripeRepository) EnsureCustomer(ctx context.Context, client *models.Client) (string, error) {
+	if client.StripeCustomerID != "" {
+		return client.StripeCustomerID, nil
+	}
+
+	params := &stripe.CustomerParams{
+		Email: stripe.String(client.Email),
+		Name:  stripe.String(client.Name),
+	}
+	if client.CompanyName != "" {
+		params.Name = stripe.String(client.CompanyName)
+	}
+	if client.PhoneNumber != "" {
+		params.Phone = stripe.String(client.PhoneNumber)
+	}
+	params.AddMetadata("client_id", client.UUID.String())
+
+	created, err := customer.New(params)
+	if err != nil {
+		return "", fmt.Errorf("failed to create Stripe customer for client %s: %w", client.UUID, err)
+	}
+
+	stored, err := r.clients.SetStripeCustomerID(ctx, client.UUID, created.ID)
+	if err != nil {
+		return "", err
+	}
+	if stored != created.ID {
+		log.Printf("Client %s already had Stripe customer %s, %s is unused.", client.UUID, stored, created.ID)
+	}
+	client.StripeCustomerID = stored
+	return stored, nil
+}
+
+// ListPaymentMethods lists the cards saved on a customer, most recent first
+func (r *StripeRepository) ListPaymentMethods(ctx context.Context, customerID string) ([]models.SavedPaymentMethod, error) {
+	params := &stripe.CustomerListPaymentMethodsParams{
+		Customer: stripe.String(customerID),
+		Type:     stripe.String(string(stripe.PaymentMethodTypeCard)),
+	}
+	params.Context = ctx
+
+	var methods []models.SavedPaymentMethod
+	iter := customer.ListPaymentMethods(params)
+	for iter.Next() {
+		method := iter.PaymentMethod()
+		saved := models.SavedPaymentMethod{ID: method.ID}
+		if method.Card != nil {
+			saved.Brand = string(method.Card.Brand)
+			saved.Last4 = method.Card.Last4
+			saved.ExpMonth = int(method.Card.ExpMonth)
+			saved.ExpYear = int(method.Card.ExpYear)
+		}
+		methods = append(methods, saved)
+	}
+	if err := iter.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list payment methods for customer %s: %w", customerID, err)
+	}
+	return methods, nil
+}
+
+// SetDefaultPaymentMethod makes the card the customer's default on the Stripe side too
+func (r *StripeRepository) SetDefaultPaymentMethod(ctx context.Context, customerID string, paymentMethodID string) error {
+	params := &stripe.CustomerParams{
+		InvoiceSettings: &stripe.CustomerInvoiceSettingsParams{
+			DefaultPaymentMethod: stripe.String(paymentMethodID),
+		},
+	}
+	params.Context = ctx
+
+	if _, err := customer.Update(customerID, params); err != nil {
+		return fmt.Errorf("failed to set default payment method for customer %s: %w", customerID, err)
+	}
+	return nil
+}
+
+// ChargeOffSession charges a saved card without the client present. A successful charge is
+// recorded in the ledger straight away, the payment_intent.succeeded webhook for it then finds
+// nothing new to record.
+func (r *StripeRepository) ChargeOffSession(ctx context.Context, invoice *models.Invoice, customerID string, paymentMethodID string, amount models.Money) (*models.PaymentLedgerEntry, error) {
+	params := &stripe.PaymentIntentParams{
+		Amount:        stripe.Int64(amount.Cents()),
+		Currency:      stripe.String(string(stripe.CurrencyUSD)),
+		Customer:      stripe.String(customerID),
+		PaymentMethod: stripe.String(paymentMethodID),
+		OffSession:    stripe.Bool(true),
+		Confirm:       stripe.Bool(true),
+		Description:   stripe.String("Invoice " + invoice.Number()),
+		Metadata: map[string]string{
+			"invoice_id":     invoice.UUID.String(),
+			"invoice_number": invoice.Number(),
+			"charge_type":    offSessionChargeType,
+		},
+	}
+	params.Context = ctx
+	// a retried charge for the same balance gets the first charge back instead of a second one,
+	// once it is recorded the balance changes and the next charge gets a new key
+	params.SetIdempotencyKey(fmt.Sprintf("off-session-%s-%d-%d", invoice.UUID, invoice.Balance.Cents(), amount.Cents()))
+
+	intent, err := paymentintent.New(params)
+	if err != nil {
+		return nil, fmt.Errorf("off-session charge for invoice %s failed: %w", invoice.UUID, err)
+	}
+	if intent.Status != stripe.PaymentIntentStatusSucceeded {
+		return nil, fmt.Errorf("off-session charge for invoice %s is %s", invoice.UUID, intent.Status)
+	}
+
+	entry := offSessionLedgerEntry(invoice.UUID, intent)
+	if _, _, err := r.ledger.RecordPayment(ctx, entry); err != nil {
+		return nil, fmt.Errorf("charged payment intent %s but failed to record it: %w", intent.ID, err)
+	}
+	return entry, nil
+}
+
+// offSessionLedgerEntry keys the entry on the payment intent rather than a webhook event, so
+// recording the charge and the webhook for it can't both count it
+func offSessionLedgerEntry(invoiceID uuid.UUID, intent *stripe.PaymentIntent) *models.PaymentLedgerEntry {
+	entry := &models.PaymentLedgerEntry{
+		InvoiceID:       invoiceID,
+		StripeEventID:   "payment_intent:" + intent.ID,
+		Amount:          models.MoneyFromCents(intent.AmountReceived),
+		PaymentIntentID: intent.ID,
+		OccurredAt:      time.Unix(intent.Created, 0).UTC(),
+	}
+	if intent.LatestCharge != nil {
+		entry.ChargeID = intent.LatestCharge.ID
+	}
+	return entry
+}
+
+// RefundPaymentIntent refunds part of one payment intent and records it in the ledger straight
+// away. The charge.refunded webhook for the same refund then finds nothing new to record.
+func (r *StripeRepository) RefundPaymentIntent(ctx context.Context, invoice *models.Invoice, allocation models.RefundAllocation) (*models.PaymentLedgerEntry, error) {
+	paymentIntentID := allocation.PaymentIntentID
 	params := &stripe.RefundParams{
-		PaymentIntent: stripe.String(invoice.PaymentIntent),
+		PaymentIntent: stripe.String(paymentIntentID),
+		Amount:        stripe.Int64(allocation.Amount.Cents()),
 	}
+	params.Context = ctx
+	params.AddMetadata("invoice_id", invoice.UUID.String())
+	params.AddMetadata("invoice_number", invoice.Number())
+	// a retry of the same allocation, e.g. after a timeout, gets the first refund back instead
+	// of refunding again
+	params.SetIdempotencyKey(fmt.Sprintf("refund-%s-%d-%d", paymentIntentID, allocation.Refundable.Cents(), allocation.Amount.Cents()))
 
 	result, err := refund.New(params)
 	if err != nil {
-		return "", err
+		return nil, fmt.Errorf("failed to refund payment intent %s: %w", paymentIntentID, err)
+	}
+
+	entry := &models.PaymentLedgerEntry{
+		InvoiceID:       invoice.UUID,
+		StripeEventID:   "refund:" + result.ID,
+		Amount:          models.MoneyFromCents(result.Amount),
+		PaymentIntentID: paymentIntentID,
+		RefundID:        result.ID,
+		OccurredAt:      time.Unix(result.Created, 0).UTC(),
+	}
+	if result.Charge == nil {
+		// the webhook records it once Stripe reports the charge
+		log.Printf("Refund %s has no charge yet, leaving it to the webhook.", result.ID)
+		return entry, nil
+	}
+	entry.ChargeID = result.Charge.ID
+
+	refundedCharge, err := charge.Get(result.Charge.ID, nil)
+	if err != nil {
+		log.Printf("Refund %s issued but charge %s could not be read, leaving it to the webhook: %v", result.ID, result.Charge.ID, err)
+		return entry, nil
+	}
+
+	if _, _, err := r.ledger.RecordRefund(ctx, entry, models.MoneyFromCents(refundedCharge.AmountRefunded)); err != nil {
+		log.Printf("Refund %s issued but not recorded, leaving it to the webhook: %v", result.ID, err)
 	}
+	// the ledger may have recorded a different amount if other refunds on the charge were still unrecorded
+	entry.Amount = models.MoneyFromCents(result.Amount)
 
-	return result.ID, nil
+	return entry, nil
 }
 
-// Webhook handles incoming Stripe webhooks
+// Webhook handles incoming Stripe webhooks. Payments and refunds are appended to the ledger,
+// which is what the invoice totals are derived from, so a replayed event is a no-op.
 func (r *StripeRepository) Webhook(ctx context.Context, payload []byte, signatureHeader string) error {
 	webhookSecret := os.Getenv("STRIPE_WEBHOOK_SECRET")
 	if webhookSecret == "" {
@@ -190,10 +434,12 @@ func (r *StripeRepository) Webhook(ctx context.Context, payload []byte, signatur
 		return fmt.Errorf("error verifying webhook signature: %w", err)
 	}
 
+	// status changes the ledger makes from here on are Stripe's
+	ctx = models.WithActor(ctx, models.ActorStripe)
+
 	switch event.Type {
 	case stripe.EventTypeCheckoutSessionCompleted:
 		var checkoutSession stripe.CheckoutSession
-		var invoice models.Invoice
 		if err := json.Unmarshal(event.Data.Raw, &checkoutSession); err != nil {
 			return fmt.Errorf("error parsing webhook JSON: %w", err)
 		}
@@ -204,30 +450,75 @@ func (r *StripeRepository) Webhook(ctx context.Context, payload []byte, signatur
 			return nil
 		}
 
-		if checkoutSession.PaymentStatus == stripe.CheckoutSessionPaymentStatusPaid {
-			if err := r.db.WithContext(ctx).Where("uuid = ?", invoiceID).First(&invoice).Error; err != nil {
-				return fmt.Errorf("failed to get invoice: %w", err)
-			}
+		if checkoutSession.PaymentStatus != stripe.CheckoutSessionPaymentStatusPaid {
+			return nil
+		}
 
-			amountPaid := float64(checkoutSession.AmountTotal) / 100
-			totalAmountPaid := invoice.AmountPaid + amountPaid
-			newBalance := invoice.Amount - totalAmountPaid
+		parsedInvoiceID, err := uuid.Parse(invoiceID)
+		if err != nil {
+			log.Printf("invalid invoice_id %q in webhook metadata for session %s", invoiceID, checkoutSession.ID)
+			return nil
+		}
 
-			status := "paid"
-			if newBalance > 0.01 {
-				status = "partially_paid"
-			}
+		entry := &models.PaymentLedgerEntry{
+			InvoiceID:         parsedInvoiceID,
+			StripeEventID:     event.ID,
+			Amount:            models.MoneyFromCents(checkoutSession.AmountTotal),
+			CheckoutSessionID: checkoutSession.ID,
+			OccurredAt:        time.Unix(event.Created, 0).UTC(),
+		}
+		if checkoutSession.PaymentIntent != nil {
+			entry.PaymentIntentID = checkoutSession.PaymentIntent.ID
+		}
 
-			err := r.db.WithContext(ctx).Model(&models.Invoice{}).Where("uuid = ?", invoiceID).Updates(map[string]interface{}{
-				"status":         status,
-				"payment_intent": checkoutSession.PaymentIntent.ID,
-				"amount_paid":    totalAmountPaid,
-				"balance":        newBalance,
-			}).Error
+		invoice, recorded, err := r.ledger.RecordPayment(ctx, entry)
+		if err != nil {
+			return fmt.Errorf("failed to record payment for %s: %w", invoiceID, err)
+		}
+		if !recorded {
+			log.Printf("Stripe event %s already recorded for invoice %s.", event.ID, invoiceID)
+			return nil
+		}
+		log.Printf("Recorded payment of %s for invoice %s, balance now %s.", entry.Amount, invoice.UUID, invoice.Balance)
 
-			if err != nil {
-				return fmt.Errorf("failed to update invoice for %s: %w", invoiceID, err)
+	case stripe.EventTypePaymentIntentSucceeded:
+		var intent stripe.PaymentIntent
+		if err := json.Unmarshal(event.Data.Raw, &intent); err != nil {
+			return fmt.Errorf("error parsing webhook JSON for payment_intent.succeeded: %w", err)
+		}
+
+		// checkout payments are recorded from checkout.session.completed
+		if intent.Metadata["charge_type"] != offSessionChargeType {
+			return nil
+		}
+
+		parsedInvoiceID, err := uuid.Parse(intent.Metadata["invoice_id"])
+		if err != nil {
+			log.Printf("invalid invoice_id %q in metadata for payment intent %s", intent.Metadata["invoice_id"], intent.ID)
+			return nil
+		}
+
+		invoice, recorded, err := r.ledger.RecordPayment(ctx, offSessionLedgerEntry(parsedInvoiceID, &intent))
+		if err != nil {
+			return fmt.Errorf("failed to record off-session payment for %s: %w", parsedInvoiceID, err)
+		}
+		if !recorded {
+			log.Printf("Off-session payment intent %s already recorded for invoice %s.", intent.ID, parsedInvoiceID)
+			return nil
+		}
+		log.Printf("Recorded off-session payment of %s for invoice %s, balance now %s.", models.MoneyFromCents(intent.AmountReceived), invoice.UUID, invoice.Balance)
+
+	case stripe.EventTypePaymentIntentPaymentFailed:
+		var intent stripe.PaymentIntent
+		if err := json.Unmarshal(event.Data.Raw, &intent); err != nil {
+			return fmt.Errorf("error parsing webhook JSON for payment_intent.payment_failed: %w", err)
+		}
+		if intent.Metadata["charge_type"] == offSessionChargeType {
+			reason := ""
+			if intent.LastPaymentError != nil {
+				reason = intent.LastPaymentError.Msg
 			}
+			log.Printf("Off-session charge %s for invoice %s failed: %s", intent.ID, intent.Metadata["invoice_id"], reason)
 		}
 
 	case stripe.EventTypeChargeRefunded:
@@ -236,16 +527,41 @@ func (r *StripeRepository) Webhook(ctx context.Context, payload []byte, signatur
 			return fmt.Errorf("error parsing webhook JSON for charge.refunded: %w", err)
 		}
 
-		var invoice models.Invoice
-		if err := r.db.WithContext(ctx).Where("payment_intent = ?", charge.PaymentIntent.ID).First(&invoice).Error; err != nil {
-			log.Printf("Could not find invoice for payment intent %s to mark as refunded.", charge.PaymentIntent.ID)
+		if charge.PaymentIntent == nil {
+			log.Printf("Charge %s has no payment intent, ignoring refund.", charge.ID)
 			return nil
 		}
 
-		if err := r.db.WithContext(ctx).Model(&invoice).Update("status", "refunded").Error; err != nil {
-			return fmt.Errorf("failed to update invoice %s to refunded: %w", invoice.UUID, err)
+		invoiceID, err := r.invoiceIDForPaymentIntent(ctx, charge.PaymentIntent.ID)
+		if err != nil {
+			return err
+		}
+		if invoiceID == nil {
+			log.Printf("Could not find invoice for payment intent %s to record refund.", charge.PaymentIntent.ID)
+			return nil
 		}
-		log.Printf("Invoice %s marked as refunded.", invoice.UUID)
+
+		entry := &models.PaymentLedgerEntry{
+			InvoiceID:       *invoiceID,
+			StripeEventID:   event.ID,
+			PaymentIntentID: charge.PaymentIntent.ID,
+			ChargeID:        charge.ID,
+			OccurredAt:      time.Unix(event.Created, 0).UTC(),
+		}
+		if charge.Refunds != nil && len(charge.Refunds.Data) > 0 {
+			entry.RefundID = charge.Refunds.Data[0].ID
+		}
+
+		// charge.AmountRefunded is cumulative, the ledger only records what is new
+		invoice, recorded, err := r.ledger.RecordRefund(ctx, entry, models.MoneyFromCents(charge.AmountRefunded))
+		if err != nil {
+			return fmt.Errorf("failed to record refund for invoice %s: %w", *invoiceID, err)
+		}
+		if !recorded {
+			log.Printf("Refund event %s for charge %s already recorded.", event.ID, charge.ID)
+			return nil
+		}
+		log.Printf("Recorded refund of %s for invoice %s, status now %s.", entry.Amount, invoice.UUID, invoice.Status)
 
 	default:
 		log.Printf("Unhandled event type: %s\n", event.Type)
@@ -254,6 +570,28 @@ func (r *StripeRepository) Webhook(ctx context.Context, payload []byte, signatur
 	return nil
 }
 
+// invoiceIDForPaymentIntent looks the payment intent up in the ledger, falling back to the
+// invoice's last payment intent for payments made before the ledger existed
+func (r *StripeRepository) invoiceIDForPaymentIntent(ctx context.Context, paymentIntentID string) (*uuid.UUID, error) {
+	invoiceID, err := r.ledger.GetInvoiceIDByPaymentIntent(ctx, paymentIntentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up payment intent %s: %w", paymentIntentID, err)
+	}
+	if invoiceID != nil {
+		return invoiceID, nil
+	}
+
+	var invoice models.Invoice
+	err = r.db.WithContext(ctx).Select("uuid").Where("payment_intent = ?", paymentIntentID).First(&invoice).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up payment intent %s: %w", paymentIntentID, err)
+	}
+	return &invoice.UUID, nil
+}
+
 func (r *StripeRepository) SendAdminConfirmationEmail(ctx context.Context, invoice *models.Invoice, staffRequirements []models.StaffRequirement) (string, error) {
 	// TODO: implement send admin confirmation email
 	return "", nil
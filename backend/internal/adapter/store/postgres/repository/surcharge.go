@@ -0,0 +1,135 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"backend/internal/core/models"
+	ports "backend/internal/core/ports"
+)
+
+type SurchargeRepository struct {
+	db *gorm.DB
+}
+
+func NewSurchargeRepository(db *gorm.DB) ports.SurchargeRepository {
+	return &SurchargeRepository{db: db}
+}
+
+func (r *SurchargeRepository) CreateSurchargeRule(ctx context.Context, rule *models.SurchargeRule) error {
+	if err := checkSharedBranchScope(ctx, rule.BranchID); err != nil {
+		return err
+	}
+	if rule.UUID == uuid.Nil {
+		rule.UUID = uuid.New()
+	}
+
+	return r.db.WithContext(ctx).Create(rule).Error
+}
+
+func (r *SurchargeRepository) GetSurchargeRuleByID(ctx context.Context, id uuid.UUID) (*models.SurchargeRule, error) {
+	var rule models.SurchargeRule
+	if err := r.db.WithContext(ctx).Scopes(sharedOrBranchInScope(ctx, "branch_id")).Where("uuid = ?", id).First(&rule).Error; err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+func (r *SurchargeRepository) GetSurchargeRules(ctx context.Context, branchID *uuid.UUID) ([]models.SurchargeRule, error) {
+	if branchID != nil {
+		if err := checkBranchScope(ctx, *branchID); err != nil {
+			return nil, err
+		}
+	}
+
+	var rules []models.SurchargeRule
+	query := r.db.WithContext(ctx).Scopes(sharedOrBranchInScope(ctx, "branch_id")).Order("type").Order("created_at")
+	if branchID != nil {
+		query = query.Where("branch_id = ?", *branchID)
+	}
+	if err := query.Find(&rules).Error; err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+func (r *SurchargeRepository) UpdateSurchargeRule(ctx context.Context, rule *models.SurchargeRule) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// branch users change their own branch's rules, the shared ones are left to superadmins
+		var existing models.SurchargeRule
+		if err := tx.Where("uuid = ?", rule.UUID).First(&existing).Error; err != nil {
+			return err
+		}
+		if err := checkSharedBranchScope(ctx, existing.BranchID); err != nil {
+			return err
+		}
+		if err := checkSharedBranchScope(ctx, rule.BranchID); err != nil {
+			return err
+		}
+		return tx.Save(rule).Error
+	})
+}
+
+func (r *SurchargeRepository) DeleteSurchargeRule(ctx context.Context, id uuid.UUID) error {
+	return deleteInBranchScope(ctx, r.db, &models.SurchargeRule{}, id)
+}
+
+func (r *SurchargeRepository) GetApplicableSurchargeRules(ctx context.Context, branchID uuid.UUID) ([]models.SurchargeRule, error) {
+	var rules []models.SurchargeRule
+	err := r.db.WithContext(ctx).
+		Where("active = ?", true).
+		Where("branch_id = ? OR branch_id IS NULL", branchID).
+		Find(&rules).Error
+	if err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+func (r *SurchargeRepository) CreateHoliday(ctx context.Context, holiday *models.Holiday) error {
+	if err := checkSharedBranchScope(ctx, holiday.BranchID); err != nil {
+		return err
+	}
+	if holiday.UUID == uuid.Nil {
+		holiday.UUID = uuid.New()
+	}
+
+	return r.db.WithContext(ctx).Create(holiday).Error
+}
+
+func (r *SurchargeRepository) GetHolidays(ctx context.Context, branchID *uuid.UUID) ([]models.Holiday, error) {
+	if branchID != nil {
+		if err := checkBranchScope(ctx, *branchID); err != nil {
+			return nil, err
+		}
+	}
+
+	var holidays []models.Holiday
+	query := r.db.WithContext(ctx).Scopes(sharedOrBranchInScope(ctx, "branch_id")).Order("date")
+	if branchID != nil {
+		query = query.Where("branch_id = ? OR branch_id IS NULL", *branchID)
+	}
+	if err := query.Find(&holidays).Error; err != nil {
+		return nil, err
+	}
+	return holidays, nil
+}
+
+func (r *SurchargeRepository) DeleteHoliday(ctx context.Context, id uuid.UUID) error {
+	return deleteInBranchScope(ctx, r.db, &models.Holiday{}, id)
+}
+
+func (r *SurchargeRepository) GetHolidaysBetween(ctx context.Context, branchID uuid.UUID, from time.Time, to time.Time) ([]models.Holiday, error) {
+	var holidays []models.Holiday
+	err := r.db.WithContext(ctx).
+		Where("branch_id = ? OR branch_id IS NULL", branchID).
+		Where("date BETWEEN ? AND ?", from.Format("2006-01-02"), to.Format("2006-01-02")).
+		Find(&holidays).Error
+	if err != nil {
+		return nil, err
+	}
+	return holidays, nil
+}
@@ -0,0 +1,120 @@
+package repository
+
+import (
+	"backend/internal/core/models"
+	ports "backend/internal/core/ports"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type UserRepository struct {
+	db *gorm.DB
+}
+
+func NewUserRepository(db *gorm.DB) ports.UserRepository {
+	return &UserRepository{db: db}
+}
+
+// GetUserByID returns nil, nil when there is no such user in the context's branch
+func (r *UserRepository) GetUserByID(ctx context.Context, id uuid.UUID) (*models.User, error) {
+	var user models.User
+	err := r.db.WithContext(ctx).Scopes(branchInScope(ctx, "branch_id")).Where("uuid = ?", id).First(&user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user %s: %w", id, err)
+	}
+	return &user, nil
+}
+
+// GetUserByEmail matches case insensitively and returns nil, nil when there is no such user
+func (r *UserRepository) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	var user models.User
+	err := r.db.WithContext(ctx).Where("LOWER(email) = ?", strings.ToLower(strings.TrimSpace(email))).First(&user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user %s: %w", email, err)
+	}
+	return &user, nil
+}
+
+func (r *UserRepository) GetUserByCognitoSub(ctx context.Context, sub string) (*models.User, error) {
+	var user models.User
+	err := r.db.WithContext(ctx).Where("cognito_sub = ?", sub).First(&user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user for Cognito sub %s: %w", sub, err)
+	}
+	return &user, nil
+}
+
+func (r *UserRepository) LinkCognitoSub(ctx context.Context, id uuid.UUID, sub string) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&models.User{}).
+		Where("uuid = ? AND cognito_sub IS NULL", id).
+		Update("cognito_sub", sub)
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to link user %s to Cognito: %w", id, result.Error)
+	}
+	return result.RowsAffected > 0, nil
+}
+
+func (r *UserRepository) ListUsers(ctx context.Context, filter models.UserFilter) ([]models.User, error) {
+	query := r.db.WithContext(ctx).Scopes(branchInScope(ctx, "branch_id"))
+	if filter.BranchID != nil {
+		query = query.Where("branch_id = ?", *filter.BranchID)
+	}
+	if filter.Role != "" {
+		query = query.Where("role = ?", filter.Role)
+	}
+	if !filter.IncludeDeactivated {
+		query = query.Where("deactivated_at IS NULL")
+	}
+
+	var users []models.User
+	if err := query.Order("LOWER(first_name), LOWER(last_name), LOWER(email)").Find(&users).Error; err != nil {
+		return nil, fmt.Errorf("failed to list users: %w", err)
+	}
+	return users, nil
+}
+
+func (r *UserRepository) CreateUser(ctx context.Context, user *models.User) error {
+	if err := checkBranchScope(ctx, user.BranchID); err != nil {
+		return err
+	}
+	if user.UUID == uuid.Nil {
+		user.UUID = uuid.New()
+	}
+	return r.db.WithContext(ctx).Omit("Branch").Create(user).Error
+}
+
+func (r *UserRepository) UpdateUser(ctx context.Context, user *models.User) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// branch admins can't edit another branch's users or move theirs to another branch
+		var count int64
+		err := tx.Model(&models.User{}).Scopes(branchInScope(ctx, "branch_id")).Where("uuid = ?", user.UUID).Count(&count).Error
+		if err != nil {
+			return fmt.Errorf("failed to check user %s: %w", user.UUID, err)
+		}
+		if count == 0 {
+			return models.ErrOutOfBranchScope
+		}
+		if err := checkBranchScope(ctx, user.BranchID); err != nil {
+			return err
+		}
+		return tx.Omit("Branch").Save(user).Error
+	})
+}
+
+func (r *UserRepository) DeleteUser(ctx context.Context, id uuid.UUID) error {
+	return r.db.WithContext(ctx).Scopes(branchInScope(ctx, "branch_id")).Where("uuid = ?", id).Delete(&models.User{}).Error
+}
@@ -16,8 +16,8 @@ func SeedDatabase(db *gorm.DB) error {
 
 	// Create branches
 	branches := []models.Branch{
-		{Name: "Downtown Branch", Latitude: 40.7128, Longitude: -74.0060},
-		{Name: "Uptown Branch", Latitude: 40.8075, Longitude: -73.9626},
+		{Name: "Downtown Branch", Code: "DTN", Latitude: 40.7128, Longitude: -74.0060},
+		{Name: "Uptown Branch", Code: "UPT", Latitude: 40.8075, Longitude: -73.9626},
 	}
 
 	if err := db.Create(&branches).Error; err != nil {
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/joho/godotenv"
 	"gopkg.in/yaml.v3"
@@ -17,13 +18,28 @@ type Config struct {
 	AWS                *AWS
 	S3                 *S3
 	Stripe             *Stripe
+	Email              *Email
+	Blob               *Blob
 	TermsAndConditions string
+	Cancellation       *Cancellation
+	Cognito            *Cognito
 }
 
 type TOSConfig struct {
 	Invoice struct {
 		TermsAndConditions string `yaml:"terms_and_conditions"`
 	} `yaml:"invoice"`
+	Cancellation Cancellation `yaml:"cancellation"`
+}
+
+// Cancellation is the refund schedule from the terms and conditions
+type Cancellation struct {
+	RefundTiers []RefundTier `yaml:"refund_tiers"`
+}
+
+type RefundTier struct {
+	DaysBeforeEvent int     `yaml:"days_before_event"`
+	RefundPercent   float64 `yaml:"refund_percent"`
 }
 
 type App struct {
@@ -42,18 +58,61 @@ type AWS struct {
 	SecretKey string
 }
 
+// Cognito is the user pool API callers sign in with. Tokens are checked against the pool's
+// JWKS, JWKSURL and Issuer override where they come from, e.g. for a local stand-in.
+type Cognito struct {
+	Region     string
+	UserPoolID string
+	// app clients whose tokens are accepted
+	ClientIDs []string
+	JWKSURL   string
+	Issuer    string
+}
+
 type S3 struct {
-	Bucket    string
-	Region    string
+	Bucket string
+	Region string
+	// optional, without them the AWS default credential chain is used
 	AccessKey string
 	SecretKey string
 }
 
+// Blob picks where uploaded files are kept. Provider is s3 or local, it defaults to s3 when
+// S3_BUCKET is set.
+type Blob struct {
+	Provider string
+	// where the local provider writes files
+	Dir string
+}
+
 type Stripe struct {
 	APIKey        string
 	WebhookSecret string
 }
 
+// Email picks the mail transport. Provider is mailgun, smtp or file.
+type Email struct {
+	Provider string
+	From     string
+	Mailgun  Mailgun
+	SMTP     SMTP
+	// where the file provider writes .eml files
+	FileDir string
+}
+
+type Mailgun struct {
+	Domain            string
+	APIKey            string
+	WebhookSigningKey string
+}
+
+type SMTP struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+}
+
 func New(app *App, http *HTTP, aws *AWS, s3 *S3) *Config {
 	return &Config{
 		App:  app,
@@ -91,25 +150,38 @@ func LoadConfig() (*Config, error) {
 		return nil, errors.New("STRIPE_WEBHOOK_SECRET is required")
 	}
 
-	termsAndConditions := func() string {
+	emailFrom := os.Getenv("EMAIL_FROM")
+	if emailFrom == "" {
+		emailFrom = os.Getenv("MAILGUN_FROM")
+	}
 
-		data, err := os.ReadFile("internal/config/tos.yaml")
-		if err != nil {
-			fmt.Printf("Error reading YAML file: %v\n", err)
-			return ""
-		}
+	emailFileDir := os.Getenv("EMAIL_FILE_DIR")
+	if emailFileDir == "" {
+		emailFileDir = "tmp/emails"
+	}
 
-		var tosConfig TOSConfig
-		err = yaml.Unmarshal(data, &tosConfig)
-		if err != nil {
-			fmt.Printf("Error unmarshaling YAML: %v\n", err)
-			return ""
-		}
+	blobDir := os.Getenv("BLOB_DIR")
+	if blobDir == "" {
+		blobDir = "tmp/blobs"
+	}
 
-		terms := tosConfig.Invoice.TermsAndConditions
+	s3Region := os.Getenv("S3_REGION")
+	if s3Region == "" {
+		s3Region = os.Getenv("AWS_REGION")
+	}
 
-		return terms
-	}()
+	// the cancellation refunds come from here, starting without them would refund nothing
+	var tos TOSConfig
+	data, err := os.ReadFile("internal/config/tos.yaml")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read terms and conditions: %w", err)
+	}
+	if err := yaml.Unmarshal(data, &tos); err != nil {
+		return nil, fmt.Errorf("failed to parse terms and conditions: %w", err)
+	}
+	if len(tos.Cancellation.RefundTiers) == 0 {
+		return nil, errors.New("terms and conditions have no cancellation refund tiers")
+	}
 
 	return &Config{
 		Port:        port,
@@ -117,11 +189,62 @@ func LoadConfig() (*Config, error) {
 		App:         &App{Env: env},
 		HTTP:        &HTTP{},
 		AWS:         &AWS{},
-		S3:          &S3{},
+		S3: &S3{
+			Bucket:    os.Getenv("S3_BUCKET"),
+			Region:    s3Region,
+			AccessKey: os.Getenv("S3_ACCESS_KEY"),
+			SecretKey: os.Getenv("S3_SECRET_KEY"),
+		},
 		Stripe: &Stripe{
 			APIKey:        stripeAPIKey,
 			WebhookSecret: stripeWebhookSecret,
 		},
-		TermsAndConditions: termsAndConditions,
+		Email: &Email{
+			Provider: os.Getenv("EMAIL_PROVIDER"),
+			From:     emailFrom,
+			Mailgun: Mailgun{
+				Domain:            os.Getenv("MAILGUN_DOMAIN"),
+				APIKey:            os.Getenv("MAILGUN_API_KEY"),
+				WebhookSigningKey: os.Getenv("MAILGUN_WEBHOOK_SIGNING_KEY"),
+			},
+			SMTP: SMTP{
+				Host:     os.Getenv("SMTP_HOST"),
+				Port:     os.Getenv("SMTP_PORT"),
+				Username: os.Getenv("SMTP_USERNAME"),
+				Password: os.Getenv("SMTP_PASSWORD"),
+			},
+			FileDir: emailFileDir,
+		},
+		Blob: &Blob{
+			Provider: os.Getenv("BLOB_STORE"),
+			Dir:      blobDir,
+		},
+		TermsAndConditions: tos.Invoice.TermsAndConditions,
+		Cancellation:       &tos.Cancellation,
+		Cognito:            CognitoFromEnv(),
 	}, nil
 }
+
+// CognitoFromEnv reads the user pool settings, the Lambdas use it to check tokens the same way
+// the API does
+func CognitoFromEnv() *Cognito {
+	region := os.Getenv("COGNITO_REGION")
+	if region == "" {
+		region = os.Getenv("AWS_REGION")
+	}
+
+	var clientIDs []string
+	for _, id := range strings.Split(os.Getenv("COGNITO_CLIENT_IDS"), ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			clientIDs = append(clientIDs, id)
+		}
+	}
+
+	return &Cognito{
+		Region:     region,
+		UserPoolID: os.Getenv("COGNITO_USER_POOL_ID"),
+		ClientIDs:  clientIDs,
+		JWKSURL:    os.Getenv("COGNITO_JWKS_URL"),
+		Issuer:     os.Getenv("COGNITO_ISSUER"),
+	}
+}
@@ -0,0 +1,30 @@
+package models
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+)
+
+var ErrOutOfBranchScope = errors.New("not permitted outside your branch")
+
+type branchScopeKey struct{}
+
+// WithBranchScope limits what is read and changed for the rest of the request to one branch.
+// Contexts without a scope, superadmins and the backend's own jobs, reach every branch.
+func WithBranchScope(ctx context.Context, branchID uuid.UUID) context.Context {
+	return context.WithValue(ctx, branchScopeKey{}, branchID)
+}
+
+// BranchScope is the branch WithBranchScope limited ctx to, false when ctx reaches every branch
+func BranchScope(ctx context.Context) (uuid.UUID, bool) {
+	branchID, ok := ctx.Value(branchScopeKey{}).(uuid.UUID)
+	return branchID, ok
+}
+
+// InBranchScope reports whether ctx may reach the branch
+func InBranchScope(ctx context.Context, branchID uuid.UUID) bool {
+	scope, ok := BranchScope(ctx)
+	return !ok || scope == branchID
+}
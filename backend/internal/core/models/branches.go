@@ -7,8 +7,10 @@ import (
 type Branch struct {
 	UUID      uuid.UUID `gorm:"type:uuid;primaryKey"`
 	Name      string
+	Code      string // unique and upper case, prefixes the branch's invoice numbers
 	Latitude  float64
 	Longitude float64
+	Region    string
 
 	Users    []User    `gorm:"foreignKey:BranchID"`
 	Requests []Request `gorm:"foreignKey:ClosestBranchID"`
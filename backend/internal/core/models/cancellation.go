@@ -0,0 +1,109 @@
+package models
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrRequestAlreadyCancelled = errors.New("request has already been cancelled")
+	ErrRequestNotCancelled     = errors.New("request has not been cancelled")
+)
+
+// CancellationRefundTier refunds RefundPercent of what was paid when a request is cancelled at
+// least DaysBeforeEvent days before the event starts
+type CancellationRefundTier struct {
+	DaysBeforeEvent int     `json:"days_before_event"`
+	RefundPercent   float64 `json:"refund_percent"`
+}
+
+// CancellationPolicy is the refund schedule from the terms and conditions
+type CancellationPolicy struct {
+	Tiers []CancellationRefundTier `json:"tiers"`
+}
+
+// DaysBeforeEvent counts whole days between the cancellation and the event start
+func DaysBeforeEvent(cancelledAt, eventStart time.Time) int {
+	if !eventStart.After(cancelledAt) {
+		return 0
+	}
+	return int(eventStart.Sub(cancelledAt) / (24 * time.Hour))
+}
+
+// RefundPercent is the percent of the amount paid that is refunded for a cancellation made
+// daysBeforeEvent days out, the most generous tier that is met wins
+func (p CancellationPolicy) RefundPercent(daysBeforeEvent int) float64 {
+	percent := 0.0
+	for _, tier := range p.Tiers {
+		if daysBeforeEvent >= tier.DaysBeforeEvent && tier.RefundPercent > percent {
+			percent = tier.RefundPercent
+		}
+	}
+	return percent
+}
+
+// RequestCancellation is what cancelling a request refunds. Refunds is empty for a quote.
+type RequestCancellation struct {
+	RequestID       uuid.UUID            `json:"request_id"`
+	InvoiceIDs      []uuid.UUID          `json:"invoice_ids"`
+	CancelledAt     time.Time            `json:"cancelled_at"`
+	EventStart      time.Time            `json:"event_start"`
+	DaysBeforeEvent int                  `json:"days_before_event"`
+	RefundPercent   float64              `json:"refund_percent"`
+	AmountPaid      Money                `json:"amount_paid"`
+	RefundAmount    Money                `json:"refund_amount"`
+	Refunds         []PaymentLedgerEntry `json:"refunds"`
+}
+
+// RefundablePayment is what is left to refund on one payment intent
+type RefundablePayment struct {
+	PaymentIntentID string
+	Refundable      Money
+	LastPaidAt      time.Time
+}
+
+// RefundAllocation is the part of a refund taken from one payment intent
+type RefundAllocation struct {
+	PaymentIntentID string
+	Amount          Money
+	// what was left to refund on the intent before this refund, together with the intent and
+	// amount it tells a retry of this refund apart from the next one
+	Refundable Money
+}
+
+// SplitRefund spreads a refund over the payment intents that were charged, most recent payment
+// first, never taking more from an intent than is left on it. Whatever can't be covered is
+// returned as the shortfall.
+func SplitRefund(amount Money, payments []RefundablePayment) ([]RefundAllocation, Money) {
+	sorted := make([]RefundablePayment, len(payments))
+	copy(sorted, payments)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].LastPaidAt.After(sorted[j].LastPaidAt)
+	})
+
+	var allocations []RefundAllocation
+	for _, payment := range sorted {
+		if amount <= 0 {
+			break
+		}
+		if payment.Refundable <= 0 || payment.PaymentIntentID == "" {
+			continue
+		}
+
+		take := payment.Refundable
+		if take > amount {
+			take = amount
+		}
+		allocations = append(allocations, RefundAllocation{
+			PaymentIntentID: payment.PaymentIntentID,
+			Amount:          take,
+			Refundable:      payment.Refundable,
+		})
+		amount -= take
+	}
+
+	return allocations, amount
+}
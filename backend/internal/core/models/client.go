@@ -0,0 +1,85 @@
+package models
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrClientNotFound = errors.New("client not found")
+	// off-session charges are only made for accounts staff have approved
+	ErrOffSessionNotApproved = errors.New("client is not approved for off-session charges")
+	ErrNoSavedPaymentMethod  = errors.New("client has no saved payment method")
+)
+
+// Client is whoever pays for requests, matched by email. Their Stripe customer keeps their
+// payments and saved payment methods together across requests.
+type Client struct {
+	UUID                   uuid.UUID `gorm:"type:uuid;primaryKey" json:"uuid"`
+	Email                  string    `json:"email"`
+	Name                   string    `json:"name"`
+	CompanyName            string    `json:"company_name"`
+	PhoneNumber            string    `json:"phone_number"`
+	StripeCustomerID       string    `json:"stripe_customer_id,omitempty"`
+	DefaultPaymentMethodID string    `json:"default_payment_method_id,omitempty"`
+	// approved accounts can be charged without the client present, e.g. the balance after the event
+	OffSessionApproved bool      `json:"off_session_approved"`
+	CreatedAt          time.Time `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt          time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+// NormalizeClientEmail is the form emails are matched on
+func NormalizeClientEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// NewClientFromRequest fills in a client from the details on a request
+func NewClientFromRequest(request *Request) *Client {
+	client := &Client{
+		UUID:        uuid.New(),
+		Email:       NormalizeClientEmail(request.Email),
+		Name:        clientName(*request),
+		PhoneNumber: request.PhoneNumber,
+	}
+	if request.IsCompany {
+		client.CompanyName = request.CompanyName
+	}
+	return client
+}
+
+// ClientUpdate changes the fields staff can edit, nil fields are left as they are
+type ClientUpdate struct {
+	Name               *string `json:"name"`
+	CompanyName        *string `json:"company_name"`
+	PhoneNumber        *string `json:"phone_number"`
+	OffSessionApproved *bool   `json:"off_session_approved"`
+}
+
+// Apply copies the set fields onto the client
+func (u ClientUpdate) Apply(client *Client) {
+	if u.Name != nil {
+		client.Name = *u.Name
+	}
+	if u.CompanyName != nil {
+		client.CompanyName = *u.CompanyName
+	}
+	if u.PhoneNumber != nil {
+		client.PhoneNumber = *u.PhoneNumber
+	}
+	if u.OffSessionApproved != nil {
+		client.OffSessionApproved = *u.OffSessionApproved
+	}
+}
+
+// SavedPaymentMethod is a card saved on the client's Stripe customer
+type SavedPaymentMethod struct {
+	ID        string `json:"id"`
+	Brand     string `json:"brand"`
+	Last4     string `json:"last4"`
+	ExpMonth  int    `json:"exp_month"`
+	ExpYear   int    `json:"exp_year"`
+	IsDefault bool   `json:"is_default"`
+}
@@ -11,8 +11,8 @@ type CustomLineItems struct {
 	RequestID   uuid.UUID `gorm:"type:uuid;not null" json:"request_id"`
 	Description string    `gorm:"not null" json:"description"`
 	Quantity    int       `gorm:"not null;default:1;check:quantity > 0" json:"quantity"`
-	Rate        float64   `gorm:"not null;default:0;check:rate >= 0" json:"rate"`
-	Total       float64   `gorm:"->;check:total >= 0" json:"total"`
+	Rate        Money     `gorm:"not null;default:0;check:rate >= 0" json:"rate"`
+	Total       Money     `gorm:"->;check:total >= 0" json:"total"`
 	CreatedAt   time.Time `gorm:"autoCreateTime" json:"created_at"`
 	Request     Request   `gorm:"foreignKey:RequestID" json:"-"`
 }
@@ -0,0 +1,74 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	DiscountTypeNone       = "none"
+	DiscountTypePercentage = "percentage"
+	DiscountTypeFixed      = "fixed"
+)
+
+// Discount is what an account executive applies to an invoice, a percentage of the subtotal
+// or a fixed amount off it
+type Discount struct {
+	Type string `json:"discount_type"`
+	// set for percentage discounts, exact to three decimal places
+	Percent float64 `json:"discount_percent,omitempty"`
+	// set for fixed discounts
+	Fixed     Money  `json:"discount_fixed,omitempty"`
+	PromoCode string `json:"promo_code,omitempty"`
+}
+
+// Amount returns how much the discount takes off a subtotal, never more than the subtotal itself
+func (d Discount) Amount(subtotal Money) Money {
+	var amount Money
+	switch d.Type {
+	case DiscountTypePercentage:
+		amount = subtotal.Percent(d.Percent, RoundingNearestCent)
+	case DiscountTypeFixed:
+		amount = d.Fixed
+	}
+
+	if amount < 0 {
+		return 0
+	}
+	if amount > subtotal {
+		return subtotal
+	}
+	return amount
+}
+
+// PromoCode is a reusable discount that clients can redeem on their invoice
+type PromoCode struct {
+	UUID            uuid.UUID  `gorm:"type:uuid;primaryKey" json:"uuid"`
+	Code            string     `gorm:"uniqueIndex" json:"code"`
+	DiscountType    string     `json:"discount_type"`
+	DiscountPercent float64    `json:"discount_percent"`
+	DiscountFixed   Money      `json:"discount_fixed"`
+	BranchID        *uuid.UUID `gorm:"type:uuid" json:"branch_id,omitempty"`
+	ValidFrom       time.Time  `json:"valid_from"`
+	ValidTo         *time.Time `json:"valid_to,omitempty"`
+	MaxRedemptions  int        `json:"max_redemptions"` // 0 means unlimited
+	Redemptions     int        `json:"redemptions"`
+	Active          bool       `json:"active"`
+	CreatedAt       time.Time  `gorm:"autoCreateTime" json:"created_at"`
+}
+
+// Discount returns the discount redeeming the code applies
+func (p *PromoCode) Discount() Discount {
+	return Discount{Type: p.DiscountType, Percent: p.DiscountPercent, Fixed: p.DiscountFixed, PromoCode: p.Code}
+}
+
+// RateBreakdown is the result of pricing a request: the staff and line item subtotal, the
+// discount taken off it, the fees on the discounted subtotal and the total amount due
+type RateBreakdown struct {
+	Subtotal       Money `json:"subtotal"`
+	Discount       Money `json:"discount"`
+	TransactionFee Money `json:"transactionFee"`
+	ServiceFee     Money `json:"serviceFee"`
+	Amount         Money `json:"amount"`
+}
@@ -0,0 +1,43 @@
+package models
+
+import (
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// DunningSchedule sets when payment reminders go out for unpaid invoices. OffsetDays are days
+// relative to the due date, so {-3, 0, 7, 14} reminds three days before, on the day, and one
+// and two weeks late. A schedule with no branch is the default for every branch.
+type DunningSchedule struct {
+	UUID       uuid.UUID     `gorm:"type:uuid;primaryKey" json:"uuid"`
+	BranchID   *uuid.UUID    `gorm:"type:uuid" json:"branch_id,omitempty"`
+	OffsetDays pq.Int64Array `gorm:"type:integer[]" json:"offset_days"`
+	Enabled    bool          `json:"enabled"`
+	CreatedAt  time.Time     `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt  time.Time     `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+// NextReminder returns the first reminder date after the last one sent, or false once the
+// schedule is used up. Dates are whole UTC days so a late run never sends two reminders for
+// steps it missed.
+func (s *DunningSchedule) NextReminder(dueDate time.Time, lastReminder *time.Time) (time.Time, bool) {
+	offsets := append([]int64(nil), s.OffsetDays...)
+	sort.Slice(offsets, func(i, j int) bool { return offsets[i] < offsets[j] })
+
+	due := truncateToDay(dueDate)
+	for _, offset := range offsets {
+		reminder := due.AddDate(0, 0, int(offset))
+		if lastReminder == nil || reminder.After(truncateToDay(*lastReminder)) {
+			return reminder, true
+		}
+	}
+	return time.Time{}, false
+}
+
+func truncateToDay(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
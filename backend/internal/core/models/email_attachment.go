@@ -0,0 +1,26 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MaxEmailAttachmentSize keeps uploads well under the 25 MB message limit of most providers
+const MaxEmailAttachmentSize = 10 << 20
+
+// ErrEmailAttachmentNotFound is returned when an email references an attachment that doesn't exist
+var ErrEmailAttachmentNotFound = errors.New("email attachment not found")
+
+// EmailAttachment is an uploaded file that emails can reference by ID. The file itself is in the
+// blob store, StorageKey is derived from the checksum so identical files share one blob.
+type EmailAttachment struct {
+	UUID        uuid.UUID `gorm:"type:uuid;primaryKey" json:"uuid"`
+	Filename    string    `json:"filename"`
+	ContentType string    `json:"content_type"`
+	Size        int64     `json:"size"`
+	Checksum    string    `json:"checksum"`
+	StorageKey  string    `json:"-"`
+	CreatedAt   time.Time `gorm:"autoCreateTime" json:"created_at"`
+}
@@ -0,0 +1,107 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// What an email was sent for
+const (
+	EmailKindInvoice             = "invoice"
+	EmailKindCustom              = "custom"
+	EmailKindFollowUp            = "follow_up"
+	EmailKindScheduled           = "scheduled"
+	EmailKindPaymentConfirmation = "payment_confirmation"
+)
+
+// Delivery events reported by the Mailgun webhook. A permanent failure is recorded as a bounce,
+// a temporary one as failed since Mailgun keeps retrying it.
+const (
+	EmailEventSent       = "sent"
+	EmailEventDelivered  = "delivered"
+	EmailEventOpened     = "opened"
+	EmailEventClicked    = "clicked"
+	EmailEventFailed     = "failed"
+	EmailEventBounced    = "bounced"
+	EmailEventComplained = "complained"
+)
+
+// ErrInvalidWebhookSignature is returned for webhooks that don't verify or are too old
+var ErrInvalidWebhookSignature = errors.New("invalid webhook signature")
+
+// EmailLog is one email handed to Mailgun. Status is the furthest the email has got, so an
+// opened email doesn't go back to delivered when a late delivered event arrives.
+type EmailLog struct {
+	UUID          uuid.UUID    `gorm:"type:uuid;primaryKey" json:"uuid"`
+	MessageID     string       `json:"message_id"`
+	RequestID     *uuid.UUID   `gorm:"type:uuid" json:"request_id,omitempty"`
+	InvoiceID     *uuid.UUID   `gorm:"type:uuid" json:"invoice_id,omitempty"`
+	OutboxEmailID *uuid.UUID   `gorm:"type:uuid" json:"outbox_email_id,omitempty"`
+	Kind          string       `json:"kind"`
+	Recipient     string       `json:"recipient"`
+	Subject       string       `json:"subject"`
+	Status        string       `json:"status"`
+	SentAt        time.Time    `json:"sent_at"`
+	DeliveredAt   *time.Time   `json:"delivered_at,omitempty"`
+	OpenedAt      *time.Time   `json:"opened_at,omitempty"`
+	ClickedAt     *time.Time   `json:"clicked_at,omitempty"`
+	BouncedAt     *time.Time   `json:"bounced_at,omitempty"`
+	ComplainedAt  *time.Time   `json:"complained_at,omitempty"`
+	UpdatedAt     time.Time    `gorm:"autoUpdateTime" json:"updated_at"`
+	Events        []EmailEvent `gorm:"foreignKey:EmailLogID" json:"events,omitempty"`
+}
+
+// EmailEvent is a single webhook event for a logged email
+type EmailEvent struct {
+	UUID           uuid.UUID `gorm:"type:uuid;primaryKey" json:"uuid"`
+	EmailLogID     uuid.UUID `gorm:"type:uuid" json:"email_log_id"`
+	MailgunEventID string    `json:"mailgun_event_id"`
+	Event          string    `json:"event"`
+	Recipient      string    `json:"recipient"`
+	Severity       string    `json:"severity,omitempty"`
+	Reason         string    `json:"reason,omitempty"`
+	URL            string    `json:"url,omitempty"`
+	OccurredAt     time.Time `json:"occurred_at"`
+	CreatedAt      time.Time `gorm:"autoCreateTime" json:"created_at"`
+
+	// the Mailgun message the event belongs to, only used to find the log
+	MessageID string `gorm:"-" json:"-"`
+}
+
+// emailEventRank orders statuses so events arriving out of order can't move an email backwards
+var emailEventRank = map[string]int{
+	EmailEventSent:       0,
+	EmailEventFailed:     1,
+	EmailEventDelivered:  2,
+	EmailEventOpened:     3,
+	EmailEventClicked:    4,
+	EmailEventBounced:    5,
+	EmailEventComplained: 6,
+}
+
+// Apply records the event's timestamp on the log and moves the status forward
+func (l *EmailLog) Apply(event *EmailEvent) {
+	at := event.OccurredAt
+	switch event.Event {
+	case EmailEventDelivered:
+		l.DeliveredAt = &at
+	case EmailEventOpened:
+		if l.OpenedAt == nil {
+			l.OpenedAt = &at
+		}
+	case EmailEventClicked:
+		if l.ClickedAt == nil {
+			l.ClickedAt = &at
+		}
+	case EmailEventBounced:
+		l.BouncedAt = &at
+	case EmailEventComplained:
+		l.ComplainedAt = &at
+	}
+
+	if emailEventRank[event.Event] > emailEventRank[l.Status] {
+		l.Status = event.Event
+	}
+}
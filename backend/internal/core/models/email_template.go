@@ -0,0 +1,319 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Emails that can be rendered from a template
+const (
+	EmailTemplateInvoice             = "invoice"
+	EmailTemplateFollowUp            = "follow_up"
+	EmailTemplatePaymentConfirmation = "payment_confirmation"
+	EmailTemplateCustom              = "custom"
+)
+
+// EmailTemplateNames lists every template, in the order the admin UI shows them
+var EmailTemplateNames = []string{
+	EmailTemplateInvoice,
+	EmailTemplateFollowUp,
+	EmailTemplatePaymentConfirmation,
+	EmailTemplateCustom,
+}
+
+// ErrUnknownEmailTemplate is returned for a template name that isn't in EmailTemplateNames
+var ErrUnknownEmailTemplate = errors.New("unknown email template")
+
+// EmailTemplate is one saved version of a template. Saving never edits a row, it adds the next
+// version, and the highest version is the one in use. A template with no branch is the default
+// for branches that haven't customised it.
+type EmailTemplate struct {
+	UUID      uuid.UUID  `gorm:"type:uuid;primaryKey" json:"uuid"`
+	BranchID  *uuid.UUID `gorm:"type:uuid" json:"branch_id,omitempty"`
+	Name      string     `json:"name"`
+	Version   int        `json:"version"`
+	Subject   string     `json:"subject"`
+	HTML      string     `gorm:"column:html" json:"html"`
+	Text      string     `json:"text"`
+	CreatedAt time.Time  `gorm:"autoCreateTime" json:"created_at"`
+}
+
+// RenderedEmail is a template executed against its view model
+type RenderedEmail struct {
+	Subject string `json:"subject"`
+	HTML    string `json:"html"`
+	Text    string `json:"text"`
+}
+
+// InvoiceEmailView is the data the invoice template sees
+type InvoiceEmailView struct {
+	ClientName     string
+	ClientEmail    string
+	RequestID      string
+	InvoiceNumber  string
+	BranchName     string
+	DueDate        time.Time
+	PaymentTerms   string
+	Items          []InvoiceEmailItem
+	Subtotal       Money
+	DiscountLabel  string
+	Discount       Money
+	ServiceFee     Money
+	TransactionFee Money
+	Total          Money
+	Balance        Money
+	Notes          string
+	PaymentURL     string
+}
+
+// InvoiceEmailItem is a staff line on the invoice email, with its surcharges itemized below it
+type InvoiceEmailItem struct {
+	Position   string
+	Date       time.Time
+	StartTime  time.Time
+	EndTime    time.Time
+	Count      int
+	Rate       Money
+	Amount     Money
+	Surcharges []InvoiceEmailSurcharge
+}
+
+type InvoiceEmailSurcharge struct {
+	Description string
+	Percent     string
+	Hours       float64
+	Amount      Money
+}
+
+// FollowUpEmailView is the data the payment reminder template sees. DaysPastDue is negative
+// for reminders sent before the due date.
+type FollowUpEmailView struct {
+	ClientName    string
+	RequestID     string
+	InvoiceNumber string
+	DueDate       time.Time
+	DaysPastDue   int
+	Balance       Money
+	PaymentURL    string
+}
+
+// DaysUntilDue is DaysPastDue flipped for reminders sent ahead of the due date
+func (v FollowUpEmailView) DaysUntilDue() int {
+	return -v.DaysPastDue
+}
+
+// PaymentConfirmationEmailView is the data the payment receipt template sees
+type PaymentConfirmationEmailView struct {
+	ClientName    string
+	RequestID     string
+	InvoiceNumber string
+	AmountPaid    Money
+	TotalPaid     Money
+	Balance       Money
+	PaidAt        time.Time
+}
+
+// CustomEmailView is a message typed by staff. Paragraphs are split on blank lines and each
+// paragraph into lines, the template escapes them.
+type CustomEmailView struct {
+	ClientName    string
+	RequestID     string
+	InvoiceNumber string
+	Paragraphs    [][]string
+	Balance       Money
+	PaymentURL    string
+}
+
+func clientName(request Request) string {
+	return strings.TrimSpace(request.FirstName + " " + request.LastName)
+}
+
+// NewInvoiceEmailView builds the invoice template data from an invoice with its request loaded
+func NewInvoiceEmailView(invoice *Invoice, staffRequirements []StaffRequirement, paymentURL string) InvoiceEmailView {
+	view := InvoiceEmailView{
+		ClientName:     clientName(invoice.Request),
+		ClientEmail:    invoice.Request.Email,
+		RequestID:      invoice.RequestID.String(),
+		InvoiceNumber:  invoice.Number(),
+		BranchName:     invoice.Request.ClosestBranchName,
+		DueDate:        invoice.DueDate,
+		PaymentTerms:   invoice.PaymentTerms,
+		Subtotal:       invoice.Subtotal,
+		Discount:       invoice.DiscountAmount,
+		ServiceFee:     invoice.ServiceFee,
+		TransactionFee: invoice.TransactionFee,
+		Total:          invoice.Amount,
+		Balance:        invoice.Balance,
+		Notes:          invoice.Notes,
+		PaymentURL:     paymentURL,
+	}
+
+	if invoice.DiscountAmount > 0 {
+		view.DiscountLabel = discountLabel(invoice)
+	}
+
+	for _, req := range staffRequirements {
+		item := InvoiceEmailItem{
+			Position:  req.Position,
+			Date:      req.Date,
+			StartTime: req.StartTime,
+			EndTime:   req.EndTime,
+			Count:     req.Count,
+			Rate:      req.Rate,
+			Amount:    req.Amount,
+		}
+		// the base charge is the staff line itself
+		for _, charge := range req.Charges {
+			if charge.Type == ChargeTypeBase {
+				continue
+			}
+			item.Surcharges = append(item.Surcharges, InvoiceEmailSurcharge{
+				Description: charge.Description,
+				Percent:     strconv.FormatFloat(charge.Percent, 'f', -1, 64),
+				Hours:       charge.Hours,
+				Amount:      charge.Amount,
+			})
+		}
+		view.Items = append(view.Items, item)
+	}
+
+	return view
+}
+
+// discountLabel describes the invoice discount, e.g. "Discount (10%)" or "Discount (SUMMER25)"
+func discountLabel(invoice *Invoice) string {
+	switch {
+	case invoice.PromoCode != "":
+		return fmt.Sprintf("Discount (%s)", invoice.PromoCode)
+	case invoice.DiscountType == DiscountTypePercentage:
+		return fmt.Sprintf("Discount (%s%%)", strconv.FormatFloat(invoice.DiscountPercent, 'f', -1, 64))
+	default:
+		return "Discount"
+	}
+}
+
+// NewFollowUpEmailView builds the reminder template data, counting whole days from the due date
+func NewFollowUpEmailView(invoice *Invoice, paymentURL string, now time.Time) FollowUpEmailView {
+	return FollowUpEmailView{
+		ClientName:    clientName(invoice.Request),
+		RequestID:     invoice.RequestID.String(),
+		InvoiceNumber: invoice.Number(),
+		DueDate:       invoice.DueDate,
+		DaysPastDue:   int(truncateToDay(now).Sub(truncateToDay(invoice.DueDate)).Hours() / 24),
+		Balance:       invoice.Balance,
+		PaymentURL:    paymentURL,
+	}
+}
+
+func NewPaymentConfirmationEmailView(invoice *Invoice, amountPaid Money, paidAt time.Time) PaymentConfirmationEmailView {
+	return PaymentConfirmationEmailView{
+		ClientName:    clientName(invoice.Request),
+		RequestID:     invoice.RequestID.String(),
+		InvoiceNumber: invoice.Number(),
+		AmountPaid:    amountPaid,
+		TotalPaid:     invoice.AmountPaid,
+		Balance:       invoice.Balance,
+		PaidAt:        paidAt,
+	}
+}
+
+func NewCustomEmailView(invoice *Invoice, content string, paymentURL string) CustomEmailView {
+	view := CustomEmailView{
+		ClientName:    clientName(invoice.Request),
+		RequestID:     invoice.RequestID.String(),
+		InvoiceNumber: invoice.Number(),
+		Balance:       invoice.Balance,
+		PaymentURL:    paymentURL,
+	}
+
+	content = strings.ReplaceAll(strings.TrimSpace(content), "\r\n", "\n")
+	for _, paragraph := range strings.Split(content, "\n\n") {
+		paragraph = strings.TrimSpace(paragraph)
+		if paragraph == "" {
+			continue
+		}
+		view.Paragraphs = append(view.Paragraphs, strings.Split(paragraph, "\n"))
+	}
+
+	return view
+}
+
+// SampleEmailView returns made up data for previewing a template without a real invoice
+func SampleEmailView(name string) (interface{}, error) {
+	due := truncateToDay(time.Now()).AddDate(0, 0, 14)
+	eventDay := due.AddDate(0, 0, 7)
+	start := eventDay.Add(18 * time.Hour)
+
+	switch name {
+	case EmailTemplateInvoice:
+		return InvoiceEmailView{
+			ClientName:    "Jane Smith",
+			ClientEmail:   "jane@example.com",
+			RequestID:     "00000000-0000-0000-0000-000000000000",
+			InvoiceNumber: "LA-2026-000123",
+			BranchName:    "Los Angeles",
+			DueDate:       due,
+			PaymentTerms:  "Net 14",
+			Items: []InvoiceEmailItem{{
+				Position:  "Bartender",
+				Date:      eventDay,
+				StartTime: start,
+				EndTime:   start.Add(6 * time.Hour),
+				Count:     2,
+				Rate:      4500,
+				Amount:    58500,
+				Surcharges: []InvoiceEmailSurcharge{{
+					Description: "Overtime",
+					Percent:     "50",
+					Hours:       2,
+					Amount:      4500,
+				}},
+			}},
+			Subtotal:       58500,
+			DiscountLabel:  "Discount (10%)",
+			Discount:       5850,
+			ServiceFee:     16349,
+			TransactionFee: 1843,
+			Total:          70842,
+			Balance:        70842,
+			Notes:          "Please arrive 30 minutes early.",
+			PaymentURL:     "https://checkout.stripe.com/example",
+		}, nil
+	case EmailTemplateFollowUp:
+		return FollowUpEmailView{
+			ClientName:    "Jane Smith",
+			RequestID:     "00000000-0000-0000-0000-000000000000",
+			InvoiceNumber: "LA-2026-000123",
+			DueDate:       due.AddDate(0, 0, -21),
+			DaysPastDue:   7,
+			Balance:       70842,
+			PaymentURL:    "https://checkout.stripe.com/example",
+		}, nil
+	case EmailTemplatePaymentConfirmation:
+		return PaymentConfirmationEmailView{
+			ClientName:    "Jane Smith",
+			RequestID:     "00000000-0000-0000-0000-000000000000",
+			InvoiceNumber: "LA-2026-000123",
+			AmountPaid:    70842,
+			TotalPaid:     70842,
+			Balance:       0,
+			PaidAt:        time.Now(),
+		}, nil
+	case EmailTemplateCustom:
+		return CustomEmailView{
+			ClientName:    "Jane Smith",
+			RequestID:     "00000000-0000-0000-0000-000000000000",
+			InvoiceNumber: "LA-2026-000123",
+			Paragraphs:    [][]string{{"Hi Jane,"}, {"Your invoice is attached.", "Let us know if you have any questions."}},
+			Balance:       70842,
+			PaymentURL:    "https://checkout.stripe.com/example",
+		}, nil
+	default:
+		return nil, ErrUnknownEmailTemplate
+	}
+}
@@ -1,27 +1,110 @@
 package models
 
 import (
+	"errors"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
+// Outbox statuses. Failed emails are retried with backoff until they run out of attempts and
+// become dead, cancelled emails are kept for the record but never sent.
+const (
+	EmailStatusPending   = "pending"
+	EmailStatusSending   = "sending"
+	EmailStatusSent      = "sent"
+	EmailStatusFailed    = "failed"
+	EmailStatusDead      = "dead"
+	EmailStatusCancelled = "cancelled"
+)
+
+// ErrEmailNotFound is returned when no outbox row matches the given id
+var ErrEmailNotFound = errors.New("email not found")
+
+// ErrEmailNotEditable is returned when an email has already been sent, cancelled or picked up by the outbox
+var ErrEmailNotEditable = errors.New("email can no longer be changed")
+
+// DefaultEmailMaxAttempts is how many times the outbox tries to send an email before giving up
+const DefaultEmailMaxAttempts = 5
+
+// Email is a row in the email outbox, scheduled emails wait here until SendAt
 type Email struct {
-	RequestID uuid.UUID `gorm:"not null"`
-	ID        uuid.UUID `gorm:"type:uuid;primary_key;"`
-	Subject   string    `gorm:"not null"`
-	CC        []string  `gorm:"type:text[]"`
-	BCC       []string  `gorm:"type:text[]"`
-	ReplyTo   string    `gorm:"not null"`
-	CreatedAt time.Time `gorm:"not null"`
-	UpdatedAt time.Time `gorm:"not null"`
-	Content   string    `gorm:"not null"`
-	SendAt    time.Time `gorm:"not null"`
+	RequestID     uuid.UUID      `gorm:"not null"`
+	InvoiceID     *uuid.UUID     `gorm:"type:uuid"`
+	ID            uuid.UUID      `gorm:"type:uuid;primary_key;"`
+	Subject       string         `gorm:"not null"`
+	CC            pq.StringArray `gorm:"type:text[]"`
+	BCC           pq.StringArray `gorm:"type:text[]"`
+	AttachmentIDs pq.StringArray `gorm:"type:text[]"`
+	ReplyTo       string         `gorm:"not null"`
+	CreatedAt     time.Time      `gorm:"not null"`
+	UpdatedAt     time.Time      `gorm:"not null"`
+	Content       string         `gorm:"not null"`
+	TextContent   string         `gorm:"not null"`
+	SendAt        time.Time      `gorm:"not null"`
+	Status        string         `gorm:"not null"`
+	Attempts      int            `gorm:"not null"`
+	MaxAttempts   int            `gorm:"not null"`
+	NextAttemptAt time.Time      `gorm:"not null"`
+	LastError     string         `gorm:"not null"`
+	SentAt        *time.Time
+}
+
+func (Email) TableName() string {
+	return "email_outbox"
+}
+
+// Editable reports whether the email is still waiting to be sent and can be changed or cancelled
+func (e *Email) Editable() bool {
+	return e.Status == EmailStatusPending || e.Status == EmailStatusFailed
 }
 
+// EmailHeaders are the optional extras every send path accepts. Attachments are uploaded first
+// and referenced here by ID.
 type EmailHeaders struct {
-	Subject string   `json:"subject,omitempty"`
-	CC      []string `json:"cc,omitempty"`
-	BCC     []string `json:"bcc,omitempty"`
-	ReplyTo string   `json:"replyTo,omitempty"`
+	Subject       string      `json:"subject,omitempty"`
+	CC            []string    `json:"cc,omitempty"`
+	BCC           []string    `json:"bcc,omitempty"`
+	ReplyTo       string      `json:"replyTo,omitempty"`
+	AttachmentIDs []uuid.UUID `json:"attachmentIds,omitempty"`
+}
+
+// AttachmentIDStrings is AttachmentIDs in the form the outbox stores them
+func (h EmailHeaders) AttachmentIDStrings() pq.StringArray {
+	if len(h.AttachmentIDs) == 0 {
+		return nil
+	}
+	ids := make(pq.StringArray, len(h.AttachmentIDs))
+	for i, id := range h.AttachmentIDs {
+		ids[i] = id.String()
+	}
+	return ids
+}
+
+// ScheduledEmailUpdate is a partial update to a scheduled email, nil fields are left alone
+type ScheduledEmailUpdate struct {
+	SendAt  *time.Time    `json:"send_at,omitempty"`
+	Content *string       `json:"content,omitempty"`
+	Headers *EmailHeaders `json:"headers,omitempty"`
+}
+
+// MailMessage is a fully rendered email ready for a mail transport
+type MailMessage struct {
+	From        string
+	To          []string
+	CC          []string
+	BCC         []string
+	ReplyTo     string
+	Subject     string
+	HTML        string
+	Text        string
+	Attachments []MailAttachment
+}
+
+// MailAttachment is a file sent along with a MailMessage
+type MailAttachment struct {
+	Filename    string
+	ContentType string
+	Data        []byte
 }
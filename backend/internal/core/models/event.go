@@ -1,25 +1,153 @@
 package models
 
 import (
+	"errors"
+	"fmt"
+	"slices"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+// Event lifecycle. An event is scheduled once its request is confirmed, runs while staff are on
+// site and is completed or cancelled after. Completed and cancelled are final.
+const (
+	EventStatusScheduled  = "scheduled"
+	EventStatusInProgress = "in_progress"
+	EventStatusCompleted  = "completed"
+	EventStatusCancelled  = "cancelled"
+)
+
+var (
+	ErrEventNotFound          = errors.New("event not found")
+	ErrShiftNotFound          = errors.New("shift not found")
+	ErrInvalidEventTransition = errors.New("invalid event status transition")
+	ErrEventClosed            = errors.New("event is completed or cancelled")
+	ErrRequestNotConfirmed    = errors.New("request has no payment yet")
+	ErrInvalidShiftTimes      = errors.New("shift must end after it starts")
+	ErrEventRequestCancelled  = errors.New("request has been cancelled")
+)
+
+// the statuses each status may move to, completed and cancelled are final
+var eventTransitions = map[string][]string{
+	EventStatusScheduled:  {EventStatusInProgress, EventStatusCancelled},
+	EventStatusInProgress: {EventStatusCompleted, EventStatusCancelled},
+}
+
+// ValidateEventTransition returns ErrInvalidEventTransition, naming both statuses, when the move
+// isn't allowed
+func ValidateEventTransition(from, to string) error {
+	if !slices.Contains(eventTransitions[from], to) {
+		return fmt.Errorf("%w: %s to %s", ErrInvalidEventTransition, from, to)
+	}
+	return nil
+}
+
+// Event is the staffed occasion a confirmed request turns into, its shifts are the windows
+// staff work in
 type Event struct {
-	UUID       uuid.UUID `gorm:"type:uuid;primaryKey"`
-	RequestID  uuid.UUID
-	StartDate  time.Time
-	EndDate    time.Time
-	StartHour  time.Time
-	EndHour    time.Time
-	Status     string
-	Notes      string
-	BranchID   uuid.UUID
-	BranchName string
-	CreatedAt  time.Time
-
-	Request Request `gorm:"foreignKey:RequestID"`
-	Branch  Branch  `gorm:"foreignKey:BranchID"`
-	Shifts  []Shift `gorm:"foreignKey:EventID"`
+	UUID       uuid.UUID `gorm:"type:uuid;primaryKey" json:"uuid"`
+	RequestID  uuid.UUID `json:"request_id"`
+	StartDate  time.Time `json:"start_date"`
+	EndDate    time.Time `json:"end_date"`
+	StartHour  time.Time `json:"start_hour"`
+	EndHour    time.Time `json:"end_hour"`
+	Status     string    `json:"status"`
+	Notes      string    `json:"notes"`
+	BranchID   uuid.UUID `json:"branch_id"`
+	BranchName string    `json:"branch_name"`
+	CreatedAt  time.Time `json:"created_at"`
+
+	Request Request `gorm:"foreignKey:RequestID" json:"-"`
+	Branch  Branch  `gorm:"foreignKey:BranchID" json:"-"`
+	Shifts  []Shift `gorm:"foreignKey:EventID" json:"shifts,omitempty"`
+}
+
+// Closed reports whether the event is over or called off and can't change anymore
+func (e *Event) Closed() bool {
+	return e.Status == EventStatusCompleted || e.Status == EventStatusCancelled
+}
+
+// NewEventForRequest schedules the request's event with one shift per date and time window
+// among its staff requirements, positions sharing a window share the shift. A request without
+// staff requirements gets an event over its dates and no shifts.
+func NewEventForRequest(request *Request, staff []StaffRequirement) *Event {
+	event := &Event{
+		UUID:       uuid.New(),
+		RequestID:  request.UUID,
+		StartDate:  request.StartDate,
+		EndDate:    request.EndDate,
+		StartHour:  request.StartDate,
+		EndHour:    request.EndDate,
+		Status:     EventStatusScheduled,
+		BranchID:   request.ClosestBranchID,
+		BranchName: request.ClosestBranchName,
+		CreatedAt:  time.Now().UTC(),
+	}
+
+	type window struct {
+		date       string
+		start, end int64
+	}
+	shifts := map[window]*Shift{}
+	for _, requirement := range staff {
+		key := window{requirement.Date.Format(time.DateOnly), requirement.StartTime.Unix(), requirement.EndTime.Unix()}
+		shift, ok := shifts[key]
+		if !ok {
+			shift = &Shift{
+				UUID:      uuid.New(),
+				EventID:   event.UUID,
+				Date:      requirement.Date,
+				StartTime: requirement.StartTime,
+				EndTime:   requirement.EndTime,
+			}
+			shifts[key] = shift
+		}
+		shift.StaffNeeded += requirement.Count
+	}
+
+	for _, shift := range shifts {
+		event.Shifts = append(event.Shifts, *shift)
+	}
+	slices.SortFunc(event.Shifts, func(a, b Shift) int {
+		if c := a.Date.Compare(b.Date); c != 0 {
+			return c
+		}
+		if c := a.StartTime.Compare(b.StartTime); c != 0 {
+			return c
+		}
+		return a.EndTime.Compare(b.EndTime)
+	})
+
+	// the event runs from its first shift to its last
+	for i, shift := range event.Shifts {
+		if i == 0 || shift.Date.Before(event.StartDate) {
+			event.StartDate = shift.Date
+		}
+		if i == 0 || shift.Date.After(event.EndDate) {
+			event.EndDate = shift.Date
+		}
+		if i == 0 || shift.StartTime.Before(event.StartHour) {
+			event.StartHour = shift.StartTime
+		}
+		if i == 0 || shift.EndTime.After(event.EndHour) {
+			event.EndHour = shift.EndTime
+		}
+	}
+	return event
+}
+
+// EventFilter narrows the event list, zero fields don't filter
+type EventFilter struct {
+	BranchID *uuid.UUID
+	Status   string
+	// events running on or after From and on or before To
+	From *time.Time
+	To   *time.Time
+}
+
+// EventStatusUpdate moves an event along its lifecycle
+type EventStatusUpdate struct {
+	Status string  `json:"status" binding:"required"`
+	Notes  *string `json:"notes"`
 }
@@ -1,44 +1,207 @@
 package models
 
 import (
+	"errors"
+	"fmt"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+// A request is billed by an optional deposit, change orders for anything added once the final
+// invoice has gone out, and the final invoice, which bills whatever the others haven't
+const (
+	InvoiceKindDeposit     = "deposit"
+	InvoiceKindChangeOrder = "change_order"
+	InvoiceKindFinal       = "final"
+)
+
+var ErrNothingToInvoice = errors.New("nothing to invoice")
+
+// ErrFinalInvoiceExists is returned when creating a final invoice for a request that already has
+// a live one, void it first or add a change order instead
+var ErrFinalInvoiceExists = errors.New("request already has a final invoice")
+
+// ErrInvoiceNotDraft is returned when changing the discount on an invoice that has gone out, the
+// client has already been billed the total
+var ErrInvoiceNotDraft = errors.New("discounts can only be changed on a draft invoice")
+
+// ErrInvoiceNumbered is returned when deleting an invoice that has gone out, void it instead
+var ErrInvoiceNumbered = errors.New("invoice has been numbered and can only be voided")
+
+// Sequence numbers a request's invoices in the order they were issued, starting at 1.
+// PriorInvoiced is the deposits and change orders deducted from a final invoice's total.
+// InvoiceNumber is the client-facing number, allocated when the invoice first goes out.
 type Invoice struct {
 	UUID              uuid.UUID `gorm:"type:uuid;primaryKey"`
 	RequestID         uuid.UUID
+	Kind              string `json:"kind"`
+	Sequence          int    `json:"sequence"`
 	DueDate           time.Time
-	Subtotal          float64
+	Subtotal          Money
 	DiscountType      string
-	DiscountValue     float64
-	TransactionFee    float64
-	ServiceFee        float64
-	Amount            float64
-	AmountPaid        float64
-	Balance           float64
+	DiscountPercent   float64
+	DiscountFixed     Money
+	DiscountAmount    Money
+	PromoCode         string
+	TransactionFee    Money
+	ServiceFee        Money
+	PriorInvoiced     Money `json:"prior_invoiced"`
+	Amount            Money
+	AmountPaid        Money
+	Balance           Money
 	Status            string
 	PaymentTerms      string
 	Notes             string
 	ShipTo            string
 	POEditCounter     float64 `json:"po_edit_counter"`
 	PONumber          string  `json:"po_number"`
+	InvoiceNumber     *string `json:"invoice_number"`
 	PaymentIntent     string
 	LastSent          time.Time `json:"last_sent"`
 	FollowUpCount     int       `json:"follow_up_count"`
 	FollowUpDelayDays int       `json:"follow_up_delay"`
+	// date the last dunning reminder went out
+	FollowUpDate *time.Time `gorm:"type:date" json:"follow_up_date,omitempty"`
+	// set once the balance has been charged to a saved card after the event, that's only tried once
+	AutoChargeAttemptedAt *time.Time `json:"auto_charge_attempted_at,omitempty"`
 
 	TermsAndConditions string  `json:"terms_and_conditions"`
 	Request            Request `gorm:"foreignKey:RequestID"`
 }
 
+// DefaultBranchCode prefixes invoice numbers for requests without a branch code
+const DefaultBranchCode = "EVS"
+
+// FormatInvoiceNumber builds the client-facing number, e.g. LA-2026-000123
+func FormatInvoiceNumber(branchCode string, year int, number int) string {
+	return fmt.Sprintf("%s-%d-%06d", branchCode, year, number)
+}
+
+// Number is the invoice number shown to clients, "Draft" until one has been allocated
+func (i *Invoice) Number() string {
+	if i.InvoiceNumber == nil || *i.InvoiceNumber == "" {
+		return "Draft"
+	}
+	return *i.InvoiceNumber
+}
+
+// Discount returns the discount currently applied to the invoice
+func (i *Invoice) Discount() Discount {
+	return Discount{Type: i.DiscountType, Percent: i.DiscountPercent, Fixed: i.DiscountFixed, PromoCode: i.PromoCode}
+}
+
+// SetDiscount records the discount on the invoice, ApplyBreakdown sets the amount it takes off
+func (i *Invoice) SetDiscount(discount Discount) {
+	i.DiscountType = discount.Type
+	i.DiscountPercent = discount.Percent
+	i.DiscountFixed = discount.Fixed
+	i.PromoCode = discount.PromoCode
+}
+
+// ApplyBreakdown copies calculated totals onto the invoice, less anything already invoiced
+func (i *Invoice) ApplyBreakdown(breakdown *RateBreakdown) {
+	i.Subtotal = breakdown.Subtotal
+	i.DiscountAmount = breakdown.Discount
+	i.TransactionFee = breakdown.TransactionFee
+	i.ServiceFee = breakdown.ServiceFee
+	i.Amount = breakdown.Amount - i.PriorInvoiced
+}
+
+// KindLabel names the kind of invoice for clients, e.g. on the PDF and in Stripe checkout
+func (i *Invoice) KindLabel() string {
+	switch i.Kind {
+	case InvoiceKindDeposit:
+		return "Deposit"
+	case InvoiceKindChangeOrder:
+		return "Change Order"
+	default:
+		return "Invoice"
+	}
+}
+
+// FinalInvoice returns the request's live final invoice, nil if it has none
+func FinalInvoice(invoices []Invoice) *Invoice {
+	for i := range invoices {
+		if invoices[i].Kind == InvoiceKindFinal && invoices[i].Status != InvoiceStatusVoid {
+			return &invoices[i]
+		}
+	}
+	return nil
+}
+
+// PriorInvoiced totals the live deposits and change orders a final invoice deducts
+func PriorInvoiced(invoices []Invoice) Money {
+	var total Money
+	for _, invoice := range invoices {
+		if invoice.Kind != InvoiceKindFinal && invoice.Status != InvoiceStatusVoid {
+			total += invoice.Amount
+		}
+	}
+	return total
+}
+
+// InvoicedBreakdown is the part of the request already priced on its invoices: the final invoice
+// before deposits are taken off, plus every change order. Deposits are advances against the
+// final invoice so they add nothing.
+func InvoicedBreakdown(invoices []Invoice) RateBreakdown {
+	var breakdown RateBreakdown
+	for _, invoice := range invoices {
+		if invoice.Status == InvoiceStatusVoid || invoice.Kind == InvoiceKindDeposit {
+			continue
+		}
+		breakdown.Subtotal += invoice.Subtotal
+		breakdown.Discount += invoice.DiscountAmount
+		breakdown.TransactionFee += invoice.TransactionFee
+		breakdown.ServiceFee += invoice.ServiceFee
+		breakdown.Amount += invoice.Amount
+		if invoice.Kind == InvoiceKindFinal {
+			breakdown.Amount += invoice.PriorInvoiced
+		}
+	}
+	return breakdown
+}
+
+// Deposit asks for a deposit invoice of either a fixed amount or a percent of the request total
+type Deposit struct {
+	Amount  Money   `json:"amount"`
+	Percent float64 `json:"percent"`
+	// defaults to a week out, never after the event
+	DueDate *time.Time `json:"due_date"`
+}
+
+// RequestInvoiceSummary rolls up every invoice issued for a request. Void invoices count towards
+// what was paid, since a cancellation can keep part of it, but not towards what is owed.
+type RequestInvoiceSummary struct {
+	RequestID     uuid.UUID `json:"request_id"`
+	Invoices      []Invoice `json:"invoices"`
+	TotalInvoiced Money     `json:"total_invoiced"`
+	TotalPaid     Money     `json:"total_paid"`
+	TotalBalance  Money     `json:"total_balance"`
+}
+
+func SummarizeInvoices(requestID uuid.UUID, invoices []Invoice) *RequestInvoiceSummary {
+	summary := &RequestInvoiceSummary{RequestID: requestID, Invoices: invoices}
+	for _, invoice := range invoices {
+		summary.TotalPaid += invoice.AmountPaid
+		if invoice.Status == InvoiceStatusVoid {
+			continue
+		}
+		summary.TotalInvoiced += invoice.Amount
+		summary.TotalBalance += invoice.Balance
+	}
+	return summary
+}
+
 type InvoiceResponse struct {
 	UUID      uuid.UUID `json:"id"` // Invoice UUID
 	RequestID uuid.UUID `json:"request_id"`
+	Kind      string    `json:"kind"`
+	Sequence  int       `json:"sequence"`
+	Number    *string   `json:"invoice_number"`
 	DueDate   time.Time `json:"due_date"`
-	Amount    float64   `json:"amount"`
-	Balance   float64   `json:"balance"`
+	Amount    Money     `json:"amount"`
+	Balance   Money     `json:"balance"`
 	Status    string    `json:"status"`
 	PONumber  string    `json:"po_number"`
 
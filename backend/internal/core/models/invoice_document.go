@@ -0,0 +1,18 @@
+package models
+
+// InvoiceDocument is everything printed on an invoice PDF
+type InvoiceDocument struct {
+	Invoice            *Invoice
+	StaffRequirements  []StaffRequirement
+	CustomLineItems    []CustomLineItems
+	TermsAndConditions string
+}
+
+// InvoicePDFFilename is the name the invoice PDF is downloaded and attached as, e.g.
+// invoice-LA-2026-000123.pdf
+func InvoicePDFFilename(invoice *Invoice) string {
+	if invoice.InvoiceNumber == nil || *invoice.InvoiceNumber == "" {
+		return "invoice-draft.pdf"
+	}
+	return "invoice-" + *invoice.InvoiceNumber + ".pdf"
+}
@@ -0,0 +1,114 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Invoice lifecycle. A draft is still being priced, sent has gone to the client, payment
+// statuses are derived from the payment ledger and overdue is set by the sweep once the due
+// date passes. Void marks the invoice of a cancelled request, whatever was kept after the
+// cancellation refund is final and nothing more is owed.
+const (
+	InvoiceStatusDraft         = "draft"
+	InvoiceStatusSent          = "sent"
+	InvoiceStatusPartiallyPaid = "partially_paid"
+	InvoiceStatusPaid          = "paid"
+	InvoiceStatusOverdue       = "overdue"
+	InvoiceStatusVoid          = "void"
+	InvoiceStatusRefunded      = "refunded"
+)
+
+var ErrInvalidInvoiceTransition = errors.New("invalid invoice status transition")
+
+// the statuses each status may move to, void and refunded are final
+var invoiceTransitions = map[string][]string{
+	InvoiceStatusDraft:         {InvoiceStatusSent, InvoiceStatusPartiallyPaid, InvoiceStatusPaid, InvoiceStatusVoid},
+	InvoiceStatusSent:          {InvoiceStatusPartiallyPaid, InvoiceStatusPaid, InvoiceStatusOverdue, InvoiceStatusVoid},
+	InvoiceStatusPartiallyPaid: {InvoiceStatusPaid, InvoiceStatusOverdue, InvoiceStatusRefunded, InvoiceStatusVoid},
+	InvoiceStatusOverdue:       {InvoiceStatusPaid, InvoiceStatusRefunded, InvoiceStatusVoid},
+	// a partial refund, or line items added after payment, reopen a paid invoice
+	InvoiceStatusPaid: {InvoiceStatusPartiallyPaid, InvoiceStatusRefunded, InvoiceStatusVoid},
+}
+
+// IsInvoiceStatus reports whether status is part of the invoice lifecycle
+func IsInvoiceStatus(status string) bool {
+	if status == InvoiceStatusVoid || status == InvoiceStatusRefunded {
+		return true
+	}
+	_, ok := invoiceTransitions[status]
+	return ok
+}
+
+// CanTransitionInvoice reports whether an invoice may move from one status to another
+func CanTransitionInvoice(from, to string) bool {
+	for _, allowed := range invoiceTransitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateInvoiceTransition returns ErrInvalidInvoiceTransition, naming both statuses, when the
+// move isn't allowed
+func ValidateInvoiceTransition(from, to string) error {
+	if !IsInvoiceStatus(to) {
+		return fmt.Errorf("%w: unknown status %q", ErrInvalidInvoiceTransition, to)
+	}
+	if !CanTransitionInvoice(from, to) {
+		return fmt.Errorf("%w: %s to %s", ErrInvalidInvoiceTransition, from, to)
+	}
+	return nil
+}
+
+// IsInvoiceOverdue reports whether an invoice is marked overdue, or is open past its due date
+// with money still owed and just hasn't been swept yet
+func IsInvoiceOverdue(status string, balance Money, dueDate, now time.Time) bool {
+	if status == InvoiceStatusOverdue {
+		return true
+	}
+	return CanTransitionInvoice(status, InvoiceStatusOverdue) && balance > 0 && dueDate.Before(now)
+}
+
+// InvoiceStatusChange is one entry in an invoice's status history
+type InvoiceStatusChange struct {
+	UUID       uuid.UUID `gorm:"type:uuid;primaryKey" json:"uuid"`
+	InvoiceID  uuid.UUID `gorm:"type:uuid" json:"invoice_id"`
+	FromStatus string    `json:"from_status"`
+	ToStatus   string    `json:"to_status"`
+	// user email, or system/stripe for changes nobody made by hand
+	Actor     string    `json:"actor"`
+	Reason    string    `json:"reason"`
+	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`
+}
+
+func (InvoiceStatusChange) TableName() string {
+	return "invoice_status_history"
+}
+
+// Actors for changes made by the backend itself
+const (
+	ActorSystem  = "system"
+	ActorStripe  = "stripe"
+	ActorUnknown = "unknown"
+)
+
+type actorKey struct{}
+
+// WithActor records who is acting for the rest of the request
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFromContext is who WithActor recorded, ActorUnknown when nobody was
+func ActorFromContext(ctx context.Context) string {
+	if actor, ok := ctx.Value(actorKey{}).(string); ok && actor != "" {
+		return actor
+	}
+	return ActorUnknown
+}
@@ -0,0 +1,213 @@
+package models
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Money is an exact amount in US cents. It is stored in the existing NUMERIC columns as a
+// decimal dollar value and serialized to JSON as a dollar number, so API payloads keep the
+// shape they had when amounts were float64.
+type Money int64
+
+// MoneyFromCents wraps an amount already expressed in cents, e.g. a Stripe amount
+func MoneyFromCents(cents int64) Money {
+	return Money(cents)
+}
+
+// NewMoneyFromFloat converts a dollar amount, rounding half away from zero to the nearest cent
+func NewMoneyFromFloat(dollars float64) Money {
+	return Money(math.Round(dollars * 100))
+}
+
+// ParseMoney parses a decimal dollar string such as "1234.5" or "-0.125" without going
+// through float64. Digits past the cent are rounded half away from zero.
+func ParseMoney(s string) (Money, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, errors.New("empty money value")
+	}
+
+	// exponent notation is rare enough that float precision is acceptable
+	if strings.ContainsAny(s, "eE") {
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid money value %q: %w", s, err)
+		}
+		return NewMoneyFromFloat(f), nil
+	}
+
+	negative := false
+	switch s[0] {
+	case '-':
+		negative = true
+		s = s[1:]
+	case '+':
+		s = s[1:]
+	}
+
+	whole, frac, _ := strings.Cut(s, ".")
+	if whole == "" {
+		whole = "0"
+	}
+	for _, part := range []string{whole, frac} {
+		for _, r := range part {
+			if r < '0' || r > '9' {
+				return 0, fmt.Errorf("invalid money value %q", s)
+			}
+		}
+	}
+
+	dollars, err := strconv.ParseInt(whole, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid money value %q: %w", s, err)
+	}
+
+	frac += "000"
+	cents, _ := strconv.ParseInt(frac[:2], 10, 64)
+	total := dollars*100 + cents
+	if frac[2] >= '5' {
+		total++
+	}
+
+	if negative {
+		total = -total
+	}
+	return Money(total), nil
+}
+
+// Cents returns the amount in cents, the unit Stripe expects
+func (m Money) Cents() int64 {
+	return int64(m)
+}
+
+// Float64 returns the amount in dollars, for display math only
+func (m Money) Float64() float64 {
+	return float64(m) / 100
+}
+
+// String formats the amount as a plain decimal, e.g. "-12.05"
+func (m Money) String() string {
+	sign := ""
+	cents := int64(m)
+	if cents < 0 {
+		sign = "-"
+		cents = -cents
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, cents/100, cents%100)
+}
+
+// Mul multiplies the amount by a whole quantity
+func (m Money) Mul(quantity int64) Money {
+	return m * Money(quantity)
+}
+
+// MulFloat multiplies the amount by a fractional factor such as hours worked, rounding to the cent
+func (m Money) MulFloat(factor float64) Money {
+	return Money(math.Round(float64(m) * factor))
+}
+
+// Percent returns percent% of the amount using one of the pricing policy rounding modes.
+// Percentages are exact to three decimal places.
+func (m Money) Percent(percent float64, rounding string) Money {
+	thousandths := int64(math.Round(percent * 1000))
+	return m.scale(thousandths, 100000, rounding)
+}
+
+// scale computes m * num / den in integer arithmetic and rounds the result
+func (m Money) scale(num, den int64, rounding string) Money {
+	unit := int64(1)
+	if rounding == RoundingNearestDollar || rounding == RoundingUpDollar {
+		unit = 100
+	}
+	den *= unit
+
+	product := int64(m) * num
+	quotient, remainder := product/den, product%den
+
+	switch rounding {
+	case RoundingUpCent, RoundingUpDollar:
+		if remainder > 0 {
+			quotient++
+		}
+	default:
+		if remainder < 0 {
+			remainder = -remainder
+		}
+		if 2*remainder >= den {
+			if product < 0 {
+				quotient--
+			} else {
+				quotient++
+			}
+		}
+	}
+
+	return Money(quotient * unit)
+}
+
+// Scan implements sql.Scanner for NUMERIC columns
+func (m *Money) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*m = 0
+		return nil
+	case []byte:
+		parsed, err := ParseMoney(string(v))
+		if err != nil {
+			return err
+		}
+		*m = parsed
+		return nil
+	case string:
+		parsed, err := ParseMoney(v)
+		if err != nil {
+			return err
+		}
+		*m = parsed
+		return nil
+	case float64:
+		*m = NewMoneyFromFloat(v)
+		return nil
+	case int64:
+		*m = Money(v * 100)
+		return nil
+	default:
+		return fmt.Errorf("cannot scan %T into Money", value)
+	}
+}
+
+// Value implements driver.Valuer, writing an exact decimal string
+func (m Money) Value() (driver.Value, error) {
+	return m.String(), nil
+}
+
+// GormDataType keeps the columns as NUMERIC
+func (Money) GormDataType() string {
+	return "numeric"
+}
+
+// MarshalJSON writes the amount as a dollar number, e.g. 1234.50
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// UnmarshalJSON accepts a dollar number or a quoted decimal string
+func (m *Money) UnmarshalJSON(data []byte) error {
+	text := strings.Trim(string(data), `"`)
+	if text == "null" || text == "" {
+		*m = 0
+		return nil
+	}
+
+	parsed, err := ParseMoney(text)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
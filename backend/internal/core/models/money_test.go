@@ -0,0 +1,228 @@
+package models
+
+import (
+	"math"
+	"math/big"
+	"regexp"
+	"testing"
+)
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Money
+	}{
+		{"0", 0},
+		{"12", 1200},
+		{"12.3", 1230},
+		{"12.34", 1234},
+		{" 12.34 ", 1234},
+		{"+12.34", 1234},
+		{"-12.34", -1234},
+		{".5", 50},
+		{"7.", 700},
+		// past the cent rounds half away from zero
+		{"0.125", 13},
+		{"0.1249999", 12},
+		{"-0.125", -13},
+		{"-0.124", -12},
+		{"1.995", 200},
+		{"1.5e2", 15000},
+	}
+	for _, tt := range tests {
+		got, err := ParseMoney(tt.in)
+		if err != nil {
+			t.Errorf("ParseMoney(%q): %v", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseMoney(%q) = %d cents, want %d", tt.in, got, tt.want)
+		}
+	}
+
+	for _, bad := range []string{"", "  ", "abc", "1,000.00", "1.2.3", "$5", "--1", "1e"} {
+		if got, err := ParseMoney(bad); err == nil {
+			t.Errorf("ParseMoney(%q) = %d cents, want an error", bad, got)
+		}
+	}
+}
+
+func FuzzMoneyStringRoundTrip(f *testing.F) {
+	for _, cents := range []int64{0, 1, -1, 5, 99, 100, 101, -1205, 123456789, math.MaxInt64, math.MinInt64 + 1} {
+		f.Add(cents)
+	}
+	f.Fuzz(func(t *testing.T, cents int64) {
+		// String can't negate the smallest int64
+		if cents == math.MinInt64 {
+			t.Skip()
+		}
+		m := MoneyFromCents(cents)
+		parsed, err := ParseMoney(m.String())
+		if err != nil {
+			t.Fatalf("ParseMoney(%q): %v", m.String(), err)
+		}
+		if parsed != m {
+			t.Fatalf("ParseMoney(%q) = %d cents, want %d", m.String(), parsed, cents)
+		}
+
+		data, err := m.MarshalJSON()
+		if err != nil {
+			t.Fatalf("MarshalJSON: %v", err)
+		}
+		var decoded Money
+		if err := decoded.UnmarshalJSON(data); err != nil || decoded != m {
+			t.Fatalf("JSON %s decoded to %d cents (%v), want %d", data, decoded, err, cents)
+		}
+	})
+}
+
+// plain decimals short enough that the dollars fit in an int64
+var plainDecimal = regexp.MustCompile(`^[+-]?[0-9]{0,15}(\.[0-9]{0,20})?$`)
+
+func FuzzParseMoney(f *testing.F) {
+	for _, s := range []string{"0", "12.34", "-0.125", "0.005", "-.995", "+7.", "999999999999999.999", "1e3", "abc"} {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		m, err := ParseMoney(s)
+		if err != nil {
+			return
+		}
+		// whatever parses prints back to the same amount
+		again, err := ParseMoney(m.String())
+		if err != nil || again != m {
+			t.Fatalf("ParseMoney(%q) = %d cents, but %q parses to %d (%v)", s, m, m.String(), again, err)
+		}
+
+		if !plainDecimal.MatchString(s) || s == "+" || s == "-" || s == "." {
+			return
+		}
+		exact, ok := new(big.Rat).SetString(s)
+		if !ok {
+			return
+		}
+		want := roundHalfAway(exact.Mul(exact, big.NewRat(100, 1)))
+		if m.Cents() != want {
+			t.Fatalf("ParseMoney(%q) = %d cents, want %d", s, m, want)
+		}
+	})
+}
+
+func TestPercentRoundingModes(t *testing.T) {
+	tests := []struct {
+		amount   Money
+		percent  float64
+		rounding string
+		want     Money
+	}{
+		// 3.5% of $10.01 is 35.035 cents
+		{1001, 3.5, RoundingNearestCent, 35},
+		{1001, 3.5, RoundingUpCent, 36},
+		{1001, 3.5, RoundingNearestDollar, 0},
+		{1001, 3.5, RoundingUpDollar, 100},
+		// 30% of $123.45 is 3703.5 cents, halves round away from zero
+		{12345, 30, RoundingNearestCent, 3704},
+		{12345, 30, RoundingUpCent, 3704},
+		{12345, 30, RoundingNearestDollar, 3700},
+		{12345, 30, RoundingUpDollar, 3800},
+		// 50% of $3.00 is exactly $1.50
+		{300, 50, RoundingNearestDollar, 200},
+		{300, 50, RoundingUpDollar, 200},
+		// exact results are left alone
+		{10000, 12.5, RoundingUpCent, 1250},
+		{10000, 25, RoundingUpDollar, 2500},
+		// refunds and credits are negative, nearest rounds away from zero and up rounds towards it
+		{-1001, 3.5, RoundingNearestCent, -35},
+		{-1001, 3.5, RoundingUpCent, -35},
+		{-12345, 30, RoundingNearestCent, -3704},
+		{-12345, 30, RoundingUpDollar, -3700},
+		// three decimal places of percent are exact
+		{100000, 2.875, RoundingNearestCent, 2875},
+		// an unknown mode rounds to the nearest cent
+		{1001, 3.5, "", 35},
+	}
+	for _, tt := range tests {
+		if got := tt.amount.Percent(tt.percent, tt.rounding); got != tt.want {
+			t.Errorf("%d cents .Percent(%v, %q) = %d, want %d", tt.amount, tt.percent, tt.rounding, got, tt.want)
+		}
+	}
+}
+
+func FuzzPercent(f *testing.F) {
+	f.Add(int64(1001), int32(3500))
+	f.Add(int64(12345), int32(30000))
+	f.Add(int64(-12345), int32(30000))
+	f.Add(int64(99999999), int32(333))
+	f.Add(int64(1), int32(-50000))
+	f.Fuzz(func(t *testing.T, cents int64, thousandths int32) {
+		// up to $100M and 1000% keeps the product well inside an int64
+		cents %= 10_000_000_000
+		thousandths %= 1_000_000
+		m := MoneyFromCents(cents)
+		percent := float64(thousandths) / 1000
+
+		// cents * percent / 100, exactly
+		exact := new(big.Rat).SetFrac(
+			new(big.Int).Mul(big.NewInt(cents), big.NewInt(int64(thousandths))),
+			big.NewInt(100000),
+		)
+		dollars := new(big.Rat).Quo(exact, big.NewRat(100, 1))
+
+		want := map[string]int64{
+			RoundingNearestCent:   roundHalfAway(exact),
+			RoundingUpCent:        ceil(exact),
+			RoundingNearestDollar: roundHalfAway(dollars) * 100,
+			RoundingUpDollar:      ceil(dollars) * 100,
+		}
+		for rounding, cents := range want {
+			if got := m.Percent(percent, rounding); got.Cents() != cents {
+				t.Fatalf("%d cents .Percent(%v, %q) = %d, want %d", m, percent, rounding, got, cents)
+			}
+		}
+	})
+}
+
+func TestDiscountAmount(t *testing.T) {
+	tests := []struct {
+		discount Discount
+		subtotal Money
+		want     Money
+	}{
+		{Discount{Type: DiscountTypeNone}, 10000, 0},
+		{Discount{Type: DiscountTypeNone, Percent: 10, Fixed: 500}, 10000, 0},
+		{Discount{Type: DiscountTypePercentage, Percent: 10}, 12345, 1235},
+		{Discount{Type: DiscountTypePercentage, Percent: 12.345}, 100000, 12345},
+		{Discount{Type: DiscountTypePercentage, Percent: 150}, 10000, 10000},
+		{Discount{Type: DiscountTypePercentage, Percent: -5}, 10000, 0},
+		// the amount of a percentage discount ignores the fixed field and the other way round
+		{Discount{Type: DiscountTypePercentage, Percent: 10, Fixed: 500}, 10000, 1000},
+		{Discount{Type: DiscountTypeFixed, Fixed: 2550}, 10000, 2550},
+		{Discount{Type: DiscountTypeFixed, Fixed: 2550, Percent: 50}, 10000, 2550},
+		{Discount{Type: DiscountTypeFixed, Fixed: 25000}, 10000, 10000},
+		{Discount{Type: DiscountTypeFixed, Fixed: -100}, 10000, 0},
+	}
+	for _, tt := range tests {
+		if got := tt.discount.Amount(tt.subtotal); got != tt.want {
+			t.Errorf("%+v.Amount(%d) = %d, want %d", tt.discount, tt.subtotal, got, tt.want)
+		}
+	}
+}
+
+// roundHalfAway rounds to the nearest integer, halves away from zero
+func roundHalfAway(r *big.Rat) int64 {
+	num, den := new(big.Int).Abs(r.Num()), r.Denom()
+	twice := new(big.Int).Add(new(big.Int).Mul(num, big.NewInt(2)), den)
+	rounded := twice.Quo(twice, new(big.Int).Mul(den, big.NewInt(2)))
+	if r.Sign() < 0 {
+		rounded.Neg(rounded)
+	}
+	return rounded.Int64()
+}
+
+func ceil(r *big.Rat) int64 {
+	quotient, remainder := new(big.Int).QuoRem(r.Num(), r.Denom(), new(big.Int))
+	if remainder.Sign() > 0 {
+		quotient.Add(quotient, big.NewInt(1))
+	}
+	return quotient.Int64()
+}
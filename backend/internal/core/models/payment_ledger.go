@@ -0,0 +1,66 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Ledger entry kinds, amounts are always positive and the kind gives the direction
+const (
+	LedgerEntryPayment = "payment"
+	LedgerEntryRefund  = "refund"
+)
+
+// PaymentLedgerEntry is one payment or refund from Stripe. Entries are never updated or deleted,
+// the invoice totals are recomputed from them.
+type PaymentLedgerEntry struct {
+	UUID              uuid.UUID `gorm:"type:uuid;primaryKey" json:"uuid"`
+	InvoiceID         uuid.UUID `gorm:"type:uuid" json:"invoice_id"`
+	StripeEventID     string    `json:"stripe_event_id"`
+	Kind              string    `json:"kind"`
+	Amount            Money     `json:"amount"`
+	PaymentIntentID   string    `json:"payment_intent_id,omitempty"`
+	CheckoutSessionID string    `json:"checkout_session_id,omitempty"`
+	ChargeID          string    `json:"charge_id,omitempty"`
+	RefundID          string    `json:"refund_id,omitempty"`
+	OccurredAt        time.Time `json:"occurred_at"`
+	CreatedAt         time.Time `gorm:"autoCreateTime" json:"created_at"`
+}
+
+func (PaymentLedgerEntry) TableName() string {
+	return "payment_ledger"
+}
+
+// LedgerTotals sums an invoice's ledger entries by kind
+type LedgerTotals struct {
+	Paid     Money
+	Refunded Money
+}
+
+// ApplyLedger derives what has been paid, what is still owed and the payment status from the
+// ledger. The status only moves where the lifecycle allows, so an invoice nothing has been paid
+// on keeps its status, an overdue one stays overdue until it is settled and a void one stays void.
+func (i *Invoice) ApplyLedger(totals LedgerTotals) {
+	i.AmountPaid = totals.Paid - totals.Refunded
+	i.Balance = i.Amount - i.AmountPaid
+
+	if i.Status == InvoiceStatusVoid {
+		i.Balance = 0
+		return
+	}
+
+	var status string
+	switch {
+	case totals.Refunded > 0 && i.AmountPaid <= 0:
+		status = InvoiceStatusRefunded
+	case i.AmountPaid > 0 && i.Balance <= 0:
+		status = InvoiceStatusPaid
+	case i.AmountPaid > 0:
+		status = InvoiceStatusPartiallyPaid
+	}
+
+	if status != "" && CanTransitionInvoice(i.Status, status) {
+		i.Status = status
+	}
+}
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Rounding modes supported by a pricing policy when computing fees
+const (
+	RoundingNearestCent   = "nearest_cent"
+	RoundingUpCent        = "up_cent"
+	RoundingNearestDollar = "nearest_dollar"
+	RoundingUpDollar      = "up_dollar"
+)
+
+// PricingPolicy holds the fee configuration used by the rate calculator. A policy with no
+// branch and no client is the global default, branch and client policies override it.
+type PricingPolicy struct {
+	UUID                  uuid.UUID  `gorm:"type:uuid;primaryKey" json:"uuid"`
+	Name                  string     `json:"name"`
+	BranchID              *uuid.UUID `gorm:"type:uuid" json:"branch_id,omitempty"`
+	ClientEmail           string     `json:"client_email,omitempty"`
+	TransactionFeePercent float64    `json:"transaction_fee_percent"`
+	ServiceFeePercent     float64    `json:"service_fee_percent"`
+	MinimumServiceFee     Money      `json:"minimum_service_fee"`
+	MinimumTotal          Money      `json:"minimum_total"`
+	Rounding              string     `json:"rounding"`
+	EffectiveFrom         time.Time  `json:"effective_from"`
+	EffectiveTo           *time.Time `json:"effective_to,omitempty"`
+	CreatedAt             time.Time  `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt             time.Time  `gorm:"autoUpdateTime" json:"updated_at"`
+}
@@ -1,16 +1,49 @@
 package models
 
 import (
+	"errors"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+// Rate is a branch's hourly rate for a staff type. Rates are effective dated so the rate card
+// keeps its history, a shift is priced by the rate in force on its date.
 type Rate struct {
-	UUID       uuid.UUID `gorm:"type:uuid;primaryKey"`
-	BranchID   uuid.UUID `gorm:"type:uuid"`
-	StaffType  string    `gorm:"type:varchar(255)"`
-	HourlyRate float64   `gorm:"type:decimal(10,2)"`
-	CreatedAt  time.Time `gorm:"autoCreateTime"`
-	// updatedAt  time.Time `gorm:"autoUpdateTime"`
+	UUID          uuid.UUID  `gorm:"type:uuid;primaryKey" json:"uuid"`
+	BranchID      uuid.UUID  `gorm:"type:uuid" json:"branch_id"`
+	StaffType     string     `gorm:"type:varchar(255)" json:"staff_type"`
+	HourlyRate    Money      `gorm:"type:decimal(10,2)" json:"hourly_rate"`
+	EffectiveFrom time.Time  `gorm:"type:date" json:"effective_from"`
+	EffectiveTo   *time.Time `gorm:"type:date" json:"effective_to,omitempty"`
+	CreatedAt     time.Time  `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt     time.Time  `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+// Rate sources, from most to least specific
+const (
+	RateSourceBranch = "branch"
+	RateSourceRegion = "region"
+	RateSourceGlobal = "global"
+)
+
+// ErrRateNotFound is returned when no rate on a branch card matches the given id
+var ErrRateNotFound = errors.New("rate not found")
+
+// ErrNoApplicableRate is returned when no branch, regional or global rate covers a staff type
+var ErrNoApplicableRate = errors.New("no applicable rate")
+
+// DefaultRate is a fallback hourly rate for a region, or for every branch when Region is empty
+type DefaultRate struct {
+	UUID       uuid.UUID `gorm:"type:uuid;primaryKey" json:"uuid"`
+	Region     string    `json:"region"`
+	StaffType  string    `gorm:"type:varchar(255)" json:"staff_type"`
+	HourlyRate Money     `gorm:"type:decimal(10,2)" json:"hourly_rate"`
+	CreatedAt  time.Time `gorm:"autoCreateTime" json:"created_at"`
+}
+
+// ResolvedRate is the hourly rate that applies to a staff type at a branch and where it came from
+type ResolvedRate struct {
+	HourlyRate Money  `json:"hourly_rate"`
+	Source     string `json:"source"`
 }
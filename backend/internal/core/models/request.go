@@ -22,6 +22,15 @@ type Request struct {
 	EventLocation          string
 	DateRequested          time.Time
 	CustomRequirementsText string
+	// set when mail to Email bounces, cleared when the address changes
+	EmailInvalid       bool
+	EmailInvalidReason string
+	// set once the request is cancelled, its invoices are void from then on
+	CancelledAt *time.Time
+	// what the cancellation still has to refund, more than zero when a refund failed
+	CancellationRefundDue Money `gorm:"type:decimal(10,2)"`
+	// the client the request is billed to, matched by Email
+	ClientID *uuid.UUID `gorm:"type:uuid"`
 
 	Invoices          []Invoice          `gorm:"foreignKey:RequestID"`
 	StaffRequirements []StaffRequirement `gorm:"foreignKey:RequestID"`
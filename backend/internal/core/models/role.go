@@ -0,0 +1,131 @@
+package models
+
+import "slices"
+
+// roles a user can have, stored in users.role
+const (
+	RoleSuperAdmin       = "superadmin"
+	RoleAdmin            = "admin"
+	RoleAccountExecutive = "account-executive"
+	RoleStaff            = "staff"
+	RoleClient           = "client"
+)
+
+// Permission is one thing a role may do, route groups declare the permissions they need
+type Permission string
+
+const (
+	PermissionRequestsRead   Permission = "requests:read"
+	PermissionRequestsWrite  Permission = "requests:write"
+	PermissionRequestsDelete Permission = "requests:delete"
+	PermissionRequestsCancel Permission = "requests:cancel"
+
+	PermissionInvoicesRead   Permission = "invoices:read"
+	PermissionInvoicesWrite  Permission = "invoices:write"
+	PermissionInvoicesDelete Permission = "invoices:delete"
+
+	// creating checkout links and payment intents for the client to pay
+	PermissionPaymentsCollect Permission = "payments:collect"
+	// charging a saved card without the client present
+	PermissionPaymentsCharge Permission = "payments:charge"
+	PermissionPaymentsRefund Permission = "payments:refund"
+
+	PermissionStaffingRead  Permission = "staffing:read"
+	PermissionStaffingWrite Permission = "staffing:write"
+
+	// events scheduled from confirmed requests and their shifts
+	PermissionEventsRead  Permission = "events:read"
+	PermissionEventsWrite Permission = "events:write"
+
+	// rate cards, pricing policies, promo codes and surcharges
+	PermissionPricingRead  Permission = "pricing:read"
+	PermissionPricingWrite Permission = "pricing:write"
+
+	PermissionEmailsRead     Permission = "emails:read"
+	PermissionEmailsSend     Permission = "emails:send"
+	PermissionEmailTemplates Permission = "email-templates:write"
+	PermissionDunningManage  Permission = "dunning:manage"
+	PermissionClientsRead    Permission = "clients:read"
+	PermissionClientsWrite   Permission = "clients:write"
+	// the staff directory, inviting users and changing their role, branch or access
+	PermissionUsersRead    Permission = "users:read"
+	PermissionUsersManage  Permission = "users:manage"
+	PermissionSystemManage Permission = "system:manage"
+)
+
+var allPermissions = []Permission{
+	PermissionRequestsRead, PermissionRequestsWrite, PermissionRequestsDelete, PermissionRequestsCancel,
+	PermissionInvoicesRead, PermissionInvoicesWrite, PermissionInvoicesDelete,
+	PermissionPaymentsCollect, PermissionPaymentsCharge, PermissionPaymentsRefund,
+	PermissionStaffingRead, PermissionStaffingWrite,
+	PermissionEventsRead, PermissionEventsWrite,
+	PermissionPricingRead, PermissionPricingWrite,
+	PermissionEmailsRead, PermissionEmailsSend, PermissionEmailTemplates, PermissionDunningManage,
+	PermissionClientsRead, PermissionClientsWrite,
+	PermissionUsersRead, PermissionUsersManage,
+	PermissionSystemManage,
+}
+
+// what each role may do, a role that isn't listed may do nothing
+var rolePermissions = map[string][]Permission{
+	RoleSuperAdmin: allPermissions,
+	// admins run everything but the scheduler itself
+	RoleAdmin: slices.DeleteFunc(slices.Clone(allPermissions), func(p Permission) bool {
+		return p == PermissionSystemManage
+	}),
+	// account executives work requests through to payment, but can't delete, cancel, refund
+	// or change pricing
+	RoleAccountExecutive: {
+		PermissionRequestsRead, PermissionRequestsWrite,
+		PermissionInvoicesRead, PermissionInvoicesWrite,
+		PermissionPaymentsCollect, PermissionPaymentsCharge,
+		PermissionStaffingRead, PermissionStaffingWrite,
+		PermissionEventsRead, PermissionEventsWrite,
+		PermissionPricingRead,
+		PermissionEmailsRead, PermissionEmailsSend,
+		PermissionClientsRead, PermissionClientsWrite,
+		PermissionUsersRead,
+	},
+	RoleStaff: {
+		PermissionRequestsRead,
+		PermissionStaffingRead,
+		PermissionEventsRead,
+	},
+	// clients only use the public routes for now
+	RoleClient: {},
+}
+
+// how far up each role is, users only manage roles below their own
+var roleRanks = map[string]int{
+	RoleClient:           1,
+	RoleStaff:            2,
+	RoleAccountExecutive: 3,
+	RoleAdmin:            4,
+	RoleSuperAdmin:       5,
+}
+
+// ValidRole reports whether role is one of the known roles
+func ValidRole(role string) bool {
+	_, ok := rolePermissions[role]
+	return ok
+}
+
+// RolePermissions lists what a role may do
+func RolePermissions(role string) []Permission {
+	return slices.Clone(rolePermissions[role])
+}
+
+// Can reports whether the user's role grants the permission
+func (u *User) Can(permission Permission) bool {
+	return slices.Contains(rolePermissions[u.Role], permission)
+}
+
+// Outranks reports whether the user may manage users with the role, superadmins manage everyone
+func (u *User) Outranks(role string) bool {
+	return u.Role == RoleSuperAdmin || roleRanks[u.Role] > roleRanks[role]
+}
+
+// AllBranches reports whether the user acts across branches rather than just their own
+func (u *User) AllBranches() bool {
+	return u.Role == RoleSuperAdmin
+}
@@ -6,13 +6,36 @@ import (
 	"github.com/google/uuid"
 )
 
+// Shift is one window staff work at an event, generated from the request's staff requirements
 type Shift struct {
-	UUID      uuid.UUID `gorm:"type:uuid;primaryKey"`
-	EventID   uuid.UUID
-	Date      time.Time
-	StartTime time.Time
-	EndTime   time.Time
+	UUID      uuid.UUID `gorm:"type:uuid;primaryKey" json:"uuid"`
+	EventID   uuid.UUID `json:"event_id"`
+	Date      time.Time `json:"date"`
+	StartTime time.Time `json:"start_time"`
+	EndTime   time.Time `json:"end_time"`
+	// how many staff the window needs, across positions
+	StaffNeeded int `json:"staff_needed"`
 
-	Event            Event             `gorm:"foreignKey:EventID"`
-	ShiftAssignments []ShiftAssignment `gorm:"foreignKey:ShiftID"`
+	Event            Event             `gorm:"foreignKey:EventID" json:"-"`
+	ShiftAssignments []ShiftAssignment `gorm:"foreignKey:ShiftID" json:"-"`
+}
+
+// ShiftInput creates or replaces a shift's window
+type ShiftInput struct {
+	Date        time.Time `json:"date" binding:"required"`
+	StartTime   time.Time `json:"start_time" binding:"required"`
+	EndTime     time.Time `json:"end_time" binding:"required"`
+	StaffNeeded int       `json:"staff_needed" binding:"gte=0"`
+}
+
+// Apply copies the input onto the shift, it fails when the window ends before it starts
+func (in ShiftInput) Apply(shift *Shift) error {
+	if !in.EndTime.After(in.StartTime) {
+		return ErrInvalidShiftTimes
+	}
+	shift.Date = in.Date
+	shift.StartTime = in.StartTime
+	shift.EndTime = in.EndTime
+	shift.StaffNeeded = in.StaffNeeded
+	return nil
 }
@@ -13,9 +13,10 @@ type StaffRequirement struct {
 	StartTime time.Time `json:"start_time"`
 	EndTime   time.Time `json:"end_time"`
 	Position  string    `json:"position"`
-	Rate      float64   `json:"rate"`
+	Rate      Money     `json:"rate"`
 	Count     int       `json:"count"`
-	Amount    float64   `json:"amount"`
+	Amount    Money     `json:"amount"`
 
-	Request Request `gorm:"foreignKey:RequestID" json:"-"`
+	Request Request                  `gorm:"foreignKey:RequestID" json:"-"`
+	Charges []StaffRequirementCharge `gorm:"foreignKey:StaffRequirementID" json:"charges,omitempty"`
 }
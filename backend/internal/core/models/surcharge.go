@@ -0,0 +1,55 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Surcharge rule types understood by the surcharge engine
+const (
+	SurchargeTypeOvertime = "overtime"  // hours past ThresholdHours in a single shift
+	SurchargeTypeNight    = "night"     // hours between StartHour and EndHour
+	SurchargeTypeHoliday  = "holiday"   // whole shifts that start on a branch holiday
+	SurchargeTypeLeadTime = "lead_time" // shifts booked less than ThresholdHours before they start
+)
+
+// ChargeTypeBase is the plain rate * hours * count line every staff requirement has
+const ChargeTypeBase = "base"
+
+// SurchargeRule is a configurable differential applied on top of the base staff rate. Rules
+// without a branch apply everywhere, a branch rule replaces the global rule of the same type.
+type SurchargeRule struct {
+	UUID           uuid.UUID  `gorm:"type:uuid;primaryKey" json:"uuid"`
+	Name           string     `json:"name"`
+	Type           string     `json:"type"`
+	BranchID       *uuid.UUID `gorm:"type:uuid" json:"branch_id,omitempty"`
+	Percent        float64    `json:"percent"`
+	ThresholdHours float64    `json:"threshold_hours"`
+	StartHour      int        `json:"start_hour"`
+	EndHour        int        `json:"end_hour"`
+	Active         bool       `json:"active"`
+	CreatedAt      time.Time  `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt      time.Time  `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+// Holiday is a day on a branch's holiday calendar, or on every branch's when BranchID is nil
+type Holiday struct {
+	UUID      uuid.UUID  `gorm:"type:uuid;primaryKey" json:"uuid"`
+	BranchID  *uuid.UUID `gorm:"type:uuid" json:"branch_id,omitempty"`
+	Date      time.Time  `gorm:"type:date" json:"date"`
+	Name      string     `json:"name"`
+	CreatedAt time.Time  `gorm:"autoCreateTime" json:"created_at"`
+}
+
+// StaffRequirementCharge is one itemized line of a staff requirement's amount, either the base
+// charge or a surcharge. The staff requirement amount is the sum of its charges.
+type StaffRequirementCharge struct {
+	UUID               uuid.UUID `gorm:"type:uuid;primaryKey" json:"uuid"`
+	StaffRequirementID uuid.UUID `gorm:"type:uuid" json:"staff_requirement_id"`
+	Type               string    `json:"type"`
+	Description        string    `json:"description"`
+	Hours              float64   `json:"hours"`
+	Percent            float64   `json:"percent"`
+	Amount             Money     `json:"amount"`
+}
@@ -1,9 +1,20 @@
 package models
 
-import "github.com/google/uuid"
+import (
+	"errors"
+	"time"
+)
 
-// TokenPayload represents the payload of a JWT token
+var ErrInvalidToken = errors.New("invalid token")
+
+// TokenPayload is what a verified Cognito ID or access token says about its user. Access
+// tokens carry no email, their user is found by Subject alone.
 type TokenPayload struct {
-	UserId uuid.UUID
-	Email  string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	// id or access
+	TokenUse  string
+	Groups    []string
+	ExpiresAt time.Time
 }
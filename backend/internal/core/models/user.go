@@ -1,22 +1,114 @@
 package models
 
 import (
+	"errors"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+var (
+	ErrUserNotFound = errors.New("user not found")
+	ErrUserExists   = errors.New("a user with this email already exists")
+	ErrInvalidRole  = errors.New("unknown role")
+	// users manage only users and roles below their own, superadmins manage everyone
+	ErrRoleNotAllowed = errors.New("not permitted to manage this role")
+	// nobody changes their own role or branch, or deactivates themselves
+	ErrCannotChangeSelf = errors.New("not permitted on your own account")
+)
+
 // User represents a user in our system
 type User struct {
-	UUID              uuid.UUID `gorm:"type:uuid;primaryKey"`
-	Email             string
-	FirstName         string
-	LastName          string
-	PhoneNumber       string
-	ProfilePictureURL string // S3 URL
-	BranchID          uuid.UUID
-	Role              string
-	CreatedAt         time.Time
-
-	Branch Branch `gorm:"foreignKey:BranchID"`
+	UUID              uuid.UUID `json:"uuid" gorm:"type:uuid;primaryKey"`
+	Email             string    `json:"email"`
+	FirstName         string    `json:"first_name"`
+	LastName          string    `json:"last_name"`
+	PhoneNumber       string    `json:"phone_number"`
+	ProfilePictureURL string    `json:"profile_picture_url"` // S3 URL
+	BranchID          uuid.UUID `json:"branch_id"`
+	Role              string    `json:"role"`
+	// the Cognito user's sub, set when the user first signs in
+	CognitoSub *string `json:"-"`
+	// set for users added through the directory, they are pending until they sign up
+	InvitedAt *time.Time `json:"invited_at,omitempty"`
+	// deactivated users can't sign in, their rows stay for the history they are part of
+	DeactivatedAt *time.Time `json:"deactivated_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+
+	Branch Branch `json:"-" gorm:"foreignKey:BranchID"`
+}
+
+// Active reports whether the user may sign in
+func (u *User) Active() bool {
+	return u.DeactivatedAt == nil
+}
+
+// Pending reports whether the user was invited and hasn't signed up yet
+func (u *User) Pending() bool {
+	return u.InvitedAt != nil && u.CognitoSub == nil
+}
+
+// UserFilter narrows the user directory, zero fields don't filter
+type UserFilter struct {
+	BranchID           *uuid.UUID
+	Role               string
+	IncludeDeactivated bool
+}
+
+// UserInvite adds someone to the directory ahead of them signing up with Cognito
+type UserInvite struct {
+	Email       string    `json:"email" binding:"required,email"`
+	FirstName   string    `json:"first_name"`
+	LastName    string    `json:"last_name"`
+	PhoneNumber string    `json:"phone_number"`
+	Role        string    `json:"role" binding:"required"`
+	BranchID    uuid.UUID `json:"branch_id" binding:"required"`
+}
+
+// UserSignUp is what Cognito knows about someone who just confirmed their account
+type UserSignUp struct {
+	CognitoSub  string
+	Email       string
+	FirstName   string
+	LastName    string
+	PhoneNumber string
+	BranchID    uuid.UUID
+}
+
+// ProfileUpdate changes the fields users edit on their own profile, nil fields are left as
+// they are
+type ProfileUpdate struct {
+	FirstName         *string `json:"first_name"`
+	LastName          *string `json:"last_name"`
+	PhoneNumber       *string `json:"phone_number"`
+	ProfilePictureURL *string `json:"profile_picture_url"`
+}
+
+// Apply copies the set fields onto the user
+func (u ProfileUpdate) Apply(user *User) {
+	if u.FirstName != nil {
+		user.FirstName = strings.TrimSpace(*u.FirstName)
+	}
+	if u.LastName != nil {
+		user.LastName = strings.TrimSpace(*u.LastName)
+	}
+	if u.PhoneNumber != nil {
+		user.PhoneNumber = strings.TrimSpace(*u.PhoneNumber)
+	}
+	if u.ProfilePictureURL != nil {
+		user.ProfilePictureURL = *u.ProfilePictureURL
+	}
+}
+
+// UserAccessUpdate changes what a user may do and where, nil fields are left as they are
+type UserAccessUpdate struct {
+	Role     *string    `json:"role"`
+	BranchID *uuid.UUID `json:"branch_id"`
+}
+
+// Profile is the signed in user together with what their role lets them do
+type Profile struct {
+	*User
+	Permissions []Permission `json:"permissions"`
 }
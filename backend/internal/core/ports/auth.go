@@ -1,16 +1,15 @@
 package ports
 
 import (
+	"context"
 	"net/http"
 
 	"backend/internal/core/models"
-
-	"github.com/google/uuid"
 )
 
 type TokenService interface {
-	GenerateToken(userId uuid.UUID, email string) (string, error)
-	VerifyToken(tokenString string) (*models.TokenPayload, error)
+	// checks the token's signature, issuer, audience and expiry, models.ErrInvalidToken if any fail
+	VerifyToken(ctx context.Context, tokenString string) (*models.TokenPayload, error)
 }
 
 type AuthService interface {
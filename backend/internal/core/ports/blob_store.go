@@ -0,0 +1,11 @@
+package ports
+
+import "context"
+
+// BlobStore keeps files such as email attachments, S3 in production and a directory locally
+type BlobStore interface {
+	// overwrites whatever is already stored under the key
+	Put(ctx context.Context, key string, contentType string, data []byte) error
+	Get(ctx context.Context, key string) ([]byte, error)
+	Delete(ctx context.Context, key string) error
+}
@@ -7,14 +7,19 @@ import (
 )
 
 type CalculateRatesService interface {
-	CalculateRates(ctx context.Context, request *models.Request) (float64, float64, float64, float64, error)
-	GetRates(ctx context.Context, request *models.Request) (float64, float64, float64, float64, error)
+	CalculateRates(ctx context.Context, request *models.Request, discount models.Discount) (*models.RateBreakdown, error)
+	GetRates(ctx context.Context, request *models.Request, discount models.Discount) (*models.RateBreakdown, error)
 	// return updated requirements, as well as custom line items if they exist
-	UpdateRates(ctx context.Context, request *models.Request, customLineItems []models.CustomLineItems) (float64, float64, float64, float64, error)
+	UpdateRates(ctx context.Context, request *models.Request, customLineItems []models.CustomLineItems, discount models.Discount) (*models.RateBreakdown, error)
+	CalculateFees(ctx context.Context, request *models.Request, subtotal models.Money, discount models.Discount) (*models.RateBreakdown, error)
 }
 
 type CalculateRatesRepository interface {
-	CalculateRates(ctx context.Context, request *models.Request) (float64, float64, float64, float64, error)
-	GetRates(ctx context.Context, request *models.Request) (float64, float64, float64, float64, error)
-	UpdateRates(ctx context.Context, request *models.Request, customLineItems []models.CustomLineItems) (float64, float64, float64, float64, error)
+	CalculateRates(ctx context.Context, request *models.Request, discount models.Discount) (*models.RateBreakdown, error)
+	GetRates(ctx context.Context, request *models.Request, discount models.Discount) (*models.RateBreakdown, error)
+	UpdateRates(ctx context.Context, request *models.Request, customLineItems []models.CustomLineItems, discount models.Discount) (*models.RateBreakdown, error)
+	// applies the discount and pricing policy to an already known subtotal
+	CalculateFees(ctx context.Context, request *models.Request, subtotal models.Money, discount models.Discount) (*models.RateBreakdown, error)
+	// everything the request costs, staff plus every saved custom line item
+	CalculateRequestTotal(ctx context.Context, request *models.Request, discount models.Discount) (*models.RateBreakdown, error)
 }
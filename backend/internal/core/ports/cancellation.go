@@ -0,0 +1,19 @@
+// cancelling a request refunds part of what was paid according to the cancellation policy
+package ports
+
+import (
+	"backend/internal/core/models"
+	"context"
+
+	"github.com/google/uuid"
+)
+
+type CancellationService interface {
+	GetCancellationPolicy() models.CancellationPolicy
+	// what cancelling the request now would refund, nothing is changed
+	QuoteCancellation(ctx context.Context, requestID uuid.UUID) (*models.RequestCancellation, error)
+	// cancels the request, voids its invoice and refunds the policy amount
+	CancelRequest(ctx context.Context, requestID uuid.UUID) (*models.RequestCancellation, error)
+	// refunds what a cancelled request's failed refund left owing
+	RetryCancellationRefund(ctx context.Context, requestID uuid.UUID) (*models.RequestCancellation, error)
+}
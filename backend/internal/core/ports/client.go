@@ -0,0 +1,38 @@
+package ports
+
+import (
+	"backend/internal/core/models"
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type ClientRepository interface {
+	// nil, nil when there is no such client
+	GetClientByID(ctx context.Context, id uuid.UUID) (*models.Client, error)
+	// the request's client, created from the request and linked to it if it has none yet
+	GetClientForRequest(ctx context.Context, requestID uuid.UUID) (*models.Client, error)
+	GetAllClients(ctx context.Context) ([]models.Client, error)
+	UpdateClient(ctx context.Context, client *models.Client) error
+	// stores the Stripe customer unless the client already has one, and returns the one kept
+	SetStripeCustomerID(ctx context.Context, clientID uuid.UUID, customerID string) (string, error)
+	SetDefaultPaymentMethod(ctx context.Context, clientID uuid.UUID, paymentMethodID string) error
+	// open invoices of approved clients whose event ended before the given time and that
+	// haven't been charged automatically yet
+	GetInvoicesDueForAutoCharge(ctx context.Context, eventEndedBefore time.Time) ([]models.Invoice, error)
+	// claims the invoice's one automatic charge, false if it was already claimed
+	MarkAutoChargeAttempted(ctx context.Context, invoiceID uuid.UUID, at time.Time) (bool, error)
+}
+
+type ClientService interface {
+	GetClientByID(ctx context.Context, id uuid.UUID) (*models.Client, error)
+	GetAllClients(ctx context.Context) ([]models.Client, error)
+	UpdateClient(ctx context.Context, id uuid.UUID, update models.ClientUpdate) (*models.Client, error)
+	GetPaymentMethods(ctx context.Context, id uuid.UUID) ([]models.SavedPaymentMethod, error)
+	SetDefaultPaymentMethod(ctx context.Context, id uuid.UUID, paymentMethodID string) (*models.Client, error)
+	// charges the invoice balance to the client's saved payment method, approved clients only
+	ChargeInvoice(ctx context.Context, invoiceID uuid.UUID) (*models.PaymentLedgerEntry, error)
+	// charges the remaining balance of approved clients once their event is over
+	ChargeBalancesAfterEvents(ctx context.Context) error
+}
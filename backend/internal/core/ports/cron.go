@@ -6,6 +6,8 @@ type CronRepository interface {
 	Run() error
 	Stop() error
 	ProcessScheduledEmails(ctx context.Context) error
+	// registers a recurring job, spec uses the cron package syntax e.g. "@every 1h"
+	Schedule(spec string, name string, job func(ctx context.Context) error) error
 }
 
 type CronService interface {
@@ -0,0 +1,34 @@
+// dunning schedules drive the automatic payment reminders for unpaid invoices
+package ports
+
+import (
+	"backend/internal/core/models"
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type DunningRepository interface {
+	// the branch's own schedule, or the default one, nil when neither exists
+	GetDunningSchedule(ctx context.Context, branchID uuid.UUID) (*models.DunningSchedule, error)
+	UpsertDunningSchedule(ctx context.Context, schedule *models.DunningSchedule) error
+	DeleteDunningSchedule(ctx context.Context, branchID uuid.UUID) error
+
+	// sent invoices with a balance that are not paid or refunded, with their request
+	GetOpenInvoices(ctx context.Context) ([]models.Invoice, error)
+	// bumps the follow up count and moves the follow up date to sentAt, but only while the date
+	// is still previous, false when another instance claimed the reminder first
+	ClaimReminder(ctx context.Context, invoiceID uuid.UUID, previous *time.Time, sentAt time.Time) (bool, error)
+	// undoes a claim whose reminder couldn't be sent, so the next run tries again
+	ReleaseReminder(ctx context.Context, invoiceID uuid.UUID, previous *time.Time, sentAt time.Time) error
+}
+
+type DunningService interface {
+	GetDunningSchedule(ctx context.Context, branchID uuid.UUID) (*models.DunningSchedule, error)
+	UpdateDunningSchedule(ctx context.Context, schedule *models.DunningSchedule) error
+	DeleteDunningSchedule(ctx context.Context, branchID uuid.UUID) error
+
+	// sends every reminder that is due, safe to run as often as the scheduler likes
+	SendDueReminders(ctx context.Context) error
+}
@@ -4,18 +4,46 @@ import (
 	"backend/internal/core/models"
 	"context"
 	"time"
+
+	"github.com/google/uuid"
 )
 
 type EmailService interface {
-	SendEmail(ctx context.Context, invoice *models.Invoice, staffRequirements []models.StaffRequirement) error
-	SendEmailWithPaymentURL(ctx context.Context, invoice *models.Invoice, staffRequirements []models.StaffRequirement, paymentURL string) error
-	SendCustomEmail(ctx context.Context, invoice *models.Invoice, staffRequirements []models.StaffRequirement, emailContent string, headers models.EmailHeaders, attachmentData []byte, filename string, paymentURL string) error
+	SendEmail(ctx context.Context, invoice *models.Invoice, staffRequirements []models.StaffRequirement, headers models.EmailHeaders) error
+	SendEmailWithPaymentURL(ctx context.Context, invoice *models.Invoice, staffRequirements []models.StaffRequirement, paymentURL string, headers models.EmailHeaders) error
+	SendCustomEmail(ctx context.Context, invoice *models.Invoice, staffRequirements []models.StaffRequirement, emailContent string, headers models.EmailHeaders, paymentURL string) error
 	ScheduleEmail(ctx context.Context, invoice *models.Invoice, staffRequirements []models.StaffRequirement, sendAt time.Time, customContent string, headers models.EmailHeaders, paymentURL string) error
+	// one reminder per invoice, paymentURLs is keyed by invoice UUID
+	SendFollowUpEmails(ctx context.Context, invoices []models.Invoice, paymentURLs map[string]string, headers models.EmailHeaders) error
+	// receipt for a payment that just landed on the invoice
+	SendPaymentConfirmationEmail(ctx context.Context, invoice *models.Invoice, amountPaid models.Money, paidAt time.Time) error
+	// stores an uploaded file once so any email can attach it by ID
+	StoreAttachment(ctx context.Context, filename, contentType string, data []byte) (*models.EmailAttachment, error)
+	GetAttachment(ctx context.Context, id uuid.UUID) (*models.EmailAttachment, []byte, error)
+	GetScheduledEmail(ctx context.Context, id uuid.UUID) (*models.Email, error)
+	GetScheduledEmailsByRequestID(ctx context.Context, requestID uuid.UUID) ([]models.Email, error)
+	GetScheduledEmailsByBranchID(ctx context.Context, branchID uuid.UUID, status string) ([]models.Email, error)
+	UpdateScheduledEmail(ctx context.Context, id uuid.UUID, update models.ScheduledEmailUpdate) (*models.Email, error)
+	CancelScheduledEmail(ctx context.Context, id uuid.UUID) (*models.Email, error)
+
+	// records a delivery webhook, a bounce marks the request's email address invalid
+	HandleWebhook(ctx context.Context, payload []byte) error
+	GetEmailLogsByRequestID(ctx context.Context, requestID uuid.UUID) ([]models.EmailLog, error)
+	GetEmailLogsByInvoiceID(ctx context.Context, invoiceID uuid.UUID) ([]models.EmailLog, error)
 }
 
 type EmailRepository interface {
-	SendEmail(ctx context.Context, invoice *models.Invoice, staffRequirements []models.StaffRequirement) error
-	SendEmailWithPaymentURL(ctx context.Context, invoice *models.Invoice, staffRequirements []models.StaffRequirement, paymentURL string) error
-	SendCustomEmail(ctx context.Context, invoice *models.Invoice, staffRequirements []models.StaffRequirement, emailContent string, headers models.EmailHeaders, attachmentData []byte, filename string, paymentURL string) error
+	SendEmail(ctx context.Context, invoice *models.Invoice, staffRequirements []models.StaffRequirement, headers models.EmailHeaders) error
+	SendEmailWithPaymentURL(ctx context.Context, invoice *models.Invoice, staffRequirements []models.StaffRequirement, paymentURL string, headers models.EmailHeaders) error
+	SendCustomEmail(ctx context.Context, invoice *models.Invoice, staffRequirements []models.StaffRequirement, emailContent string, headers models.EmailHeaders, paymentURL string) error
 	ScheduleEmail(ctx context.Context, invoice *models.Invoice, staffRequirements []models.StaffRequirement, sendAt time.Time, customContent string, headers models.EmailHeaders, paymentURL string) error
+	// one reminder per invoice, paymentURLs is keyed by invoice UUID
+	SendFollowUpEmails(ctx context.Context, invoices []models.Invoice, paymentURLs map[string]string, headers models.EmailHeaders) error
+	// receipt for a payment that just landed on the invoice
+	SendPaymentConfirmationEmail(ctx context.Context, invoice *models.Invoice, amountPaid models.Money, paidAt time.Time) error
+	// stores an uploaded file once so any email can attach it by ID
+	StoreAttachment(ctx context.Context, filename, contentType string, data []byte) (*models.EmailAttachment, error)
+	GetAttachment(ctx context.Context, id uuid.UUID) (*models.EmailAttachment, []byte, error)
+	// verifies a delivery webhook and parses it, nil for events we don't track
+	ParseWebhookEvent(payload []byte) (*models.EmailEvent, error)
 }
@@ -0,0 +1,15 @@
+package ports
+
+import (
+	"backend/internal/core/models"
+	"context"
+
+	"github.com/google/uuid"
+)
+
+type EmailAttachmentRepository interface {
+	CreateEmailAttachment(ctx context.Context, attachment *models.EmailAttachment) error
+	GetEmailAttachmentByID(ctx context.Context, id uuid.UUID) (*models.EmailAttachment, error)
+	// returns ErrEmailAttachmentNotFound if any of the IDs is missing, results follow the order of ids
+	GetEmailAttachmentsByIDs(ctx context.Context, ids []uuid.UUID) ([]models.EmailAttachment, error)
+}
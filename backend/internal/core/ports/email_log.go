@@ -0,0 +1,20 @@
+// email logs track every email handed to Mailgun and what happened to it afterwards
+package ports
+
+import (
+	"backend/internal/core/models"
+	"context"
+
+	"github.com/google/uuid"
+)
+
+type EmailLogRepository interface {
+	CreateEmailLog(ctx context.Context, entry *models.EmailLog) error
+	// stores the event against the log with the same message ID, returns the updated log, or
+	// nil when the message isn't one of ours. Replayed events are ignored.
+	RecordEmailEvent(ctx context.Context, event *models.EmailEvent) (*models.EmailLog, error)
+	GetEmailLogsByRequestID(ctx context.Context, requestID uuid.UUID) ([]models.EmailLog, error)
+	GetEmailLogsByInvoiceID(ctx context.Context, invoiceID uuid.UUID) ([]models.EmailLog, error)
+	// flags the request's email only while it is still the bounced recipient
+	MarkRequestEmailInvalid(ctx context.Context, requestID uuid.UUID, recipient, reason string) error
+}
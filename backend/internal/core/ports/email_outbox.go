@@ -0,0 +1,28 @@
+// the email outbox is the durable queue scheduled emails wait in until they are sent
+package ports
+
+import (
+	"backend/internal/core/models"
+	"context"
+
+	"github.com/google/uuid"
+)
+
+type EmailOutboxRepository interface {
+	EnqueueEmail(ctx context.Context, email *models.Email) error
+	// locks up to limit due emails and marks them as sending, safe to call from several instances
+	ClaimDueEmails(ctx context.Context, limit int) ([]models.Email, error)
+	// same as ClaimDueEmails but only for the given emails, used when Redis says they are due
+	ClaimEmails(ctx context.Context, ids []uuid.UUID) ([]models.Email, error)
+	MarkEmailSent(ctx context.Context, id uuid.UUID) error
+	// schedules a retry with backoff, or marks the email dead once it is out of attempts
+	MarkEmailFailed(ctx context.Context, email *models.Email, sendErr error) error
+
+	GetEmailByID(ctx context.Context, id uuid.UUID) (*models.Email, error)
+	GetEmailsByRequestID(ctx context.Context, requestID uuid.UUID) ([]models.Email, error)
+	// status is optional, empty lists every email for the branch
+	GetEmailsByBranchID(ctx context.Context, branchID uuid.UUID, status string) ([]models.Email, error)
+	// applies the update to a pending or failed email, fails once the email is being sent
+	UpdateScheduledEmail(ctx context.Context, id uuid.UUID, update models.ScheduledEmailUpdate) (*models.Email, error)
+	CancelEmail(ctx context.Context, id uuid.UUID) (*models.Email, error)
+}
@@ -0,0 +1,42 @@
+// email templates are versioned per branch and rendered with html/template
+package ports
+
+import (
+	"backend/internal/core/models"
+	"context"
+
+	"github.com/google/uuid"
+)
+
+type EmailTemplateRepository interface {
+	// saves the template as the next version for its branch and name
+	CreateEmailTemplate(ctx context.Context, template *models.EmailTemplate) error
+	GetEmailTemplateByID(ctx context.Context, id uuid.UUID) (*models.EmailTemplate, error)
+	// newest first, a nil branch lists the default template's versions
+	GetEmailTemplateVersions(ctx context.Context, branchID *uuid.UUID, name string) ([]models.EmailTemplate, error)
+	// newest version for the branch, falling back to the default, nil when neither was saved
+	GetActiveEmailTemplate(ctx context.Context, branchID *uuid.UUID, name string) (*models.EmailTemplate, error)
+	// drops every version the branch saved so it goes back to the default
+	DeleteEmailTemplates(ctx context.Context, branchID uuid.UUID, name string) error
+}
+
+type EmailRenderer interface {
+	// renders the template the branch uses, the built-in one when nothing was saved
+	Render(ctx context.Context, branchID *uuid.UUID, name string, view interface{}) (*models.RenderedEmail, error)
+	// renders unsaved template source, used to check and preview edits
+	RenderTemplate(template *models.EmailTemplate, view interface{}) (*models.RenderedEmail, error)
+	// the built-in template shipped with the app, version 0
+	DefaultTemplate(name string) (*models.EmailTemplate, error)
+}
+
+type EmailTemplateService interface {
+	GetEmailTemplate(ctx context.Context, branchID *uuid.UUID, name string) (*models.EmailTemplate, error)
+	GetEmailTemplates(ctx context.Context, branchID *uuid.UUID) ([]models.EmailTemplate, error)
+	GetEmailTemplateVersions(ctx context.Context, branchID *uuid.UUID, name string) ([]models.EmailTemplate, error)
+	SaveEmailTemplate(ctx context.Context, template *models.EmailTemplate) error
+	// saves an old version again as the newest one
+	RestoreEmailTemplateVersion(ctx context.Context, id uuid.UUID) (*models.EmailTemplate, error)
+	ResetEmailTemplate(ctx context.Context, branchID uuid.UUID, name string) error
+	// renders the template against sample data, empty source fields use the template in force
+	PreviewEmailTemplate(ctx context.Context, template *models.EmailTemplate) (*models.RenderedEmail, error)
+}
@@ -0,0 +1,51 @@
+package ports
+
+import (
+	"backend/internal/core/models"
+	"context"
+
+	"github.com/google/uuid"
+)
+
+type EventRepository interface {
+	// creates the event with its shifts, false when the request already has an event
+	CreateEvent(ctx context.Context, event *models.Event) (bool, error)
+	// nil, nil when there is no such event in the context's branch
+	GetEventByID(ctx context.Context, id uuid.UUID) (*models.Event, error)
+	// nil, nil when the request hasn't been scheduled
+	GetEventByRequestID(ctx context.Context, requestID uuid.UUID) (*models.Event, error)
+	GetEvents(ctx context.Context, filter models.EventFilter) ([]models.Event, error)
+	// moves the event from one status to another, false when it was no longer in from
+	UpdateEventStatus(ctx context.Context, id uuid.UUID, from, to string, notes *string) (bool, error)
+	// requests with money paid on them that aren't cancelled or scheduled yet
+	GetRequestsAwaitingEvent(ctx context.Context) ([]uuid.UUID, error)
+}
+
+type EventService interface {
+	GetEvents(ctx context.Context, filter models.EventFilter) ([]models.Event, error)
+	GetEventByID(ctx context.Context, id uuid.UUID) (*models.Event, error)
+	GetEventByRequestID(ctx context.Context, requestID uuid.UUID) (*models.Event, error)
+	// schedules the request's event now, returning the existing one if it was scheduled already
+	ScheduleRequest(ctx context.Context, requestID uuid.UUID) (*models.Event, error)
+	UpdateEventStatus(ctx context.Context, id uuid.UUID, update models.EventStatusUpdate) (*models.Event, error)
+	// schedules every request that has been paid on, run by cron
+	ScheduleConfirmedRequests(ctx context.Context) error
+}
+
+type ShiftRepository interface {
+	CreateShift(ctx context.Context, shift *models.Shift) error
+	// nil, nil when there is no such shift in the context's branch
+	GetShiftByID(ctx context.Context, id uuid.UUID) (*models.Shift, error)
+	GetShiftsByEventID(ctx context.Context, eventID uuid.UUID) ([]models.Shift, error)
+	UpdateShift(ctx context.Context, shift *models.Shift) error
+	// deletes the shift and its assignments
+	DeleteShift(ctx context.Context, id uuid.UUID) error
+}
+
+type ShiftService interface {
+	CreateShift(ctx context.Context, eventID uuid.UUID, input models.ShiftInput) (*models.Shift, error)
+	GetShiftByID(ctx context.Context, id uuid.UUID) (*models.Shift, error)
+	GetShiftsByEventID(ctx context.Context, eventID uuid.UUID) ([]models.Shift, error)
+	UpdateShift(ctx context.Context, id uuid.UUID, input models.ShiftInput) (*models.Shift, error)
+	DeleteShift(ctx context.Context, id uuid.UUID) error
+}
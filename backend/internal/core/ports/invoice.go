@@ -10,11 +10,28 @@ import (
 type InvoiceRepository interface {
 	CreateInvoice(ctx context.Context, invoice *models.Invoice, request *models.Request) error // needs request for certain fields like request id
 	GetInvoiceByID(ctx context.Context, id uuid.UUID) (*models.Invoice, error)
+	// the request's final invoice, or its latest one when it has no final invoice
 	GetInvoiceByRequestID(ctx context.Context, requestID uuid.UUID) (*models.Invoice, error)
+	GetInvoicesByRequestID(ctx context.Context, requestID uuid.UUID) ([]models.Invoice, error)
+	// stores an invoice whose amounts the caller already worked out, as a draft with the
+	// request's next sequence number
+	IssueInvoice(ctx context.Context, invoice *models.Invoice) error
 	GetInvoiceByBranchID(ctx context.Context, branchID uuid.UUID) ([]models.InvoiceResponse, error)
 	UpdateInvoice(ctx context.Context, invoice *models.Invoice) error
+	// saves the invoice's new discount and totals, counting a redemption of the promo code it
+	// now carries and releasing the one it had, all or nothing
+	ApplyDiscount(ctx context.Context, invoice *models.Invoice) error
 	DeleteInvoice(ctx context.Context, id uuid.UUID) error
+	// open invoices past their due date, and ones already marked overdue
 	CheckForOverdueInvoices(ctx context.Context) ([]models.Invoice, error)
+	// moves the invoice from FromStatus to ToStatus and records the change, fails with
+	// ErrInvalidInvoiceTransition if the invoice is no longer in FromStatus
+	TransitionInvoiceStatus(ctx context.Context, change *models.InvoiceStatusChange) error
+	GetInvoiceStatusHistory(ctx context.Context, invoiceID uuid.UUID) ([]models.InvoiceStatusChange, error)
+	// allocates the next number for the invoice's branch and year unless it already has one,
+	// moving an invoice out of draft does this too
+	AssignInvoiceNumber(ctx context.Context, invoice *models.Invoice) error
+	SearchInvoices(ctx context.Context, query string) ([]models.Invoice, error)
 }
 
 type InvoiceService interface {
@@ -26,4 +43,23 @@ type InvoiceService interface {
 	DeleteInvoice(ctx context.Context, id uuid.UUID) error
 	CheckForOverdueInvoices(ctx context.Context) ([]models.Invoice, error)
 	RecalculateInvoiceAfterPaymentWithNewItems(ctx context.Context, invoiceID uuid.UUID, newCustomLineItems []models.CustomLineItems) (*models.Invoice, error)
+	// discounts can only be changed before any payment has been taken
+	ApplyDiscount(ctx context.Context, invoiceID uuid.UUID, discount models.Discount) (*models.Invoice, error)
+	RemoveDiscount(ctx context.Context, invoiceID uuid.UUID) (*models.Invoice, error)
+	GetInvoicePDF(ctx context.Context, invoiceID uuid.UUID) (*models.Invoice, []byte, error)
+	// moves the invoice to status if the lifecycle allows it, the actor comes from the context
+	TransitionInvoice(ctx context.Context, invoiceID uuid.UUID, status string, reason string) (*models.Invoice, error)
+	// stamps LastSent and moves a draft to sent
+	MarkInvoiceSent(ctx context.Context, invoice *models.Invoice) error
+	// moves every open invoice past its due date to overdue
+	MarkOverdueInvoices(ctx context.Context) error
+	GetInvoiceStatusHistory(ctx context.Context, invoiceID uuid.UUID) ([]models.InvoiceStatusChange, error)
+	GetInvoicesByRequestID(ctx context.Context, requestID uuid.UUID) ([]models.Invoice, error)
+	GetRequestInvoiceSummary(ctx context.Context, requestID uuid.UUID) (*models.RequestInvoiceSummary, error)
+	CreateDepositInvoice(ctx context.Context, requestID uuid.UUID, deposit models.Deposit) (*models.Invoice, error)
+	// bills line items added after the final invoice went out
+	CreateChangeOrderInvoice(ctx context.Context, requestID uuid.UUID, customLineItems []models.CustomLineItems) (*models.Invoice, error)
+	// numbers the invoice before it is shown to the client, a no-op once it has a number
+	AssignInvoiceNumber(ctx context.Context, invoice *models.Invoice) error
+	SearchInvoices(ctx context.Context, query string) ([]models.Invoice, error)
 }
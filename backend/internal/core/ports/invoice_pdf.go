@@ -0,0 +1,17 @@
+package ports
+
+import (
+	"backend/internal/core/models"
+	"context"
+)
+
+// InvoicePDFRenderer lays out an invoice as a PDF, the same document always gives the same bytes
+type InvoicePDFRenderer interface {
+	RenderInvoicePDF(doc *models.InvoiceDocument) ([]byte, error)
+}
+
+type InvoicePDFRepository interface {
+	// loads the invoice's staff requirements and line items and renders them, the invoice must
+	// have its request loaded
+	GenerateInvoicePDF(ctx context.Context, invoice *models.Invoice) ([]byte, error)
+}
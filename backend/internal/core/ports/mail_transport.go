@@ -0,0 +1,18 @@
+// mail transports deliver rendered emails, the provider is picked by config
+package ports
+
+import (
+	"backend/internal/core/models"
+	"context"
+)
+
+type MailTransport interface {
+	// sends the message and returns the provider's message ID
+	Send(ctx context.Context, message *models.MailMessage) (string, error)
+}
+
+// MailWebhookParser is implemented by transports whose provider reports delivery events
+type MailWebhookParser interface {
+	// verifies a delivery webhook and parses it, nil for events we don't track
+	ParseWebhookEvent(payload []byte) (*models.EmailEvent, error)
+}
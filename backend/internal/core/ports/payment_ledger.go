@@ -0,0 +1,21 @@
+package ports
+
+import (
+	"backend/internal/core/models"
+	"context"
+
+	"github.com/google/uuid"
+)
+
+type PaymentLedgerRepository interface {
+	// appends the entry and re-derives the invoice totals in one transaction. A Stripe event that
+	// was already recorded changes nothing and returns false.
+	RecordPayment(ctx context.Context, entry *models.PaymentLedgerEntry) (*models.Invoice, bool, error)
+	// Stripe reports the charge's refunded total, only the part not yet in the ledger is recorded
+	RecordRefund(ctx context.Context, entry *models.PaymentLedgerEntry, chargeRefundedTotal models.Money) (*models.Invoice, bool, error)
+	// the invoice a payment intent paid, nil when the ledger doesn't know it
+	GetInvoiceIDByPaymentIntent(ctx context.Context, paymentIntentID string) (*uuid.UUID, error)
+	GetLedgerEntriesByInvoiceID(ctx context.Context, invoiceID uuid.UUID) ([]models.PaymentLedgerEntry, error)
+	// what is left to refund on each payment intent that paid the invoice
+	GetRefundablePayments(ctx context.Context, invoiceID uuid.UUID) ([]models.RefundablePayment, error)
+}
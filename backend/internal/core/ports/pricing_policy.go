@@ -0,0 +1,29 @@
+// pricing policies decide the transaction and service fees charged on top of the staff subtotal
+package ports
+
+import (
+	"backend/internal/core/models"
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type PricingPolicyRepository interface {
+	CreatePricingPolicy(ctx context.Context, policy *models.PricingPolicy) error
+	GetPricingPolicyByID(ctx context.Context, id uuid.UUID) (*models.PricingPolicy, error)
+	GetPricingPolicies(ctx context.Context, branchID *uuid.UUID) ([]models.PricingPolicy, error)
+	UpdatePricingPolicy(ctx context.Context, policy *models.PricingPolicy) error
+	DeletePricingPolicy(ctx context.Context, id uuid.UUID) error
+	// most specific policy in force at the given time, client beats branch beats global
+	GetApplicablePricingPolicy(ctx context.Context, branchID uuid.UUID, clientEmail string, at time.Time) (*models.PricingPolicy, error)
+}
+
+type PricingPolicyService interface {
+	CreatePricingPolicy(ctx context.Context, policy *models.PricingPolicy) error
+	GetPricingPolicyByID(ctx context.Context, id uuid.UUID) (*models.PricingPolicy, error)
+	GetPricingPolicies(ctx context.Context, branchID *uuid.UUID) ([]models.PricingPolicy, error)
+	UpdatePricingPolicy(ctx context.Context, policy *models.PricingPolicy) error
+	DeletePricingPolicy(ctx context.Context, id uuid.UUID) error
+	GetApplicablePricingPolicy(ctx context.Context, branchID uuid.UUID, clientEmail string, at time.Time) (*models.PricingPolicy, error)
+}
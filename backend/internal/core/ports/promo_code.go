@@ -0,0 +1,26 @@
+// promo codes are reusable discounts that can be applied to an invoice
+package ports
+
+import (
+	"backend/internal/core/models"
+	"context"
+
+	"github.com/google/uuid"
+)
+
+type PromoCodeRepository interface {
+	CreatePromoCode(ctx context.Context, promoCode *models.PromoCode) error
+	GetPromoCodeByID(ctx context.Context, id uuid.UUID) (*models.PromoCode, error)
+	GetPromoCodeByCode(ctx context.Context, code string) (*models.PromoCode, error)
+	GetPromoCodes(ctx context.Context) ([]models.PromoCode, error)
+	UpdatePromoCode(ctx context.Context, promoCode *models.PromoCode) error
+	DeletePromoCode(ctx context.Context, id uuid.UUID) error
+}
+
+type PromoCodeService interface {
+	CreatePromoCode(ctx context.Context, promoCode *models.PromoCode) error
+	GetPromoCodeByID(ctx context.Context, id uuid.UUID) (*models.PromoCode, error)
+	GetPromoCodes(ctx context.Context) ([]models.PromoCode, error)
+	UpdatePromoCode(ctx context.Context, promoCode *models.PromoCode) error
+	DeletePromoCode(ctx context.Context, id uuid.UUID) error
+}
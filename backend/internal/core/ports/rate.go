@@ -0,0 +1,57 @@
+// hourly staff rates, resolved per branch with regional and global fallbacks
+package ports
+
+import (
+	"backend/internal/core/models"
+	"context"
+	"io"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// lookups return nil without an error when no rate is configured
+type RateRepository interface {
+	// creates the rates in one transaction, closing the open-ended rates they supersede
+	CreateRates(ctx context.Context, rates []models.Rate) error
+	GetRateByID(ctx context.Context, id uuid.UUID) (*models.Rate, error)
+	GetRatesByBranchID(ctx context.Context, branchID uuid.UUID, staffType string) ([]models.Rate, error)
+	UpdateRate(ctx context.Context, rate *models.Rate) error
+	DeleteRate(ctx context.Context, id uuid.UUID) error
+
+	// branch rate in force on the given date
+	GetBranchRate(ctx context.Context, branchID uuid.UUID, staffType string, at time.Time) (*models.Rate, error)
+	// default for the region the branch belongs to
+	GetRegionalDefaultRate(ctx context.Context, branchID uuid.UUID, staffType string) (*models.DefaultRate, error)
+	GetGlobalDefaultRate(ctx context.Context, staffType string) (*models.DefaultRate, error)
+
+	// the fallbacks themselves, a nil region lists every region's and the global ones. They
+	// cover every branch, so only contexts without a branch scope change them.
+	GetDefaultRates(ctx context.Context, region *string) ([]models.DefaultRate, error)
+	GetDefaultRateByID(ctx context.Context, id uuid.UUID) (*models.DefaultRate, error)
+	CreateDefaultRate(ctx context.Context, rate *models.DefaultRate) error
+	UpdateDefaultRate(ctx context.Context, rate *models.DefaultRate) error
+	DeleteDefaultRate(ctx context.Context, id uuid.UUID) error
+}
+
+type RateService interface {
+	CreateRate(ctx context.Context, rate *models.Rate) error
+	GetRateByID(ctx context.Context, id uuid.UUID) (*models.Rate, error)
+	GetRatesByBranchID(ctx context.Context, branchID uuid.UUID, staffType string) ([]models.Rate, error)
+	UpdateRate(ctx context.Context, rate *models.Rate) error
+	DeleteRate(ctx context.Context, id uuid.UUID) error
+
+	// rate cards are CSV with a staff_type,hourly_rate,effective_from,effective_to header
+	ImportRateCard(ctx context.Context, branchID uuid.UUID, r io.Reader) ([]models.Rate, error)
+	ExportRateCard(ctx context.Context, branchID uuid.UUID, w io.Writer) error
+
+	// regional and global fallbacks, an empty region is the global default
+	GetDefaultRates(ctx context.Context, region *string) ([]models.DefaultRate, error)
+	GetDefaultRateByID(ctx context.Context, id uuid.UUID) (*models.DefaultRate, error)
+	CreateDefaultRate(ctx context.Context, rate *models.DefaultRate) error
+	UpdateDefaultRate(ctx context.Context, rate *models.DefaultRate) error
+	DeleteDefaultRate(ctx context.Context, id uuid.UUID) error
+
+	// returns models.ErrNoApplicableRate when nothing in the fallback chain covers the staff type
+	ResolveRate(ctx context.Context, branchID uuid.UUID, staffType string, at time.Time) (*models.ResolvedRate, error)
+}
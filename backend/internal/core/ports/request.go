@@ -3,6 +3,7 @@ package ports
 import (
 	"backend/internal/core/models"
 	"context"
+	"time"
 
 	"github.com/google/uuid"
 )
@@ -15,6 +16,11 @@ type RequestRepository interface {
 	GetRequestsByBranchID(ctx context.Context, branchID uuid.UUID) ([]models.Request, error)
 	UpdateRequest(ctx context.Context, request *models.Request) error
 	DeleteRequest(ctx context.Context, od uuid.UUID) error
+	// marks the request cancelled with refundDue still to refund and voids its invoices, false
+	// when it was already cancelled
+	CancelRequest(ctx context.Context, id uuid.UUID, cancelledAt time.Time, refundDue models.Money) (bool, error)
+	// takes a refund that went through off what the cancellation still has to refund
+	SettleCancellationRefund(ctx context.Context, id uuid.UUID, refunded models.Money) error
 }
 
 type RequestService interface {
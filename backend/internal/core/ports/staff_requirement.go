@@ -15,7 +15,6 @@ type StaffRequirementRepository interface {
 	GetAllStaffRequirementsByRequestID(ctx context.Context, requestID uuid.UUID) ([]models.StaffRequirement, error) // get all staff requirements by request id
 	UpdateStaffRequirement(ctx context.Context, staffRequirement *models.StaffRequirement) error                    // update a staff requirement
 	DeleteStaffRequirement(ctx context.Context, id uuid.UUID) error                                                 // delete a staff requirement
-	GetRate(ctx context.Context, staffType string, location string) (float64, error)
 }
 
 type StaffRequirementService interface {
@@ -24,5 +23,4 @@ type StaffRequirementService interface {
 	GetAllStaffRequirementsByRequestID(ctx context.Context, requestID uuid.UUID) ([]models.StaffRequirement, error)
 	UpdateStaffRequirement(ctx context.Context, staffRequirement *models.StaffRequirement) error
 	DeleteStaffRequirement(ctx context.Context, id uuid.UUID) error
-	GetRate(ctx context.Context, staffType string, location string) (float64, error)
 }
@@ -3,20 +3,37 @@ package ports
 import (
 	"backend/internal/core/models"
 	"context"
+
+	"github.com/google/uuid"
 )
 
 type StripeService interface {
 	CreatePaymentIntent(ctx context.Context, invoice *models.Invoice) (string, error)
 	CreateCheckoutSession(ctx context.Context, invoice *models.Invoice, staffRequirements []models.StaffRequirement) (string, error)
-	RefundPayment(ctx context.Context, invoice *models.Invoice) (string, error)
+	// refunds everything still held on the invoice, across every payment intent that paid it
+	RefundPayment(ctx context.Context, invoice *models.Invoice) ([]models.PaymentLedgerEntry, error)
+	// refunds amount, split over the invoice's payment intents most recent first
+	RefundAmount(ctx context.Context, invoice *models.Invoice, amount models.Money) ([]models.PaymentLedgerEntry, error)
 	Webhook(ctx context.Context, payload []byte, signatureHeader string) error
 	SendAdminConfirmationEmail(ctx context.Context, invoice *models.Invoice, staffRequirements []models.StaffRequirement) (string, error)
+	GetLedgerEntriesByInvoiceID(ctx context.Context, invoiceID uuid.UUID) ([]models.PaymentLedgerEntry, error)
+	GetPaymentMethods(ctx context.Context, client *models.Client) ([]models.SavedPaymentMethod, error)
+	SetDefaultPaymentMethod(ctx context.Context, client *models.Client, paymentMethodID string) error
+	// charges amount to a saved payment method without the client present
+	ChargeOffSession(ctx context.Context, invoice *models.Invoice, client *models.Client, paymentMethodID string, amount models.Money) (*models.PaymentLedgerEntry, error)
 }
 
 type StripeRepository interface {
 	CreatePaymentIntent(ctx context.Context, invoice *models.Invoice) (string, error)
 	CreateCheckoutSession(ctx context.Context, invoice *models.Invoice, staffRequirements []models.StaffRequirement) (string, error)
-	RefundPayment(ctx context.Context, invoice *models.Invoice) (string, error)
+	// refunds the allocation, retrying the same allocation can't refund it twice
+	RefundPaymentIntent(ctx context.Context, invoice *models.Invoice, allocation models.RefundAllocation) (*models.PaymentLedgerEntry, error)
 	Webhook(ctx context.Context, payload []byte, signatureHeader string) error
 	SendAdminConfirmationEmail(ctx context.Context, invoice *models.Invoice, staffRequirements []models.StaffRequirement) (string, error)
+	// the client's Stripe customer, created the first time it is needed
+	EnsureCustomer(ctx context.Context, client *models.Client) (string, error)
+	ListPaymentMethods(ctx context.Context, customerID string) ([]models.SavedPaymentMethod, error)
+	SetDefaultPaymentMethod(ctx context.Context, customerID string, paymentMethodID string) error
+	// confirms a payment intent off-session and records it in the ledger straight away
+	ChargeOffSession(ctx context.Context, invoice *models.Invoice, customerID string, paymentMethodID string, amount models.Money) (*models.PaymentLedgerEntry, error)
 }
@@ -0,0 +1,41 @@
+// surcharge rules and holiday calendars drive the differentials added to staff amounts
+package ports
+
+import (
+	"backend/internal/core/models"
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type SurchargeRepository interface {
+	CreateSurchargeRule(ctx context.Context, rule *models.SurchargeRule) error
+	GetSurchargeRuleByID(ctx context.Context, id uuid.UUID) (*models.SurchargeRule, error)
+	GetSurchargeRules(ctx context.Context, branchID *uuid.UUID) ([]models.SurchargeRule, error)
+	UpdateSurchargeRule(ctx context.Context, rule *models.SurchargeRule) error
+	DeleteSurchargeRule(ctx context.Context, id uuid.UUID) error
+	// active rules for the branch plus the global ones
+	GetApplicableSurchargeRules(ctx context.Context, branchID uuid.UUID) ([]models.SurchargeRule, error)
+
+	CreateHoliday(ctx context.Context, holiday *models.Holiday) error
+	GetHolidays(ctx context.Context, branchID *uuid.UUID) ([]models.Holiday, error)
+	DeleteHoliday(ctx context.Context, id uuid.UUID) error
+	// holidays for the branch plus the global ones, from and to are inclusive dates
+	GetHolidaysBetween(ctx context.Context, branchID uuid.UUID, from time.Time, to time.Time) ([]models.Holiday, error)
+}
+
+type SurchargeService interface {
+	CreateSurchargeRule(ctx context.Context, rule *models.SurchargeRule) error
+	GetSurchargeRuleByID(ctx context.Context, id uuid.UUID) (*models.SurchargeRule, error)
+	GetSurchargeRules(ctx context.Context, branchID *uuid.UUID) ([]models.SurchargeRule, error)
+	UpdateSurchargeRule(ctx context.Context, rule *models.SurchargeRule) error
+	DeleteSurchargeRule(ctx context.Context, id uuid.UUID) error
+
+	CreateHoliday(ctx context.Context, holiday *models.Holiday) error
+	GetHolidays(ctx context.Context, branchID *uuid.UUID) ([]models.Holiday, error)
+	DeleteHoliday(ctx context.Context, id uuid.UUID) error
+
+	// prices a staff requirement from its rate, filling in the itemized charges and the amount
+	PriceStaffRequirement(ctx context.Context, request *models.Request, staff *models.StaffRequirement) error
+}
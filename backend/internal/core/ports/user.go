@@ -8,9 +8,35 @@ import (
 )
 
 type UserRepository interface {
+	// nil, nil when there is no such user in the context's branch
 	GetUserByID(ctx context.Context, id uuid.UUID) (*models.User, error)
+	// matches across branches, emails identify people wherever they work
 	GetUserByEmail(ctx context.Context, email string) (*models.User, error)
+	// nil, nil when no user is linked to the Cognito sub
+	GetUserByCognitoSub(ctx context.Context, sub string) (*models.User, error)
+	// links the user to the Cognito sub unless it is already linked, false if it was
+	LinkCognitoSub(ctx context.Context, id uuid.UUID, sub string) (bool, error)
+	// users in the context's branch, ordered by name
+	ListUsers(ctx context.Context, filter models.UserFilter) ([]models.User, error)
 	CreateUser(ctx context.Context, user *models.User) error
 	UpdateUser(ctx context.Context, user *models.User) error
 	DeleteUser(ctx context.Context, id uuid.UUID) error
 }
+
+type UserService interface {
+	GetUserByID(ctx context.Context, id uuid.UUID) (*models.User, error)
+	GetUserByEmail(ctx context.Context, email string) (*models.User, error)
+	ListUsers(ctx context.Context, filter models.UserFilter) ([]models.User, error)
+	// adds a pending user, their row is claimed when they sign up with the same email
+	InviteUser(ctx context.Context, actor *models.User, invite models.UserInvite) (*models.User, error)
+	UpdateAccess(ctx context.Context, actor *models.User, id uuid.UUID, update models.UserAccessUpdate) (*models.User, error)
+	DeactivateUser(ctx context.Context, actor *models.User, id uuid.UUID) (*models.User, error)
+	ReactivateUser(ctx context.Context, actor *models.User, id uuid.UUID) (*models.User, error)
+	GetProfile(ctx context.Context, user *models.User) *models.Profile
+	UpdateProfile(ctx context.Context, user *models.User, update models.ProfileUpdate) (*models.Profile, error)
+	// records a confirmed Cognito sign up, claiming the invite for the email if there is one.
+	// Only for the post confirmation trigger, the sign up has to come from Cognito itself.
+	RegisterSignUp(ctx context.Context, signUp models.UserSignUp) (*models.User, error)
+	// records a sign up as staff without ever claiming an invite
+	RegisterStaffSignUp(ctx context.Context, signUp models.UserSignUp) (*models.User, error)
+}
@@ -14,14 +14,18 @@ func NewCalculateRatesService(repo ports.CalculateRatesRepository) *CalculateRat
 	return &CalculateRatesService{repo: repo}
 }
 
-func (s *CalculateRatesService) CalculateRates(ctx context.Context, request *models.Request) (float64, float64, float64, float64, error) {
-	return s.repo.CalculateRates(ctx, request)
+func (s *CalculateRatesService) CalculateRates(ctx context.Context, request *models.Request, discount models.Discount) (*models.RateBreakdown, error) {
+	return s.repo.CalculateRates(ctx, request, discount)
 }
 
-func (s *CalculateRatesService) GetRates(ctx context.Context, request *models.Request) (float64, float64, float64, float64, error) {
-	return s.repo.GetRates(ctx, request)
+func (s *CalculateRatesService) GetRates(ctx context.Context, request *models.Request, discount models.Discount) (*models.RateBreakdown, error) {
+	return s.repo.GetRates(ctx, request, discount)
 }
 
-func (s *CalculateRatesService) UpdateRates(ctx context.Context, request *models.Request, customLineItems []models.CustomLineItems) (float64, float64, float64, float64, error) {
-	return s.repo.UpdateRates(ctx, request, customLineItems)
+func (s *CalculateRatesService) UpdateRates(ctx context.Context, request *models.Request, customLineItems []models.CustomLineItems, discount models.Discount) (*models.RateBreakdown, error) {
+	return s.repo.UpdateRates(ctx, request, customLineItems, discount)
+}
+
+func (s *CalculateRatesService) CalculateFees(ctx context.Context, request *models.Request, subtotal models.Money, discount models.Discount) (*models.RateBreakdown, error) {
+	return s.repo.CalculateFees(ctx, request, subtotal, discount)
 }
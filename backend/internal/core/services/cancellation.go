@@ -0,0 +1,185 @@
+package services
+
+import (
+	"backend/internal/config"
+	"backend/internal/core/models"
+	ports "backend/internal/core/ports"
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type CancellationService struct {
+	requestRepo    ports.RequestRepository
+	invoiceService ports.InvoiceService
+	stripeService  ports.StripeService
+	policy         models.CancellationPolicy
+}
+
+func NewCancellationService(requestRepo ports.RequestRepository, invoiceService ports.InvoiceService, stripeService ports.StripeService, cfg *config.Config) *CancellationService {
+	var policy models.CancellationPolicy
+	if cfg.Cancellation != nil {
+		for _, tier := range cfg.Cancellation.RefundTiers {
+			policy.Tiers = append(policy.Tiers, models.CancellationRefundTier{
+				DaysBeforeEvent: tier.DaysBeforeEvent,
+				RefundPercent:   tier.RefundPercent,
+			})
+		}
+	}
+
+	return &CancellationService{
+		requestRepo:    requestRepo,
+		invoiceService: invoiceService,
+		stripeService:  stripeService,
+		policy:         policy,
+	}
+}
+
+func (s *CancellationService) GetCancellationPolicy() models.CancellationPolicy {
+	return s.policy
+}
+
+func (s *CancellationService) QuoteCancellation(ctx context.Context, requestID uuid.UUID) (*models.RequestCancellation, error) {
+	quote, _, err := s.quote(ctx, requestID, time.Now().UTC())
+	return quote, err
+}
+
+func (s *CancellationService) CancelRequest(ctx context.Context, requestID uuid.UUID) (*models.RequestCancellation, error) {
+	cancelledAt := time.Now().UTC()
+
+	quote, invoices, err := s.quote(ctx, requestID, cancelledAt)
+	if err != nil {
+		return nil, err
+	}
+
+	// the refund owed is stored with the cancellation, so a refund that fails part way can be
+	// retried for the rest
+	cancelled, err := s.requestRepo.CancelRequest(ctx, requestID, cancelledAt, quote.RefundAmount)
+	if err != nil {
+		return nil, err
+	}
+	if !cancelled {
+		return nil, models.ErrRequestAlreadyCancelled
+	}
+
+	quote.Refunds, err = s.refund(ctx, requestID, invoices, quote.RefundAmount)
+	if err != nil {
+		return quote, fmt.Errorf("request cancelled but refund failed, retry the refund for the rest: %w", err)
+	}
+	return quote, nil
+}
+
+// RetryCancellationRefund refunds what is still owed after a cancellation's refund failed
+func (s *CancellationService) RetryCancellationRefund(ctx context.Context, requestID uuid.UUID) (*models.RequestCancellation, error) {
+	request, err := s.requestRepo.GetRequestById(ctx, requestID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get request: %w", err)
+	}
+	if request.CancelledAt == nil {
+		return nil, models.ErrRequestNotCancelled
+	}
+
+	invoices, err := s.invoiceService.GetInvoicesByRequestID(ctx, requestID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get invoices: %w", err)
+	}
+
+	retry := &models.RequestCancellation{
+		RequestID:    requestID,
+		InvoiceIDs:   make([]uuid.UUID, 0, len(invoices)),
+		CancelledAt:  *request.CancelledAt,
+		EventStart:   request.StartDate,
+		RefundAmount: request.CancellationRefundDue,
+	}
+	for _, invoice := range invoices {
+		retry.InvoiceIDs = append(retry.InvoiceIDs, invoice.UUID)
+		retry.AmountPaid += invoice.AmountPaid
+	}
+
+	retry.Refunds, err = s.refund(ctx, requestID, invoices, request.CancellationRefundDue)
+	if err != nil {
+		return retry, fmt.Errorf("refund failed, retry the refund for the rest: %w", err)
+	}
+	return retry, nil
+}
+
+// refund takes amount off the most recent invoices first, each one refunds at most what is
+// left paid on it. Every refund that goes through is settled against what the cancellation
+// owes before the next one is tried.
+func (s *CancellationService) refund(ctx context.Context, requestID uuid.UUID, invoices []models.Invoice, amount models.Money) ([]models.PaymentLedgerEntry, error) {
+	var issued []models.PaymentLedgerEntry
+	remaining := amount
+	for i := len(invoices) - 1; i >= 0 && remaining > 0; i-- {
+		invoice := &invoices[i]
+		if invoice.AmountPaid <= 0 {
+			continue
+		}
+
+		take := min(remaining, invoice.AmountPaid)
+		refunds, err := s.stripeService.RefundAmount(ctx, invoice, take)
+		issued = append(issued, refunds...)
+
+		var refunded models.Money
+		for _, refund := range refunds {
+			refunded += refund.Amount
+		}
+		if refunded > 0 {
+			if settleErr := s.requestRepo.SettleCancellationRefund(ctx, requestID, refunded); settleErr != nil {
+				log.Printf("Refunded %s on request %s but failed to settle it: %v", refunded, requestID, settleErr)
+			}
+		}
+
+		if err != nil {
+			// what was refunded is in the ledger, the rest stays owed on the request
+			log.Printf("Refund of %s on invoice %s for cancelled request %s failed: %v", take, invoice.UUID, requestID, err)
+			return issued, err
+		}
+		remaining -= take
+	}
+
+	if remaining > 0 {
+		return issued, fmt.Errorf("%s of the refund isn't covered by payments on the request", remaining)
+	}
+	return issued, nil
+}
+
+// quote works out the refund for cancelling at cancelledAt from the event start and what has
+// been paid across the request's invoices so far
+func (s *CancellationService) quote(ctx context.Context, requestID uuid.UUID, cancelledAt time.Time) (*models.RequestCancellation, []models.Invoice, error) {
+	request, err := s.requestRepo.GetRequestById(ctx, requestID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get request: %w", err)
+	}
+	if request.CancelledAt != nil {
+		return nil, nil, models.ErrRequestAlreadyCancelled
+	}
+
+	invoices, err := s.invoiceService.GetInvoicesByRequestID(ctx, requestID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get invoices: %w", err)
+	}
+
+	days := models.DaysBeforeEvent(cancelledAt, request.StartDate)
+	percent := s.policy.RefundPercent(days)
+
+	quote := &models.RequestCancellation{
+		RequestID:       requestID,
+		InvoiceIDs:      make([]uuid.UUID, 0, len(invoices)),
+		CancelledAt:     cancelledAt,
+		EventStart:      request.StartDate,
+		DaysBeforeEvent: days,
+		RefundPercent:   percent,
+	}
+	for _, invoice := range invoices {
+		quote.InvoiceIDs = append(quote.InvoiceIDs, invoice.UUID)
+		quote.AmountPaid += invoice.AmountPaid
+	}
+	if quote.AmountPaid > 0 {
+		quote.RefundAmount = quote.AmountPaid.Percent(percent, models.RoundingNearestCent)
+	}
+
+	return quote, invoices, nil
+}
@@ -0,0 +1,148 @@
+package services
+
+import (
+	"backend/internal/core/models"
+	ports "backend/internal/core/ports"
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type ClientService struct {
+	clientRepo     ports.ClientRepository
+	invoiceService ports.InvoiceService
+	stripeService  ports.StripeService
+}
+
+func NewClientService(clientRepo ports.ClientRepository, invoiceService ports.InvoiceService, stripeService ports.StripeService) *ClientService {
+	return &ClientService{
+		clientRepo:     clientRepo,
+		invoiceService: invoiceService,
+		stripeService:  stripeService,
+	}
+}
+
+func (s *ClientService) GetClientByID(ctx context.Context, id uuid.UUID) (*models.Client, error) {
+	client, err := s.clientRepo.GetClientByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if client == nil {
+		return nil, models.ErrClientNotFound
+	}
+	return client, nil
+}
+
+func (s *ClientService) GetAllClients(ctx context.Context) ([]models.Client, error) {
+	return s.clientRepo.GetAllClients(ctx)
+}
+
+func (s *ClientService) UpdateClient(ctx context.Context, id uuid.UUID, update models.ClientUpdate) (*models.Client, error) {
+	client, err := s.GetClientByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	update.Apply(client)
+	if err := s.clientRepo.UpdateClient(ctx, client); err != nil {
+		return nil, fmt.Errorf("failed to update client: %w", err)
+	}
+	return client, nil
+}
+
+func (s *ClientService) GetPaymentMethods(ctx context.Context, id uuid.UUID) ([]models.SavedPaymentMethod, error) {
+	client, err := s.GetClientByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.stripeService.GetPaymentMethods(ctx, client)
+}
+
+func (s *ClientService) SetDefaultPaymentMethod(ctx context.Context, id uuid.UUID, paymentMethodID string) (*models.Client, error) {
+	client, err := s.GetClientByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.stripeService.SetDefaultPaymentMethod(ctx, client, paymentMethodID); err != nil {
+		return nil, err
+	}
+	if err := s.clientRepo.SetDefaultPaymentMethod(ctx, client.UUID, paymentMethodID); err != nil {
+		return nil, fmt.Errorf("failed to store default payment method: %w", err)
+	}
+	client.DefaultPaymentMethodID = paymentMethodID
+	return client, nil
+}
+
+func (s *ClientService) ChargeInvoice(ctx context.Context, invoiceID uuid.UUID) (*models.PaymentLedgerEntry, error) {
+	invoice, err := s.invoiceService.GetInvoiceByID(ctx, invoiceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get invoice: %w", err)
+	}
+	return s.chargeBalance(ctx, invoice)
+}
+
+// ChargeBalancesAfterEvents is run by cron. Each invoice gets one automatic attempt, a declined
+// card is left to the usual payment reminders.
+func (s *ClientService) ChargeBalancesAfterEvents(ctx context.Context) error {
+	ctx = models.WithActor(ctx, models.ActorSystem)
+	now := time.Now().UTC()
+
+	invoices, err := s.clientRepo.GetInvoicesDueForAutoCharge(ctx, now)
+	if err != nil {
+		return err
+	}
+
+	charged, failed := 0, 0
+	for i := range invoices {
+		invoice := &invoices[i]
+
+		claimed, err := s.clientRepo.MarkAutoChargeAttempted(ctx, invoice.UUID, now)
+		if err != nil {
+			return err
+		}
+		if !claimed {
+			continue
+		}
+
+		if _, err := s.chargeBalance(ctx, invoice); err != nil {
+			log.Printf("[CRON] Failed to charge balance of invoice %s: %v", invoice.UUID, err)
+			failed++
+			continue
+		}
+		charged++
+	}
+
+	if charged > 0 || failed > 0 {
+		log.Printf("[CRON] Charged %d invoice balances after events, %d failed", charged, failed)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d invoice balances could not be charged", failed)
+	}
+	return nil
+}
+
+// chargeBalance charges what is left on the invoice to the client's saved card
+func (s *ClientService) chargeBalance(ctx context.Context, invoice *models.Invoice) (*models.PaymentLedgerEntry, error) {
+	if invoice.Status == models.InvoiceStatusVoid || invoice.Status == models.InvoiceStatusRefunded {
+		return nil, fmt.Errorf("invoice %s is %s", invoice.UUID, invoice.Status)
+	}
+	if invoice.Balance <= 0 {
+		return nil, fmt.Errorf("invoice %s has no balance to charge", invoice.UUID)
+	}
+
+	client, err := s.clientRepo.GetClientForRequest(ctx, invoice.RequestID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client: %w", err)
+	}
+
+	// the charge shows up on the client's statement under the invoice number
+	if err := s.invoiceService.AssignInvoiceNumber(ctx, invoice); err != nil {
+		return nil, err
+	}
+
+	return s.stripeService.ChargeOffSession(ctx, invoice, client, "", invoice.Balance)
+}
@@ -0,0 +1,147 @@
+package services
+
+import (
+	"backend/internal/core/models"
+	"backend/internal/core/ports"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// reminders further out than this from the due date are almost certainly a typo
+const maxDunningOffsetDays = 365
+
+type DunningService struct {
+	repo                ports.DunningRepository
+	emailSvc            ports.EmailService
+	stripeSvc           ports.StripeService
+	staffRequirementSvc ports.StaffRequirementService
+}
+
+func NewDunningService(repo ports.DunningRepository, emailSvc ports.EmailService, stripeSvc ports.StripeService, staffRequirementSvc ports.StaffRequirementService) *DunningService {
+	return &DunningService{
+		repo:                repo,
+		emailSvc:            emailSvc,
+		stripeSvc:           stripeSvc,
+		staffRequirementSvc: staffRequirementSvc,
+	}
+}
+
+func (s *DunningService) GetDunningSchedule(ctx context.Context, branchID uuid.UUID) (*models.DunningSchedule, error) {
+	schedule, err := s.repo.GetDunningSchedule(ctx, branchID)
+	if err != nil {
+		return nil, err
+	}
+	if schedule == nil {
+		return nil, errors.New("no dunning schedule configured")
+	}
+	return schedule, nil
+}
+
+// UpdateDunningSchedule replaces the branch's own cadence, the branch stops following the default
+func (s *DunningService) UpdateDunningSchedule(ctx context.Context, schedule *models.DunningSchedule) error {
+	if schedule.BranchID == nil {
+		return errors.New("branch_id is required")
+	}
+	if schedule.Enabled && len(schedule.OffsetDays) == 0 {
+		return errors.New("at least one reminder offset is required")
+	}
+
+	seen := make(map[int64]bool)
+	for _, offset := range schedule.OffsetDays {
+		if offset < -maxDunningOffsetDays || offset > maxDunningOffsetDays {
+			return fmt.Errorf("reminder offset %d is out of range", offset)
+		}
+		if seen[offset] {
+			return fmt.Errorf("reminder offset %d is listed twice", offset)
+		}
+		seen[offset] = true
+	}
+
+	return s.repo.UpsertDunningSchedule(ctx, schedule)
+}
+
+// DeleteDunningSchedule puts the branch back on the default cadence
+func (s *DunningService) DeleteDunningSchedule(ctx context.Context, branchID uuid.UUID) error {
+	return s.repo.DeleteDunningSchedule(ctx, branchID)
+}
+
+func (s *DunningService) SendDueReminders(ctx context.Context) error {
+	invoices, err := s.repo.GetOpenInvoices(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get open invoices: %w", err)
+	}
+
+	now := time.Now().UTC()
+	schedules := make(map[uuid.UUID]*models.DunningSchedule)
+	sent, failed := 0, 0
+
+	for i := range invoices {
+		invoice := &invoices[i]
+
+		branchID := invoice.Request.ClosestBranchID
+		schedule, ok := schedules[branchID]
+		if !ok {
+			schedule, err = s.repo.GetDunningSchedule(ctx, branchID)
+			if err != nil {
+				return fmt.Errorf("failed to get dunning schedule: %w", err)
+			}
+			schedules[branchID] = schedule
+		}
+		if schedule == nil || !schedule.Enabled {
+			continue
+		}
+
+		next, ok := schedule.NextReminder(invoice.DueDate, invoice.FollowUpDate)
+		if !ok || next.After(now) {
+			continue
+		}
+
+		// every API instance runs the job, the claim makes sure only one sends the reminder
+		claimed, err := s.repo.ClaimReminder(ctx, invoice.UUID, invoice.FollowUpDate, now)
+		if err != nil {
+			return fmt.Errorf("failed to claim reminder for invoice %s: %w", invoice.UUID, err)
+		}
+		if !claimed {
+			continue
+		}
+
+		if err := s.sendReminder(ctx, invoice); err != nil {
+			log.Printf("Failed to send payment reminder for invoice %s: %v", invoice.UUID, err)
+			if err := s.repo.ReleaseReminder(ctx, invoice.UUID, invoice.FollowUpDate, now); err != nil {
+				log.Printf("Failed to release reminder for invoice %s: %v", invoice.UUID, err)
+			}
+			failed++
+			continue
+		}
+		sent++
+	}
+
+	if sent > 0 || failed > 0 {
+		log.Printf("Sent %d payment reminders, %d failed", sent, failed)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d payment reminders failed", failed)
+	}
+	return nil
+}
+
+// sendReminder emails one invoice with a fresh checkout link, old links expire after a day
+func (s *DunningService) sendReminder(ctx context.Context, invoice *models.Invoice) error {
+	staffRequirements, err := s.staffRequirementSvc.GetAllStaffRequirementsByRequestID(ctx, invoice.RequestID)
+	if err != nil {
+		return fmt.Errorf("failed to get staff requirements: %w", err)
+	}
+
+	checkoutURL, err := s.stripeSvc.CreateCheckoutSession(ctx, invoice, staffRequirements)
+	if err != nil {
+		return fmt.Errorf("failed to create checkout session: %w", err)
+	}
+
+	paymentURLs := map[string]string{invoice.UUID.String(): checkoutURL}
+	return s.emailSvc.SendFollowUpEmails(ctx, []models.Invoice{*invoice}, paymentURLs, models.EmailHeaders{})
+}
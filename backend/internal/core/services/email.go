@@ -4,29 +4,137 @@ import (
 	"backend/internal/core/models"
 	ports "backend/internal/core/ports"
 	"context"
+	"errors"
+	"fmt"
 	"time"
+
+	"github.com/google/uuid"
 )
 
+var validEmailStatuses = map[string]bool{
+	models.EmailStatusPending:   true,
+	models.EmailStatusSending:   true,
+	models.EmailStatusSent:      true,
+	models.EmailStatusFailed:    true,
+	models.EmailStatusDead:      true,
+	models.EmailStatusCancelled: true,
+}
+
 type EmailService struct {
-	repo ports.EmailRepository
+	repo   ports.EmailRepository
+	outbox ports.EmailOutboxRepository
+	logs   ports.EmailLogRepository
 }
 
-func NewEmailService(repo ports.EmailRepository) *EmailService {
-	return &EmailService{repo: repo}
+func NewEmailService(repo ports.EmailRepository, outbox ports.EmailOutboxRepository, logs ports.EmailLogRepository) *EmailService {
+	return &EmailService{repo: repo, outbox: outbox, logs: logs}
 }
 
-func (s *EmailService) SendEmail(ctx context.Context, invoice *models.Invoice, staffRequirements []models.StaffRequirement) error {
-	return s.repo.SendEmail(ctx, invoice, staffRequirements)
+func (s *EmailService) SendEmail(ctx context.Context, invoice *models.Invoice, staffRequirements []models.StaffRequirement, headers models.EmailHeaders) error {
+	return s.repo.SendEmail(ctx, invoice, staffRequirements, headers)
 }
 
-func (s *EmailService) SendEmailWithPaymentURL(ctx context.Context, invoice *models.Invoice, staffRequirements []models.StaffRequirement, paymentURL string) error {
-	return s.repo.SendEmailWithPaymentURL(ctx, invoice, staffRequirements, paymentURL)
+func (s *EmailService) SendEmailWithPaymentURL(ctx context.Context, invoice *models.Invoice, staffRequirements []models.StaffRequirement, paymentURL string, headers models.EmailHeaders) error {
+	return s.repo.SendEmailWithPaymentURL(ctx, invoice, staffRequirements, paymentURL, headers)
 }
 
-func (s *EmailService) SendCustomEmail(ctx context.Context, invoice *models.Invoice, staffRequirements []models.StaffRequirement, emailContent string, headers models.EmailHeaders, attachmentData []byte, filename string, paymentURL string) error {
-	return s.repo.SendCustomEmail(ctx, invoice, staffRequirements, emailContent, headers, attachmentData, filename, paymentURL)
+func (s *EmailService) SendCustomEmail(ctx context.Context, invoice *models.Invoice, staffRequirements []models.StaffRequirement, emailContent string, headers models.EmailHeaders, paymentURL string) error {
+	return s.repo.SendCustomEmail(ctx, invoice, staffRequirements, emailContent, headers, paymentURL)
 }
 
 func (s *EmailService) ScheduleEmail(ctx context.Context, invoice *models.Invoice, staffRequirements []models.StaffRequirement, sendAt time.Time, customContent string, headers models.EmailHeaders, paymentURL string) error {
 	return s.repo.ScheduleEmail(ctx, invoice, staffRequirements, sendAt, customContent, headers, paymentURL)
 }
+
+func (s *EmailService) SendFollowUpEmails(ctx context.Context, invoices []models.Invoice, paymentURLs map[string]string, headers models.EmailHeaders) error {
+	return s.repo.SendFollowUpEmails(ctx, invoices, paymentURLs, headers)
+}
+
+func (s *EmailService) SendPaymentConfirmationEmail(ctx context.Context, invoice *models.Invoice, amountPaid models.Money, paidAt time.Time) error {
+	return s.repo.SendPaymentConfirmationEmail(ctx, invoice, amountPaid, paidAt)
+}
+
+func (s *EmailService) StoreAttachment(ctx context.Context, filename, contentType string, data []byte) (*models.EmailAttachment, error) {
+	if filename == "" {
+		return nil, errors.New("attachment filename is required")
+	}
+	if len(data) == 0 {
+		return nil, errors.New("attachment is empty")
+	}
+	if len(data) > models.MaxEmailAttachmentSize {
+		return nil, fmt.Errorf("attachment is larger than %d MB", models.MaxEmailAttachmentSize>>20)
+	}
+	return s.repo.StoreAttachment(ctx, filename, contentType, data)
+}
+
+func (s *EmailService) GetAttachment(ctx context.Context, id uuid.UUID) (*models.EmailAttachment, []byte, error) {
+	return s.repo.GetAttachment(ctx, id)
+}
+
+func (s *EmailService) GetScheduledEmail(ctx context.Context, id uuid.UUID) (*models.Email, error) {
+	return s.outbox.GetEmailByID(ctx, id)
+}
+
+func (s *EmailService) GetScheduledEmailsByRequestID(ctx context.Context, requestID uuid.UUID) ([]models.Email, error) {
+	return s.outbox.GetEmailsByRequestID(ctx, requestID)
+}
+
+func (s *EmailService) GetScheduledEmailsByBranchID(ctx context.Context, branchID uuid.UUID, status string) ([]models.Email, error) {
+	if status != "" && !validEmailStatuses[status] {
+		return nil, fmt.Errorf("invalid email status %q", status)
+	}
+	return s.outbox.GetEmailsByBranchID(ctx, branchID, status)
+}
+
+func (s *EmailService) UpdateScheduledEmail(ctx context.Context, id uuid.UUID, update models.ScheduledEmailUpdate) (*models.Email, error) {
+	if update.SendAt == nil && update.Content == nil && update.Headers == nil {
+		return nil, errors.New("nothing to update")
+	}
+	if update.SendAt != nil && update.SendAt.Before(time.Now()) {
+		return nil, errors.New("send_at cannot be in the past")
+	}
+	if update.Content != nil && *update.Content == "" {
+		return nil, errors.New("email content cannot be empty")
+	}
+
+	return s.outbox.UpdateScheduledEmail(ctx, id, update)
+}
+
+func (s *EmailService) CancelScheduledEmail(ctx context.Context, id uuid.UUID) (*models.Email, error) {
+	return s.outbox.CancelEmail(ctx, id)
+}
+
+func (s *EmailService) HandleWebhook(ctx context.Context, payload []byte) error {
+	event, err := s.repo.ParseWebhookEvent(payload)
+	if err != nil {
+		return err
+	}
+	if event == nil {
+		return nil
+	}
+
+	entry, err := s.logs.RecordEmailEvent(ctx, event)
+	if err != nil {
+		return fmt.Errorf("failed to record email event: %w", err)
+	}
+	// not an email we logged, e.g. one sent before tracking existed
+	if entry == nil {
+		return nil
+	}
+
+	if event.Event == models.EmailEventBounced && entry.RequestID != nil && event.Recipient != "" {
+		if err := s.logs.MarkRequestEmailInvalid(ctx, *entry.RequestID, event.Recipient, event.Reason); err != nil {
+			return fmt.Errorf("failed to flag request email: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (s *EmailService) GetEmailLogsByRequestID(ctx context.Context, requestID uuid.UUID) ([]models.EmailLog, error) {
+	return s.logs.GetEmailLogsByRequestID(ctx, requestID)
+}
+
+func (s *EmailService) GetEmailLogsByInvoiceID(ctx context.Context, invoiceID uuid.UUID) ([]models.EmailLog, error) {
+	return s.logs.GetEmailLogsByInvoiceID(ctx, invoiceID)
+}
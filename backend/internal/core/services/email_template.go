@@ -0,0 +1,135 @@
+package services
+
+import (
+	"backend/internal/core/models"
+	"backend/internal/core/ports"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+type EmailTemplateService struct {
+	repo     ports.EmailTemplateRepository
+	renderer ports.EmailRenderer
+}
+
+func NewEmailTemplateService(repo ports.EmailTemplateRepository, renderer ports.EmailRenderer) *EmailTemplateService {
+	return &EmailTemplateService{repo: repo, renderer: renderer}
+}
+
+// GetEmailTemplate returns the template the branch uses, the built-in one when nothing was saved
+func (s *EmailTemplateService) GetEmailTemplate(ctx context.Context, branchID *uuid.UUID, name string) (*models.EmailTemplate, error) {
+	// checked up front so an unknown name isn't reported as "no saved template"
+	defaultTemplate, err := s.renderer.DefaultTemplate(name)
+	if err != nil {
+		return nil, err
+	}
+
+	template, err := s.repo.GetActiveEmailTemplate(ctx, branchID, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get email template: %w", err)
+	}
+	if template == nil {
+		return defaultTemplate, nil
+	}
+	return template, nil
+}
+
+func (s *EmailTemplateService) GetEmailTemplates(ctx context.Context, branchID *uuid.UUID) ([]models.EmailTemplate, error) {
+	templates := make([]models.EmailTemplate, 0, len(models.EmailTemplateNames))
+	for _, name := range models.EmailTemplateNames {
+		template, err := s.GetEmailTemplate(ctx, branchID, name)
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, *template)
+	}
+	return templates, nil
+}
+
+func (s *EmailTemplateService) GetEmailTemplateVersions(ctx context.Context, branchID *uuid.UUID, name string) ([]models.EmailTemplate, error) {
+	if _, err := s.renderer.DefaultTemplate(name); err != nil {
+		return nil, err
+	}
+	return s.repo.GetEmailTemplateVersions(ctx, branchID, name)
+}
+
+// SaveEmailTemplate adds the template as the newest version once it renders against sample data,
+// so a typo can't break every email the branch sends
+func (s *EmailTemplateService) SaveEmailTemplate(ctx context.Context, template *models.EmailTemplate) error {
+	if strings.TrimSpace(template.Subject) == "" {
+		return errors.New("subject is required")
+	}
+	if strings.TrimSpace(template.HTML) == "" {
+		return errors.New("html is required")
+	}
+
+	if _, err := s.render(template); err != nil {
+		return err
+	}
+
+	template.UUID = uuid.Nil
+	if err := s.repo.CreateEmailTemplate(ctx, template); err != nil {
+		return fmt.Errorf("failed to save email template: %w", err)
+	}
+	return nil
+}
+
+// RestoreEmailTemplateVersion saves an old version again as the newest one, history is never rewritten
+func (s *EmailTemplateService) RestoreEmailTemplateVersion(ctx context.Context, id uuid.UUID) (*models.EmailTemplate, error) {
+	old, err := s.repo.GetEmailTemplateByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get email template: %w", err)
+	}
+
+	restored := models.EmailTemplate{
+		BranchID: old.BranchID,
+		Name:     old.Name,
+		Subject:  old.Subject,
+		HTML:     old.HTML,
+		Text:     old.Text,
+	}
+	if err := s.SaveEmailTemplate(ctx, &restored); err != nil {
+		return nil, err
+	}
+	return &restored, nil
+}
+
+// ResetEmailTemplate drops the branch's versions so it goes back to the default template
+func (s *EmailTemplateService) ResetEmailTemplate(ctx context.Context, branchID uuid.UUID, name string) error {
+	if _, err := s.renderer.DefaultTemplate(name); err != nil {
+		return err
+	}
+	return s.repo.DeleteEmailTemplates(ctx, branchID, name)
+}
+
+func (s *EmailTemplateService) PreviewEmailTemplate(ctx context.Context, template *models.EmailTemplate) (*models.RenderedEmail, error) {
+	current, err := s.GetEmailTemplate(ctx, template.BranchID, template.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	preview := *template
+	if preview.Subject == "" {
+		preview.Subject = current.Subject
+	}
+	if preview.HTML == "" {
+		preview.HTML = current.HTML
+	}
+	if preview.Text == "" {
+		preview.Text = current.Text
+	}
+
+	return s.render(&preview)
+}
+
+func (s *EmailTemplateService) render(template *models.EmailTemplate) (*models.RenderedEmail, error) {
+	view, err := models.SampleEmailView(template.Name)
+	if err != nil {
+		return nil, err
+	}
+	return s.renderer.RenderTemplate(template, view)
+}
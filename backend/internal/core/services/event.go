@@ -0,0 +1,132 @@
+package services
+
+import (
+	"backend/internal/core/models"
+	ports "backend/internal/core/ports"
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/google/uuid"
+)
+
+type EventService struct {
+	eventRepo            ports.EventRepository
+	requestRepo          ports.RequestRepository
+	staffRequirementRepo ports.StaffRequirementRepository
+}
+
+func NewEventService(eventRepo ports.EventRepository, requestRepo ports.RequestRepository, staffRequirementRepo ports.StaffRequirementRepository) *EventService {
+	return &EventService{
+		eventRepo:            eventRepo,
+		requestRepo:          requestRepo,
+		staffRequirementRepo: staffRequirementRepo,
+	}
+}
+
+func (s *EventService) GetEvents(ctx context.Context, filter models.EventFilter) ([]models.Event, error) {
+	return s.eventRepo.GetEvents(ctx, filter)
+}
+
+func (s *EventService) GetEventByID(ctx context.Context, id uuid.UUID) (*models.Event, error) {
+	event, err := s.eventRepo.GetEventByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if event == nil {
+		return nil, models.ErrEventNotFound
+	}
+	return event, nil
+}
+
+func (s *EventService) GetEventByRequestID(ctx context.Context, requestID uuid.UUID) (*models.Event, error) {
+	event, err := s.eventRepo.GetEventByRequestID(ctx, requestID)
+	if err != nil {
+		return nil, err
+	}
+	if event == nil {
+		return nil, models.ErrEventNotFound
+	}
+	return event, nil
+}
+
+// ScheduleRequest confirms the request by hand, for requests agreed without a payment through
+// Stripe. Paid requests are scheduled by the cron sweep.
+func (s *EventService) ScheduleRequest(ctx context.Context, requestID uuid.UUID) (*models.Event, error) {
+	existing, err := s.eventRepo.GetEventByRequestID(ctx, requestID)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return existing, nil
+	}
+
+	request, err := s.requestRepo.GetRequestById(ctx, requestID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get request: %w", err)
+	}
+	if request.CancelledAt != nil {
+		return nil, models.ErrRequestAlreadyCancelled
+	}
+
+	staff, err := s.staffRequirementRepo.GetAllStaffRequirementsByRequestID(ctx, requestID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get staff requirements: %w", err)
+	}
+
+	event := models.NewEventForRequest(&request, staff)
+	created, err := s.eventRepo.CreateEvent(ctx, event)
+	if err != nil {
+		return nil, err
+	}
+	if !created {
+		// scheduled by someone else in the meantime
+		return s.GetEventByRequestID(ctx, requestID)
+	}
+	log.Printf("Scheduled event %s for request %s with %d shifts", event.UUID, requestID, len(event.Shifts))
+	return event, nil
+}
+
+func (s *EventService) UpdateEventStatus(ctx context.Context, id uuid.UUID, update models.EventStatusUpdate) (*models.Event, error) {
+	event, err := s.GetEventByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := models.ValidateEventTransition(event.Status, update.Status); err != nil {
+		return nil, err
+	}
+
+	updated, err := s.eventRepo.UpdateEventStatus(ctx, id, event.Status, update.Status, update.Notes)
+	if err != nil {
+		return nil, err
+	}
+	if !updated {
+		return nil, fmt.Errorf("%w: event is no longer %s", models.ErrInvalidEventTransition, event.Status)
+	}
+	log.Printf("%s moved event %s from %s to %s", models.ActorFromContext(ctx), id, event.Status, update.Status)
+	return s.GetEventByID(ctx, id)
+}
+
+// ScheduleConfirmedRequests is run by cron. A request counts as confirmed once money has been
+// paid on any of its invoices, usually the deposit.
+func (s *EventService) ScheduleConfirmedRequests(ctx context.Context) error {
+	ctx = models.WithActor(ctx, models.ActorSystem)
+
+	requestIDs, err := s.eventRepo.GetRequestsAwaitingEvent(ctx)
+	if err != nil {
+		return err
+	}
+
+	scheduled := 0
+	for _, requestID := range requestIDs {
+		if _, err := s.ScheduleRequest(ctx, requestID); err != nil {
+			log.Printf("[CRON] Failed to schedule event for request %s: %v", requestID, err)
+			continue
+		}
+		scheduled++
+	}
+	if scheduled > 0 {
+		log.Printf("[CRON] Scheduled events for %d confirmed requests", scheduled)
+	}
+	return nil
+}
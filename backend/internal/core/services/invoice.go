@@ -5,10 +5,11 @@ import (
 	"backend/internal/core/models"
 	"backend/internal/core/ports"
 	"context"
+	"errors"
 	"fmt"
-
-	// "fmt"
-	// "time"
+	"log"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 )
@@ -17,59 +18,47 @@ type InvoiceService struct {
 	invoiceRepo        ports.InvoiceRepository
 	requestRepo        ports.RequestRepository
 	rateCalculatorRepo ports.CalculateRatesRepository
+	promoCodeRepo      ports.PromoCodeRepository
+	invoicePDFRepo     ports.InvoicePDFRepository
 	cfg                *config.Config
 }
 
-func NewInvoiceService(invoiceRepo ports.InvoiceRepository, requestRepo ports.RequestRepository, rateCalculatorRepo ports.CalculateRatesRepository, cfg *config.Config) ports.InvoiceService {
+func NewInvoiceService(invoiceRepo ports.InvoiceRepository, requestRepo ports.RequestRepository, rateCalculatorRepo ports.CalculateRatesRepository, promoCodeRepo ports.PromoCodeRepository, invoicePDFRepo ports.InvoicePDFRepository, cfg *config.Config) ports.InvoiceService {
 	return &InvoiceService{
 		invoiceRepo:        invoiceRepo,
 		requestRepo:        requestRepo,
 		rateCalculatorRepo: rateCalculatorRepo,
+		promoCodeRepo:      promoCodeRepo,
+		invoicePDFRepo:     invoicePDFRepo,
 		cfg:                cfg,
 	}
 }
 
-// CreateInvoiceFromRequest creates a new invoice based on a request and its staff requirements
-// func (s *InvoiceService) CreateInvoiceFromRequest(ctx context.Context, request *models.Request, staffRequirements []models.StaffRequirement) error {
-// 	// Calculate total from staff requirements
-// 	var subtotal float64
-// 	for _, staffReq := range staffRequirements {
-// 		// Calculate hours between start and end time
-// 		startTime, _ := time.Parse(time.RFC3339, staffReq.StartTime.Format(time.RFC3339))
-// 		endTime, _ := time.Parse(time.RFC3339, staffReq.EndTime.Format(time.RFC3339))
-// 		hours := endTime.Sub(startTime).Hours()
-
-// 		// Add to subtotal: rate * hours * count
-// 		subtotal += staffReq.Rate * hours * float64(staffReq.Count)
-// 	}
-
-// 	// Calculate fees
-// 	transactionFee := subtotal * 0.035              // 3.5% transaction fee
-// 	serviceFee := (subtotal + transactionFee) * 0.5 // 50% service fee
-// 	amount := subtotal + transactionFee + serviceFee
-
-// 	// Create a new invoice with temporary pre-filled fields
-// 	invoice := &models.Invoice{
-// 		UUID:           uuid.New(),
-// 		RequestID:      request.UUID,
-// 		DueDate:        time.Now().AddDate(0, 0, 14),
-// 		Subtotal:       uuid.MustParse("1000"),
-// 		TransactionFee: uuid.MustParse("35"),
-// 		ServiceFee:     uuid.MustParse("170"),
-// 		Amount:         uuid.MustParse("1205"),
-// 		Balance:        uuid.MustParse("1205"), // Initial balance is same as amount
-// 		Status:         "pending",
-// 		PaymentTerms:   "Due on receipt",
-// 		ShipTo:         request.EventLocation,
-// 		PONumber:       fmt.Sprintf("PO-%s", request.UUID.String()[:8]), // Generate a PO number based on request UUID
-// 	}
-
-// 	return s.invoiceRepo.CreateInvoice(ctx, invoice)
-// }
-
+// CreateInvoice creates the request's final invoice. A request only has one live final invoice,
+// and one created after a deposit or change order only bills what they leave.
 func (s *InvoiceService) CreateInvoice(ctx context.Context, invoice *models.Invoice, request *models.Request) error {
+	invoices, err := s.invoiceRepo.GetInvoicesByRequestID(ctx, request.UUID)
+	if err != nil {
+		return fmt.Errorf("failed to get invoices: %w", err)
+	}
+	if models.FinalInvoice(invoices) != nil {
+		return models.ErrFinalInvoiceExists
+	}
+
 	invoice.TermsAndConditions = s.cfg.TermsAndConditions
-	return s.invoiceRepo.CreateInvoice(ctx, invoice, request)
+	if err := s.invoiceRepo.CreateInvoice(ctx, invoice, request); err != nil {
+		return err
+	}
+	if models.PriorInvoiced(invoices) == 0 {
+		return nil
+	}
+
+	final, err := s.reconcileFinalInvoice(ctx, request)
+	if err != nil {
+		return err
+	}
+	*invoice = *final
+	return nil
 }
 
 func (s *InvoiceService) GetInvoiceByID(ctx context.Context, id uuid.UUID) (*models.Invoice, error) {
@@ -90,11 +79,36 @@ func (s *InvoiceService) GetInvoiceByRequestID(ctx context.Context, requestID uu
 	return invoice, nil
 }
 
+func (s *InvoiceService) GetInvoicePDF(ctx context.Context, invoiceID uuid.UUID) (*models.Invoice, []byte, error) {
+	invoice, err := s.GetInvoiceByID(ctx, invoiceID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get invoice: %w", err)
+	}
+
+	pdf, err := s.invoicePDFRepo.GenerateInvoicePDF(ctx, invoice)
+	if err != nil {
+		return nil, nil, err
+	}
+	return invoice, pdf, nil
+}
+
 func (s *InvoiceService) GetInvoiceByBranchID(ctx context.Context, branchID uuid.UUID) ([]models.InvoiceResponse, error) {
 	return s.invoiceRepo.GetInvoiceByBranchID(ctx, branchID)
 }
 
+// UpdateInvoice saves the invoice, a changed status has to be a transition the lifecycle allows
 func (s *InvoiceService) UpdateInvoice(ctx context.Context, invoice *models.Invoice) error {
+	existing, err := s.invoiceRepo.GetInvoiceByID(ctx, invoice.UUID)
+	if err != nil {
+		return err
+	}
+
+	if invoice.Status != existing.Status {
+		if err := s.transition(ctx, existing, invoice.Status, "invoice updated"); err != nil {
+			return err
+		}
+	}
+
 	invoice.TermsAndConditions = s.cfg.TermsAndConditions
 	return s.invoiceRepo.UpdateInvoice(ctx, invoice)
 }
@@ -103,54 +117,411 @@ func (s *InvoiceService) DeleteInvoice(ctx context.Context, id uuid.UUID) error
 	return s.invoiceRepo.DeleteInvoice(ctx, id)
 }
 
+func (s *InvoiceService) AssignInvoiceNumber(ctx context.Context, invoice *models.Invoice) error {
+	if invoice.InvoiceNumber != nil && *invoice.InvoiceNumber != "" {
+		return nil
+	}
+	if err := s.invoiceRepo.AssignInvoiceNumber(ctx, invoice); err != nil {
+		return fmt.Errorf("failed to number invoice: %w", err)
+	}
+	return nil
+}
+
+func (s *InvoiceService) SearchInvoices(ctx context.Context, query string) ([]models.Invoice, error) {
+	return s.invoiceRepo.SearchInvoices(ctx, query)
+}
+
 func (s *InvoiceService) CheckForOverdueInvoices(ctx context.Context) ([]models.Invoice, error) {
 	return s.invoiceRepo.CheckForOverdueInvoices(ctx)
 }
 
-// RecalculateInvoiceAfterPaymentWithNewItems recalculates invoice totals when new custom line items
-// are added to an already paid or partially paid invoice
+func (s *InvoiceService) TransitionInvoice(ctx context.Context, invoiceID uuid.UUID, status string, reason string) (*models.Invoice, error) {
+	invoice, err := s.GetInvoiceByID(ctx, invoiceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get invoice: %w", err)
+	}
+
+	if err := s.transition(ctx, invoice, status, reason); err != nil {
+		return nil, err
+	}
+	return invoice, nil
+}
+
+func (s *InvoiceService) MarkInvoiceSent(ctx context.Context, invoice *models.Invoice) error {
+	current, err := s.invoiceRepo.GetInvoiceByID(ctx, invoice.UUID)
+	if err != nil {
+		return err
+	}
+
+	if current.Status == models.InvoiceStatusDraft {
+		if err := s.transition(ctx, current, models.InvoiceStatusSent, "invoice emailed"); err != nil {
+			return err
+		}
+	}
+
+	current.LastSent = time.Now().UTC()
+	current.TermsAndConditions = s.cfg.TermsAndConditions
+	if err := s.invoiceRepo.UpdateInvoice(ctx, current); err != nil {
+		return err
+	}
+
+	invoice.Status = current.Status
+	invoice.LastSent = current.LastSent
+	return nil
+}
+
+// MarkOverdueInvoices is the overdue sweep, run by cron
+func (s *InvoiceService) MarkOverdueInvoices(ctx context.Context) error {
+	ctx = models.WithActor(ctx, models.ActorSystem)
+
+	invoices, err := s.invoiceRepo.CheckForOverdueInvoices(ctx)
+	if err != nil {
+		return err
+	}
+
+	marked, failed := 0, 0
+	for i := range invoices {
+		invoice := &invoices[i]
+		if invoice.Status == models.InvoiceStatusOverdue {
+			continue
+		}
+
+		reason := fmt.Sprintf("due %s", invoice.DueDate.Format("2006-01-02"))
+		if err := s.transition(ctx, invoice, models.InvoiceStatusOverdue, reason); err != nil {
+			log.Printf("[CRON] Failed to mark invoice %s overdue: %v", invoice.UUID, err)
+			failed++
+			continue
+		}
+		marked++
+	}
+
+	if marked > 0 || failed > 0 {
+		log.Printf("[CRON] Marked %d invoices overdue, %d failed", marked, failed)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d invoices could not be marked overdue", failed)
+	}
+	return nil
+}
+
+func (s *InvoiceService) GetInvoiceStatusHistory(ctx context.Context, invoiceID uuid.UUID) ([]models.InvoiceStatusChange, error) {
+	return s.invoiceRepo.GetInvoiceStatusHistory(ctx, invoiceID)
+}
+
+// transition is the one place invoice status changes are validated, the invoice is updated in
+// place once the change is stored
+func (s *InvoiceService) transition(ctx context.Context, invoice *models.Invoice, status string, reason string) error {
+	if err := models.ValidateInvoiceTransition(invoice.Status, status); err != nil {
+		return err
+	}
+
+	err := s.invoiceRepo.TransitionInvoiceStatus(ctx, &models.InvoiceStatusChange{
+		InvoiceID:  invoice.UUID,
+		FromStatus: invoice.Status,
+		ToStatus:   status,
+		Actor:      models.ActorFromContext(ctx),
+		Reason:     reason,
+	})
+	if err != nil {
+		return err
+	}
+
+	invoice.Status = status
+	return nil
+}
+
+// RecalculateInvoiceAfterPaymentWithNewItems adds custom line items to the invoice's request. A
+// final invoice that is still a draft is repriced to include them, once it has been sent or paid
+// it is left as it is and the items are billed on a change order instead.
 func (s *InvoiceService) RecalculateInvoiceAfterPaymentWithNewItems(ctx context.Context, invoiceID uuid.UUID, newCustomLineItems []models.CustomLineItems) (*models.Invoice, error) {
-	// Get the existing invoice
 	invoice, err := s.GetInvoiceByID(ctx, invoiceID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get invoice: %w", err)
 	}
 
-	// Get the request associated with this invoice
-	request, err := s.requestRepo.GetRequestById(ctx, invoice.RequestID)
+	return s.CreateChangeOrderInvoice(ctx, invoice.RequestID, newCustomLineItems)
+}
+
+func (s *InvoiceService) GetInvoicesByRequestID(ctx context.Context, requestID uuid.UUID) ([]models.Invoice, error) {
+	invoices, err := s.invoiceRepo.GetInvoicesByRequestID(ctx, requestID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range invoices {
+		invoices[i].TermsAndConditions = s.cfg.TermsAndConditions
+	}
+	return invoices, nil
+}
+
+func (s *InvoiceService) GetRequestInvoiceSummary(ctx context.Context, requestID uuid.UUID) (*models.RequestInvoiceSummary, error) {
+	invoices, err := s.GetInvoicesByRequestID(ctx, requestID)
+	if err != nil {
+		return nil, err
+	}
+	return models.SummarizeInvoices(requestID, invoices), nil
+}
+
+// CreateDepositInvoice bills part of the request up front. The final invoice has to still be a
+// draft, it is repriced to bill only what the deposit leaves.
+func (s *InvoiceService) CreateDepositInvoice(ctx context.Context, requestID uuid.UUID, deposit models.Deposit) (*models.Invoice, error) {
+	if (deposit.Amount > 0) == (deposit.Percent > 0) {
+		return nil, errors.New("a deposit needs either an amount or a percent")
+	}
+	if deposit.Percent > 100 {
+		return nil, errors.New("deposit percent cannot be more than 100")
+	}
+
+	request, err := s.requestRepo.GetRequestById(ctx, requestID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get request: %w", err)
 	}
 
-	// Use existing UpdateRates functionality to recalculate totals including custom line items
-	newAmount, newTransactionFee, newServiceFee, newSubtotal, err := s.rateCalculatorRepo.UpdateRates(ctx, &request, newCustomLineItems)
+	invoices, err := s.invoiceRepo.GetInvoicesByRequestID(ctx, requestID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to recalculate rates with custom line items: %w", err)
+		return nil, fmt.Errorf("failed to get invoices: %w", err)
 	}
 
-	// Calculate new balance (new total amount minus what's already been paid)
-	newBalance := newAmount - invoice.AmountPaid
+	discount := models.Discount{Type: models.DiscountTypeNone}
+	final := models.FinalInvoice(invoices)
+	if final != nil {
+		if !isRepriceable(final) {
+			return nil, errors.New("the final invoice has already been sent, a deposit can no longer be taken")
+		}
+		discount = final.Discount()
+	}
 
-	// Update the invoice with new totals
-	invoice.Subtotal = newSubtotal
-	invoice.TransactionFee = newTransactionFee
-	invoice.ServiceFee = newServiceFee
-	invoice.Amount = newAmount
-	invoice.Balance = newBalance
+	total, err := s.rateCalculatorRepo.CalculateRequestTotal(ctx, &request, discount)
+	if err != nil {
+		return nil, fmt.Errorf("failed to calculate request total: %w", err)
+	}
+
+	amount := deposit.Amount
+	if deposit.Percent > 0 {
+		amount = total.Amount.Percent(deposit.Percent, models.RoundingNearestCent)
+	}
+	remaining := total.Amount - models.PriorInvoiced(invoices)
+	if amount <= 0 || amount > remaining {
+		return nil, fmt.Errorf("deposit of %s is more than the %s left to invoice", amount, remaining)
+	}
+
+	now := time.Now().UTC()
+	dueDate := now.AddDate(0, 0, 7)
+	if deposit.DueDate != nil {
+		dueDate = *deposit.DueDate
+	}
+	if !request.StartDate.IsZero() && dueDate.After(request.StartDate) {
+		dueDate = request.StartDate
+	}
+
+	invoice := &models.Invoice{
+		RequestID:    requestID,
+		Kind:         models.InvoiceKindDeposit,
+		DueDate:      dueDate,
+		Subtotal:     amount,
+		DiscountType: models.DiscountTypeNone,
+		Amount:       amount,
+		Balance:      amount,
+		PaymentTerms: "Due on receipt",
+		Notes:        fmt.Sprintf("Deposit toward a total of %s", total.Amount),
+		ShipTo:       request.EventLocation,
+	}
+	if final != nil {
+		invoice.PONumber = final.PONumber
+	}
+	if err := s.invoiceRepo.IssueInvoice(ctx, invoice); err != nil {
+		return nil, fmt.Errorf("failed to create deposit invoice: %w", err)
+	}
+
+	if _, err := s.reconcileFinalInvoice(ctx, &request); err != nil {
+		return nil, err
+	}
+
+	invoice.Request = request
+	invoice.TermsAndConditions = s.cfg.TermsAndConditions
+	return invoice, nil
+}
+
+// CreateChangeOrderInvoice saves any new line items and bills what the request total has grown
+// by since the final invoice went out, so staff added later is billed too. While the final
+// invoice is still a draft it is repriced instead and returned.
+func (s *InvoiceService) CreateChangeOrderInvoice(ctx context.Context, requestID uuid.UUID, customLineItems []models.CustomLineItems) (*models.Invoice, error) {
+	request, err := s.requestRepo.GetRequestById(ctx, requestID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get request: %w", err)
+	}
 
-	// Set status based on remaining balance
-	if newBalance <= 0.01 {
-		invoice.Status = "paid"
-		invoice.Balance = 0
-	} else {
-		invoice.Status = "partially_paid"
+	invoices, err := s.invoiceRepo.GetInvoicesByRequestID(ctx, requestID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get invoices: %w", err)
+	}
+
+	final := models.FinalInvoice(invoices)
+	if final == nil {
+		return nil, errors.New("request has no final invoice to change")
 	}
+	discount := final.Discount()
 
-	// Save the updated invoice
-	err = s.UpdateInvoice(ctx, invoice)
+	if len(customLineItems) > 0 {
+		// UpdateRates saves the new custom line items against the request
+		if _, err := s.rateCalculatorRepo.UpdateRates(ctx, &request, customLineItems, discount); err != nil {
+			return nil, fmt.Errorf("failed to save custom line items: %w", err)
+		}
+	}
+
+	// a final invoice that hasn't gone out yet is simply repriced, change orders only bill
+	// what was added after it was sent
+	if isRepriceable(final) {
+		return s.reconcileFinalInvoice(ctx, &request)
+	}
+
+	total, err := s.rateCalculatorRepo.CalculateRequestTotal(ctx, &request, discount)
 	if err != nil {
+		return nil, fmt.Errorf("failed to calculate request total: %w", err)
+	}
+
+	invoiced := models.InvoicedBreakdown(invoices)
+	if total.Amount <= invoiced.Amount {
+		return nil, fmt.Errorf("%w: the request total of %s has already been invoiced", models.ErrNothingToInvoice, total.Amount)
+	}
+
+	notes := "Staff and line items added since the last invoice"
+	if len(customLineItems) > 0 {
+		descriptions := make([]string, 0, len(customLineItems))
+		for _, item := range customLineItems {
+			descriptions = append(descriptions, fmt.Sprintf("%d x %s", item.Quantity, item.Description))
+		}
+		notes = "Added: " + strings.Join(descriptions, ", ")
+	}
+
+	invoice := &models.Invoice{
+		RequestID:       requestID,
+		Kind:            models.InvoiceKindChangeOrder,
+		DueDate:         request.StartDate,
+		Subtotal:        total.Subtotal - invoiced.Subtotal,
+		DiscountType:    discount.Type,
+		DiscountPercent: discount.Percent,
+		DiscountFixed:   discount.Fixed,
+		DiscountAmount:  total.Discount - invoiced.Discount,
+		PromoCode:       discount.PromoCode,
+		TransactionFee:  total.TransactionFee - invoiced.TransactionFee,
+		ServiceFee:      total.ServiceFee - invoiced.ServiceFee,
+		Amount:          total.Amount - invoiced.Amount,
+		Balance:         total.Amount - invoiced.Amount,
+		PaymentTerms:    "Due on receipt",
+		Notes:           notes,
+		ShipTo:          request.EventLocation,
+		PONumber:        final.PONumber,
+	}
+	if err := s.invoiceRepo.IssueInvoice(ctx, invoice); err != nil {
+		return nil, fmt.Errorf("failed to create change order invoice: %w", err)
+	}
+
+	invoice.Request = request
+	invoice.TermsAndConditions = s.cfg.TermsAndConditions
+	return invoice, nil
+}
+
+// isRepriceable reports whether an invoice can still be changed in place, i.e. it is a final
+// invoice nobody has seen or paid yet
+func isRepriceable(invoice *models.Invoice) bool {
+	return invoice.Kind == models.InvoiceKindFinal && invoice.Status == models.InvoiceStatusDraft && invoice.AmountPaid == 0
+}
+
+// reconcileFinalInvoice reprices a draft final invoice to bill the whole request less the
+// deposits and change orders issued so far. Final invoices that have gone out are left alone.
+func (s *InvoiceService) reconcileFinalInvoice(ctx context.Context, request *models.Request) (*models.Invoice, error) {
+	invoices, err := s.invoiceRepo.GetInvoicesByRequestID(ctx, request.UUID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get invoices: %w", err)
+	}
+
+	final := models.FinalInvoice(invoices)
+	if final == nil || !isRepriceable(final) {
+		return final, nil
+	}
+
+	breakdown, err := s.rateCalculatorRepo.CalculateRequestTotal(ctx, request, final.Discount())
+	if err != nil {
+		return nil, fmt.Errorf("failed to calculate request total: %w", err)
+	}
+
+	final.PriorInvoiced = models.PriorInvoiced(invoices)
+	final.ApplyBreakdown(breakdown)
+	final.Balance = final.Amount
+
+	if err := s.UpdateInvoice(ctx, final); err != nil {
+		return nil, fmt.Errorf("failed to update final invoice: %w", err)
+	}
+	return final, nil
+}
+
+// ApplyDiscount applies a percentage, fixed or promo code discount to a draft invoice and
+// recalculates the fees on the discounted subtotal
+func (s *InvoiceService) ApplyDiscount(ctx context.Context, invoiceID uuid.UUID, discount models.Discount) (*models.Invoice, error) {
+	invoice, err := s.GetInvoiceByID(ctx, invoiceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get invoice: %w", err)
+	}
+
+	if invoice.Status != models.InvoiceStatusDraft || invoice.AmountPaid > 0 {
+		return nil, models.ErrInvoiceNotDraft
+	}
+	if invoice.Kind != models.InvoiceKindFinal {
+		return nil, errors.New("discounts apply to the final invoice")
+	}
+
+	request, err := s.requestRepo.GetRequestById(ctx, invoice.RequestID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get request: %w", err)
+	}
+
+	if discount.PromoCode != "" {
+		promoCode, err := s.resolvePromoCode(ctx, discount.PromoCode, &request)
+		if err != nil {
+			return nil, err
+		}
+		discount = promoCode.Discount()
+	} else if err := validateDiscount(discount); err != nil {
+		return nil, err
+	}
+
+	breakdown, err := s.rateCalculatorRepo.CalculateFees(ctx, &request, invoice.Subtotal, discount)
+	if err != nil {
+		return nil, fmt.Errorf("failed to recalculate rates with discount: %w", err)
+	}
+
+	invoice.SetDiscount(discount)
+	invoice.ApplyBreakdown(breakdown)
+	invoice.Balance = invoice.Amount
+	invoice.TermsAndConditions = s.cfg.TermsAndConditions
+
+	// the promo code is only redeemed, and the previous one released, if the invoice saves
+	if err := s.invoiceRepo.ApplyDiscount(ctx, invoice); err != nil {
 		return nil, fmt.Errorf("failed to update invoice: %w", err)
 	}
 
 	return invoice, nil
 }
+
+// RemoveDiscount takes any discount off a draft invoice
+func (s *InvoiceService) RemoveDiscount(ctx context.Context, invoiceID uuid.UUID) (*models.Invoice, error) {
+	return s.ApplyDiscount(ctx, invoiceID, models.Discount{Type: models.DiscountTypeNone})
+}
+
+// resolvePromoCode looks up a promo code and checks it can be used on the request
+func (s *InvoiceService) resolvePromoCode(ctx context.Context, code string, request *models.Request) (*models.PromoCode, error) {
+	promoCode, err := s.promoCodeRepo.GetPromoCodeByCode(ctx, code)
+	if err != nil {
+		return nil, fmt.Errorf("promo code %q not found", code)
+	}
+
+	now := time.Now().UTC()
+	if !promoCode.Active || now.Before(promoCode.ValidFrom) || (promoCode.ValidTo != nil && !now.Before(*promoCode.ValidTo)) {
+		return nil, fmt.Errorf("promo code %q is not active", code)
+	}
+	if promoCode.BranchID != nil && *promoCode.BranchID != request.ClosestBranchID {
+		return nil, fmt.Errorf("promo code %q is not valid for this branch", code)
+	}
+
+	return promoCode, nil
+}
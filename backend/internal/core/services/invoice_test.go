@@ -0,0 +1,187 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"backend/internal/config"
+	"backend/internal/core/models"
+	"backend/internal/core/ports"
+)
+
+// memoryInvoices keeps invoices in memory, methods a test doesn't use panic through the nil
+// embedded interface
+type memoryInvoices struct {
+	ports.InvoiceRepository
+	invoices []models.Invoice
+}
+
+func (r *memoryInvoices) CreateInvoice(ctx context.Context, invoice *models.Invoice, request *models.Request) error {
+	invoice.UUID = uuid.New()
+	invoice.RequestID = request.UUID
+	invoice.Kind = models.InvoiceKindFinal
+	invoice.Status = models.InvoiceStatusDraft
+	invoice.Subtotal = 100000
+	invoice.Amount = 100000
+	invoice.Balance = 100000
+	return r.IssueInvoice(ctx, invoice)
+}
+
+func (r *memoryInvoices) IssueInvoice(ctx context.Context, invoice *models.Invoice) error {
+	if invoice.UUID == uuid.Nil {
+		invoice.UUID = uuid.New()
+	}
+	if invoice.Status == "" {
+		invoice.Status = models.InvoiceStatusDraft
+	}
+	invoice.Sequence = len(r.invoices) + 1
+	r.invoices = append(r.invoices, *invoice)
+	return nil
+}
+
+func (r *memoryInvoices) GetInvoicesByRequestID(ctx context.Context, requestID uuid.UUID) ([]models.Invoice, error) {
+	var invoices []models.Invoice
+	for _, invoice := range r.invoices {
+		if invoice.RequestID == requestID {
+			invoices = append(invoices, invoice)
+		}
+	}
+	return invoices, nil
+}
+
+func (r *memoryInvoices) GetInvoiceByID(ctx context.Context, id uuid.UUID) (*models.Invoice, error) {
+	for _, invoice := range r.invoices {
+		if invoice.UUID == id {
+			return &invoice, nil
+		}
+	}
+	return nil, errors.New("invoice not found")
+}
+
+func (r *memoryInvoices) UpdateInvoice(ctx context.Context, invoice *models.Invoice) error {
+	for i := range r.invoices {
+		if r.invoices[i].UUID == invoice.UUID {
+			r.invoices[i] = *invoice
+			return nil
+		}
+	}
+	return errors.New("invoice not found")
+}
+
+func (r *memoryInvoices) ApplyDiscount(ctx context.Context, invoice *models.Invoice) error {
+	return r.UpdateInvoice(ctx, invoice)
+}
+
+func (r *memoryInvoices) finals() int {
+	var n int
+	for _, invoice := range r.invoices {
+		if invoice.Kind == models.InvoiceKindFinal {
+			n++
+		}
+	}
+	return n
+}
+
+// flatTotal prices every request at $1000 before fees
+type flatTotal struct {
+	ports.CalculateRatesRepository
+}
+
+func (flatTotal) CalculateRequestTotal(ctx context.Context, request *models.Request, discount models.Discount) (*models.RateBreakdown, error) {
+	return &models.RateBreakdown{Subtotal: 100000, Amount: 100000}, nil
+}
+
+// CalculateFees takes the discount off and adds no fees
+func (flatTotal) CalculateFees(ctx context.Context, request *models.Request, subtotal models.Money, discount models.Discount) (*models.RateBreakdown, error) {
+	amount := discount.Amount(subtotal)
+	return &models.RateBreakdown{Subtotal: subtotal, Discount: amount, Amount: subtotal - amount}, nil
+}
+
+func newTestInvoiceService(invoices *memoryInvoices) *InvoiceService {
+	return &InvoiceService{invoiceRepo: invoices, rateCalculatorRepo: flatTotal{}, cfg: &config.Config{}}
+}
+
+func TestCreateInvoiceTwice(t *testing.T) {
+	invoices := &memoryInvoices{}
+	service := newTestInvoiceService(invoices)
+	request := &models.Request{UUID: uuid.New()}
+
+	if err := service.CreateInvoice(context.Background(), &models.Invoice{}, request); err != nil {
+		t.Fatalf("first CreateInvoice: %v", err)
+	}
+	err := service.CreateInvoice(context.Background(), &models.Invoice{}, request)
+	if !errors.Is(err, models.ErrFinalInvoiceExists) {
+		t.Fatalf("second CreateInvoice = %v, want ErrFinalInvoiceExists", err)
+	}
+	if n := invoices.finals(); n != 1 {
+		t.Fatalf("request has %d final invoices, want 1", n)
+	}
+
+	// once the first is void the request can be invoiced again
+	invoices.invoices[0].Status = models.InvoiceStatusVoid
+	if err := service.CreateInvoice(context.Background(), &models.Invoice{}, request); err != nil {
+		t.Fatalf("CreateInvoice after voiding: %v", err)
+	}
+}
+
+func TestCreateInvoiceAfterADeposit(t *testing.T) {
+	request := &models.Request{UUID: uuid.New()}
+	invoices := &memoryInvoices{invoices: []models.Invoice{{
+		UUID:      uuid.New(),
+		RequestID: request.UUID,
+		Kind:      models.InvoiceKindDeposit,
+		Status:    models.InvoiceStatusPaid,
+		Amount:    25000,
+	}}}
+	service := newTestInvoiceService(invoices)
+
+	invoice := &models.Invoice{}
+	if err := service.CreateInvoice(context.Background(), invoice, request); err != nil {
+		t.Fatalf("CreateInvoice: %v", err)
+	}
+	if invoice.PriorInvoiced != 25000 || invoice.Amount != 75000 || invoice.Balance != 75000 {
+		t.Fatalf("final invoice has prior %d, amount %d, balance %d, want 25000, 75000, 75000",
+			invoice.PriorInvoiced, invoice.Amount, invoice.Balance)
+	}
+	stored, _ := invoices.GetInvoiceByID(context.Background(), invoice.UUID)
+	if stored.Amount != 75000 {
+		t.Fatalf("stored final invoice amount = %d, want 75000", stored.Amount)
+	}
+}
+
+func TestApplyDiscountOnlyToDrafts(t *testing.T) {
+	request := models.Request{UUID: uuid.New()}
+	invoices := &memoryInvoices{}
+	service := newTestInvoiceService(invoices)
+	service.requestRepo = &memoryRequests{requests: map[uuid.UUID]models.Request{request.UUID: request}}
+
+	invoice := &models.Invoice{}
+	if err := service.CreateInvoice(context.Background(), invoice, &request); err != nil {
+		t.Fatalf("CreateInvoice: %v", err)
+	}
+	tenPercent := models.Discount{Type: models.DiscountTypePercentage, Percent: 10}
+
+	discounted, err := service.ApplyDiscount(context.Background(), invoice.UUID, tenPercent)
+	if err != nil {
+		t.Fatalf("ApplyDiscount on a draft: %v", err)
+	}
+	if discounted.Amount != 90000 {
+		t.Fatalf("discounted amount = %d, want 90000", discounted.Amount)
+	}
+
+	for _, status := range []string{models.InvoiceStatusSent, models.InvoiceStatusPartiallyPaid, models.InvoiceStatusPaid} {
+		invoices.invoices[0].Status = status
+		if _, err := service.ApplyDiscount(context.Background(), invoice.UUID, tenPercent); !errors.Is(err, models.ErrInvoiceNotDraft) {
+			t.Errorf("ApplyDiscount on a %s invoice = %v, want ErrInvoiceNotDraft", status, err)
+		}
+		if _, err := service.RemoveDiscount(context.Background(), invoice.UUID); !errors.Is(err, models.ErrInvoiceNotDraft) {
+			t.Errorf("RemoveDiscount on a %s invoice = %v, want ErrInvoiceNotDraft", status, err)
+		}
+		if stored := invoices.invoices[0]; stored.Amount != 90000 || stored.DiscountPercent != 10 {
+			t.Errorf("%s invoice changed to amount %d at %v%%", status, stored.Amount, stored.DiscountPercent)
+		}
+	}
+}
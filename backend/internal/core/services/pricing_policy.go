@@ -0,0 +1,82 @@
+package services
+
+import (
+	"backend/internal/core/models"
+	"backend/internal/core/ports"
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type PricingPolicyService struct {
+	repo ports.PricingPolicyRepository
+}
+
+func NewPricingPolicyService(repo ports.PricingPolicyRepository) *PricingPolicyService {
+	return &PricingPolicyService{repo: repo}
+}
+
+func (s *PricingPolicyService) CreatePricingPolicy(ctx context.Context, policy *models.PricingPolicy) error {
+	if err := validatePricingPolicy(policy); err != nil {
+		return err
+	}
+	return s.repo.CreatePricingPolicy(ctx, policy)
+}
+
+func (s *PricingPolicyService) GetPricingPolicyByID(ctx context.Context, id uuid.UUID) (*models.PricingPolicy, error) {
+	return s.repo.GetPricingPolicyByID(ctx, id)
+}
+
+func (s *PricingPolicyService) GetPricingPolicies(ctx context.Context, branchID *uuid.UUID) ([]models.PricingPolicy, error) {
+	return s.repo.GetPricingPolicies(ctx, branchID)
+}
+
+func (s *PricingPolicyService) UpdatePricingPolicy(ctx context.Context, policy *models.PricingPolicy) error {
+	if err := validatePricingPolicy(policy); err != nil {
+		return err
+	}
+	return s.repo.UpdatePricingPolicy(ctx, policy)
+}
+
+func (s *PricingPolicyService) DeletePricingPolicy(ctx context.Context, id uuid.UUID) error {
+	return s.repo.DeletePricingPolicy(ctx, id)
+}
+
+func (s *PricingPolicyService) GetApplicablePricingPolicy(ctx context.Context, branchID uuid.UUID, clientEmail string, at time.Time) (*models.PricingPolicy, error) {
+	return s.repo.GetApplicablePricingPolicy(ctx, branchID, clientEmail, at)
+}
+
+// validatePricingPolicy fills in defaults and rejects policies the calculator can't apply
+func validatePricingPolicy(policy *models.PricingPolicy) error {
+	if policy.Name == "" {
+		return errors.New("pricing policy name is required")
+	}
+	if policy.TransactionFeePercent < 0 || policy.TransactionFeePercent > 100 {
+		return errors.New("transaction fee percent must be between 0 and 100")
+	}
+	if policy.ServiceFeePercent < 0 || policy.ServiceFeePercent > 100 {
+		return errors.New("service fee percent must be between 0 and 100")
+	}
+	if policy.MinimumServiceFee < 0 || policy.MinimumTotal < 0 {
+		return errors.New("minimums cannot be negative")
+	}
+
+	switch policy.Rounding {
+	case "":
+		policy.Rounding = models.RoundingNearestCent
+	case models.RoundingNearestCent, models.RoundingUpCent, models.RoundingNearestDollar, models.RoundingUpDollar:
+	default:
+		return errors.New("invalid rounding mode: " + policy.Rounding)
+	}
+
+	if policy.EffectiveFrom.IsZero() {
+		policy.EffectiveFrom = time.Now().UTC()
+	}
+	if policy.EffectiveTo != nil && !policy.EffectiveTo.After(policy.EffectiveFrom) {
+		return errors.New("effective_to must be after effective_from")
+	}
+
+	return nil
+}
@@ -0,0 +1,96 @@
+package services
+
+import (
+	"backend/internal/core/models"
+	"backend/internal/core/ports"
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type PromoCodeService struct {
+	repo ports.PromoCodeRepository
+}
+
+func NewPromoCodeService(repo ports.PromoCodeRepository) *PromoCodeService {
+	return &PromoCodeService{repo: repo}
+}
+
+func (s *PromoCodeService) CreatePromoCode(ctx context.Context, promoCode *models.PromoCode) error {
+	if err := validatePromoCode(promoCode); err != nil {
+		return err
+	}
+	return s.repo.CreatePromoCode(ctx, promoCode)
+}
+
+func (s *PromoCodeService) GetPromoCodeByID(ctx context.Context, id uuid.UUID) (*models.PromoCode, error) {
+	return s.repo.GetPromoCodeByID(ctx, id)
+}
+
+func (s *PromoCodeService) GetPromoCodes(ctx context.Context) ([]models.PromoCode, error) {
+	return s.repo.GetPromoCodes(ctx)
+}
+
+func (s *PromoCodeService) UpdatePromoCode(ctx context.Context, promoCode *models.PromoCode) error {
+	if err := validatePromoCode(promoCode); err != nil {
+		return err
+	}
+	return s.repo.UpdatePromoCode(ctx, promoCode)
+}
+
+func (s *PromoCodeService) DeletePromoCode(ctx context.Context, id uuid.UUID) error {
+	return s.repo.DeletePromoCode(ctx, id)
+}
+
+func validatePromoCode(promoCode *models.PromoCode) error {
+	promoCode.Code = strings.ToUpper(strings.TrimSpace(promoCode.Code))
+	if promoCode.Code == "" {
+		return errors.New("promo code is required")
+	}
+	if err := validateDiscount(promoCode.Discount()); err != nil {
+		return err
+	}
+	if promoCode.DiscountType == models.DiscountTypeNone {
+		return errors.New("promo code must have a percentage or fixed discount")
+	}
+	if promoCode.MaxRedemptions < 0 {
+		return errors.New("max redemptions cannot be negative")
+	}
+	if promoCode.ValidFrom.IsZero() {
+		promoCode.ValidFrom = time.Now().UTC()
+	}
+	if promoCode.ValidTo != nil && !promoCode.ValidTo.After(promoCode.ValidFrom) {
+		return errors.New("valid_to must be after valid_from")
+	}
+	return nil
+}
+
+// validateDiscount rejects discounts the calculator can't apply
+func validateDiscount(discount models.Discount) error {
+	switch discount.Type {
+	case models.DiscountTypeNone:
+		if discount.Percent != 0 || discount.Fixed != 0 {
+			return errors.New("a discount of type none takes no percent or amount")
+		}
+	case models.DiscountTypePercentage:
+		if discount.Percent <= 0 || discount.Percent > 100 {
+			return errors.New("percentage discount must be between 0 and 100")
+		}
+		if discount.Fixed != 0 {
+			return errors.New("percentage discount takes discount_percent, not discount_fixed")
+		}
+	case models.DiscountTypeFixed:
+		if discount.Fixed <= 0 {
+			return errors.New("fixed discount must be greater than 0")
+		}
+		if discount.Percent != 0 {
+			return errors.New("fixed discount takes discount_fixed, not discount_percent")
+		}
+	default:
+		return errors.New("invalid discount type: " + discount.Type)
+	}
+	return nil
+}
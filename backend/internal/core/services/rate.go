@@ -0,0 +1,253 @@
+package services
+
+import (
+	"backend/internal/core/models"
+	"backend/internal/core/ports"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const rateCardDateFormat = "2006-01-02"
+
+var rateCardHeader = []string{"staff_type", "hourly_rate", "effective_from", "effective_to"}
+
+type RateService struct {
+	repo ports.RateRepository
+}
+
+func NewRateService(repo ports.RateRepository) *RateService {
+	return &RateService{repo: repo}
+}
+
+func (s *RateService) CreateRate(ctx context.Context, rate *models.Rate) error {
+	if err := validateRate(rate); err != nil {
+		return err
+	}
+
+	rates := []models.Rate{*rate}
+	if err := s.repo.CreateRates(ctx, rates); err != nil {
+		return err
+	}
+	*rate = rates[0]
+	return nil
+}
+
+func (s *RateService) GetRateByID(ctx context.Context, id uuid.UUID) (*models.Rate, error) {
+	return s.repo.GetRateByID(ctx, id)
+}
+
+func (s *RateService) GetRatesByBranchID(ctx context.Context, branchID uuid.UUID, staffType string) ([]models.Rate, error) {
+	return s.repo.GetRatesByBranchID(ctx, branchID, staffType)
+}
+
+// UpdateRate corrects a rate in place. Staff lines keep the rate they were priced with, so
+// this never changes invoices that have already been issued.
+func (s *RateService) UpdateRate(ctx context.Context, rate *models.Rate) error {
+	if err := validateRate(rate); err != nil {
+		return err
+	}
+	return s.repo.UpdateRate(ctx, rate)
+}
+
+func (s *RateService) DeleteRate(ctx context.Context, id uuid.UUID) error {
+	return s.repo.DeleteRate(ctx, id)
+}
+
+// ImportRateCard reads a CSV rate card and adds every row to the branch's rates in one go
+func (s *RateService) ImportRateCard(ctx context.Context, branchID uuid.UUID, r io.Reader) ([]models.Rate, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rate card: %w", err)
+	}
+	if len(records) == 0 {
+		return nil, errors.New("rate card is empty")
+	}
+
+	columns := map[string]int{}
+	for i, name := range records[0] {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range rateCardHeader[:3] {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("rate card is missing the %s column", name)
+		}
+	}
+
+	field := func(record []string, name string) string {
+		i, ok := columns[name]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	var rates []models.Rate
+	for line, record := range records[1:] {
+		row := line + 2
+
+		hourlyRate, err := models.ParseMoney(field(record, "hourly_rate"))
+		if err != nil {
+			return nil, fmt.Errorf("row %d: %w", row, err)
+		}
+
+		effectiveFrom, err := time.Parse(rateCardDateFormat, field(record, "effective_from"))
+		if err != nil {
+			return nil, fmt.Errorf("row %d: invalid effective_from: %w", row, err)
+		}
+
+		rate := models.Rate{
+			BranchID:      branchID,
+			StaffType:     field(record, "staff_type"),
+			HourlyRate:    hourlyRate,
+			EffectiveFrom: effectiveFrom,
+		}
+
+		if value := field(record, "effective_to"); value != "" {
+			effectiveTo, err := time.Parse(rateCardDateFormat, value)
+			if err != nil {
+				return nil, fmt.Errorf("row %d: invalid effective_to: %w", row, err)
+			}
+			rate.EffectiveTo = &effectiveTo
+		}
+
+		if err := validateRate(&rate); err != nil {
+			return nil, fmt.Errorf("row %d: %w", row, err)
+		}
+		rates = append(rates, rate)
+	}
+
+	if err := s.repo.CreateRates(ctx, rates); err != nil {
+		return nil, err
+	}
+	return rates, nil
+}
+
+// ExportRateCard writes the branch's full rate history in the same format ImportRateCard reads
+func (s *RateService) ExportRateCard(ctx context.Context, branchID uuid.UUID, w io.Writer) error {
+	rates, err := s.repo.GetRatesByBranchID(ctx, branchID, "")
+	if err != nil {
+		return fmt.Errorf("failed to get rates: %w", err)
+	}
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write(rateCardHeader); err != nil {
+		return err
+	}
+	for _, rate := range rates {
+		effectiveTo := ""
+		if rate.EffectiveTo != nil {
+			effectiveTo = rate.EffectiveTo.Format(rateCardDateFormat)
+		}
+		record := []string{rate.StaffType, rate.HourlyRate.String(), rate.EffectiveFrom.Format(rateCardDateFormat), effectiveTo}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func (s *RateService) GetDefaultRates(ctx context.Context, region *string) ([]models.DefaultRate, error) {
+	return s.repo.GetDefaultRates(ctx, region)
+}
+
+func (s *RateService) GetDefaultRateByID(ctx context.Context, id uuid.UUID) (*models.DefaultRate, error) {
+	return s.repo.GetDefaultRateByID(ctx, id)
+}
+
+func (s *RateService) CreateDefaultRate(ctx context.Context, rate *models.DefaultRate) error {
+	if err := validateDefaultRate(rate); err != nil {
+		return err
+	}
+	return s.repo.CreateDefaultRate(ctx, rate)
+}
+
+// UpdateDefaultRate changes a fallback rate, like a branch rate it only affects requests priced
+// from now on
+func (s *RateService) UpdateDefaultRate(ctx context.Context, rate *models.DefaultRate) error {
+	if err := validateDefaultRate(rate); err != nil {
+		return err
+	}
+	return s.repo.UpdateDefaultRate(ctx, rate)
+}
+
+func (s *RateService) DeleteDefaultRate(ctx context.Context, id uuid.UUID) error {
+	return s.repo.DeleteDefaultRate(ctx, id)
+}
+
+// ResolveRate falls back from the branch rate in force on the given date to the branch's
+// regional default and then the global default. A request without a closest branch
+// (uuid.Nil) goes straight to the global default.
+func (s *RateService) ResolveRate(ctx context.Context, branchID uuid.UUID, staffType string, at time.Time) (*models.ResolvedRate, error) {
+	if branchID != uuid.Nil {
+		rate, err := s.repo.GetBranchRate(ctx, branchID, staffType, at)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get branch rate: %w", err)
+		}
+		if rate != nil {
+			return &models.ResolvedRate{HourlyRate: rate.HourlyRate, Source: models.RateSourceBranch}, nil
+		}
+
+		regional, err := s.repo.GetRegionalDefaultRate(ctx, branchID, staffType)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get regional default rate: %w", err)
+		}
+		if regional != nil {
+			return &models.ResolvedRate{HourlyRate: regional.HourlyRate, Source: models.RateSourceRegion}, nil
+		}
+	}
+
+	global, err := s.repo.GetGlobalDefaultRate(ctx, staffType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get global default rate: %w", err)
+	}
+	if global != nil {
+		return &models.ResolvedRate{HourlyRate: global.HourlyRate, Source: models.RateSourceGlobal}, nil
+	}
+
+	return nil, fmt.Errorf("%w for %q", models.ErrNoApplicableRate, staffType)
+}
+
+func validateDefaultRate(rate *models.DefaultRate) error {
+	rate.Region = strings.TrimSpace(rate.Region)
+	rate.StaffType = strings.TrimSpace(rate.StaffType)
+	if rate.StaffType == "" {
+		return errors.New("staff type is required")
+	}
+	if rate.HourlyRate <= 0 {
+		return errors.New("hourly rate must be greater than 0")
+	}
+	return nil
+}
+
+func validateRate(rate *models.Rate) error {
+	rate.StaffType = strings.TrimSpace(rate.StaffType)
+	if rate.StaffType == "" {
+		return errors.New("staff type is required")
+	}
+	if rate.BranchID == uuid.Nil {
+		return errors.New("branch is required")
+	}
+	if rate.HourlyRate <= 0 {
+		return errors.New("hourly rate must be greater than 0")
+	}
+	if rate.EffectiveFrom.IsZero() {
+		now := time.Now().UTC()
+		rate.EffectiveFrom = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	}
+	if rate.EffectiveTo != nil && !rate.EffectiveTo.After(rate.EffectiveFrom) {
+		return errors.New("effective_to must be after effective_from")
+	}
+	return nil
+}
@@ -5,6 +5,7 @@ import (
 	ports "backend/internal/core/ports"
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/google/uuid"
 )
@@ -15,15 +16,19 @@ type RequestService struct {
 	geolocationService      ports.GeolocationService
 	staffRequirementService ports.StaffRequirementService
 	invoiceService          ports.InvoiceService
+	surchargeService        ports.SurchargeService
+	rateService             ports.RateService
 }
 
 // NewRequestService creates a new instance of RequestService
-func NewRequestService(repo ports.RequestRepository, geolocationService ports.GeolocationService, staffRequirementService ports.StaffRequirementService, invoiceService ports.InvoiceService) *RequestService {
+func NewRequestService(repo ports.RequestRepository, geolocationService ports.GeolocationService, staffRequirementService ports.StaffRequirementService, invoiceService ports.InvoiceService, surchargeService ports.SurchargeService, rateService ports.RateService) *RequestService {
 	return &RequestService{
 		requestRepo:             repo,
 		geolocationService:      geolocationService,
 		staffRequirementService: staffRequirementService,
 		invoiceService:          invoiceService,
+		surchargeService:        surchargeService,
+		rateService:             rateService,
 	}
 }
 
@@ -32,6 +37,9 @@ func (s *RequestService) CreateRequest(ctx context.Context, request *models.Requ
 	// Generate UUID
 	request.UUID = uuid.New()
 
+	// Lead time surcharges are measured from when the request was made
+	request.DateRequested = time.Now().UTC()
+
 	// Use the geolocation service to find coordinates and closest branch
 	if request.EventLocation != "" {
 		latitude, longitude, err := s.geolocationService.GeoCodeAddress(ctx, request.EventLocation)
@@ -44,17 +52,21 @@ func (s *RequestService) CreateRequest(ctx context.Context, request *models.Requ
 		}
 	}
 
-	// For each staff requirement, get the rate and assign it
+	// For each staff requirement, resolve the rate in force for the closest branch on the shift
+	// date and assign it. A position with no rate anywhere fails the request rather than
+	// invoicing a $0 line. The rate is stored on the line, so later rate card changes don't
+	// reprice requests that have already been invoiced.
 	for i := range staff {
-		rate, err := s.staffRequirementService.GetRate(ctx, staff[i].Position, request.EventLocation)
+		rate, err := s.rateService.ResolveRate(ctx, request.ClosestBranchID, staff[i].Position, staff[i].StartTime)
 		if err != nil {
-			fmt.Printf("could not get rate for position %s: %v\n", staff[i].Position, err)
+			return fmt.Errorf("could not get rate for position %s: %w", staff[i].Position, err)
 		}
-		staff[i].Rate = rate
+		staff[i].Rate = rate.HourlyRate
 
-		// Calculate hours worked and the total amount for the requirement
-		hoursWorked := staff[i].EndTime.Sub(staff[i].StartTime).Hours()
-		staff[i].Amount = staff[i].Rate * hoursWorked * float64(staff[i].Count)
+		// Calculate the base amount plus any overtime, night, holiday or lead time surcharges
+		if err := s.surchargeService.PriceStaffRequirement(ctx, request, &staff[i]); err != nil {
+			return fmt.Errorf("failed to price staff requirement: %w", err)
+		}
 	}
 
 	// Create the request first
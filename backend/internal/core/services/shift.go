@@ -0,0 +1,104 @@
+package services
+
+import (
+	"backend/internal/core/models"
+	ports "backend/internal/core/ports"
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+type ShiftService struct {
+	shiftRepo ports.ShiftRepository
+	eventRepo ports.EventRepository
+}
+
+func NewShiftService(shiftRepo ports.ShiftRepository, eventRepo ports.EventRepository) *ShiftService {
+	return &ShiftService{
+		shiftRepo: shiftRepo,
+		eventRepo: eventRepo,
+	}
+}
+
+func (s *ShiftService) CreateShift(ctx context.Context, eventID uuid.UUID, input models.ShiftInput) (*models.Shift, error) {
+	if _, err := s.openEvent(ctx, eventID); err != nil {
+		return nil, err
+	}
+
+	shift := &models.Shift{EventID: eventID}
+	if err := input.Apply(shift); err != nil {
+		return nil, err
+	}
+	if err := s.shiftRepo.CreateShift(ctx, shift); err != nil {
+		return nil, fmt.Errorf("failed to create shift: %w", err)
+	}
+	return shift, nil
+}
+
+func (s *ShiftService) GetShiftByID(ctx context.Context, id uuid.UUID) (*models.Shift, error) {
+	shift, err := s.shiftRepo.GetShiftByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if shift == nil {
+		return nil, models.ErrShiftNotFound
+	}
+	return shift, nil
+}
+
+func (s *ShiftService) GetShiftsByEventID(ctx context.Context, eventID uuid.UUID) ([]models.Shift, error) {
+	event, err := s.eventRepo.GetEventByID(ctx, eventID)
+	if err != nil {
+		return nil, err
+	}
+	if event == nil {
+		return nil, models.ErrEventNotFound
+	}
+	return s.shiftRepo.GetShiftsByEventID(ctx, eventID)
+}
+
+func (s *ShiftService) UpdateShift(ctx context.Context, id uuid.UUID, input models.ShiftInput) (*models.Shift, error) {
+	shift, err := s.GetShiftByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.openEvent(ctx, shift.EventID); err != nil {
+		return nil, err
+	}
+
+	if err := input.Apply(shift); err != nil {
+		return nil, err
+	}
+	if err := s.shiftRepo.UpdateShift(ctx, shift); err != nil {
+		return nil, fmt.Errorf("failed to update shift: %w", err)
+	}
+	return shift, nil
+}
+
+func (s *ShiftService) DeleteShift(ctx context.Context, id uuid.UUID) error {
+	shift, err := s.GetShiftByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if _, err := s.openEvent(ctx, shift.EventID); err != nil {
+		return err
+	}
+	return s.shiftRepo.DeleteShift(ctx, id)
+}
+
+// openEvent gets the shift's event, which has to still be running or ahead for its shifts to
+// change
+func (s *ShiftService) openEvent(ctx context.Context, eventID uuid.UUID) (*models.Event, error) {
+	event, err := s.eventRepo.GetEventByID(ctx, eventID)
+	if err != nil {
+		return nil, err
+	}
+	if event == nil {
+		return nil, models.ErrEventNotFound
+	}
+	if event.Closed() {
+		return nil, models.ErrEventClosed
+	}
+	return event, nil
+}
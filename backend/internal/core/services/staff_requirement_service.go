@@ -5,21 +5,31 @@ import (
 	"backend/internal/core/models"
 	"backend/internal/core/ports"
 	"context"
+	"fmt"
 
 	"github.com/google/uuid"
 )
 
 type StaffRequirementService struct {
 	staffRequirementRepository ports.StaffRequirementRepository
+	requestRepository          ports.RequestRepository
+	surchargeService           ports.SurchargeService
+	rateService                ports.RateService
 }
 
-func NewStaffRequirementService(staffRequirementRepository ports.StaffRequirementRepository) *StaffRequirementService {
+func NewStaffRequirementService(staffRequirementRepository ports.StaffRequirementRepository, requestRepository ports.RequestRepository, surchargeService ports.SurchargeService, rateService ports.RateService) *StaffRequirementService {
 	return &StaffRequirementService{
 		staffRequirementRepository: staffRequirementRepository,
+		requestRepository:          requestRepository,
+		surchargeService:           surchargeService,
+		rateService:                rateService,
 	}
 }
 
 func (s *StaffRequirementService) CreateStaffRequirement(ctx context.Context, staffRequirement *models.StaffRequirement) error {
+	if err := s.price(ctx, staffRequirement); err != nil {
+		return err
+	}
 	return s.staffRequirementRepository.CreateStaffRequirement(ctx, staffRequirement)
 }
 
@@ -32,6 +42,9 @@ func (s *StaffRequirementService) GetAllStaffRequirementsByRequestID(ctx context
 }
 
 func (s *StaffRequirementService) UpdateStaffRequirement(ctx context.Context, staffRequirement *models.StaffRequirement) error {
+	if err := s.price(ctx, staffRequirement); err != nil {
+		return err
+	}
 	return s.staffRequirementRepository.UpdateStaffRequirement(ctx, staffRequirement)
 }
 
@@ -39,6 +52,23 @@ func (s *StaffRequirementService) DeleteStaffRequirement(ctx context.Context, id
 	return s.staffRequirementRepository.DeleteStaffRequirement(ctx, id)
 }
 
-func (s *StaffRequirementService) GetRate(ctx context.Context, staffType string, location string) (float64, error) {
-	return s.staffRequirementRepository.GetRate(ctx, staffType, location)
+// price resolves the rate and works out the line's amount and itemized charges the same way a
+// new request does, any rate, amount or charges the client sent are replaced
+func (s *StaffRequirementService) price(ctx context.Context, staffRequirement *models.StaffRequirement) error {
+	request, err := s.requestRepository.GetRequestById(ctx, staffRequirement.RequestID)
+	if err != nil {
+		return fmt.Errorf("failed to get request: %w", err)
+	}
+
+	rate, err := s.rateService.ResolveRate(ctx, request.ClosestBranchID, staffRequirement.Position, staffRequirement.StartTime)
+	if err != nil {
+		return fmt.Errorf("could not get rate for position %s: %w", staffRequirement.Position, err)
+	}
+	staffRequirement.Rate = rate.HourlyRate
+
+	staffRequirement.Charges = nil
+	if err := s.surchargeService.PriceStaffRequirement(ctx, &request, staffRequirement); err != nil {
+		return fmt.Errorf("failed to price staff requirement: %w", err)
+	}
+	return nil
 }
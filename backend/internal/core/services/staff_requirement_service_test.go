@@ -0,0 +1,151 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"backend/internal/core/models"
+	"backend/internal/core/ports"
+)
+
+type memoryRequests struct {
+	ports.RequestRepository
+	requests map[uuid.UUID]models.Request
+}
+
+func (r *memoryRequests) GetRequestById(ctx context.Context, id uuid.UUID) (models.Request, error) {
+	request, ok := r.requests[id]
+	if !ok {
+		return models.Request{}, gorm.ErrRecordNotFound
+	}
+	return request, nil
+}
+
+type memoryStaffRequirements struct {
+	ports.StaffRequirementRepository
+	saved []models.StaffRequirement
+}
+
+func (r *memoryStaffRequirements) CreateStaffRequirement(ctx context.Context, staffRequirement *models.StaffRequirement) error {
+	r.saved = append(r.saved, *staffRequirement)
+	return nil
+}
+
+func (r *memoryStaffRequirements) UpdateStaffRequirement(ctx context.Context, staffRequirement *models.StaffRequirement) error {
+	r.saved = append(r.saved, *staffRequirement)
+	return nil
+}
+
+// overtimeRules has one global rule, 50% on hours past 8 in a shift
+type overtimeRules struct {
+	ports.SurchargeRepository
+}
+
+func (overtimeRules) GetApplicableSurchargeRules(ctx context.Context, branchID uuid.UUID) ([]models.SurchargeRule, error) {
+	return []models.SurchargeRule{{
+		UUID:           uuid.New(),
+		Name:           "Overtime",
+		Type:           models.SurchargeTypeOvertime,
+		Percent:        50,
+		ThresholdHours: 8,
+		Active:         true,
+	}}, nil
+}
+
+// branchRates has a $20 bartender rate at every branch and nothing else
+type branchRates struct {
+	ports.RateRepository
+}
+
+func (branchRates) GetBranchRate(ctx context.Context, branchID uuid.UUID, staffType string, at time.Time) (*models.Rate, error) {
+	if staffType != "Bartender" {
+		return nil, nil
+	}
+	return &models.Rate{BranchID: branchID, StaffType: staffType, HourlyRate: 2000}, nil
+}
+
+func (branchRates) GetRegionalDefaultRate(ctx context.Context, branchID uuid.UUID, staffType string) (*models.DefaultRate, error) {
+	return nil, nil
+}
+
+func (branchRates) GetGlobalDefaultRate(ctx context.Context, staffType string) (*models.DefaultRate, error) {
+	return nil, nil
+}
+
+func TestStaffRequirementsArePricedOnTheServer(t *testing.T) {
+	request := models.Request{UUID: uuid.New(), ClosestBranchID: uuid.New(), DateRequested: time.Now().UTC()}
+	staff := &memoryStaffRequirements{}
+	service := NewStaffRequirementService(
+		staff,
+		&memoryRequests{requests: map[uuid.UUID]models.Request{request.UUID: request}},
+		NewSurchargeService(overtimeRules{}),
+		NewRateService(branchRates{}),
+	)
+
+	start := time.Now().UTC().AddDate(0, 1, 0).Truncate(time.Hour)
+	line := models.StaffRequirement{
+		RequestID: request.UUID,
+		Position:  "Bartender",
+		StartTime: start,
+		EndTime:   start.Add(10 * time.Hour),
+		Count:     2,
+		// whatever the client claims the line costs is ignored
+		Rate:    1,
+		Amount:  1,
+		Charges: []models.StaffRequirementCharge{{Type: models.ChargeTypeBase, Amount: 1}},
+	}
+
+	for name, save := range map[string]func(context.Context, *models.StaffRequirement) error{
+		"create": service.CreateStaffRequirement,
+		"update": service.UpdateStaffRequirement,
+	} {
+		staffRequirement := line
+		if err := save(context.Background(), &staffRequirement); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+
+		// $20 x 10 hours x 2 staff, plus 50% on the 2 hours past 8
+		saved := staff.saved[len(staff.saved)-1]
+		if saved.Rate != 2000 {
+			t.Errorf("%s: rate = %d, want the branch's 2000", name, saved.Rate)
+		}
+		if saved.Amount != 44000 {
+			t.Errorf("%s: amount = %d, want 44000", name, saved.Amount)
+		}
+		if len(saved.Charges) != 2 || saved.Charges[0].Amount != 40000 || saved.Charges[1].Type != models.SurchargeTypeOvertime || saved.Charges[1].Amount != 4000 {
+			t.Errorf("%s: charges = %+v, want a 40000 base and 4000 overtime", name, saved.Charges)
+		}
+	}
+}
+
+func TestStaffRequirementWithoutARate(t *testing.T) {
+	request := models.Request{UUID: uuid.New(), ClosestBranchID: uuid.New()}
+	staff := &memoryStaffRequirements{}
+	service := NewStaffRequirementService(
+		staff,
+		&memoryRequests{requests: map[uuid.UUID]models.Request{request.UUID: request}},
+		NewSurchargeService(overtimeRules{}),
+		NewRateService(branchRates{}),
+	)
+
+	start := time.Now().UTC().AddDate(0, 1, 0)
+	err := service.CreateStaffRequirement(context.Background(), &models.StaffRequirement{
+		RequestID: request.UUID,
+		Position:  "Sommelier",
+		StartTime: start,
+		EndTime:   start.Add(4 * time.Hour),
+		Rate:      5000,
+		Count:     1,
+	})
+	if !errors.Is(err, models.ErrNoApplicableRate) {
+		t.Fatalf("CreateStaffRequirement = %v, want ErrNoApplicableRate", err)
+	}
+	if len(staff.saved) != 0 {
+		t.Fatalf("saved %d lines, want none", len(staff.saved))
+	}
+}
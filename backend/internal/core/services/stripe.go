@@ -4,14 +4,18 @@ import (
 	"backend/internal/core/models"
 	ports "backend/internal/core/ports"
 	"context"
+	"fmt"
+
+	"github.com/google/uuid"
 )
 
 type StripeService struct {
-	repo ports.StripeRepository
+	repo   ports.StripeRepository
+	ledger ports.PaymentLedgerRepository
 }
 
-func NewStripeService(repo ports.StripeRepository) *StripeService {
-	return &StripeService{repo: repo}
+func NewStripeService(repo ports.StripeRepository, ledger ports.PaymentLedgerRepository) *StripeService {
+	return &StripeService{repo: repo, ledger: ledger}
 }
 
 func (s *StripeService) CreatePaymentIntent(ctx context.Context, invoice *models.Invoice) (string, error) {
@@ -22,8 +26,36 @@ func (s *StripeService) CreateCheckoutSession(ctx context.Context, invoice *mode
 	return s.repo.CreateCheckoutSession(ctx, invoice, staffRequirements)
 }
 
-func (s *StripeService) RefundPayment(ctx context.Context, invoice *models.Invoice) (string, error) {
-	return s.repo.RefundPayment(ctx, invoice)
+func (s *StripeService) RefundPayment(ctx context.Context, invoice *models.Invoice) ([]models.PaymentLedgerEntry, error) {
+	return s.RefundAmount(ctx, invoice, invoice.AmountPaid)
+}
+
+func (s *StripeService) RefundAmount(ctx context.Context, invoice *models.Invoice, amount models.Money) ([]models.PaymentLedgerEntry, error) {
+	if amount <= 0 {
+		return nil, nil
+	}
+
+	payments, err := s.ledger.GetRefundablePayments(ctx, invoice.UUID)
+	if err != nil {
+		return nil, err
+	}
+
+	allocations, shortfall := models.SplitRefund(amount, payments)
+	if shortfall > 0 {
+		return nil, fmt.Errorf("only %s of %s can be refunded on invoice %s", amount-shortfall, amount, invoice.UUID)
+	}
+
+	var refunds []models.PaymentLedgerEntry
+	for _, allocation := range allocations {
+		entry, err := s.repo.RefundPaymentIntent(ctx, invoice, allocation)
+		if err != nil {
+			// refunds already issued stand, report them with the error
+			return refunds, err
+		}
+		refunds = append(refunds, *entry)
+	}
+
+	return refunds, nil
 }
 
 func (s *StripeService) Webhook(ctx context.Context, payload []byte, signatureHeader string) error {
@@ -33,3 +65,66 @@ func (s *StripeService) Webhook(ctx context.Context, payload []byte, signatureHe
 func (s *StripeService) SendAdminConfirmationEmail(ctx context.Context, invoice *models.Invoice, staffRequirements []models.StaffRequirement) (string, error) {
 	return s.repo.SendAdminConfirmationEmail(ctx, invoice, staffRequirements)
 }
+
+func (s *StripeService) GetLedgerEntriesByInvoiceID(ctx context.Context, invoiceID uuid.UUID) ([]models.PaymentLedgerEntry, error) {
+	return s.ledger.GetLedgerEntriesByInvoiceID(ctx, invoiceID)
+}
+
+// GetPaymentMethods lists the client's saved cards, none if they have never been through checkout
+func (s *StripeService) GetPaymentMethods(ctx context.Context, client *models.Client) ([]models.SavedPaymentMethod, error) {
+	if client.StripeCustomerID == "" {
+		return []models.SavedPaymentMethod{}, nil
+	}
+
+	methods, err := s.repo.ListPaymentMethods(ctx, client.StripeCustomerID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range methods {
+		methods[i].IsDefault = methods[i].ID == client.DefaultPaymentMethodID
+	}
+	return methods, nil
+}
+
+func (s *StripeService) SetDefaultPaymentMethod(ctx context.Context, client *models.Client, paymentMethodID string) error {
+	methods, err := s.GetPaymentMethods(ctx, client)
+	if err != nil {
+		return err
+	}
+	for _, method := range methods {
+		if method.ID == paymentMethodID {
+			return s.repo.SetDefaultPaymentMethod(ctx, client.StripeCustomerID, paymentMethodID)
+		}
+	}
+	return fmt.Errorf("payment method %s is not saved for client %s", paymentMethodID, client.UUID)
+}
+
+// ChargeOffSession charges a saved card, the client's default unless one is given
+func (s *StripeService) ChargeOffSession(ctx context.Context, invoice *models.Invoice, client *models.Client, paymentMethodID string, amount models.Money) (*models.PaymentLedgerEntry, error) {
+	if !client.OffSessionApproved {
+		return nil, models.ErrOffSessionNotApproved
+	}
+	if amount <= 0 {
+		return nil, fmt.Errorf("nothing to charge on invoice %s", invoice.UUID)
+	}
+	if client.StripeCustomerID == "" {
+		return nil, models.ErrNoSavedPaymentMethod
+	}
+
+	if paymentMethodID == "" {
+		paymentMethodID = client.DefaultPaymentMethodID
+	}
+	if paymentMethodID == "" {
+		// no default picked yet, use the card saved most recently
+		methods, err := s.repo.ListPaymentMethods(ctx, client.StripeCustomerID)
+		if err != nil {
+			return nil, err
+		}
+		if len(methods) == 0 {
+			return nil, models.ErrNoSavedPaymentMethod
+		}
+		paymentMethodID = methods[0].ID
+	}
+
+	return s.repo.ChargeOffSession(ctx, invoice, client.StripeCustomerID, paymentMethodID, amount)
+}
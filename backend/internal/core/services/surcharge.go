@@ -0,0 +1,240 @@
+package services
+
+import (
+	"backend/internal/core/models"
+	"backend/internal/core/ports"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type SurchargeService struct {
+	repo ports.SurchargeRepository
+}
+
+func NewSurchargeService(repo ports.SurchargeRepository) *SurchargeService {
+	return &SurchargeService{repo: repo}
+}
+
+func (s *SurchargeService) CreateSurchargeRule(ctx context.Context, rule *models.SurchargeRule) error {
+	if err := validateSurchargeRule(rule); err != nil {
+		return err
+	}
+	return s.repo.CreateSurchargeRule(ctx, rule)
+}
+
+func (s *SurchargeService) GetSurchargeRuleByID(ctx context.Context, id uuid.UUID) (*models.SurchargeRule, error) {
+	return s.repo.GetSurchargeRuleByID(ctx, id)
+}
+
+func (s *SurchargeService) GetSurchargeRules(ctx context.Context, branchID *uuid.UUID) ([]models.SurchargeRule, error) {
+	return s.repo.GetSurchargeRules(ctx, branchID)
+}
+
+func (s *SurchargeService) UpdateSurchargeRule(ctx context.Context, rule *models.SurchargeRule) error {
+	if err := validateSurchargeRule(rule); err != nil {
+		return err
+	}
+	return s.repo.UpdateSurchargeRule(ctx, rule)
+}
+
+func (s *SurchargeService) DeleteSurchargeRule(ctx context.Context, id uuid.UUID) error {
+	return s.repo.DeleteSurchargeRule(ctx, id)
+}
+
+func (s *SurchargeService) CreateHoliday(ctx context.Context, holiday *models.Holiday) error {
+	if holiday.Name == "" {
+		return errors.New("holiday name is required")
+	}
+	if holiday.Date.IsZero() {
+		return errors.New("holiday date is required")
+	}
+	return s.repo.CreateHoliday(ctx, holiday)
+}
+
+func (s *SurchargeService) GetHolidays(ctx context.Context, branchID *uuid.UUID) ([]models.Holiday, error) {
+	return s.repo.GetHolidays(ctx, branchID)
+}
+
+func (s *SurchargeService) DeleteHoliday(ctx context.Context, id uuid.UUID) error {
+	return s.repo.DeleteHoliday(ctx, id)
+}
+
+// PriceStaffRequirement evaluates the shift against the branch's surcharge rules. The base
+// charge is rate * hours * count, overtime and night surcharges apply to the hours they cover,
+// holiday and lead time surcharges apply to the whole base charge.
+func (s *SurchargeService) PriceStaffRequirement(ctx context.Context, request *models.Request, staff *models.StaffRequirement) error {
+	hours := staff.EndTime.Sub(staff.StartTime).Hours()
+	count := float64(staff.Count)
+	base := staff.Rate.MulFloat(hours * count)
+
+	charges := []models.StaffRequirementCharge{{
+		UUID:        uuid.New(),
+		Type:        models.ChargeTypeBase,
+		Description: "Base rate",
+		Hours:       hours,
+		Amount:      base,
+	}}
+
+	if hours > 0 {
+		rules, err := s.repo.GetApplicableSurchargeRules(ctx, request.ClosestBranchID)
+		if err != nil {
+			return fmt.Errorf("failed to get surcharge rules: %w", err)
+		}
+
+		for _, rule := range effectiveSurchargeRules(rules) {
+			charge, err := s.evaluateSurchargeRule(ctx, rule, request, staff, hours, base)
+			if err != nil {
+				return err
+			}
+			if charge != nil && charge.Amount > 0 {
+				charges = append(charges, *charge)
+			}
+		}
+	}
+
+	var amount models.Money
+	for _, charge := range charges {
+		amount += charge.Amount
+	}
+
+	staff.Charges = charges
+	staff.Amount = amount
+	return nil
+}
+
+// evaluateSurchargeRule returns the surcharge a rule adds to the shift, or nil if it doesn't apply
+func (s *SurchargeService) evaluateSurchargeRule(ctx context.Context, rule models.SurchargeRule, request *models.Request, staff *models.StaffRequirement, hours float64, base models.Money) (*models.StaffRequirementCharge, error) {
+	charge := &models.StaffRequirementCharge{
+		UUID:        uuid.New(),
+		Type:        rule.Type,
+		Description: rule.Name,
+		Percent:     rule.Percent,
+	}
+
+	switch rule.Type {
+	case models.SurchargeTypeOvertime:
+		if hours <= rule.ThresholdHours {
+			return nil, nil
+		}
+		charge.Hours = hours - rule.ThresholdHours
+
+	case models.SurchargeTypeNight:
+		charge.Hours = overlapHours(staff.StartTime, staff.EndTime, rule.StartHour, rule.EndHour)
+		if charge.Hours <= 0 {
+			return nil, nil
+		}
+
+	case models.SurchargeTypeHoliday:
+		holidays, err := s.repo.GetHolidaysBetween(ctx, request.ClosestBranchID, staff.StartTime, staff.StartTime)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get holidays: %w", err)
+		}
+		if len(holidays) == 0 {
+			return nil, nil
+		}
+		charge.Description = fmt.Sprintf("%s (%s)", rule.Name, holidays[0].Name)
+		charge.Hours = hours
+		charge.Amount = base.Percent(rule.Percent, models.RoundingNearestCent)
+		return charge, nil
+
+	case models.SurchargeTypeLeadTime:
+		requested := request.DateRequested
+		if requested.IsZero() {
+			requested = time.Now().UTC()
+		}
+		if staff.StartTime.Sub(requested).Hours() >= rule.ThresholdHours {
+			return nil, nil
+		}
+		charge.Hours = hours
+		charge.Amount = base.Percent(rule.Percent, models.RoundingNearestCent)
+		return charge, nil
+
+	default:
+		return nil, nil
+	}
+
+	charge.Amount = staff.Rate.MulFloat(charge.Hours*float64(staff.Count)).Percent(rule.Percent, models.RoundingNearestCent)
+	return charge, nil
+}
+
+// effectiveSurchargeRules keeps one rule per type, a branch rule wins over the global rule
+func effectiveSurchargeRules(rules []models.SurchargeRule) []models.SurchargeRule {
+	byType := map[string]models.SurchargeRule{}
+	for _, rule := range rules {
+		existing, ok := byType[rule.Type]
+		if !ok || (existing.BranchID == nil && rule.BranchID != nil) {
+			byType[rule.Type] = rule
+		}
+	}
+
+	// evaluate in a fixed order so the itemized charges always read the same way
+	var effective []models.SurchargeRule
+	for _, ruleType := range []string{models.SurchargeTypeOvertime, models.SurchargeTypeNight, models.SurchargeTypeHoliday, models.SurchargeTypeLeadTime} {
+		if rule, ok := byType[ruleType]; ok {
+			effective = append(effective, rule)
+		}
+	}
+	return effective
+}
+
+// overlapHours returns how many hours of start-end fall within the daily startHour-endHour
+// window, in the shift's own timezone. Windows may wrap midnight, e.g. 22 to 6.
+func overlapHours(start, end time.Time, startHour, endHour int) float64 {
+	if startHour == endHour || !end.After(start) {
+		return 0
+	}
+
+	loc := start.Location()
+	total := 0.0
+	for day := time.Date(start.Year(), start.Month(), start.Day()-1, 0, 0, 0, 0, loc); day.Before(end); day = day.AddDate(0, 0, 1) {
+		windowStart := time.Date(day.Year(), day.Month(), day.Day(), startHour, 0, 0, 0, loc)
+		windowEnd := time.Date(day.Year(), day.Month(), day.Day(), endHour, 0, 0, 0, loc)
+		if endHour < startHour {
+			windowEnd = windowEnd.AddDate(0, 0, 1)
+		}
+
+		from, to := windowStart, windowEnd
+		if start.After(from) {
+			from = start
+		}
+		if end.Before(to) {
+			to = end
+		}
+		if to.After(from) {
+			total += to.Sub(from).Hours()
+		}
+	}
+	return total
+}
+
+func validateSurchargeRule(rule *models.SurchargeRule) error {
+	if rule.Name == "" {
+		return errors.New("surcharge rule name is required")
+	}
+	if rule.Percent < 0 {
+		return errors.New("surcharge percent cannot be negative")
+	}
+
+	switch rule.Type {
+	case models.SurchargeTypeOvertime, models.SurchargeTypeLeadTime:
+		if rule.ThresholdHours <= 0 {
+			return errors.New("threshold_hours must be greater than 0 for " + rule.Type + " rules")
+		}
+	case models.SurchargeTypeNight:
+		if rule.StartHour < 0 || rule.StartHour > 23 || rule.EndHour < 0 || rule.EndHour > 23 {
+			return errors.New("start_hour and end_hour must be between 0 and 23")
+		}
+		if rule.StartHour == rule.EndHour {
+			return errors.New("start_hour and end_hour cannot be the same")
+		}
+	case models.SurchargeTypeHoliday:
+	default:
+		return errors.New("invalid surcharge rule type: " + rule.Type)
+	}
+
+	return nil
+}
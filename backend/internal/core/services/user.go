@@ -0,0 +1,266 @@
+package services
+
+import (
+	"backend/internal/core/models"
+	ports "backend/internal/core/ports"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type UserService struct {
+	userRepo ports.UserRepository
+}
+
+func NewUserService(userRepo ports.UserRepository) *UserService {
+	return &UserService{userRepo: userRepo}
+}
+
+func (s *UserService) GetUserByID(ctx context.Context, id uuid.UUID) (*models.User, error) {
+	user, err := s.userRepo.GetUserByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, models.ErrUserNotFound
+	}
+	return user, nil
+}
+
+func (s *UserService) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	user, err := s.userRepo.GetUserByEmail(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, models.ErrUserNotFound
+	}
+	return user, nil
+}
+
+func (s *UserService) ListUsers(ctx context.Context, filter models.UserFilter) ([]models.User, error) {
+	return s.userRepo.ListUsers(ctx, filter)
+}
+
+func (s *UserService) InviteUser(ctx context.Context, actor *models.User, invite models.UserInvite) (*models.User, error) {
+	email := strings.ToLower(strings.TrimSpace(invite.Email))
+	if err := checkAssignableRole(actor, invite.Role); err != nil {
+		return nil, err
+	}
+
+	existing, err := s.userRepo.GetUserByEmail(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, models.ErrUserExists
+	}
+
+	now := time.Now().UTC()
+	user := &models.User{
+		Email:       email,
+		FirstName:   strings.TrimSpace(invite.FirstName),
+		LastName:    strings.TrimSpace(invite.LastName),
+		PhoneNumber: strings.TrimSpace(invite.PhoneNumber),
+		BranchID:    invite.BranchID,
+		Role:        invite.Role,
+		InvitedAt:   &now,
+		CreatedAt:   now,
+	}
+	if err := s.userRepo.CreateUser(ctx, user); err != nil {
+		return nil, fmt.Errorf("failed to invite user: %w", err)
+	}
+	log.Printf("%s invited %s as %s", actor.Email, user.Email, user.Role)
+	return user, nil
+}
+
+func (s *UserService) UpdateAccess(ctx context.Context, actor *models.User, id uuid.UUID, update models.UserAccessUpdate) (*models.User, error) {
+	user, err := s.managedUser(ctx, actor, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if update.Role != nil {
+		if err := checkAssignableRole(actor, *update.Role); err != nil {
+			return nil, err
+		}
+		user.Role = *update.Role
+	}
+	if update.BranchID != nil {
+		user.BranchID = *update.BranchID
+	}
+
+	if err := s.userRepo.UpdateUser(ctx, user); err != nil {
+		return nil, fmt.Errorf("failed to update user: %w", err)
+	}
+	log.Printf("%s set %s to %s in branch %s", actor.Email, user.Email, user.Role, user.BranchID)
+	return user, nil
+}
+
+// DeactivateUser stops the user signing in, the next request they make is refused
+func (s *UserService) DeactivateUser(ctx context.Context, actor *models.User, id uuid.UUID) (*models.User, error) {
+	user, err := s.managedUser(ctx, actor, id)
+	if err != nil {
+		return nil, err
+	}
+	if !user.Active() {
+		return user, nil
+	}
+
+	now := time.Now().UTC()
+	user.DeactivatedAt = &now
+	if err := s.userRepo.UpdateUser(ctx, user); err != nil {
+		return nil, fmt.Errorf("failed to deactivate user: %w", err)
+	}
+	log.Printf("%s deactivated %s", actor.Email, user.Email)
+	return user, nil
+}
+
+func (s *UserService) ReactivateUser(ctx context.Context, actor *models.User, id uuid.UUID) (*models.User, error) {
+	user, err := s.managedUser(ctx, actor, id)
+	if err != nil {
+		return nil, err
+	}
+	if user.Active() {
+		return user, nil
+	}
+
+	user.DeactivatedAt = nil
+	if err := s.userRepo.UpdateUser(ctx, user); err != nil {
+		return nil, fmt.Errorf("failed to reactivate user: %w", err)
+	}
+	log.Printf("%s reactivated %s", actor.Email, user.Email)
+	return user, nil
+}
+
+func (s *UserService) GetProfile(ctx context.Context, user *models.User) *models.Profile {
+	return &models.Profile{User: user, Permissions: models.RolePermissions(user.Role)}
+}
+
+func (s *UserService) UpdateProfile(ctx context.Context, user *models.User, update models.ProfileUpdate) (*models.Profile, error) {
+	update.Apply(user)
+	if err := s.userRepo.UpdateUser(ctx, user); err != nil {
+		return nil, fmt.Errorf("failed to update profile: %w", err)
+	}
+	return s.GetProfile(ctx, user), nil
+}
+
+// RegisterSignUp is called by the Cognito post confirmation trigger, whose attributes Cognito
+// has verified. Someone who was invited keeps the role and branch they were invited with,
+// everyone else starts as staff in the branch they signed up for.
+func (s *UserService) RegisterSignUp(ctx context.Context, signUp models.UserSignUp) (*models.User, error) {
+	return s.registerSignUp(ctx, signUp, true)
+}
+
+// RegisterStaffSignUp records someone who signed up without an invite. It never claims an
+// invited row, so it is safe to call with a sub taken from a verified token.
+func (s *UserService) RegisterStaffSignUp(ctx context.Context, signUp models.UserSignUp) (*models.User, error) {
+	return s.registerSignUp(ctx, signUp, false)
+}
+
+func (s *UserService) registerSignUp(ctx context.Context, signUp models.UserSignUp, claimInvite bool) (*models.User, error) {
+	if signUp.CognitoSub == "" {
+		return nil, errors.New("cognito sub is required")
+	}
+	user, err := s.userRepo.GetUserByCognitoSub(ctx, signUp.CognitoSub)
+	if err != nil {
+		return nil, err
+	}
+	if user != nil {
+		// Cognito retries the trigger, the first call already did the work
+		return user, nil
+	}
+
+	existing, err := s.userRepo.GetUserByEmail(ctx, signUp.Email)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		if !claimInvite {
+			return nil, models.ErrUserExists
+		}
+		return s.claimUser(ctx, existing, signUp)
+	}
+
+	if signUp.BranchID == uuid.Nil {
+		return nil, errors.New("branch ID is required")
+	}
+	user = &models.User{
+		Email:       strings.ToLower(strings.TrimSpace(signUp.Email)),
+		FirstName:   signUp.FirstName,
+		LastName:    signUp.LastName,
+		PhoneNumber: signUp.PhoneNumber,
+		BranchID:    signUp.BranchID,
+		Role:        models.RoleStaff,
+		CognitoSub:  &signUp.CognitoSub,
+		CreatedAt:   time.Now().UTC(),
+	}
+	if err := s.userRepo.CreateUser(ctx, user); err != nil {
+		return nil, fmt.Errorf("failed to create user: %w", err)
+	}
+	return user, nil
+}
+
+// claimUser links the Cognito user to the row already there for their email, filling in what
+// the invite left blank
+func (s *UserService) claimUser(ctx context.Context, user *models.User, signUp models.UserSignUp) (*models.User, error) {
+	if user.CognitoSub != nil {
+		return nil, models.ErrUserExists
+	}
+
+	linked, err := s.userRepo.LinkCognitoSub(ctx, user.UUID, signUp.CognitoSub)
+	if err != nil {
+		return nil, err
+	}
+	if !linked {
+		return nil, models.ErrUserExists
+	}
+	user.CognitoSub = &signUp.CognitoSub
+
+	profile := models.ProfileUpdate{}
+	if user.FirstName == "" && signUp.FirstName != "" {
+		profile.FirstName = &signUp.FirstName
+	}
+	if user.LastName == "" && signUp.LastName != "" {
+		profile.LastName = &signUp.LastName
+	}
+	if user.PhoneNumber == "" && signUp.PhoneNumber != "" {
+		profile.PhoneNumber = &signUp.PhoneNumber
+	}
+	profile.Apply(user)
+	if err := s.userRepo.UpdateUser(ctx, user); err != nil {
+		return nil, fmt.Errorf("failed to update user: %w", err)
+	}
+	return user, nil
+}
+
+// managedUser gets a user the actor may change, never the actor themselves and never someone
+// of their own rank or above
+func (s *UserService) managedUser(ctx context.Context, actor *models.User, id uuid.UUID) (*models.User, error) {
+	if actor.UUID == id {
+		return nil, models.ErrCannotChangeSelf
+	}
+	user, err := s.GetUserByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !actor.Outranks(user.Role) {
+		return nil, models.ErrRoleNotAllowed
+	}
+	return user, nil
+}
+
+func checkAssignableRole(actor *models.User, role string) error {
+	if !models.ValidRole(role) {
+		return fmt.Errorf("%w %q", models.ErrInvalidRole, role)
+	}
+	if !actor.Outranks(role) {
+		return models.ErrRoleNotAllowed
+	}
+	return nil
+}
@@ -1,4 +1,4 @@
-module get-user-data
+module backend/lambda/get-user-data
 
 go 1.23.2
 
@@ -7,4 +7,27 @@ replace backend => ../../
 require (
 	backend v0.0.0-00010101000000-000000000000
 	github.com/aws/aws-lambda-go v1.48.0
+	github.com/google/uuid v1.6.0
+	gorm.io/driver/postgres v1.5.11
+	gorm.io/gorm v1.26.1
+)
+
+require (
+	github.com/cespare/xxhash/v2 v2.3.0 // indirect
+	github.com/dgryski/go-rendezvous v0.0.0-20200823014737-9f7001d12a5f // indirect
+	github.com/jackc/pgpassfile v1.0.0 // indirect
+	github.com/jackc/pgservicefile v0.0.0-20240606120523-5a60cdf6a761 // indirect
+	github.com/jackc/pgx/v5 v5.7.4 // indirect
+	github.com/jackc/puddle/v2 v2.2.2 // indirect
+	github.com/jinzhu/inflection v1.0.0 // indirect
+	github.com/jinzhu/now v1.1.5 // indirect
+	github.com/joho/godotenv v1.5.1 // indirect
+	github.com/lib/pq v1.10.9 // indirect
+	github.com/redis/go-redis/v9 v9.11.0 // indirect
+	github.com/robfig/cron v1.2.0 // indirect
+	github.com/stripe/stripe-go/v82 v82.2.1 // indirect
+	golang.org/x/crypto v0.38.0 // indirect
+	golang.org/x/sync v0.14.0 // indirect
+	golang.org/x/text v0.25.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
@@ -2,20 +2,83 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+
+	"backend/internal/adapter/store/postgres/repository"
+	"backend/internal/core/models"
+	ports "backend/internal/core/ports"
+	"backend/internal/core/services"
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
-	// "backend/internal/services"
+	"github.com/google/uuid"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
 )
 
+// users are read through the API's own service so both return the same user
+var users ports.UserService
+
+func init() {
+	dsn := os.Getenv("DB_URL")
+	if dsn == "" {
+		dsn = "host=" + os.Getenv("DB_HOST") +
+			" user=" + os.Getenv("DB_USER") +
+			" password=" + os.Getenv("DB_PASSWORD") +
+			" dbname=" + os.Getenv("DB_NAME") +
+			" port=" + os.Getenv("DB_PORT") +
+			" sslmode=require"
+	}
+
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	users = services.NewUserService(repository.NewUserRepository(db))
+}
+
 func handler(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	var (
+		user *models.User
+		err  error
+	)
+	if id := request.QueryStringParameters["uuid"]; id != "" {
+		userID, parseErr := uuid.Parse(id)
+		if parseErr != nil {
+			return respond(400, map[string]string{"error": "Invalid UUID format"}), nil
+		}
+		user, err = users.GetUserByID(ctx, userID)
+	} else if email := request.QueryStringParameters["email"]; email != "" {
+		user, err = users.GetUserByEmail(ctx, email)
+	} else {
+		return respond(400, map[string]string{"error": "Missing user ID or email"}), nil
+	}
+
+	if errors.Is(err, models.ErrUserNotFound) {
+		return respond(404, map[string]string{"error": err.Error()}), nil
+	}
+	if err != nil {
+		log.Printf("Failed to get user: %v", err)
+		return respond(500, map[string]string{"error": "Failed to get user"}), nil
+	}
+	return respond(200, user), nil
+}
+
+func respond(status int, body any) events.APIGatewayProxyResponse {
+	data, err := json.Marshal(body)
+	if err != nil {
+		status, data = 500, []byte(`{"error": "Failed to encode response"}`)
+	}
 	return events.APIGatewayProxyResponse{
-		StatusCode: 200,
+		StatusCode: status,
 		Headers: map[string]string{
 			"Content-Type": "application/json",
 		},
-		Body: `{"message": "User data retrieved successfully"}`,
-	}, nil
+		Body: string(data),
+	}
 }
 
 func main() {
@@ -5,7 +5,11 @@ import (
 	"fmt"
 	"log"
 	"os"
-	"time"
+
+	"backend/internal/adapter/store/postgres/repository"
+	"backend/internal/core/models"
+	ports "backend/internal/core/ports"
+	"backend/internal/core/services"
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
@@ -14,25 +18,11 @@ import (
 	"gorm.io/gorm"
 )
 
-type User struct {
-	UUID        uuid.UUID `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
-	Email       string    `gorm:"type:varchar"`
-	FirstName   string    `gorm:"column:first_name"`
-	LastName    string    `gorm:"column:last_name"`
-	PhoneNumber string    `gorm:"column:phone_number"`
-	BranchID    uuid.UUID `gorm:"column:branch_id;type:uuid"`
-	Role        string    `gorm:"type:text"`
-	CreatedAt   time.Time `gorm:"column:created_at"`
-}
-
-func (User) TableName() string {
-	return "users"
-}
-
-var db *gorm.DB
+// users are created through the API's own service, so invites and roles work the same as in
+// the dashboard
+var users ports.UserService
 
 func init() {
-	var err error
 	dsn := os.Getenv("DB_URL")
 	if dsn == "" {
 		dsn = "host=" + os.Getenv("DB_HOST") +
@@ -43,10 +33,11 @@ func init() {
 			" sslmode=require"
 	}
 
-	db, err = gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
 	if err != nil {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
+	users = services.NewUserService(repository.NewUserRepository(db))
 
 	log.Println("Database connection established")
 }
@@ -63,38 +54,31 @@ func handler(ctx context.Context, event events.CognitoEventUserPoolsPostConfirma
 	branchIDStr, exists := userAttributes["custom:branch_id"]
 	log.Printf("Branch ID exists: %t, value: %s", exists, branchIDStr)
 
+	// invited users already have a branch, everyone else must pick one when signing up
 	var branchID uuid.UUID
 	if branchIDStr != "" {
-		if parsedBranchID, err := uuid.Parse(branchIDStr); err == nil {
-			branchID = parsedBranchID
-		} else {
+		parsedBranchID, err := uuid.Parse(branchIDStr)
+		if err != nil {
 			log.Printf("Invalid branch ID format: %s", branchIDStr)
 			return event, fmt.Errorf("invalid branch ID format")
 		}
-	} else {
-		log.Printf("No branch ID provided for user: %s", event.UserName)
-		return event, fmt.Errorf("branch ID is required")
+		branchID = parsedBranchID
 	}
 
-	user := User{
-		UUID:        uuid.New(),
+	user, err := users.RegisterSignUp(ctx, models.UserSignUp{
+		CognitoSub:  userAttributes["sub"],
 		Email:       userAttributes["email"],
 		FirstName:   userAttributes["given_name"],
 		LastName:    userAttributes["family_name"],
 		PhoneNumber: userAttributes["phone_number"],
 		BranchID:    branchID,
-		Role:        "user",
-		CreatedAt:   time.Now(),
-	}
-
-	log.Printf("About to insert user: %+v", user)
-
-	if err := db.Create(&user).Error; err != nil {
+	})
+	if err != nil {
 		log.Printf("DATABASE ERROR: %v", err)
 		return event, err
 	}
 
-	log.Printf("=== USER CREATED SUCCESSFULLY ===")
+	log.Printf("=== USER CREATED SUCCESSFULLY === %s as %s", user.UUID, user.Role)
 	return event, nil
 }
 
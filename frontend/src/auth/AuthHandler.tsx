@@ -1,9 +1,8 @@
 import { Amplify } from 'aws-amplify';
-import { signUp as amplifySignUp, signIn as amplifySignIn, signOut as amplifySignOut, getCurrentUser } from 'aws-amplify/auth';
+import { signUp as amplifySignUp, signIn as amplifySignIn, signOut as amplifySignOut, getCurrentUser, fetchAuthSession } from 'aws-amplify/auth';
 import { error } from 'console';
 // import { Auth } from 'node_modules/@supabase/auth-ui-react/dist/components/Auth';
 import Cookies from 'js-cookie';
-import { v4 as uuidv4 } from 'uuid'; 
 
 
 Amplify.configure({
@@ -75,29 +74,30 @@ export const signUp = async ({ email, password, username, branch, firstName, las
 
         console.log('Cognito signup result:', { isSignUpComplete, userId, nextStep });
 
-        const dbUuid = uuidv4();
-        
+        // /post-user only trusts the ID token, accounts still waiting on confirmation are added
+        // by the Cognito post confirmation trigger instead
         try {
-            const res = await fetch(`${import.meta.env.VITE_API_URL}/post-user`, {
-                method: 'POST',
-                headers: {
-                    'Content-Type': 'application/json',
-                },
-                body: JSON.stringify({ 
-                    uuid: dbUuid, 
-                    name: `${firstName} ${lastName}`,
-                    email: email,
-                    role: 'employee',
-                    branch_id: branch,
-                    cognito_user_id: userId 
-                })
-            });
+            const { tokens } = await fetchAuthSession();
+            const idToken = tokens?.idToken?.toString();
+            if (idToken) {
+                const res = await fetch(`${import.meta.env.VITE_API_URL}/post-user`, {
+                    method: 'POST',
+                    headers: {
+                        'Content-Type': 'application/json',
+                        'Authorization': `Bearer ${idToken}`,
+                    },
+                    body: JSON.stringify({ 
+                        name: `${firstName} ${lastName}`,
+                        branch_id: branch,
+                    })
+                });
 
-            if (!res.ok) {
-                const errorText = await res.text();
-                console.error('Failed to save user to database:', errorText);
-            } else {
-                console.log('User successfully saved to database');
+                if (!res.ok) {
+                    const errorText = await res.text();
+                    console.error('Failed to save user to database:', errorText);
+                } else {
+                    console.log('User successfully saved to database');
+                }
             }
         } catch (dbError) {
             console.error('Database save error:', dbError);
@@ -183,7 +183,7 @@ export function InvoiceCard() {
         payment_intent_id: invoiceRes.PaymentIntent,
         service_fee: invoiceRes.ServiceFee || 0,
         discount_type: invoiceRes.DiscountType,
-        discount_value: invoiceRes.DiscountValue || 0,
+        discount_value: (invoiceRes.DiscountType === 'fixed' ? invoiceRes.DiscountFixed : invoiceRes.DiscountPercent) || 0,
         type_of_event: requestRes.TypeOfEvent || "N/A",
         terms_and_conditions: invoiceRes.terms_and_conditions || "",
       };
@@ -468,10 +468,12 @@ export function InvoiceCard() {
                 <SelectValue placeholder="Select status" />
               </SelectTrigger>
               <SelectContent>
-                <SelectItem value="pending">Pending</SelectItem>
-                <SelectItem value="unpaid">Unpaid</SelectItem>
+                <SelectItem value="draft">Draft</SelectItem>
+                <SelectItem value="sent">Sent</SelectItem>
                 <SelectItem value="partially_paid">Partially Paid</SelectItem>
                 <SelectItem value="paid">Paid</SelectItem>
+                <SelectItem value="overdue">Overdue</SelectItem>
+                <SelectItem value="void">Void</SelectItem>
                 <SelectItem value="refunded">Refunded</SelectItem>
               </SelectContent>
             </Select>
@@ -479,7 +481,7 @@ export function InvoiceCard() {
             <div className="flex items-center gap-2">
               <Badge className={`${
                 invoice?.status === 'paid' ? 'bg-green-500' : 
-                invoice?.status === 'unpaid' ? 'bg-red-500' : 
+                invoice?.status === 'overdue' ? 'bg-red-500' : 
                 invoice?.status === 'partially_paid' ? 'bg-yellow-500' : 
                 'bg-gray-500'
               }`}>
@@ -47,6 +47,7 @@ export interface Invoice {
   ship_to: string | null;
   po_edit_counter: number | null;
   po_number: string | null;
+  invoice_number: string | null;
   terms_and_conditions: string;
 }
 
@@ -3,10 +3,16 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"os"
 
+	"backend/internal/adapter/store/postgres/repository"
+	"backend/internal/core/models"
+	"backend/internal/core/services"
+
+	"github.com/google/uuid"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 
@@ -14,19 +20,6 @@ import (
 	"github.com/aws/aws-lambda-go/lambda"
 )
 
-type User struct {
-	ID        string `json:"id" gorm:"column:uuid;primaryKey"`
-	FirstName string `json:"first_name" gorm:"column:first_name"`
-	LastName  string `json:"last_name" gorm:"column:last_name"`
-	Email     string `json:"email" gorm:"column:email"`
-	Role      string `json:"role" gorm:"column:role"`
-	BranchID  string `json:"branch_id" gorm:"column:branch_id"`
-}
-
-func (User) TableName() string {
-	return "users"
-}
-
 func handler(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
 	corsHeaders := map[string]string{
 		"Access-Control-Allow-Origin":  "*",
@@ -83,17 +76,25 @@ func handler(ctx context.Context, request events.APIGatewayProxyRequest) (events
 		}, nil
 	}
 	defer sqlDB.Close()
-	var user User
-	var result *gorm.DB
-
+	// read through the API's user service so both return the same user
+	users := services.NewUserService(repository.NewUserRepository(db))
+	var user *models.User
 	if userID != "" {
-		result = db.Where("uuid = ?", userID).First(&user)
+		id, parseErr := uuid.Parse(userID)
+		if parseErr != nil {
+			return events.APIGatewayProxyResponse{
+				StatusCode: 400,
+				Headers:    corsHeaders,
+				Body:       "Invalid UUID format",
+			}, nil
+		}
+		user, err = users.GetUserByID(ctx, id)
 	} else {
-		result = db.Where("email = ?", userEmail).First(&user)
+		user, err = users.GetUserByEmail(ctx, userEmail)
 	}
 
-	if result.Error != nil {
-		if result.Error == gorm.ErrRecordNotFound {
+	if err != nil {
+		if errors.Is(err, models.ErrUserNotFound) {
 			identifier := userID
 			if identifier == "" {
 				identifier = userEmail
@@ -104,11 +105,11 @@ func handler(ctx context.Context, request events.APIGatewayProxyRequest) (events
 				Body:       fmt.Sprintf("User with identifier %s not found", identifier),
 			}, nil
 		}
-		log.Printf("Failed to query user: %v", result.Error)
+		log.Printf("Failed to query user: %v", err)
 		return events.APIGatewayProxyResponse{
 			StatusCode: 500,
 			Headers:    corsHeaders,
-			Body:       fmt.Sprintf("Failed to query user: %v", result.Error),
+			Body:       fmt.Sprintf("Failed to query user: %v", err),
 		}, nil
 	}
 
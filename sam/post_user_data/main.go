@@ -3,12 +3,21 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"os"
 	"strings"
 	"time"
 
+	"backend/internal/adapter/auth"
+	"backend/internal/adapter/store/postgres/repository"
+	"backend/internal/config"
+	"backend/internal/core/models"
+	ports "backend/internal/core/ports"
+	"backend/internal/core/services"
+
+	"github.com/google/uuid"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
@@ -17,28 +26,25 @@ import (
 	"github.com/aws/aws-lambda-go/lambda"
 )
 
-type User struct {
-	UUID      string    `json:"uuid" gorm:"column:uuid;primaryKey"`
-	Email     string    `json:"email" gorm:"column:email"`
-	FirstName string    `json:"first_name" gorm:"column:first_name"`
-	LastName  string    `json:"last_name" gorm:"column:last_name"`
-	Role      string    `json:"role" gorm:"column:role"`
-	BranchID  string    `json:"branch_id" gorm:"column:branch_id"`
-	CreatedAt time.Time `json:"created_at" gorm:"column:created_at"`
-}
-
-func (User) TableName() string {
-	return "users"
-}
-
+// UserRequest is what the dashboard posts after a Cognito sign up. Who the user is comes from
+// their ID token, never the body, and they are always added as staff. Invites are only claimed
+// by the post confirmation trigger.
 type UserRequest struct {
-	UUID     string `json:"uuid"`
 	Name     string `json:"name"`
-	Email    string `json:"email"`
-	Role     string `json:"role,omitempty"`
 	BranchID string `json:"branch_id,omitempty"`
 }
 
+// tokens are checked against the user pool the same way the API checks them
+var tokens ports.TokenService
+
+func init() {
+	var err error
+	tokens, err = auth.NewCognitoTokenService(config.CognitoFromEnv())
+	if err != nil {
+		log.Fatalf("Failed to set up Cognito token verification: %v", err)
+	}
+}
+
 func handler(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
 	corsHeaders := map[string]string{
 		"Access-Control-Allow-Origin":  "*",
@@ -66,6 +72,27 @@ func handler(ctx context.Context, request events.APIGatewayProxyRequest) (events
 		log.Printf("Request query parameters: %v", request.QueryStringParameters)
 	}
 
+	token, ok := strings.CutPrefix(bearer(request.Headers), "Bearer ")
+	if !ok || strings.TrimSpace(token) == "" {
+		return events.APIGatewayProxyResponse{
+			StatusCode: 401,
+			Headers:    corsHeaders,
+			Body:       `{"error": "Unauthorized"}`,
+		}, nil
+	}
+	payload, err := tokens.VerifyToken(ctx, strings.TrimSpace(token))
+	if err == nil && (payload.TokenUse != "id" || !payload.EmailVerified || payload.Email == "") {
+		err = fmt.Errorf("%w: need an ID token with a verified email", models.ErrInvalidToken)
+	}
+	if err != nil {
+		log.Printf("Rejected token: %v", err)
+		return events.APIGatewayProxyResponse{
+			StatusCode: 401,
+			Headers:    corsHeaders,
+			Body:       `{"error": "Unauthorized"}`,
+		}, nil
+	}
+
 	var userReq UserRequest
 	log.Printf("Raw request body: %s", request.Body)
 
@@ -78,7 +105,7 @@ func handler(ctx context.Context, request events.APIGatewayProxyRequest) (events
 		}, nil
 	}
 
-	err := json.Unmarshal([]byte(request.Body), &userReq)
+	err = json.Unmarshal([]byte(request.Body), &userReq)
 	if err != nil {
 		log.Printf("ERROR: Failed to parse JSON request body: %v", err)
 		return events.APIGatewayProxyResponse{
@@ -91,24 +118,6 @@ func handler(ctx context.Context, request events.APIGatewayProxyRequest) (events
 	log.Printf("Parsed user request: %+v", userReq)
 
 	// Validate required fields
-	if userReq.UUID == "" {
-		log.Printf("ERROR: UUID is required but missing")
-		return events.APIGatewayProxyResponse{
-			StatusCode: 400,
-			Headers:    corsHeaders,
-			Body:       `{"error": "UUID is required"}`,
-		}, nil
-	}
-
-	if userReq.Email == "" {
-		log.Printf("ERROR: Email is required but missing")
-		return events.APIGatewayProxyResponse{
-			StatusCode: 400,
-			Headers:    corsHeaders,
-			Body:       `{"error": "Email is required"}`,
-		}, nil
-	}
-
 	if userReq.Name == "" {
 		log.Printf("ERROR: Name is required but missing")
 		return events.APIGatewayProxyResponse{
@@ -118,6 +127,18 @@ func handler(ctx context.Context, request events.APIGatewayProxyRequest) (events
 		}, nil
 	}
 
+	var branchID uuid.UUID
+	if userReq.BranchID != "" {
+		branchID, err = uuid.Parse(userReq.BranchID)
+		if err != nil {
+			return events.APIGatewayProxyResponse{
+				StatusCode: 400,
+				Headers:    corsHeaders,
+				Body:       `{"error": "Invalid branch ID format"}`,
+			}, nil
+		}
+	}
+
 	// Get environment variables
 	dbHost := os.Getenv("DB_HOST")
 	dbPort := os.Getenv("DB_PORT")
@@ -205,17 +226,6 @@ func handler(ctx context.Context, request events.APIGatewayProxyRequest) (events
 	}
 	log.Printf("Database ping successful!")
 
-	log.Printf("Checking if users table exists...")
-	if !db.Migrator().HasTable(&User{}) {
-		log.Printf("ERROR: Table 'users' does not exist")
-		return events.APIGatewayProxyResponse{
-			StatusCode: 500,
-			Headers:    corsHeaders,
-			Body:       `{"error": "Table 'users' does not exist. Please run migrations first."}`,
-		}, nil
-	}
-	log.Printf("Users table exists!")
-
 	// Parse name into first and last name
 	nameParts := strings.Split(strings.TrimSpace(userReq.Name), " ")
 	firstName := nameParts[0]
@@ -224,49 +234,40 @@ func handler(ctx context.Context, request events.APIGatewayProxyRequest) (events
 		lastName = strings.Join(nameParts[1:], " ")
 	}
 
-	user := User{
-		UUID:      userReq.UUID,
-		Email:     userReq.Email,
-		FirstName: firstName,
-		LastName:  lastName,
-		Role:      userReq.Role,
-		BranchID:  userReq.BranchID,
-		CreatedAt: time.Now(),
-	}
-
-	log.Printf("=== ATTEMPTING TO INSERT USER ===")
-	log.Printf("User data to insert: %+v", user)
-
-	// Check if user already exists
-	var existingUser User
-	result := db.Where("uuid = ?", user.UUID).First(&existingUser)
-	if result.Error == nil {
-		log.Printf("WARNING: User with UUID %s already exists", user.UUID)
+	log.Printf("=== ATTEMPTING TO REGISTER USER ===")
+
+	// the API's user service creates the staff user, an invited email is left for the trigger
+	users := services.NewUserService(repository.NewUserRepository(db))
+	user, err := users.RegisterStaffSignUp(ctx, models.UserSignUp{
+		CognitoSub: payload.Subject,
+		Email:      payload.Email,
+		FirstName:  firstName,
+		LastName:   lastName,
+		BranchID:   branchID,
+	})
+	if errors.Is(err, models.ErrUserExists) {
+		log.Printf("WARNING: User with email %s already exists", payload.Email)
 		return events.APIGatewayProxyResponse{
 			StatusCode: 409,
 			Headers:    corsHeaders,
-			Body:       fmt.Sprintf(`{"error": "User with UUID %s already exists"}`, user.UUID),
+			Body:       `{"error": "User already exists"}`,
 		}, nil
 	}
-
-	// Insert the user
-	result = db.Create(&user)
-	if result.Error != nil {
-		log.Printf("ERROR: Failed to insert user: %v", result.Error)
+	if err != nil {
+		log.Printf("ERROR: Failed to register user: %v", err)
 		return events.APIGatewayProxyResponse{
 			StatusCode: 500,
 			Headers:    corsHeaders,
-			Body:       fmt.Sprintf(`{"error": "Failed to insert user: %v"}`, result.Error),
+			Body:       fmt.Sprintf(`{"error": "Failed to register user: %v"}`, err),
 		}, nil
 	}
 
-	log.Printf("SUCCESS: User %s (UUID: %s) inserted successfully! Rows affected: %d",
-		userReq.Name, userReq.UUID, result.RowsAffected)
+	log.Printf("SUCCESS: User %s (UUID: %s) registered as %s", userReq.Name, user.UUID, user.Role)
 
 	responseBody := map[string]interface{}{
-		"message":       fmt.Sprintf("User %s added successfully", userReq.Name),
-		"uuid":          userReq.UUID,
-		"rows_affected": result.RowsAffected,
+		"message": fmt.Sprintf("User %s added successfully", userReq.Name),
+		"uuid":    user.UUID,
+		"role":    user.Role,
 	}
 
 	responseJSON, _ := json.Marshal(responseBody)
@@ -278,6 +279,16 @@ func handler(ctx context.Context, request events.APIGatewayProxyRequest) (events
 	}, nil
 }
 
+// bearer reads the Authorization header, API Gateway passes header names through as sent
+func bearer(headers map[string]string) string {
+	for name, value := range headers {
+		if strings.EqualFold(name, "Authorization") {
+			return value
+		}
+	}
+	return ""
+}
+
 func main() {
 	lambda.Start(handler)
 }